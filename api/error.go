@@ -51,6 +51,26 @@ const (
 	InvalidClient  = "InvalidClient"
 	InvalidTask    = "InvalidTask"
 	InvalidProject = "InvalidProject"
+
+	InvalidTimesheetStatus = "InvalidTimesheetStatus"
+	TimesheetApproved      = "TimesheetApproved"
+
+	TimerRunning    = "TimerRunning"
+	TimerNotRunning = "TimerNotRunning"
+
+	TimeInvoiced         = "TimeInvoiced"
+	InvalidInvoice       = "InvalidInvoice"
+	InvalidInvoiceStatus = "InvalidInvoiceStatus"
+	NoBillableTime       = "NoBillableTime"
+
+	InvalidApiKey = "InvalidApiKey"
+
+	InvalidHoliday = "InvalidHoliday"
+
+	InvalidDimension = "InvalidDimension"
+	ReportTooLarge   = "ReportTooLarge"
+
+	InvalidImport = "InvalidImport"
 )
 
 type Error struct {
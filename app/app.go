@@ -7,10 +7,13 @@ import (
 	"github.com/bryanmorgan/time-tracking-api/client"
 	"github.com/bryanmorgan/time-tracking-api/config"
 	"github.com/bryanmorgan/time-tracking-api/database"
+	"github.com/bryanmorgan/time-tracking-api/emails"
+	"github.com/bryanmorgan/time-tracking-api/invoice"
 	"github.com/bryanmorgan/time-tracking-api/logger"
 	"github.com/bryanmorgan/time-tracking-api/middleware"
 	"github.com/bryanmorgan/time-tracking-api/profile"
 	"github.com/bryanmorgan/time-tracking-api/reporting"
+	"github.com/bryanmorgan/time-tracking-api/scheduler"
 	"github.com/bryanmorgan/time-tracking-api/task"
 	"github.com/bryanmorgan/time-tracking-api/timesheet"
 	"github.com/bryanmorgan/time-tracking-api/version"
@@ -28,24 +31,31 @@ import (
 )
 
 type App struct {
-	Router *chi.Mux
-	DB     *sqlx.DB
+	Router    *chi.Mux
+	DB        *sqlx.DB
+	Scheduler *scheduler.Scheduler
 }
 
 func NewApp() *App {
 	config.InitConfig()
 	logger.InitLogger()
+	emails.InitSender()
 	db := database.InitPostgres()
 
 	logger.Log.Info("Application started")
 	return &App{
-		Router: newRouter(db),
-		DB:     db,
+		Router:    newRouter(db),
+		DB:        db,
+		Scheduler: newScheduler(db),
 	}
 }
 
 func (a *App) Run() {
-	runServers(a.Router, a.DB)
+	if a.Scheduler != nil {
+		a.Scheduler.Start()
+	}
+
+	runServers(a.Router, a.DB, a.Scheduler)
 }
 
 func newRouter(db *sqlx.DB) *chi.Mux {
@@ -55,6 +65,7 @@ func newRouter(db *sqlx.DB) *chi.Mux {
 	timeStore := timesheet.NewTimeStore(db)
 	taskStore := task.NewTaskStore(db)
 	reportingStore := reporting.NewReportingStore(db)
+	invoiceStore := invoice.NewInvoiceStore(db)
 
 	// Create API service routers
 	profileRouter := profile.NewRouter(profileStore)
@@ -62,6 +73,7 @@ func newRouter(db *sqlx.DB) *chi.Mux {
 	timeRouter := timesheet.NewRouter(timeStore, profileRouter)
 	taskRouter := task.NewRouter(taskStore, profileRouter)
 	reportingRouter := reporting.NewRouter(reportingStore, profileRouter)
+	invoiceRouter := invoice.NewRouter(invoiceStore, profileRouter)
 
 	r := chi.NewRouter()
 
@@ -86,6 +98,7 @@ func newRouter(db *sqlx.DB) *chi.Mux {
 		r.Mount("/time", timeRouter.Router())
 		r.Mount("/task", taskRouter.Router())
 		r.Mount("/report", reportingRouter.Router())
+		r.Mount("/invoice", invoiceRouter.Router())
 	})
 
 	r.Get("/_ping", middleware.Ping(db))
@@ -97,7 +110,7 @@ func newRouter(db *sqlx.DB) *chi.Mux {
 	return r
 }
 
-func runServers(router *chi.Mux, db *sqlx.DB) {
+func runServers(router *chi.Mux, db *sqlx.DB, jobScheduler *scheduler.Scheduler) {
 	hostname := viper.GetString("application.hostname")
 	port := viper.GetInt("application.port")
 
@@ -150,6 +163,15 @@ func runServers(router *chi.Mux, db *sqlx.DB) {
 		}
 		logger.Log.Info("HTTP Server shutdown")
 
+		// Let running jobs finish before the database is closed
+		if jobScheduler != nil {
+			jobContext, jobCancel := context.WithTimeout(context.Background(), schedulerShutdownTimeout())
+			defer jobCancel()
+			if err := jobScheduler.Stop(jobContext); err != nil {
+				logger.Log.Error("Scheduled jobs did not finish: " + err.Error())
+			}
+		}
+
 		if db != nil {
 			if err := db.Close(); err != nil {
 				logger.Log.Error("Failed to close database: " + err.Error())
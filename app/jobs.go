@@ -0,0 +1,129 @@
+package app
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/bryanmorgan/time-tracking-api/logger"
+	"github.com/bryanmorgan/time-tracking-api/profile"
+	"github.com/bryanmorgan/time-tracking-api/scheduler"
+	"github.com/bryanmorgan/time-tracking-api/timesheet"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/spf13/viper"
+)
+
+const (
+	// Used when scheduler.jobs.purgeLoginAttempts.retentionHours is not set
+	defaultLoginAttemptRetentionHours = 24 * 7
+
+	// Used when scheduler.shutdownTimeout is not set
+	defaultSchedulerShutdownTimeout = 30 * time.Second
+)
+
+// Create the scheduler with the maintenance jobs. Returns nil when scheduler.enabled is false
+func newScheduler(db *sqlx.DB) *scheduler.Scheduler {
+	if !viper.GetBool("scheduler.enabled") {
+		logger.Log.Info("Scheduler disabled")
+		return nil
+	}
+
+	s, err := scheduler.NewScheduler(db)
+	if err != nil {
+		log.Panicf("Failed to create scheduler: %s", err.Error())
+		return nil
+	}
+
+	profileService := profile.NewProfileService(profile.NewProfileAccountStore(db))
+	timeService := timesheet.NewTimeService(timesheet.NewTimeStore(db))
+
+	jobs := []struct {
+		name string
+		run  scheduler.JobFunc
+	}{
+		{"purgeSessions", purgeSessionsJob(profileService)},
+		{"purgeLoginAttempts", purgeLoginAttemptsJob(profileService)},
+		{"purgeForgotPasswordTokens", purgeForgotPasswordTokensJob(profileService)},
+		{"timesheetReminders", timesheetRemindersJob(timeService)},
+	}
+
+	for _, job := range jobs {
+		if _, err := s.Register(job.name, job.run); err != nil {
+			log.Panicf("Failed to schedule job: %s", err.Error())
+			return nil
+		}
+	}
+
+	return s
+}
+
+// How long shutdown waits for running jobs to finish
+func schedulerShutdownTimeout() time.Duration {
+	timeout := viper.GetDuration("scheduler.shutdownTimeout")
+	if timeout <= 0 {
+		return defaultSchedulerShutdownTimeout
+	}
+
+	return timeout
+}
+
+// Delete expired web sessions and API keys
+func purgeSessionsJob(profileService profile.ProfileService) scheduler.JobFunc {
+	return func(ctx context.Context) error {
+		count, appErr := profileService.PurgeExpiredSessions(ctx)
+		if appErr != nil {
+			return appErr
+		}
+
+		logger.Log.Info("Purged expired sessions", logger.Int("deleted", int(count)))
+		return nil
+	}
+}
+
+// Delete failed login attempts older than the retention period. Only attempts in the last
+// session.loginFailureWindowMinutes count towards locking a profile
+func purgeLoginAttemptsJob(profileService profile.ProfileService) scheduler.JobFunc {
+	return func(ctx context.Context) error {
+		retentionHours := viper.GetInt("scheduler.jobs.purgeLoginAttempts.retentionHours")
+		if retentionHours <= 0 {
+			retentionHours = defaultLoginAttemptRetentionHours
+		}
+
+		before := time.Now().Add(-time.Hour * time.Duration(retentionHours))
+		count, appErr := profileService.PurgeLoginAttempts(ctx, before)
+		if appErr != nil {
+			return appErr
+		}
+
+		logger.Log.Info("Purged login attempts", logger.Int("deleted", int(count)))
+		return nil
+	}
+}
+
+// Clear forgot password tokens that can no longer be used
+func purgeForgotPasswordTokensJob(profileService profile.ProfileService) scheduler.JobFunc {
+	return func(ctx context.Context) error {
+		count, appErr := profileService.PurgeForgotPasswordTokens(ctx)
+		if appErr != nil {
+			return appErr
+		}
+
+		logger.Log.Info("Purged forgot password tokens", logger.Int("cleared", int(count)))
+		return nil
+	}
+}
+
+// Remind members about last week's missing time. Runs hourly so each account is reminded at the reminder hour in its
+// own timezone
+func timesheetRemindersJob(timeService timesheet.TimeService) scheduler.JobFunc {
+	return func(ctx context.Context) error {
+		count, appErr := timeService.SendTimesheetReminders(ctx, time.Now())
+		if appErr != nil {
+			return appErr
+		}
+
+		logger.Log.Info("Checked timesheet reminders", logger.Int("accounts", count))
+		return nil
+	}
+}
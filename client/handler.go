@@ -1,9 +1,13 @@
 package client
 
 import (
+	"database/sql"
 	"encoding/json"
+	"fmt"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bryanmorgan/time-tracking-api/api"
@@ -38,25 +42,32 @@ type ProjectRequest struct {
 	Name     string
 }
 
+// Budgets left out of an update are unchanged and a budget of 0 clears it
 type ProjectContainerRequest struct {
-	Id       int
-	ClientId int
-	Name     string
-	Tasks    []TaskRequest
+	Id           int
+	ClientId     int
+	Name         string
+	BudgetHours  *float64
+	BudgetAmount *float64
+	Tasks        []TaskRequest
 }
 
 type TaskRequest struct {
-	Id       int
-	Billable bool
-	Rate     float64
+	Id           int
+	Billable     bool
+	Rate         float64
+	BudgetHours  *float64
+	BudgetAmount *float64
 }
 
 type ProjectTaskResponse struct {
-	TaskId   int     `json:"id"`
-	Name     string  `json:"name"`
-	Rate     float64 `json:"rate,omitempty"`
-	Billable bool    `json:"billable"`
-	Active   bool    `json:"active"`
+	TaskId       int     `json:"id"`
+	Name         string  `json:"name"`
+	Rate         float64 `json:"rate,omitempty"`
+	Billable     bool    `json:"billable"`
+	Active       bool    `json:"active"`
+	BudgetHours  float64 `json:"budgetHours,omitempty"`
+	BudgetAmount float64 `json:"budgetAmount,omitempty"`
 }
 
 type ProjectResponse struct {
@@ -66,14 +77,80 @@ type ProjectResponse struct {
 	ClientId      int                   `json:"clientId,omitempty"`
 	Code          string                `json:"code,omitempty"`
 	ClientName    string                `json:"clientName,omitempty"`
+	BudgetHours   float64               `json:"budgetHours,omitempty"`
+	BudgetAmount  float64               `json:"budgetAmount,omitempty"`
+	BudgetPercent float64               `json:"budgetPercent,omitempty"`
+	BudgetStatus  string                `json:"budgetStatus"`
 	Tasks         []ProjectTaskResponse `json:"tasks,omitempty"`
 }
 
+type BudgetResponse struct {
+	BudgetHours     float64 `json:"budgetHours"`
+	BudgetAmount    float64 `json:"budgetAmount"`
+	SpentHours      float64 `json:"spentHours"`
+	SpentAmount     float64 `json:"spentAmount"`
+	RemainingHours  float64 `json:"remainingHours"`
+	RemainingAmount float64 `json:"remainingAmount"`
+	PercentUsed     float64 `json:"percentUsed"`
+	Status          string  `json:"status"`
+}
+
+type TaskBudgetResponse struct {
+	TaskId   int    `json:"id"`
+	TaskName string `json:"name"`
+	BudgetResponse
+}
+
+type ProjectBudgetResponse struct {
+	ProjectId   int    `json:"id"`
+	ProjectName string `json:"name"`
+	BudgetResponse
+	Tasks []TaskBudgetResponse `json:"tasks,omitempty"`
+}
+
 type StartAndEndDateRequest struct {
 	StartDate string
 	EndDate   string
 }
 
+// Clients to import with their projects and the rates and billable flags of each project's tasks
+type ImportRequest struct {
+	Clients []ImportClientRequest
+}
+
+type ImportClientRequest struct {
+	Name     string
+	Address  string
+	Projects []ImportProjectRequest
+}
+
+type ImportProjectRequest struct {
+	Name  string
+	Code  string
+	Tasks []ImportTaskRequest
+}
+
+type ImportTaskRequest struct {
+	Name     string
+	Rate     *float64
+	Billable *bool
+}
+
+type ImportCountsResponse struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Skipped int `json:"skipped"`
+}
+
+type ImportResponse struct {
+	DryRun       bool                 `json:"dryRun"`
+	Clients      ImportCountsResponse `json:"clients"`
+	Projects     ImportCountsResponse `json:"projects"`
+	Tasks        ImportCountsResponse `json:"tasks"`
+	ProjectTasks ImportCountsResponse `json:"projectTasks"`
+	Errors       []*api.Error         `json:"errors,omitempty"`
+}
+
 func (a *ClientRouter) getClientHandler(w http.ResponseWriter, r *http.Request) {
 	clientIdString := chi.URLParam(r, "clientId")
 	if valid.IsNull(clientIdString) {
@@ -146,13 +223,8 @@ func (a *ClientRouter) createClientHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if valid.IsNull(clientRequest.Name) {
-		api.BadInputs(w, "Missing required client name", api.MissingField, "clientName")
-		return
-	}
-
-	if !valid.IsLength(clientRequest.Name, ClientNameMinLength, ClientNameMaxLength) {
-		api.BadInputs(w, "Client name must be between 1 and 64 characters", api.FieldSize, "clientName")
+	if appErr := validateClientName(clientRequest.Name); appErr != nil {
+		api.ErrorJson(w, appErr, http.StatusBadRequest)
 		return
 	}
 
@@ -303,6 +375,57 @@ func (a *ClientRouter) restoreClientHandler(w http.ResponseWriter, r *http.Reque
 	api.Json(w, r, nil)
 }
 
+// Create or update clients, projects and project tasks from a JSON body or, with a text/csv content type, a CSV file.
+// A dry run returns the counts and errors without saving anything. Otherwise everything is saved, or nothing is if
+// there are errors
+func (a *ClientRouter) importHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Body == nil {
+		api.ErrorJson(w, api.NewError(nil, "Empty Body", api.InvalidImport), http.StatusBadRequest)
+		return
+	}
+	defer api.CloseBody(r.Body)
+
+	dryRun := false
+	if dryRunString := r.URL.Query().Get("dryRun"); !valid.IsNull(dryRunString) {
+		var err error
+		dryRun, err = strconv.ParseBool(dryRunString)
+		if err != nil {
+			api.BadInputs(w, "dryRun must be true or false", api.InvalidField, "dryRun")
+			return
+		}
+	}
+
+	format := ImportFormatJson
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "text/csv" {
+		format = ImportFormatCsv
+	}
+
+	userProfile, ok := r.Context().Value(config.ProfileContextKey).(*profile.Profile)
+	if !ok || userProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid profile context", api.SystemError), http.StatusInternalServerError)
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, ImportMaxBytes)
+	result, appErr := a.clientService.Import(userProfile.AccountId, body, format, dryRun)
+	if appErr != nil {
+		if appErr.Code == api.SystemError {
+			api.ErrorJson(w, appErr, http.StatusInternalServerError)
+		} else {
+			api.ErrorJson(w, appErr, http.StatusBadRequest)
+		}
+		return
+	}
+
+	if !dryRun && len(result.Errors) > 0 {
+		api.ErrorJson(w, api.NewError(nil, fmt.Sprintf("Nothing imported. %d errors found", len(result.Errors)), api.InvalidImport,
+			api.NewErrorDetail("errors", result.Errors)), http.StatusBadRequest)
+		return
+	}
+
+	api.Json(w, r, NewImportResponse(result))
+}
+
 // --- Project
 
 func (a *ClientRouter) getProjectHandler(w http.ResponseWriter, r *http.Request) {
@@ -339,6 +462,33 @@ func (a *ClientRouter) getProjectHandler(w http.ResponseWriter, r *http.Request)
 	api.Json(w, r, NewProjectResponse(project))
 }
 
+func (a *ClientRouter) getProjectBudgetHandler(w http.ResponseWriter, r *http.Request) {
+	projectId, err := strconv.Atoi(chi.URLParam(r, "projectId"))
+	if err != nil || projectId <= 0 {
+		api.ErrorJson(w, api.NewFieldError(err, "Project id not a number", api.InvalidField, "projectId"), http.StatusBadRequest)
+		return
+	}
+
+	userProfile, ok := r.Context().Value(config.ProfileContextKey).(*profile.Profile)
+	if !ok || userProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid profile context", api.SystemError), http.StatusInternalServerError)
+		return
+	}
+
+	budget, serviceErr := a.clientService.GetProjectBudget(projectId, userProfile.AccountId)
+	if serviceErr != nil {
+		api.ErrorJson(w, serviceErr, http.StatusInternalServerError)
+		return
+	}
+
+	if budget == nil {
+		api.ErrorJson(w, api.NewError(nil, "No project data found", api.InvalidProject), http.StatusBadRequest)
+		return
+	}
+
+	api.Json(w, r, NewProjectBudgetResponse(budget))
+}
+
 func (a *ClientRouter) getAllProjectsHandler(w http.ResponseWriter, r *http.Request) {
 	userProfile, ok := r.Context().Value(config.ProfileContextKey).(*profile.Profile)
 	if !ok || userProfile == nil {
@@ -378,8 +528,8 @@ func (a *ClientRouter) createProjectHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	if valid.IsNull(projectRequest.Name) {
-		api.ErrorJson(w, api.NewFieldError(nil, "Missing required name", api.MissingField, "name"), http.StatusBadRequest)
+	if appErr := validateProjectName(projectRequest.Name); appErr != nil {
+		api.ErrorJson(w, appErr, http.StatusBadRequest)
 		return
 	}
 
@@ -388,8 +538,8 @@ func (a *ClientRouter) createProjectHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	if !valid.IsLength(projectRequest.Name, ProjectNameMinLength, ProjectNameMaxLength) {
-		api.ErrorJson(w, api.NewFieldError(nil, "Project name must be between 1 and 128 characters", api.FieldSize, "name"), http.StatusBadRequest)
+	if appErr := validateProjectBudgets(projectRequest); appErr != nil {
+		api.ErrorJson(w, appErr, http.StatusBadRequest)
 		return
 	}
 
@@ -400,7 +550,7 @@ func (a *ClientRouter) createProjectHandler(w http.ResponseWriter, r *http.Reque
 	}
 
 	// Copy request data to Tasks and Project
-	projectTasks := createProjectTaskList(projectRequest.Id, projectRequest.Tasks)
+	projectTasks := createProjectTaskList(projectRequest.Id, projectRequest.Tasks, nil)
 	projectData := Project{
 		Client: Client{
 			ClientId:  projectRequest.ClientId,
@@ -408,6 +558,8 @@ func (a *ClientRouter) createProjectHandler(w http.ResponseWriter, r *http.Reque
 		},
 		ProjectName:   projectRequest.Name,
 		ProjectActive: true,
+		BudgetHours:   requestBudget(projectRequest.BudgetHours, sql.NullFloat64{}),
+		BudgetAmount:  requestBudget(projectRequest.BudgetAmount, sql.NullFloat64{}),
 		Tasks:         projectTasks,
 	}
 
@@ -442,13 +594,29 @@ func (a *ClientRouter) updateProjectHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	if appErr := validateProjectBudgets(projectRequest); appErr != nil {
+		api.ErrorJson(w, appErr, http.StatusBadRequest)
+		return
+	}
+
 	userProfile, ok := r.Context().Value(config.ProfileContextKey).(*profile.Profile)
 	if !ok || userProfile == nil {
 		api.ErrorJson(w, api.NewError(nil, "Invalid profile context", api.SystemError), http.StatusInternalServerError)
 		return
 	}
 
-	projectTasks := createProjectTaskList(projectRequest.Id, projectRequest.Tasks)
+	existingProject, appErr := a.clientService.GetProject(projectRequest.Id, userProfile.AccountId)
+	if appErr != nil {
+		api.ErrorJson(w, appErr, http.StatusInternalServerError)
+		return
+	}
+
+	if existingProject == nil {
+		api.ErrorJson(w, api.NewError(nil, "No project found", api.InvalidProject), http.StatusBadRequest)
+		return
+	}
+
+	projectTasks := createProjectTaskList(projectRequest.Id, projectRequest.Tasks, existingProject.Tasks)
 	updateProject := Project{
 		Client: Client{
 			AccountId: userProfile.AccountId,
@@ -457,6 +625,8 @@ func (a *ClientRouter) updateProjectHandler(w http.ResponseWriter, r *http.Reque
 		ProjectName:   projectRequest.Name,
 		ProjectId:     projectRequest.Id,
 		ProjectActive: true,
+		BudgetHours:   requestBudget(projectRequest.BudgetHours, existingProject.BudgetHours),
+		BudgetAmount:  requestBudget(projectRequest.BudgetAmount, existingProject.BudgetAmount),
 		Tasks:         projectTasks,
 	}
 
@@ -657,6 +827,61 @@ func getProjectIdRequest(r *http.Request) (*ProjectIdRequest, *api.Error) {
 	return &projectIdRequest, nil
 }
 
+// Convert an import request, recording the path of each client, project and task for errors
+func NewClientImports(request *ImportRequest) []*ClientImport {
+	var clients []*ClientImport
+	for i, clientRequest := range request.Clients {
+		client := &ClientImport{
+			Path:    fmt.Sprintf("clients[%d]", i),
+			Name:    strings.TrimSpace(clientRequest.Name),
+			Address: strings.TrimSpace(clientRequest.Address),
+		}
+
+		for j, projectRequest := range clientRequest.Projects {
+			project := &ProjectImport{
+				Path: fmt.Sprintf("%s.projects[%d]", client.Path, j),
+				Name: strings.TrimSpace(projectRequest.Name),
+				Code: strings.TrimSpace(projectRequest.Code),
+			}
+
+			for k, taskRequest := range projectRequest.Tasks {
+				projectTask := &ProjectTaskImport{
+					Path: fmt.Sprintf("%s.tasks[%d]", project.Path, k),
+					Name: strings.TrimSpace(taskRequest.Name),
+				}
+				if taskRequest.Rate != nil {
+					projectTask.Rate = sql.NullFloat64{Float64: *taskRequest.Rate, Valid: true}
+				}
+				if taskRequest.Billable != nil {
+					projectTask.Billable = sql.NullBool{Bool: *taskRequest.Billable, Valid: true}
+				}
+				project.Tasks = append(project.Tasks, projectTask)
+			}
+
+			client.Projects = append(client.Projects, project)
+		}
+
+		clients = append(clients, client)
+	}
+
+	return clients
+}
+
+func NewImportResponse(result *CatalogImport) *ImportResponse {
+	if result == nil {
+		return nil
+	}
+
+	return &ImportResponse{
+		DryRun:       result.DryRun,
+		Clients:      ImportCountsResponse(result.Clients),
+		Projects:     ImportCountsResponse(result.Projects),
+		Tasks:        ImportCountsResponse(result.Tasks),
+		ProjectTasks: ImportCountsResponse(result.ProjectTasks),
+		Errors:       result.Errors,
+	}
+}
+
 func NewClientResponse(clientData *Client) *ClientResponse {
 	if clientData == nil {
 		return nil
@@ -683,6 +908,7 @@ func NewClientsResponse(clients []*Client) []*ClientResponse {
 }
 
 func NewProjectResponse(project *Project) *ProjectResponse {
+	percent := timesheet.BudgetPercentUsed(project.BudgetHours, project.BudgetAmount, project.SpentHours, project.SpentAmount)
 
 	var tasks []ProjectTaskResponse
 	for _, t := range project.Tasks {
@@ -696,18 +922,125 @@ func NewProjectResponse(project *Project) *ProjectResponse {
 		Code:          project.Code.String,
 		ClientId:      project.Client.ClientId,
 		ClientName:    project.Client.ClientName,
+		BudgetHours:   project.BudgetHours.Float64,
+		BudgetAmount:  project.BudgetAmount.Float64,
+		BudgetPercent: percent,
+		BudgetStatus:  string(timesheet.GetBudgetStatus(project.BudgetHours, project.BudgetAmount, percent, timesheet.BudgetAlertThresholds())),
 		Tasks:         tasks,
 	}
 }
 
 func NewProjectTaskResponse(t *task.ProjectTask) ProjectTaskResponse {
 	return ProjectTaskResponse{
-		TaskId:   t.TaskId,
-		Billable: t.Billable,
-		Rate:     t.Rate.Float64,
-		Name:     t.Name,
-		Active:   t.ProjectActive,
+		TaskId:       t.TaskId,
+		Billable:     t.Billable,
+		Rate:         t.Rate.Float64,
+		Name:         t.Name,
+		Active:       t.ProjectActive,
+		BudgetHours:  t.BudgetHours.Float64,
+		BudgetAmount: t.BudgetAmount.Float64,
+	}
+}
+
+func NewProjectBudgetResponse(budget *timesheet.ProjectBudget) *ProjectBudgetResponse {
+	if budget == nil {
+		return nil
+	}
+
+	thresholds := timesheet.BudgetAlertThresholds()
+	var tasks []TaskBudgetResponse
+	for _, t := range budget.Tasks {
+		tasks = append(tasks, TaskBudgetResponse{
+			TaskId:         t.TaskId,
+			TaskName:       t.TaskName,
+			BudgetResponse: newBudgetResponse(t.BudgetHours, t.BudgetAmount, t.SpentHours, t.SpentAmount, t.PercentUsed(), t.Status(thresholds)),
+		})
+	}
+
+	return &ProjectBudgetResponse{
+		ProjectId:      budget.ProjectId,
+		ProjectName:    budget.ProjectName,
+		BudgetResponse: newBudgetResponse(budget.BudgetHours, budget.BudgetAmount, budget.SpentHours, budget.SpentAmount, budget.PercentUsed(), budget.Status(thresholds)),
+		Tasks:          tasks,
+	}
+}
+
+func newBudgetResponse(budgetHours sql.NullFloat64, budgetAmount sql.NullFloat64, spentHours float64, spentAmount float64, percent float64, status timesheet.BudgetStatus) BudgetResponse {
+	response := BudgetResponse{
+		BudgetHours:  budgetHours.Float64,
+		BudgetAmount: budgetAmount.Float64,
+		SpentHours:   spentHours,
+		SpentAmount:  spentAmount,
+		PercentUsed:  percent,
+		Status:       string(status),
+	}
+
+	if budgetHours.Valid {
+		response.RemainingHours = budgetHours.Float64 - spentHours
+	}
+
+	if budgetAmount.Valid {
+		response.RemainingAmount = budgetAmount.Float64 - spentAmount
+	}
+
+	return response
+}
+
+// Check a new client's name. Shared by the create handler and imports
+func validateClientName(name string) *api.Error {
+	if valid.IsNull(name) {
+		return api.NewFieldError(nil, "Missing required client name", api.MissingField, "clientName")
+	}
+
+	if !valid.IsLength(name, ClientNameMinLength, ClientNameMaxLength) {
+		return api.NewFieldError(nil, "Client name must be between 1 and 64 characters", api.FieldSize, "clientName")
+	}
+
+	return nil
+}
+
+// Check a new project's name. Shared by the create handler and imports
+func validateProjectName(name string) *api.Error {
+	if valid.IsNull(name) {
+		return api.NewFieldError(nil, "Missing required name", api.MissingField, "name")
+	}
+
+	if !valid.IsLength(name, ProjectNameMinLength, ProjectNameMaxLength) {
+		return api.NewFieldError(nil, "Project name must be between 1 and 128 characters", api.FieldSize, "name")
 	}
+
+	return nil
+}
+
+func validateProjectBudgets(projectRequest *ProjectContainerRequest) *api.Error {
+	if isNegativeBudget(projectRequest.BudgetHours) {
+		return api.NewFieldError(nil, "Budget hours cannot be negative", api.InvalidField, "budgetHours")
+	}
+
+	if isNegativeBudget(projectRequest.BudgetAmount) {
+		return api.NewFieldError(nil, "Budget amount cannot be negative", api.InvalidField, "budgetAmount")
+	}
+
+	for _, taskRequest := range projectRequest.Tasks {
+		if isNegativeBudget(taskRequest.BudgetHours) || isNegativeBudget(taskRequest.BudgetAmount) {
+			return api.NewFieldError(nil, "Task budgets cannot be negative", api.InvalidField, "tasks")
+		}
+	}
+
+	return nil
+}
+
+func isNegativeBudget(budget *float64) bool {
+	return budget != nil && *budget < 0
+}
+
+// Returns the requested budget, or the existing budget when the request leaves it out. A budget of 0 clears it
+func requestBudget(requested *float64, existing sql.NullFloat64) sql.NullFloat64 {
+	if requested == nil {
+		return existing
+	}
+
+	return valid.ToNullFloat64(*requested)
 }
 
 func NewProjectsResponse(projects []*Project) []*ProjectResponse {
@@ -722,9 +1055,16 @@ func NewProjectsResponse(projects []*Project) []*ProjectResponse {
 	return response
 }
 
-func createProjectTaskList(projectId int, taskRequests []TaskRequest) []task.ProjectTask {
+// Task budgets left out of the request keep the budget of the matching existing task
+func createProjectTaskList(projectId int, taskRequests []TaskRequest, existingTasks []task.ProjectTask) []task.ProjectTask {
+	existingBudgets := make(map[int]task.ProjectTask)
+	for _, existingTask := range existingTasks {
+		existingBudgets[existingTask.TaskId] = existingTask
+	}
+
 	var projectTasks []task.ProjectTask
 	for _, projectTask := range taskRequests {
+		existing := existingBudgets[projectTask.Id]
 		projectTasks = append(projectTasks,
 			task.ProjectTask{
 				Task: task.Task{
@@ -734,6 +1074,8 @@ func createProjectTaskList(projectId int, taskRequests []TaskRequest) []task.Pro
 				Rate:          valid.ToNullFloat64(projectTask.Rate),
 				Billable:      projectTask.Billable,
 				ProjectActive: true,
+				BudgetHours:   requestBudget(projectTask.BudgetHours, existing.BudgetHours),
+				BudgetAmount:  requestBudget(projectTask.BudgetAmount, existing.BudgetAmount),
 			})
 	}
 	return projectTasks
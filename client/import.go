@@ -0,0 +1,664 @@
+package client
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/bryanmorgan/time-tracking-api/api"
+	"github.com/bryanmorgan/time-tracking-api/task"
+	"github.com/bryanmorgan/time-tracking-api/valid"
+)
+
+const (
+	ImportMaxRows  = 10000
+	ImportMaxBytes = 10 << 20
+
+	ImportFormatJson = "json"
+	ImportFormatCsv  = "csv"
+)
+
+// Import CSV columns and the header names accepted for each. Each row is a client, a client and project, or a project
+// task, and rows for the same client or project are merged
+const (
+	importClient   = "client"
+	importAddress  = "address"
+	importProject  = "project"
+	importCode     = "code"
+	importTask     = "task"
+	importRate     = "rate"
+	importBillable = "billable"
+)
+
+var importColumnNames = map[string]string{
+	"client":        importClient,
+	"clientname":    importClient,
+	"address":       importAddress,
+	"clientaddress": importAddress,
+	"project":       importProject,
+	"projectname":   importProject,
+	"code":          importCode,
+	"projectcode":   importCode,
+	"task":          importTask,
+	"taskname":      importTask,
+	"rate":          importRate,
+	"billable":      importBillable,
+}
+
+var TooManyImportRowsError = errors.New("too many import rows")
+
+// Whether an imported record is new, changed or already matches the import. Records used more than once keep the
+// highest state
+const (
+	importSkipped = iota + 1
+	importUpdated
+	importCreated
+)
+
+// A client to create or update with its projects. Row is the CSV line or Path the location in the JSON body and is
+// added to the errors for the client
+type ClientImport struct {
+	Row      int
+	Path     string
+	Name     string
+	Address  string
+	Projects []*ProjectImport
+}
+
+// A project matched by code if it has one, otherwise by name within its client
+type ProjectImport struct {
+	Row   int
+	Path  string
+	Name  string
+	Code  string
+	Tasks []*ProjectTaskImport
+}
+
+// A task assigned to a project. Tasks are matched by name and created if there is no task with the name. A rate or
+// billable flag that isn't set uses the task's default
+type ProjectTaskImport struct {
+	Row      int
+	Path     string
+	Name     string
+	Rate     sql.NullFloat64
+	Billable sql.NullBool
+}
+
+// The account's existing clients, projects, tasks and project tasks that an import is matched to
+type ImportCatalog struct {
+	Clients      []*Client
+	Projects     []*Project
+	Tasks        []*task.Task
+	ProjectTasks []*task.ProjectTask
+}
+
+// The records an import creates or changes, in the order they are saved. Records without an id are created. Projects
+// and project tasks reference their client, project and task so the ids of records created by the import can be used
+type ImportChanges struct {
+	Clients      []*Client
+	Projects     []*ImportProject
+	Tasks        []*task.Task
+	ProjectTasks []*ImportProjectTask
+}
+
+type ImportProject struct {
+	Project *Project
+	Client  *Client
+}
+
+type ImportProjectTask struct {
+	ProjectTask *task.ProjectTask
+	Project     *ImportProject
+	Task        *task.Task
+}
+
+type ImportCounts struct {
+	Created int
+	Updated int
+	Skipped int
+}
+
+// The result of an import. Errors has an error for each invalid field. Nothing is saved for a dry run or when there
+// are errors
+type CatalogImport struct {
+	DryRun       bool
+	Clients      ImportCounts
+	Projects     ImportCounts
+	Tasks        ImportCounts
+	ProjectTasks ImportCounts
+	Changes      *ImportChanges
+	Errors       []*api.Error
+}
+
+// Matches imported clients, projects and tasks to the account's records, creating the ones that don't exist
+type importPlanner struct {
+	accountId    int
+	clients      map[string]*Client
+	projects     map[*Client]map[string]*ImportProject
+	projectCodes map[string]*ImportProject
+	tasks        map[string]*task.Task
+	projectTasks map[*ImportProject]map[*task.Task]*ImportProjectTask
+
+	states   map[interface{}]int
+	order    []interface{}
+	imported map[*ImportProjectTask]bool
+	errors   []*api.Error
+}
+
+// Read an import from a JSON body
+func ParseImportJson(reader io.Reader) ([]*ClientImport, *api.Error) {
+	var request ImportRequest
+	if err := json.NewDecoder(reader).Decode(&request); err != nil {
+		return nil, api.NewError(err, "Invalid JSON", api.InvalidJson)
+	}
+
+	if len(request.Clients) == 0 {
+		return nil, api.NewFieldError(nil, "Import has no clients", api.InvalidImport, "clients")
+	}
+
+	return NewClientImports(&request), nil
+}
+
+// Read an import from a CSV file. The first line is a header naming the columns, in any order. Returns the clients with
+// an error for each rate or billable value that can't be read, or an error if the file can't be read
+func ParseImportCsv(reader io.Reader) ([]*ClientImport, []*api.Error, *api.Error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if err == io.EOF {
+		return nil, nil, api.NewError(nil, "Import file is empty", api.InvalidImport)
+	}
+	if err != nil {
+		return nil, nil, api.NewError(err, "Invalid CSV file", api.InvalidImport)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		// Spreadsheets often save CSV files with a byte order mark
+		name = strings.TrimPrefix(name, "\ufeff")
+		name = strings.NewReplacer(" ", "", "_", "", "-", "").Replace(strings.ToLower(strings.TrimSpace(name)))
+		if column, ok := importColumnNames[name]; ok {
+			if _, duplicate := columns[column]; !duplicate {
+				columns[column] = i
+			}
+		}
+	}
+
+	if _, ok := columns[importClient]; !ok {
+		return nil, nil, api.NewFieldError(nil, "Missing client column", api.InvalidImport, importClient)
+	}
+
+	value := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var clients []*ClientImport
+	var rowErrors []*api.Error
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, nil, api.NewError(err, "Invalid CSV file", api.InvalidImport, api.NewErrorDetail("row", parseErr.StartLine))
+		}
+		if err != nil {
+			return nil, nil, api.NewError(err, "Invalid CSV file", api.InvalidImport)
+		}
+
+		// Blank lines are skipped by the reader, so take the line number from the reader
+		line, _ := csvReader.FieldPos(0)
+
+		if isBlankRecord(record) {
+			continue
+		}
+
+		if len(clients) == ImportMaxRows {
+			return nil, nil, api.NewError(TooManyImportRowsError, fmt.Sprintf("Imports are limited to %d rows", ImportMaxRows), api.InvalidImport)
+		}
+
+		client := &ClientImport{Row: line, Name: value(record, importClient), Address: value(record, importAddress)}
+		clients = append(clients, client)
+
+		project := &ProjectImport{Row: line, Name: value(record, importProject), Code: value(record, importCode)}
+		if project.Name != "" || project.Code != "" {
+			client.Projects = append(client.Projects, project)
+		}
+
+		projectTask := &ProjectTaskImport{Row: line, Name: value(record, importTask)}
+		if projectTask.Name != "" {
+			if len(client.Projects) == 0 {
+				rowErrors = append(rowErrors, importError(api.NewFieldError(nil, "Task "+projectTask.Name+" has no project", api.MissingField, importProject), line, ""))
+			}
+			project.Tasks = append(project.Tasks, projectTask)
+		}
+
+		if rate := value(record, importRate); rate != "" {
+			parsed, err := strconv.ParseFloat(strings.TrimPrefix(rate, "$"), 64)
+			if err != nil {
+				rowErrors = append(rowErrors, importError(api.NewFieldError(err, "Rate must be a number", api.InvalidField, importRate), line, ""))
+			}
+			projectTask.Rate = sql.NullFloat64{Float64: parsed, Valid: err == nil}
+		}
+
+		if billable := value(record, importBillable); billable != "" {
+			parsed, err := parseImportBool(billable)
+			if err != nil {
+				rowErrors = append(rowErrors, importError(api.NewFieldError(err, "Billable must be true or false", api.InvalidField, importBillable), line, ""))
+			}
+			projectTask.Billable = sql.NullBool{Bool: parsed, Valid: err == nil}
+		}
+	}
+
+	if len(clients) == 0 {
+		return nil, nil, api.NewError(nil, "Import file has no rows", api.InvalidImport)
+	}
+
+	return clients, rowErrors, nil
+}
+
+func isBlankRecord(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Spreadsheets export yes and no as well as true and false
+func parseImportBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "yes", "y":
+		return true, nil
+	case "no", "n":
+		return false, nil
+	}
+
+	return strconv.ParseBool(value)
+}
+
+// Check the names and rates of an import with the same rules used to create clients, projects and tasks
+func validateImport(clients []*ClientImport) []*api.Error {
+	var importErrors []*api.Error
+	for _, client := range clients {
+		if appErr := validateClientName(client.Name); appErr != nil {
+			importErrors = append(importErrors, importError(appErr, client.Row, client.Path))
+		}
+
+		for _, project := range client.Projects {
+			if appErr := validateProjectName(project.Name); appErr != nil {
+				importErrors = append(importErrors, importError(appErr, project.Row, project.Path))
+			}
+
+			for _, projectTask := range project.Tasks {
+				if appErr := task.ValidateTaskName(projectTask.Name); appErr != nil {
+					importErrors = append(importErrors, importError(appErr, projectTask.Row, projectTask.Path))
+				}
+
+				if projectTask.Rate.Valid && projectTask.Rate.Float64 < 0 {
+					importErrors = append(importErrors, importError(api.NewFieldError(nil, "Rate cannot be negative", api.InvalidField, importRate), projectTask.Row, projectTask.Path))
+				}
+			}
+		}
+	}
+
+	return importErrors
+}
+
+// Add where the error is in the import: the CSV row or the JSON path
+func importError(appErr *api.Error, row int, path string) *api.Error {
+	if appErr.Detail == nil {
+		appErr.Detail = make(map[string]interface{})
+	}
+
+	if row > 0 {
+		appErr.Detail["row"] = row
+	} else if path != "" {
+		appErr.Detail["path"] = path
+	}
+
+	return appErr
+}
+
+func importKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// The catalog's records are listed active first, so active records are matched before archived records with the
+// same name
+func newImportPlanner(accountId int, catalog *ImportCatalog) *importPlanner {
+	p := &importPlanner{
+		accountId:    accountId,
+		clients:      make(map[string]*Client),
+		projects:     make(map[*Client]map[string]*ImportProject),
+		projectCodes: make(map[string]*ImportProject),
+		tasks:        make(map[string]*task.Task),
+		projectTasks: make(map[*ImportProject]map[*task.Task]*ImportProjectTask),
+		states:       make(map[interface{}]int),
+		imported:     make(map[*ImportProjectTask]bool),
+	}
+
+	clientIds := make(map[int]*Client)
+	for _, client := range catalog.Clients {
+		clientIds[client.ClientId] = client
+		if _, ok := p.clients[importKey(client.ClientName)]; !ok {
+			p.clients[importKey(client.ClientName)] = client
+		}
+	}
+
+	projectIds := make(map[int]*ImportProject)
+	for _, project := range catalog.Projects {
+		if client := clientIds[project.ClientId]; client != nil {
+			projectIds[project.ProjectId] = &ImportProject{Project: project, Client: client}
+			p.addProject(projectIds[project.ProjectId])
+		}
+	}
+
+	taskIds := make(map[int]*task.Task)
+	for _, t := range catalog.Tasks {
+		taskIds[t.TaskId] = t
+		if _, ok := p.tasks[importKey(t.Name)]; !ok {
+			p.tasks[importKey(t.Name)] = t
+		}
+	}
+
+	for _, projectTask := range catalog.ProjectTasks {
+		project, t := projectIds[projectTask.ProjectId], taskIds[projectTask.TaskId]
+		if project != nil && t != nil {
+			p.addProjectTask(&ImportProjectTask{ProjectTask: projectTask, Project: project, Task: t})
+		}
+	}
+
+	return p
+}
+
+// Match every client, project and task in the import, then count and list the records that are created or changed
+func (p *importPlanner) plan(clients []*ClientImport) (*CatalogImport, []*api.Error) {
+	for _, clientImport := range clients {
+		client := p.client(clientImport)
+		for _, projectImport := range clientImport.Projects {
+			project := p.project(client, projectImport)
+			if project == nil {
+				continue
+			}
+
+			for _, projectTaskImport := range projectImport.Tasks {
+				p.projectTask(project, p.task(projectTaskImport), projectTaskImport)
+			}
+		}
+	}
+
+	result := &CatalogImport{Changes: &ImportChanges{}}
+	for _, record := range p.order {
+		state := p.states[record]
+		switch record := record.(type) {
+		case *Client:
+			countImport(&result.Clients, state)
+			if state != importSkipped {
+				result.Changes.Clients = append(result.Changes.Clients, record)
+			}
+		case *ImportProject:
+			countImport(&result.Projects, state)
+			if state != importSkipped {
+				result.Changes.Projects = append(result.Changes.Projects, record)
+			}
+		case *task.Task:
+			countImport(&result.Tasks, state)
+			if state != importSkipped {
+				result.Changes.Tasks = append(result.Changes.Tasks, record)
+			}
+		case *ImportProjectTask:
+			countImport(&result.ProjectTasks, state)
+			if state != importSkipped {
+				result.Changes.ProjectTasks = append(result.Changes.ProjectTasks, record)
+			}
+		}
+	}
+
+	return result, p.errors
+}
+
+func countImport(counts *ImportCounts, state int) {
+	switch state {
+	case importCreated:
+		counts.Created++
+	case importUpdated:
+		counts.Updated++
+	default:
+		counts.Skipped++
+	}
+}
+
+// Record that the import uses a record. The first use sets its order
+func (p *importPlanner) mark(record interface{}, state int) {
+	current, ok := p.states[record]
+	if !ok {
+		p.order = append(p.order, record)
+	}
+
+	if state > current {
+		p.states[record] = state
+	}
+}
+
+// Match a client by name. The address is updated when the import has one and archived clients are restored
+func (p *importPlanner) client(clientImport *ClientImport) *Client {
+	client, ok := p.clients[importKey(clientImport.Name)]
+	if !ok {
+		client = &Client{
+			AccountId:    p.accountId,
+			ClientName:   clientImport.Name,
+			Address:      valid.ToNullString(clientImport.Address),
+			ClientActive: true,
+		}
+		p.clients[importKey(client.ClientName)] = client
+		p.mark(client, importCreated)
+		return client
+	}
+
+	state := importSkipped
+	if clientImport.Address != "" && client.Address.String != clientImport.Address {
+		client.Address = valid.ToNullString(clientImport.Address)
+		state = importUpdated
+	}
+
+	if !client.ClientActive {
+		client.ClientActive = true
+		state = importUpdated
+	}
+
+	p.mark(client, state)
+	return client
+}
+
+// Match a project by code, then by name within the client. A project matched by code takes the imported name, a
+// project matched by name takes the imported code, and archived projects are restored. Returns nil and records an
+// error when the code belongs to another client's project, since projects are never moved between clients
+func (p *importPlanner) project(client *Client, projectImport *ProjectImport) *ImportProject {
+	var project *ImportProject
+	if projectImport.Code != "" {
+		project = p.projectCodes[importKey(projectImport.Code)]
+	}
+	if project != nil && project.Client != client {
+		p.errors = append(p.errors, importError(api.NewFieldError(nil,
+			fmt.Sprintf("Project code %s belongs to project %s of client %s", projectImport.Code, project.Project.ProjectName, project.Client.ClientName),
+			api.InvalidProject, importCode), projectImport.Row, projectImport.Path))
+		return nil
+	}
+	if project == nil {
+		project = p.projects[client][importKey(projectImport.Name)]
+	}
+
+	if project == nil {
+		project = &ImportProject{
+			Project: &Project{
+				Client:        Client{AccountId: p.accountId},
+				ProjectName:   projectImport.Name,
+				Code:          valid.ToNullString(projectImport.Code),
+				ProjectActive: true,
+			},
+			Client: client,
+		}
+		p.addProject(project)
+		p.mark(project, importCreated)
+		return project
+	}
+
+	state := importSkipped
+	if project.Project.ProjectName != projectImport.Name {
+		p.removeProject(project)
+		project.Project.ProjectName = projectImport.Name
+		p.addProject(project)
+		state = importUpdated
+	}
+
+	if projectImport.Code != "" && project.Project.Code.String != projectImport.Code {
+		p.removeProject(project)
+		project.Project.Code = valid.ToNullString(projectImport.Code)
+		p.addProject(project)
+		state = importUpdated
+	}
+
+	if !project.Project.ProjectActive {
+		project.Project.ProjectActive = true
+		state = importUpdated
+	}
+
+	p.mark(project, state)
+	return project
+}
+
+func (p *importPlanner) addProject(project *ImportProject) {
+	projects := p.projects[project.Client]
+	if projects == nil {
+		projects = make(map[string]*ImportProject)
+		p.projects[project.Client] = projects
+	}
+
+	if _, ok := projects[importKey(project.Project.ProjectName)]; !ok {
+		projects[importKey(project.Project.ProjectName)] = project
+	}
+
+	if code := importKey(project.Project.Code.String); code != "" {
+		if _, ok := p.projectCodes[code]; !ok {
+			p.projectCodes[code] = project
+		}
+	}
+}
+
+func (p *importPlanner) removeProject(project *ImportProject) {
+	name := importKey(project.Project.ProjectName)
+	if p.projects[project.Client][name] == project {
+		delete(p.projects[project.Client], name)
+	}
+
+	code := importKey(project.Project.Code.String)
+	if p.projectCodes[code] == project {
+		delete(p.projectCodes, code)
+	}
+}
+
+// Match a task by name. New tasks use the imported rate and billable flag as their defaults. Archived tasks are
+// restored
+func (p *importPlanner) task(projectTaskImport *ProjectTaskImport) *task.Task {
+	t, ok := p.tasks[importKey(projectTaskImport.Name)]
+	if !ok {
+		t = &task.Task{
+			AccountId:       p.accountId,
+			Name:            projectTaskImport.Name,
+			DefaultRate:     projectTaskImport.Rate,
+			DefaultBillable: !projectTaskImport.Billable.Valid || projectTaskImport.Billable.Bool,
+			TaskActive:      true,
+		}
+		p.tasks[importKey(t.Name)] = t
+		p.mark(t, importCreated)
+		return t
+	}
+
+	state := importSkipped
+	if !t.TaskActive {
+		t.TaskActive = true
+		state = importUpdated
+	}
+
+	p.mark(t, state)
+	return t
+}
+
+// Assign a task to a project, or update the rate and billable flag of a task the project already has
+func (p *importPlanner) projectTask(project *ImportProject, t *task.Task, projectTaskImport *ProjectTaskImport) {
+	projectTask := p.projectTasks[project][t]
+	if projectTask != nil && p.imported[projectTask] {
+		p.errors = append(p.errors, importError(api.NewFieldError(nil,
+			fmt.Sprintf("Task %s is imported more than once for project %s", t.Name, project.Project.ProjectName),
+			api.InvalidTask, importTask), projectTaskImport.Row, projectTaskImport.Path))
+		return
+	}
+
+	if projectTask == nil {
+		projectTask = &ImportProjectTask{
+			ProjectTask: &task.ProjectTask{
+				Task:          task.Task{AccountId: p.accountId},
+				Rate:          t.DefaultRate,
+				Billable:      t.DefaultBillable,
+				ProjectActive: true,
+			},
+			Project: project,
+			Task:    t,
+		}
+		if projectTaskImport.Rate.Valid {
+			projectTask.ProjectTask.Rate = projectTaskImport.Rate
+		}
+		if projectTaskImport.Billable.Valid {
+			projectTask.ProjectTask.Billable = projectTaskImport.Billable.Bool
+		}
+
+		p.addProjectTask(projectTask)
+		p.imported[projectTask] = true
+		p.mark(projectTask, importCreated)
+		return
+	}
+
+	state := importSkipped
+	if projectTaskImport.Rate.Valid && projectTask.ProjectTask.Rate != projectTaskImport.Rate {
+		projectTask.ProjectTask.Rate = projectTaskImport.Rate
+		state = importUpdated
+	}
+
+	if projectTaskImport.Billable.Valid && projectTask.ProjectTask.Billable != projectTaskImport.Billable.Bool {
+		projectTask.ProjectTask.Billable = projectTaskImport.Billable.Bool
+		state = importUpdated
+	}
+
+	if !projectTask.ProjectTask.ProjectActive {
+		projectTask.ProjectTask.ProjectActive = true
+		state = importUpdated
+	}
+
+	p.imported[projectTask] = true
+	p.mark(projectTask, state)
+}
+
+func (p *importPlanner) addProjectTask(projectTask *ImportProjectTask) {
+	tasks := p.projectTasks[projectTask.Project]
+	if tasks == nil {
+		tasks = make(map[*task.Task]*ImportProjectTask)
+		p.projectTasks[projectTask.Project] = tasks
+	}
+
+	tasks[projectTask.Task] = projectTask
+}
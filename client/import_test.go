@@ -0,0 +1,196 @@
+package client
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/bryanmorgan/time-tracking-api/api"
+	"github.com/bryanmorgan/time-tracking-api/task"
+)
+
+func TestParseImportCsv(t *testing.T) {
+	t.Parallel()
+
+	file := "\ufeffClient Name,Address,Project,Project Code,Task,Rate,Billable\n" +
+		"\"Acme, Inc.\",1 Main St,Website,WEB,Design,$120,yes\n" +
+		"\"Acme, Inc.\",,Website,WEB,Support,,\n" +
+		"Globex,,,,,,\n" +
+		"\n" +
+		"Globex,,,,Build,abc,maybe\n"
+
+	clients, rowErrors, appErr := ParseImportCsv(strings.NewReader(file))
+	if appErr != nil {
+		t.Fatalf("Failed to parse CSV: [%s]", appErr)
+	}
+
+	if len(clients) != 4 {
+		t.Fatalf("Wrong number of clients: [%d]", len(clients))
+	}
+
+	design := clients[0].Projects[0].Tasks[0]
+	if clients[0].Name != "Acme, Inc." || clients[0].Address != "1 Main St" || clients[0].Projects[0].Code != "WEB" ||
+		design.Name != "Design" || design.Rate.Float64 != 120 || !design.Billable.Valid || !design.Billable.Bool {
+		t.Errorf("Invalid first row: [%+v] [%+v] [%+v]", clients[0], clients[0].Projects[0], design)
+	}
+
+	support := clients[1].Projects[0].Tasks[0]
+	if support.Rate.Valid || support.Billable.Valid {
+		t.Errorf("Empty rate and billable should not be set: [%+v]", support)
+	}
+
+	if len(clients[2].Projects) != 0 || clients[3].Row != 6 {
+		t.Errorf("Invalid client rows: [%+v] [%+v]", clients[2], clients[3])
+	}
+
+	fields := make(map[string]bool)
+	for _, rowError := range rowErrors {
+		if rowError.Detail["row"] != 6 {
+			t.Errorf("Wrong row for error: [%v]", rowError.Detail)
+		}
+		fields[rowError.Detail["field"].(string)] = true
+	}
+
+	if len(rowErrors) != 3 || !fields[importProject] || !fields[importRate] || !fields[importBillable] {
+		t.Errorf("Wrong row errors: [%v]", fields)
+	}
+
+	testCases := []struct {
+		name string
+		file string
+	}{
+		{"Empty", ""},
+		{"No rows", "client,project\n\n"},
+		{"Missing client column", "project,task\nWebsite,Design\n"},
+	}
+
+	for _, testCase := range testCases {
+		if _, _, appErr := ParseImportCsv(strings.NewReader(testCase.file)); appErr == nil || appErr.Code != api.InvalidImport {
+			t.Errorf("%s: expected an import error, got [%v]", testCase.name, appErr)
+		}
+	}
+}
+
+func TestPlanImport(t *testing.T) {
+	t.Parallel()
+
+	catalog := &ImportCatalog{
+		Clients: []*Client{{ClientId: 1, AccountId: 1, ClientName: "Acme", ClientActive: true}},
+		Projects: []*Project{{
+			Client:        Client{ClientId: 1, AccountId: 1},
+			ProjectId:     10,
+			ProjectName:   "Website",
+			Code:          sql.NullString{String: "WEB", Valid: true},
+			ProjectActive: true,
+		}},
+		Tasks: []*task.Task{
+			{TaskId: 100, AccountId: 1, Name: "Design", DefaultBillable: true, TaskActive: true},
+			{TaskId: 101, AccountId: 1, Name: "Support", TaskActive: false},
+		},
+		ProjectTasks: []*task.ProjectTask{{
+			Task:          task.Task{TaskId: 100, AccountId: 1},
+			ProjectId:     10,
+			Rate:          sql.NullFloat64{Float64: 100, Valid: true},
+			Billable:      true,
+			ProjectActive: true,
+		}},
+	}
+
+	clients := []*ClientImport{
+		{Path: "clients[0]", Name: "acme", Address: "1 Main St", Projects: []*ProjectImport{{
+			Path: "clients[0].projects[0]",
+			Name: "Website Redesign",
+			Code: "web",
+			Tasks: []*ProjectTaskImport{
+				{Name: "DESIGN", Rate: sql.NullFloat64{Float64: 100, Valid: true}},
+				{Name: "Support", Rate: sql.NullFloat64{Float64: 80, Valid: true}},
+			},
+		}}},
+		{Path: "clients[1]", Name: "Globex", Projects: []*ProjectImport{{
+			Name:  "App",
+			Tasks: []*ProjectTaskImport{{Name: "Build", Billable: sql.NullBool{Bool: false, Valid: true}}},
+		}}},
+		{Path: "clients[2]", Name: "Globex", Projects: []*ProjectImport{{
+			Name:  "app",
+			Tasks: []*ProjectTaskImport{{Path: "clients[2].projects[0].tasks[0]", Name: "build"}},
+		}}},
+	}
+
+	result, planErrors := newImportPlanner(1, catalog).plan(clients)
+
+	expected := map[string][2]ImportCounts{
+		"clients":      {result.Clients, {Created: 1, Updated: 1}},
+		"projects":     {result.Projects, {Created: 1, Updated: 1}},
+		"tasks":        {result.Tasks, {Created: 1, Updated: 1, Skipped: 1}},
+		"projectTasks": {result.ProjectTasks, {Created: 2, Skipped: 1}},
+	}
+	for name, counts := range expected {
+		if counts[0] != counts[1] {
+			t.Errorf("Wrong %s counts: [%+v] expected [%+v]", name, counts[0], counts[1])
+		}
+	}
+
+	if len(planErrors) != 1 || planErrors[0].Code != api.InvalidTask || planErrors[0].Detail["path"] != "clients[2].projects[0].tasks[0]" {
+		t.Errorf("Expected a duplicate task error: [%v]", planErrors)
+	}
+
+	changes := result.Changes
+	if len(changes.Clients) != 2 || changes.Clients[0].Address.String != "1 Main St" || changes.Clients[1].ClientId != 0 {
+		t.Errorf("Invalid client changes: [%+v]", changes.Clients)
+	}
+
+	if len(changes.Projects) != 2 || changes.Projects[0].Project.ProjectName != "Website Redesign" || changes.Projects[0].Project.Code.String != "web" ||
+		changes.Projects[1].Client != changes.Clients[1] {
+		t.Errorf("Invalid project changes: [%+v]", changes.Projects)
+	}
+
+	if len(changes.Tasks) != 2 || !changes.Tasks[0].TaskActive || changes.Tasks[1].Name != "Build" || changes.Tasks[1].DefaultBillable {
+		t.Errorf("Invalid task changes: [%+v]", changes.Tasks)
+	}
+
+	if len(changes.ProjectTasks) != 2 || changes.ProjectTasks[0].ProjectTask.Rate.Float64 != 80 || changes.ProjectTasks[1].ProjectTask.Billable {
+		t.Errorf("Invalid project task changes: [%+v]", changes.ProjectTasks)
+	}
+}
+
+func TestPlanImportCodeOfAnotherClient(t *testing.T) {
+	t.Parallel()
+
+	catalog := &ImportCatalog{
+		Clients: []*Client{
+			{ClientId: 1, AccountId: 1, ClientName: "Acme", ClientActive: true},
+			{ClientId: 2, AccountId: 1, ClientName: "Globex", ClientActive: true},
+		},
+		Projects: []*Project{{
+			Client:        Client{ClientId: 1, AccountId: 1},
+			ProjectId:     10,
+			ProjectName:   "Website",
+			Code:          sql.NullString{String: "WEB", Valid: true},
+			ProjectActive: true,
+		}},
+	}
+
+	clients := []*ClientImport{
+		{Path: "clients[0]", Name: "Globex", Projects: []*ProjectImport{{
+			Path:  "clients[0].projects[0]",
+			Name:  "Globex Website",
+			Code:  "web",
+			Tasks: []*ProjectTaskImport{{Name: "Design"}},
+		}}},
+	}
+
+	result, planErrors := newImportPlanner(1, catalog).plan(clients)
+
+	if len(planErrors) != 1 || planErrors[0].Code != api.InvalidProject || planErrors[0].Detail["path"] != "clients[0].projects[0]" ||
+		planErrors[0].Detail["field"] != importCode {
+		t.Errorf("Expected a project code error: [%v]", planErrors)
+	}
+
+	if len(result.Changes.Projects) != 0 || len(result.Changes.Tasks) != 0 || len(result.Changes.ProjectTasks) != 0 {
+		t.Errorf("Project of another client should not change: [%+v]", result.Changes)
+	}
+
+	if catalog.Projects[0].ProjectName != "Website" {
+		t.Errorf("Project was renamed: [%s]", catalog.Projects[0].ProjectName)
+	}
+}
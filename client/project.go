@@ -12,6 +12,10 @@ type Project struct {
 	ProjectName   string             `json:"-" db:"project_name"`
 	Code          sql.NullString     `json:"-"`
 	ProjectActive bool               `json:"-" db:"project_active"`
+	BudgetHours   sql.NullFloat64    `json:"-" db:"budget_hours"`
+	BudgetAmount  sql.NullFloat64    `json:"-" db:"budget_amount"`
+	SpentHours    float64            `json:"-" db:"spent_hours"`
+	SpentAmount   float64            `json:"-" db:"spent_amount"`
 	Tasks         []task.ProjectTask `json:"-"`
 }
 
@@ -30,28 +30,51 @@ func (a *ClientRouter) Router() *chi.Mux {
 		r.Use(a.profileRouter.ValidateSessionHandler)
 
 		// Client
-		r.Get("/{clientId}", a.getClientHandler)
-		r.Get("/all", a.getAllClientsHandler)
-		r.Get("/archived", a.getArchivedClientsHandler)
-		r.Post("/", a.createClientHandler)
-		r.Put("/", a.updateClientHandler)
-		r.Put("/archive", a.archiveClientHandler)
-		r.Put("/restore", a.restoreClientHandler)
-		r.Delete("/", a.deleteClientHandler)
+		r.Group(func(r chi.Router) {
+			r.Use(a.profileRouter.RolePermissionHandler(profile.AllRoles...))
+
+			r.Get("/{clientId}", a.getClientHandler)
+			r.Get("/all", a.getAllClientsHandler)
+			r.Get("/archived", a.getArchivedClientsHandler)
+		})
+
+		// Only admins manage clients
+		r.Group(func(r chi.Router) {
+			r.Use(a.profileRouter.AdminPermissionHandler)
+
+			r.Post("/", a.createClientHandler)
+			r.Put("/", a.updateClientHandler)
+			r.Put("/archive", a.archiveClientHandler)
+			r.Put("/restore", a.restoreClientHandler)
+			r.Delete("/", a.deleteClientHandler)
+
+			r.Post("/import", a.importHandler)
+		})
 
 		// Project
 		r.Route("/project", func(r chi.Router) {
-			r.Get("/{projectId}", a.getProjectHandler)
-			r.Get("/all", a.getAllProjectsHandler)
-			r.Get("/archived", a.getArchivedProjectsHandler)
-			r.Post("/", a.createProjectHandler)
-			r.Put("/", a.updateProjectHandler)
-			r.Put("/archive", a.archiveProjectHandler)
-			r.Put("/restore", a.restoreProjectHandler)
-			r.Delete("/", a.deleteProjectHandler)
-			r.Post("/copy/last/week", a.copyProjectsFromLastWeek)
-		})
+			r.Group(func(r chi.Router) {
+				r.Use(a.profileRouter.RolePermissionHandler(profile.AllRoles...))
+
+				r.Get("/{projectId}", a.getProjectHandler)
+				r.Get("/all", a.getAllProjectsHandler)
+				r.Get("/archived", a.getArchivedProjectsHandler)
+				r.Post("/copy/last/week", a.copyProjectsFromLastWeek)
+			})
+
+			r.With(a.profileRouter.RolePermissionHandler(profile.ReportingRoles...)).Get("/{projectId}/budget", a.getProjectBudgetHandler)
 
+			// Only admins manage projects
+			r.Group(func(r chi.Router) {
+				r.Use(a.profileRouter.AdminPermissionHandler)
+
+				r.Post("/", a.createProjectHandler)
+				r.Put("/", a.updateProjectHandler)
+				r.Put("/archive", a.archiveProjectHandler)
+				r.Put("/restore", a.restoreProjectHandler)
+				r.Delete("/", a.deleteProjectHandler)
+			})
+		})
 	})
 
 	return r
@@ -1,10 +1,13 @@
 package client
 
 import (
+	"fmt"
+	"io"
 	"time"
 
 	"github.com/bryanmorgan/time-tracking-api/api"
 	"github.com/bryanmorgan/time-tracking-api/database"
+	"github.com/bryanmorgan/time-tracking-api/logger"
 	"github.com/bryanmorgan/time-tracking-api/timesheet"
 	"github.com/bryanmorgan/time-tracking-api/valid"
 )
@@ -30,7 +33,11 @@ type ClientService interface {
 	DeleteClient(clientId int, accountId int) *api.Error
 	DeleteProject(projectId int, accountId int) *api.Error
 
+	GetProjectBudget(projectId int, accountId int) (*timesheet.ProjectBudget, *api.Error)
+
 	CopyProjectsFromDateRanges(profileId int, accountId int, fromStart time.Time, fromEnd time.Time, toStart time.Time, toEnd time.Time) ([]*timesheet.TimeEntry, *api.Error)
+
+	Import(accountId int, reader io.Reader, format string, dryRun bool) (*CatalogImport, *api.Error)
 }
 
 type ClientResource struct {
@@ -185,6 +192,15 @@ func (c *ClientResource) DeleteProject(projectId int, accountId int) *api.Error
 	return nil
 }
 
+func (c *ClientResource) GetProjectBudget(projectId int, accountId int) (*timesheet.ProjectBudget, *api.Error) {
+	budget, err := c.timeStore.GetProjectBudget(projectId, accountId)
+	if err != nil {
+		return nil, api.NewError(err, "Could not get project budget", api.SystemError)
+	}
+
+	return budget, nil
+}
+
 func (c *ClientResource) CopyProjectsFromDateRanges(profileId int, accountId int, fromStart time.Time, fromEnd time.Time, toStart time.Time, toEnd time.Time) ([]*timesheet.TimeEntry, *api.Error) {
 	var timeEntries []*timesheet.TimeEntry
 	var serviceErr error
@@ -204,3 +220,42 @@ func (c *ClientResource) CopyProjectsFromDateRanges(profileId int, accountId int
 
 	return timeEntries, nil
 }
+
+// Import clients with their projects and project tasks in the JSON or CSV format. Returns the counts of records that
+// are created, updated and skipped, and an error for each invalid field. Nothing is saved for a dry run or if there
+// are errors
+func (c *ClientResource) Import(accountId int, reader io.Reader, format string, dryRun bool) (*CatalogImport, *api.Error) {
+	var clients []*ClientImport
+	var importErrors []*api.Error
+	var appErr *api.Error
+	if format == ImportFormatCsv {
+		clients, importErrors, appErr = ParseImportCsv(reader)
+	} else {
+		clients, appErr = ParseImportJson(reader)
+	}
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	importErrors = append(importErrors, validateImport(clients)...)
+
+	catalog, err := c.store.GetImportCatalog(accountId)
+	if err != nil {
+		return nil, api.NewError(err, "Failed to get clients, projects and tasks", api.SystemError)
+	}
+
+	result, planErrors := newImportPlanner(accountId, catalog).plan(clients)
+	result.DryRun = dryRun
+	result.Errors = append(importErrors, planErrors...)
+	if dryRun || len(result.Errors) > 0 {
+		return result, nil
+	}
+
+	if err := c.store.SaveImport(accountId, result.Changes); err != nil {
+		return nil, api.NewError(err, "Failed to save import", api.SystemError)
+	}
+
+	logger.Log.Info(fmt.Sprintf("Imported into account %d: %d clients, %d projects, %d tasks and %d project tasks created or updated",
+		accountId, len(result.Changes.Clients), len(result.Changes.Projects), len(result.Changes.Tasks), len(result.Changes.ProjectTasks)))
+	return result, nil
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/bryanmorgan/time-tracking-api/valid"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 // Compile Only: ensure interface is implemented
@@ -38,6 +39,9 @@ type ClientStore interface {
 	DeleteProject(projectId int, accountId int) error
 
 	CopyProjectsFromDateRanges(profileId int, accountId int, fromStart time.Time, fromEnd time.Time, toStart time.Time, toEnd time.Time) (bool, error)
+
+	GetImportCatalog(accountId int) (*ImportCatalog, error)
+	SaveImport(accountId int, changes *ImportChanges) error
 }
 
 // ProfileData implements database operations for user profiles
@@ -223,7 +227,14 @@ func (c *ClientData) DeleteClient(clientId int, accountId int) error {
 
 func (c *ClientData) GetProject(projectId int, accountId int) (*Project, error) {
 	projectSql := `
-	SELECT p.project_id, p.account_id, p.project_active, code, p.project_name,
+	SELECT p.project_id, p.account_id, p.project_active, code, p.project_name, p.budget_hours, p.budget_amount,
+		   COALESCE((SELECT sum(t.hours) FROM time t WHERE t.project_id = p.project_id), 0) AS spent_hours,
+		   COALESCE((SELECT sum(t.hours * COALESCE(pt.rate, 0))
+		             FROM time t, project_task pt
+		             WHERE t.project_id = p.project_id
+		               AND pt.project_id = t.project_id
+		               AND pt.task_id = t.task_id
+		               AND pt.billable), 0) AS spent_amount,
 		   c.client_id, c.client_name
 	FROM project p,
          client c
@@ -272,7 +283,14 @@ func (c *ClientData) GetProject(projectId int, accountId int) (*Project, error)
 
 func (c *ClientData) GetAllProjects(accountId int, active bool) ([]*Project, error) {
 	projectSql := `
-	SELECT p.project_id, p.account_id, p.project_active, code, p.project_name,
+	SELECT p.project_id, p.account_id, p.project_active, code, p.project_name, p.budget_hours, p.budget_amount,
+		   COALESCE((SELECT sum(t.hours) FROM time t WHERE t.project_id = p.project_id), 0) AS spent_hours,
+		   COALESCE((SELECT sum(t.hours * COALESCE(pt.rate, 0))
+		             FROM time t, project_task pt
+		             WHERE t.project_id = p.project_id
+		               AND pt.project_id = t.project_id
+		               AND pt.task_id = t.task_id
+		               AND pt.billable), 0) AS spent_amount,
 		   c.client_id, c.client_name
 	FROM project p, client c
 	WHERE p.account_id=$1
@@ -352,17 +370,24 @@ func (c *ClientData) CreateProject(newProject *Project) (int, error) {
 	}
 
 	projectSql := `
-		INSERT INTO project (account_id, client_id, project_name, code, project_active)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO project (account_id, client_id, project_name, code, project_active, budget_hours, budget_amount)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING project_id`
 
 	var projectId int
-	err = c.db.QueryRow(projectSql, newProject.Client.AccountId, newProject.Client.ClientId, newProject.ProjectName, newProject.Code, newProject.ProjectActive).Scan(&projectId)
+	err = c.db.QueryRow(projectSql,
+		newProject.Client.AccountId,
+		newProject.Client.ClientId,
+		newProject.ProjectName,
+		newProject.Code,
+		newProject.ProjectActive,
+		newProject.BudgetHours,
+		newProject.BudgetAmount).Scan(&projectId)
 	if err != nil {
 		return 0, err
 	}
 
-	taskSql := `INSERT INTO project_task (project_id, task_id, account_id, rate, billable, project_active) VALUES ($1, $2, $3, $4, $5, $6)`
+	taskSql := `INSERT INTO project_task (project_id, task_id, account_id, rate, billable, project_active, budget_hours, budget_amount) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
 	for _, taskData := range newProject.Tasks {
 		result, err := c.db.Exec(taskSql,
 			projectId,
@@ -370,7 +395,9 @@ func (c *ClientData) CreateProject(newProject *Project) (int, error) {
 			newProject.Client.AccountId,
 			taskData.Rate,
 			taskData.Billable,
-			taskData.ProjectActive)
+			taskData.ProjectActive,
+			taskData.BudgetHours,
+			taskData.BudgetAmount)
 
 		if err != nil {
 			return 0, err
@@ -406,9 +433,27 @@ func (c *ClientData) UpdateProject(updateProject *Project) error {
 		return errors.New("invalid project id for account")
 	}
 
-	sqlStatement := `UPDATE project SET project_name=$1, client_id=$2, project_active=$3 WHERE project_id=$4`
-
-	result, err := c.db.Exec(sqlStatement, updateProject.ProjectName, updateProject.ClientId, updateProject.ProjectActive, updateProject.ProjectId)
+	// A changed budget resets the alert threshold so alerts are sent again for the new budget
+	sqlStatement := `
+	UPDATE project
+	SET project_name=$1,
+	    client_id=$2,
+	    project_active=$3,
+	    budget_alert_percent = CASE
+	                             WHEN budget_hours IS DISTINCT FROM $5 OR budget_amount IS DISTINCT FROM $6 THEN 0
+	                             ELSE budget_alert_percent
+	                           END,
+	    budget_hours=$5,
+	    budget_amount=$6
+	WHERE project_id=$4`
+
+	result, err := c.db.Exec(sqlStatement,
+		updateProject.ProjectName,
+		updateProject.ClientId,
+		updateProject.ProjectActive,
+		updateProject.ProjectId,
+		updateProject.BudgetHours,
+		updateProject.BudgetAmount)
 	if err != nil {
 		return err
 	}
@@ -422,20 +467,38 @@ func (c *ClientData) UpdateProject(updateProject *Project) error {
 		return database.NoRowAffectedError
 	}
 
-	// Delete all tasks from this project
+	taskIds := make([]int64, 0, len(updateProject.Tasks))
+	for _, taskData := range updateProject.Tasks {
+		taskIds = append(taskIds, int64(taskData.TaskId))
+	}
+
+	// Delete the tasks no longer in this project
 	deleteSql := `
 	DELETE FROM project_task
 	WHERE project_id=$1
-      AND account_id = $2`
-	result, err = c.db.Exec(deleteSql, updateProject.ProjectId, updateProject.AccountId)
+      AND account_id = $2
+      AND NOT (task_id = ANY($3))`
+	result, err = c.db.Exec(deleteSql, updateProject.ProjectId, updateProject.AccountId, pq.Int64Array(taskIds))
 	if err != nil {
 		return err
 	}
 
-	// Add all tasks for this project
+	// Add or update all tasks for this project. Like the project, a changed task budget resets its alert threshold
 	taskSql := `INSERT INTO project_task 
-			    (project_id, account_id, task_id, rate, billable, project_active) 
-				VALUES ($1, $2, $3, $4, $5, $6)`
+			    (project_id, account_id, task_id, rate, billable, project_active, budget_hours, budget_amount) 
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+				ON CONFLICT (project_id, task_id)
+				DO UPDATE SET rate = EXCLUDED.rate,
+				              billable = EXCLUDED.billable,
+				              project_active = EXCLUDED.project_active,
+				              budget_alert_percent = CASE
+				                                       WHEN project_task.budget_hours IS DISTINCT FROM EXCLUDED.budget_hours
+				                                         OR project_task.budget_amount IS DISTINCT FROM EXCLUDED.budget_amount THEN 0
+				                                       ELSE project_task.budget_alert_percent
+				                                     END,
+				              budget_hours = EXCLUDED.budget_hours,
+				              budget_amount = EXCLUDED.budget_amount
+				WHERE project_task.account_id = EXCLUDED.account_id`
 	for _, taskData := range updateProject.Tasks {
 		result, err := c.db.Exec(taskSql,
 			updateProject.ProjectId,
@@ -444,6 +507,8 @@ func (c *ClientData) UpdateProject(updateProject *Project) error {
 			taskData.Rate,
 			taskData.Billable,
 			taskData.ProjectActive,
+			taskData.BudgetHours,
+			taskData.BudgetAmount,
 		)
 		if err != nil {
 			return err
@@ -580,3 +645,185 @@ func (c *ClientData) CopyProjectsFromDateRanges(profileId int, accountId int, fr
 	// Found prior entries and successfully inserted them
 	return true, nil
 }
+
+// Get all the account's clients, projects, tasks and project tasks, including archived ones. Active records are listed
+// first
+func (c *ClientData) GetImportCatalog(accountId int) (*ImportCatalog, error) {
+	clientSql := `
+	SELECT client_id, account_id, client_name, address, client_active
+	FROM client
+	WHERE account_id = $1
+	ORDER BY client_active DESC, client_id`
+
+	projectSql := `
+	SELECT project_id, account_id, client_id, project_name, code, project_active
+	FROM project
+	WHERE account_id = $1
+	ORDER BY project_active DESC, project_id`
+
+	taskSql := `
+	SELECT task_id, account_id, task_name, default_rate, default_billable, common, task_active
+	FROM task
+	WHERE account_id = $1
+	ORDER BY task_active DESC, task_id`
+
+	projectTaskSql := `
+	SELECT project_id, task_id, account_id, rate, billable, project_active
+	FROM project_task
+	WHERE account_id = $1`
+
+	catalog := ImportCatalog{}
+	if err := c.db.Select(&catalog.Clients, clientSql, accountId); err != nil {
+		return nil, err
+	}
+
+	if err := c.db.Select(&catalog.Projects, projectSql, accountId); err != nil {
+		return nil, err
+	}
+
+	if err := c.db.Select(&catalog.Tasks, taskSql, accountId); err != nil {
+		return nil, err
+	}
+
+	if err := c.db.Select(&catalog.ProjectTasks, projectTaskSql, accountId); err != nil {
+		return nil, err
+	}
+
+	return &catalog, nil
+}
+
+// Save an import's changes in a single transaction. Clients are saved first, then projects, tasks and project tasks,
+// so each can use the ids of the records created before it
+func (c *ClientData) SaveImport(accountId int, changes *ImportChanges) error {
+	tx, err := c.db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	if err := saveImportChanges(tx, accountId, changes); err != nil {
+		database.RollbackTransaction(tx.Tx)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Log.Error("Error in commit transaction: " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func saveImportChanges(tx *sqlx.Tx, accountId int, changes *ImportChanges) error {
+	for _, client := range changes.Clients {
+		if client.ClientId == 0 {
+			insertSql := `
+			INSERT INTO client (account_id, client_name, address, client_active)
+			VALUES ($1, $2, $3, TRUE)
+			RETURNING client_id`
+
+			if err := tx.QueryRow(insertSql, accountId, client.ClientName, client.Address).Scan(&client.ClientId); err != nil {
+				return err
+			}
+			continue
+		}
+
+		updateSql := `UPDATE client SET address=$1, client_active=$2 WHERE client_id=$3 AND account_id=$4`
+		if err := execImportUpdate(tx, updateSql, client.Address, client.ClientActive, client.ClientId, accountId); err != nil {
+			return err
+		}
+	}
+
+	for _, project := range changes.Projects {
+		project.Project.ClientId = project.Client.ClientId
+		if project.Project.ProjectId == 0 {
+			insertSql := `
+			INSERT INTO project (account_id, client_id, project_name, code, project_active)
+			VALUES ($1, $2, $3, $4, TRUE)
+			RETURNING project_id`
+
+			err := tx.QueryRow(insertSql, accountId, project.Project.ClientId, project.Project.ProjectName, project.Project.Code).Scan(&project.Project.ProjectId)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		updateSql := `
+		UPDATE project SET project_name=$1, code=$2, project_active=$3
+		WHERE project_id=$4
+		  AND client_id=$5
+		  AND account_id=$6`
+		err := execImportUpdate(tx, updateSql,
+			project.Project.ProjectName,
+			project.Project.Code,
+			project.Project.ProjectActive,
+			project.Project.ProjectId,
+			project.Project.ClientId,
+			accountId)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, t := range changes.Tasks {
+		if t.TaskId == 0 {
+			insertSql := `
+			INSERT INTO task (account_id, task_name, common, default_rate, default_billable)
+			VALUES ($1, $2, FALSE, $3, $4)
+			RETURNING task_id`
+
+			if err := tx.QueryRow(insertSql, accountId, t.Name, t.DefaultRate, t.DefaultBillable).Scan(&t.TaskId); err != nil {
+				return err
+			}
+			continue
+		}
+
+		updateSql := `UPDATE task SET task_active=$1 WHERE task_id=$2 AND account_id=$3`
+		if err := execImportUpdate(tx, updateSql, t.TaskActive, t.TaskId, accountId); err != nil {
+			return err
+		}
+	}
+
+	// Project tasks are keyed by project and task, so an upsert both adds and updates them
+	projectTaskSql := `
+	INSERT INTO project_task (project_id, task_id, account_id, rate, billable, project_active)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (project_id, task_id)
+	DO UPDATE SET rate=EXCLUDED.rate, billable=EXCLUDED.billable, project_active=EXCLUDED.project_active`
+
+	for _, projectTask := range changes.ProjectTasks {
+		projectTask.ProjectTask.ProjectId = projectTask.Project.Project.ProjectId
+		projectTask.ProjectTask.TaskId = projectTask.Task.TaskId
+
+		_, err := tx.Exec(projectTaskSql,
+			projectTask.ProjectTask.ProjectId,
+			projectTask.ProjectTask.TaskId,
+			accountId,
+			projectTask.ProjectTask.Rate,
+			projectTask.ProjectTask.Billable,
+			projectTask.ProjectTask.ProjectActive)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func execImportUpdate(tx *sqlx.Tx, sqlStatement string, args ...interface{}) error {
+	result, err := tx.Exec(sqlStatement, args...)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return database.NoRowAffectedError
+	}
+
+	return nil
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bryanmorgan/time-tracking-api/api"
+	"github.com/bryanmorgan/time-tracking-api/client"
+	"github.com/bryanmorgan/time-tracking-api/config"
+	"github.com/bryanmorgan/time-tracking-api/database"
+	"github.com/bryanmorgan/time-tracking-api/logger"
+	"github.com/bryanmorgan/time-tracking-api/profile"
+	"github.com/bryanmorgan/time-tracking-api/task"
+	"github.com/bryanmorgan/time-tracking-api/timesheet"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	accountUsage = `Usage: timetrack account create -email <email> -company <name> -first <name> -last <name> [-timezone <zone>] [-password <password>]
+
+A password is generated and printed when -password is not given.`
+
+	userUsage = `Usage: timetrack user <command> -email <email>
+
+Commands:
+  reset-password [-password <password>]  Set a new password, sign out the profile's web sessions and revoke its
+                                         API keys. A password is generated and printed when -password is not given
+  unlock                                 Clear the lock and failed login attempts for the profile`
+
+	sessionsUsage = `Usage: timetrack sessions purge
+
+Deletes the web sessions and API keys that have expired.`
+
+	seedUsage = `Usage: timetrack seed [-example]
+
+Adds the ping row used by /_ping. With -example also creates the example account
+for test@example.com with a client, project and tasks.`
+
+	// Length in bytes of the random data encoded into a generated password
+	generatedPasswordBytes = 12
+)
+
+// Example account created by seed -example. This matches database/example-data.sql
+var exampleAccount = profile.AccountRequest{
+	FirstName: "Time",
+	LastName:  "Traveler",
+	Email:     "test@example.com",
+	Password:  "12345678",
+	Timezone:  "America/Los_Angeles",
+	Company:   "ACME",
+}
+
+// Load the config for GO_ENV and connect to the database for a command run outside the server
+func initCommand() *sqlx.DB {
+	config.InitConfig()
+	logger.InitLogger()
+	return database.Connect()
+}
+
+func newFlagSet(name string, usage string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, usage)
+	}
+
+	return flags
+}
+
+func printError(action string, err error) int {
+	fmt.Fprintf(os.Stderr, "%s failed: %s\n", action, err.Error())
+	return 1
+}
+
+func generatePassword() (string, error) {
+	b := make([]byte, generatedPasswordBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func accountCommand(args []string) int {
+	if len(args) == 0 || args[0] != "create" {
+		fmt.Fprintln(os.Stderr, accountUsage)
+		return 2
+	}
+
+	var request profile.AccountRequest
+	flags := newFlagSet("account create", accountUsage)
+	flags.StringVar(&request.Email, "email", "", "owner email")
+	flags.StringVar(&request.Company, "company", "", "company name")
+	flags.StringVar(&request.FirstName, "first", "", "owner first name")
+	flags.StringVar(&request.LastName, "last", "", "owner last name")
+	flags.StringVar(&request.Timezone, "timezone", "", "account timezone")
+	flags.StringVar(&request.Password, "password", "", "owner password")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	generated := request.Password == ""
+	if generated {
+		password, err := generatePassword()
+		if err != nil {
+			return printError("Generate password", err)
+		}
+		request.Password = password
+	}
+
+	if appErr := profile.ValidateAccountRequest(&request); appErr != nil {
+		fmt.Fprintln(os.Stderr, appErr.Message)
+		return 2
+	}
+
+	db := initCommand()
+	defer db.Close()
+
+	profileService := profile.NewProfileService(profile.NewProfileAccountStore(db))
+	account, owner, appErr := profileService.Create(&request)
+	if appErr != nil {
+		return printError("Create account", appErr)
+	}
+
+	fmt.Printf("Created account %d (%s) with owner %s (profile %d)\n", account.AccountId, account.Company, owner.Email, owner.ProfileId)
+	if generated {
+		fmt.Println("Password: " + request.Password)
+	}
+
+	return 0
+}
+
+func userCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, userUsage)
+		return 2
+	}
+
+	var email, password string
+	flags := newFlagSet("user "+args[0], userUsage)
+	flags.StringVar(&email, "email", "", "profile email")
+	if args[0] == "reset-password" {
+		flags.StringVar(&password, "password", "", "new password")
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	if email == "" {
+		fmt.Fprintln(os.Stderr, userUsage)
+		return 2
+	}
+
+	switch args[0] {
+	case "reset-password":
+		generated := password == ""
+		if generated {
+			var err error
+			password, err = generatePassword()
+			if err != nil {
+				return printError("Generate password", err)
+			}
+		}
+
+		db := initCommand()
+		defer db.Close()
+
+		profileService := profile.NewProfileService(profile.NewProfileAccountStore(db))
+		if appErr := profileService.ResetPassword(email, password); appErr != nil {
+			return printError("Reset password", appErr)
+		}
+
+		fmt.Println("Reset password for " + email)
+		if generated {
+			fmt.Println("Password: " + password)
+		}
+
+	case "unlock":
+		db := initCommand()
+		defer db.Close()
+
+		profileService := profile.NewProfileService(profile.NewProfileAccountStore(db))
+		if appErr := profileService.UnlockProfile(email); appErr != nil {
+			return printError("Unlock", appErr)
+		}
+
+		fmt.Println("Unlocked " + email)
+
+	default:
+		fmt.Fprintln(os.Stderr, userUsage)
+		return 2
+	}
+
+	return 0
+}
+
+func sessionsCommand(args []string) int {
+	if len(args) != 1 || args[0] != "purge" {
+		fmt.Fprintln(os.Stderr, sessionsUsage)
+		return 2
+	}
+
+	db := initCommand()
+	defer db.Close()
+
+	profileService := profile.NewProfileService(profile.NewProfileAccountStore(db))
+	count, appErr := profileService.PurgeExpiredSessions(context.Background())
+	if appErr != nil {
+		return printError("Purge sessions", appErr)
+	}
+
+	fmt.Printf("Deleted %d expired sessions\n", count)
+	return 0
+}
+
+func seedCommand(args []string) int {
+	var example bool
+	flags := newFlagSet("seed", seedUsage)
+	flags.BoolVar(&example, "example", false, "create the example account")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	db := initCommand()
+	defer db.Close()
+
+	added, err := database.SeedPing(db)
+	if err != nil {
+		return printError("Seed ping", err)
+	}
+
+	if added {
+		fmt.Println("Added ping row")
+	}
+
+	if !example {
+		return 0
+	}
+
+	if appErr := seedExampleAccount(db); appErr != nil {
+		return printError("Seed example account", appErr)
+	}
+
+	return 0
+}
+
+// Create the example account through the services so it has the same defaults as one created through the API
+func seedExampleAccount(db *sqlx.DB) *api.Error {
+	profileStore := profile.NewProfileAccountStore(db)
+	existing, err := profileStore.GetByEmail(exampleAccount.Email)
+	if err != nil {
+		return api.NewError(err, "Failed to find profile by email", api.SystemError)
+	}
+
+	if existing != nil {
+		fmt.Println("Example account already exists for " + exampleAccount.Email)
+		return nil
+	}
+
+	request := exampleAccount
+	account, _, appErr := profile.NewProfileService(profileStore).Create(&request)
+	if appErr != nil {
+		return appErr
+	}
+
+	clientService := client.NewClientService(client.NewClientStore(db), timesheet.NewTimeStore(db))
+	exampleClient, appErr := clientService.CreateClient(account.AccountId, "Apple Inc", "1 Infinite Loop Cupertino, CA")
+	if appErr != nil {
+		return appErr
+	}
+
+	_, appErr = clientService.CreateProject(&client.Project{
+		Client:        *exampleClient,
+		ProjectName:   "iPhone Launch",
+		ProjectActive: true,
+	})
+	if appErr != nil {
+		return appErr
+	}
+
+	taskService := task.NewTaskService(task.NewTaskStore(db))
+	if _, appErr = taskService.SaveTask(account.AccountId, "Development", true, 100, true); appErr != nil {
+		return appErr
+	}
+
+	if _, appErr = taskService.SaveTask(account.AccountId, "Design", true, 90, false); appErr != nil {
+		return appErr
+	}
+
+	fmt.Printf("Created example account %d for %s with password %s\n", account.AccountId, exampleAccount.Email, exampleAccount.Password)
+	return nil
+}
@@ -2,7 +2,7 @@
 CREATE DATABASE timetracker;
 CREATE USER timetraveler WITH PASSWORD  'timetraveler_changeme';
 
--- Then run: "psql timetracker < schema1.sql"
+-- Then run: "make migrate" to create the schema
 
 -- Then run: "psql timetracker" with the following:
 GRANT ALL PRIVILEGES ON ALL TABLES IN SCHEMA public TO timetraveler;
@@ -0,0 +1,277 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"github.com/bryanmorgan/time-tracking-api/logger"
+)
+
+// Migrations are embedded SQL files named <version>_<name>.up.sql and <version>_<name>.down.sql
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Key for the Postgres advisory lock held while migrating so only one server migrates at a time
+const migrationLockKey = 7234001
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Migration
+	Applied pq.NullTime
+}
+
+// Returns the embedded migrations ordered by version
+func Migrations() ([]*Migration, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d has two names: %s and %s", version, migration.Name, match[2])
+		}
+
+		content, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	var migrations []*Migration
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Apply every migration that has not been applied. Returns the number of migrations applied
+func MigrateUp(db *sqlx.DB) (int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = withMigrationLock(db, func() error {
+		applied, err := appliedMigrations(db)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+
+			if err := applyMigration(db, migration, migration.Up, true); err != nil {
+				return err
+			}
+			count++
+		}
+
+		return nil
+	})
+
+	return count, err
+}
+
+// Revert the most recently applied migrations. Returns the number of migrations reverted
+func MigrateDown(db *sqlx.DB, steps int) (int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = withMigrationLock(db, func() error {
+		applied, err := appliedMigrations(db)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && count < steps; i-- {
+			migration := migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+
+			if err := applyMigration(db, migration, migration.Down, false); err != nil {
+				return err
+			}
+			count++
+		}
+
+		return nil
+	})
+
+	return count, err
+}
+
+// Returns every embedded migration and when it was applied
+func GetMigrationStatus(db *sqlx.DB) ([]*MigrationStatus, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := createMigrationTable(db); err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var status []*MigrationStatus
+	for _, migration := range migrations {
+		migrationStatus := &MigrationStatus{Migration: *migration}
+		if appliedTime, ok := applied[migration.Version]; ok {
+			migrationStatus.Applied = pq.NullTime{Time: appliedTime, Valid: true}
+		}
+		status = append(status, migrationStatus)
+	}
+
+	return status, nil
+}
+
+func createMigrationTable(db *sqlx.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations
+		(
+			version INT PRIMARY KEY,
+			name    TEXT        NOT NULL,
+			applied TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`)
+	return err
+}
+
+func appliedMigrations(db *sqlx.DB) (map[int]time.Time, error) {
+	rows, err := db.Queryx("SELECT version, applied FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer CloseRows(rows)
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedTime time.Time
+		if err := rows.Scan(&version, &appliedTime); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedTime
+	}
+
+	return applied, rows.Err()
+}
+
+// Run the migration SQL and record it in schema_migrations in a single transaction
+func applyMigration(db *sqlx.DB, migration *Migration, statements string, up bool) error {
+	start := time.Now()
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(statements); err != nil {
+		RollbackTransaction(tx)
+		return fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+	}
+
+	if up {
+		_, err = tx.Exec("INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", migration.Version, migration.Name)
+	} else {
+		_, err = tx.Exec("DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+	}
+
+	if err != nil {
+		RollbackTransaction(tx)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	direction := "up"
+	if !up {
+		direction = "down"
+	}
+
+	logger.Log.Info("Migrated "+direction,
+		logger.Int("version", migration.Version),
+		logger.String("name", migration.Name),
+		logger.Duration("duration", time.Since(start)))
+
+	return nil
+}
+
+// Hold a session advisory lock while migrating so servers starting together do not apply the same migration
+func withMigrationLock(db *sqlx.DB, migrate func() error) error {
+	if err := createMigrationTable(db); err != nil {
+		return err
+	}
+
+	conn, err := db.Connx(context.Background())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+		return err
+	}
+
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockKey); err != nil {
+			logger.Log.Error("Failed to release migration lock: " + err.Error())
+		}
+	}()
+
+	return migrate()
+}
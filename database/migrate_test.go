@@ -0,0 +1,29 @@
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMigrations(t *testing.T) {
+	t.Parallel()
+
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %s", err)
+	}
+
+	if len(migrations) == 0 {
+		t.Fatalf("No migrations found")
+	}
+
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			t.Errorf("Migration %s has version %d, wanted %d", migration.Name, migration.Version, i+1)
+		}
+
+		if strings.TrimSpace(migration.Up) == "" || strings.TrimSpace(migration.Down) == "" {
+			t.Errorf("Migration %d_%s has an empty up or down file", migration.Version, migration.Name)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS time;
+DROP TABLE IF EXISTS project_task;
+DROP TABLE IF EXISTS task;
+DROP TABLE IF EXISTS project;
+DROP TABLE IF EXISTS client;
+DROP TABLE IF EXISTS ping;
+DROP TABLE IF EXISTS login_attempts;
+DROP TABLE IF EXISTS session;
+DROP TABLE IF EXISTS account;
+DROP TABLE IF EXISTS profile_account;
+DROP TABLE IF EXISTS profile;
@@ -1,4 +1,5 @@
--- Time Tracking PostgreSQL Schema
+-- Time Tracking PostgreSQL Schema, as in the original schema-1.sql. Tables and indexes are created only if missing so
+-- databases built from schema-1.sql can be brought under migrations with "timetrack migrate up"
 
 CREATE TABLE IF NOT EXISTS profile
 (
@@ -56,9 +57,9 @@ CREATE TABLE IF NOT EXISTS session
     type             TEXT        NOT NULL DEFAULT 'web'
 );
 
-CREATE INDEX session_profile_idx ON session (profile_id);
-CREATE INDEX token_expiration_idx ON session (token_expiration);
-CREATE INDEX session_type_idx ON session (type);
+CREATE INDEX IF NOT EXISTS session_profile_idx ON session (profile_id);
+CREATE INDEX IF NOT EXISTS token_expiration_idx ON session (token_expiration);
+CREATE INDEX IF NOT EXISTS session_type_idx ON session (type);
 
 CREATE TABLE IF NOT EXISTS login_attempts
 (
@@ -68,11 +69,11 @@ CREATE TABLE IF NOT EXISTS login_attempts
     ip_address         INET        NOT NULL
 );
 
-CREATE INDEX login_attempts_idx ON login_attempts (email, login_attempt_time);
-CREATE INDEX ip_address_idx ON login_attempts (ip_address);
+CREATE INDEX IF NOT EXISTS login_attempts_idx ON login_attempts (email, login_attempt_time);
+CREATE INDEX IF NOT EXISTS ip_address_idx ON login_attempts (ip_address);
 
 -- Table used by ping requests to validate database connection. Should only contain 1 row with an id of 1
-CREATE TABLE ping (ping_id SMALLSERIAL PRIMARY KEY);
+CREATE TABLE IF NOT EXISTS ping (ping_id SMALLSERIAL PRIMARY KEY);
 
 CREATE TABLE IF NOT EXISTS client
 (
@@ -83,7 +84,7 @@ CREATE TABLE IF NOT EXISTS client
     client_active BOOLEAN NOT NULL DEFAULT TRUE
 );
 
-CREATE INDEX client_account_idx ON client (account_id);
+CREATE INDEX IF NOT EXISTS client_account_idx ON client (account_id);
 
 
 CREATE TABLE IF NOT EXISTS project
@@ -96,7 +97,7 @@ CREATE TABLE IF NOT EXISTS project
     project_active BOOLEAN NOT NULL DEFAULT TRUE
 );
 
-CREATE INDEX project_account_idx ON project (account_id);
+CREATE INDEX IF NOT EXISTS project_account_idx ON project (account_id);
 
 
 CREATE TABLE IF NOT EXISTS task
@@ -110,7 +111,7 @@ CREATE TABLE IF NOT EXISTS task
     task_active      BOOLEAN        NOT NULL DEFAULT TRUE
 );
 
-CREATE INDEX task_account_idx ON task (account_id);
+CREATE INDEX IF NOT EXISTS task_account_idx ON task (account_id);
 
 
 CREATE TABLE IF NOT EXISTS project_task
@@ -124,7 +125,7 @@ CREATE TABLE IF NOT EXISTS project_task
     PRIMARY KEY (project_id, task_id)
 );
 
-CREATE INDEX project_task_account_idx ON project_task (account_id);
+CREATE INDEX IF NOT EXISTS project_task_account_idx ON project_task (account_id);
 
 
 CREATE TABLE IF NOT EXISTS time
@@ -140,7 +141,7 @@ CREATE TABLE IF NOT EXISTS time
     PRIMARY KEY (account_id, project_id, task_id, profile_id, day)
 );
 
-CREATE INDEX time_profile_idx ON time (account_id, profile_id, day DESC);
-CREATE INDEX time_task_idx ON time (account_id, task_id);
+CREATE INDEX IF NOT EXISTS time_profile_idx ON time (account_id, profile_id, day DESC);
+CREATE INDEX IF NOT EXISTS time_task_idx ON time (account_id, task_id);
 
 
@@ -0,0 +1,28 @@
+DROP TABLE IF EXISTS invoice_line;
+DROP TABLE IF EXISTS invoice;
+DROP TABLE IF EXISTS invoice_sequence;
+DROP TABLE IF EXISTS timer;
+DROP TABLE IF EXISTS timesheet;
+DROP INDEX IF EXISTS time_invoice_idx;
+ALTER TABLE time DROP COLUMN IF EXISTS updated_by;
+ALTER TABLE time DROP COLUMN IF EXISTS invoice_id;
+ALTER TABLE project_task DROP COLUMN IF EXISTS budget_alert_percent;
+ALTER TABLE project_task DROP COLUMN IF EXISTS budget_amount;
+ALTER TABLE project_task DROP COLUMN IF EXISTS budget_hours;
+ALTER TABLE project DROP COLUMN IF EXISTS budget_alert_percent;
+ALTER TABLE project DROP COLUMN IF EXISTS budget_amount;
+ALTER TABLE project DROP COLUMN IF EXISTS budget_hours;
+ALTER TABLE session DROP COLUMN IF EXISTS scopes;
+ALTER TABLE session DROP COLUMN IF EXISTS key_name;
+ALTER TABLE session DROP COLUMN IF EXISTS user_agent;
+ALTER TABLE session DROP COLUMN IF EXISTS ip_address;
+ALTER TABLE session DROP COLUMN IF EXISTS last_used;
+ALTER TABLE session DROP COLUMN IF EXISTS session_id;
+ALTER TABLE account DROP COLUMN IF EXISTS timer_rounding_mode;
+ALTER TABLE account DROP COLUMN IF EXISTS timer_rounding;
+ALTER TABLE account DROP COLUMN IF EXISTS email_logo_url;
+ALTER TABLE account DROP COLUMN IF EXISTS email_signature;
+ALTER TABLE profile_account DROP COLUMN IF EXISTS invitation_expiration;
+ALTER TABLE profile_account DROP COLUMN IF EXISTS invitation_token;
+ALTER TABLE profile_account DROP COLUMN IF EXISTS invited;
+ALTER TABLE profile DROP COLUMN IF EXISTS language;
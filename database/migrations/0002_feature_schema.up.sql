@@ -0,0 +1,117 @@
+-- Columns and tables added after the original schema-1.sql. Everything is added only if missing so databases
+-- already created by an earlier version of the first migration are unchanged
+ALTER TABLE profile ADD COLUMN IF NOT EXISTS language TEXT NOT NULL DEFAULT 'en';
+
+-- Last time an invitation email was sent to a new user, and the setup token sent. Each account that invites the user
+-- has its own token
+ALTER TABLE profile_account ADD COLUMN IF NOT EXISTS invited TIMESTAMPTZ NULL;
+ALTER TABLE profile_account ADD COLUMN IF NOT EXISTS invitation_token TEXT NULL UNIQUE;
+ALTER TABLE profile_account ADD COLUMN IF NOT EXISTS invitation_expiration TIMESTAMPTZ NULL;
+
+-- Email branding. NULL uses the email signature and logo in the config
+ALTER TABLE account ADD COLUMN IF NOT EXISTS email_signature TEXT NULL;
+ALTER TABLE account ADD COLUMN IF NOT EXISTS email_logo_url TEXT NULL;
+
+-- Timer rounding: increment in minutes (0 = no rounding) and direction (nearest, up, down)
+ALTER TABLE account ADD COLUMN IF NOT EXISTS timer_rounding SMALLINT NOT NULL DEFAULT 0;
+ALTER TABLE account ADD COLUMN IF NOT EXISTS timer_rounding_mode TEXT NOT NULL DEFAULT 'nearest';
+
+ALTER TABLE session ADD COLUMN IF NOT EXISTS session_id SERIAL UNIQUE;
+ALTER TABLE session ADD COLUMN IF NOT EXISTS last_used TIMESTAMPTZ NULL;
+ALTER TABLE session ADD COLUMN IF NOT EXISTS ip_address TEXT NULL;
+ALTER TABLE session ADD COLUMN IF NOT EXISTS user_agent TEXT NULL;
+
+-- API keys (type 'api') store a hash of the key as the token
+ALTER TABLE session ADD COLUMN IF NOT EXISTS key_name TEXT NULL;
+ALTER TABLE session ADD COLUMN IF NOT EXISTS scopes TEXT[] NULL;
+
+-- Optional hour and money budgets and the highest alert threshold percent already emailed
+ALTER TABLE project ADD COLUMN IF NOT EXISTS budget_hours NUMERIC(12, 2) NULL;
+ALTER TABLE project ADD COLUMN IF NOT EXISTS budget_amount NUMERIC(12, 2) NULL;
+ALTER TABLE project ADD COLUMN IF NOT EXISTS budget_alert_percent SMALLINT NOT NULL DEFAULT 0;
+
+ALTER TABLE project_task ADD COLUMN IF NOT EXISTS budget_hours NUMERIC(12, 2) NULL;
+ALTER TABLE project_task ADD COLUMN IF NOT EXISTS budget_amount NUMERIC(12, 2) NULL;
+ALTER TABLE project_task ADD COLUMN IF NOT EXISTS budget_alert_percent SMALLINT NOT NULL DEFAULT 0;
+
+-- Set once the time is invoiced and can no longer be edited
+ALTER TABLE time ADD COLUMN IF NOT EXISTS invoice_id INT NULL;
+-- Profile that last changed the entry, e.g. an admin editing on behalf of the owner
+ALTER TABLE time ADD COLUMN IF NOT EXISTS updated_by INT NULL;
+
+CREATE INDEX IF NOT EXISTS time_invoice_idx ON time (invoice_id);
+
+
+CREATE TABLE IF NOT EXISTS timesheet
+(
+    account_id       INT         NOT NULL,
+    profile_id       INT         NOT NULL,
+    start_date       DATE        NOT NULL,
+    timesheet_status TEXT        NOT NULL DEFAULT 'draft',
+    reject_reason    TEXT        NULL,
+    submitted        TIMESTAMPTZ NULL,
+    submitted_by     INT         NULL,
+    reviewed         TIMESTAMPTZ NULL,
+    reviewed_by      INT         NULL,
+    updated          TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (account_id, profile_id, start_date)
+);
+
+CREATE INDEX IF NOT EXISTS timesheet_status_idx ON timesheet (account_id, timesheet_status);
+
+
+CREATE TABLE IF NOT EXISTS timer
+(
+    timer_id   SERIAL PRIMARY KEY,
+    account_id INT         NOT NULL,
+    profile_id INT         NOT NULL,
+    project_id INT         NOT NULL,
+    task_id    INT         NOT NULL,
+    notes      TEXT        NULL,
+    started    TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Only 1 running timer per profile
+CREATE UNIQUE INDEX IF NOT EXISTS timer_profile_idx ON timer (profile_id);
+
+
+-- Last invoice number used for each account
+CREATE TABLE IF NOT EXISTS invoice_sequence
+(
+    account_id  INT PRIMARY KEY,
+    last_number INT NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS invoice
+(
+    invoice_id     SERIAL PRIMARY KEY,
+    account_id     INT            NOT NULL,
+    client_id      INT            NOT NULL,
+    invoice_number INT            NOT NULL,
+    invoice_status TEXT           NOT NULL DEFAULT 'draft',
+    from_date      DATE           NOT NULL,
+    to_date        DATE           NOT NULL,
+    client_name    TEXT           NOT NULL,
+    bill_to        TEXT           NULL,
+    total          NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    created_by     INT            NOT NULL,
+    created        TIMESTAMPTZ    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated        TIMESTAMPTZ    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (account_id, invoice_number)
+);
+
+CREATE INDEX IF NOT EXISTS invoice_client_idx ON invoice (account_id, client_id);
+
+
+CREATE TABLE IF NOT EXISTS invoice_line
+(
+    invoice_id   INT            NOT NULL,
+    project_id   INT            NOT NULL,
+    task_id      INT            NOT NULL,
+    project_name TEXT           NOT NULL,
+    task_name    TEXT           NOT NULL,
+    hours        NUMERIC(12, 2) NOT NULL,
+    rate         NUMERIC(12, 2) NOT NULL,
+    amount       NUMERIC(12, 2) NOT NULL,
+    PRIMARY KEY (invoice_id, project_id, task_id)
+);
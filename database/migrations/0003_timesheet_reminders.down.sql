@@ -0,0 +1,3 @@
+ALTER TABLE account DROP COLUMN IF EXISTS timesheet_reminder_week;
+ALTER TABLE profile DROP COLUMN IF EXISTS timesheet_digest;
+ALTER TABLE profile DROP COLUMN IF EXISTS timesheet_reminders;
@@ -0,0 +1,8 @@
+-- Profiles can opt out of missing timesheet reminder emails
+ALTER TABLE profile ADD COLUMN IF NOT EXISTS timesheet_reminders BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- Owners and admins can opt out of the missing timesheet digest separately
+ALTER TABLE profile ADD COLUMN IF NOT EXISTS timesheet_digest BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- Start date of the last week the account was sent timesheet reminders for
+ALTER TABLE account ADD COLUMN IF NOT EXISTS timesheet_reminder_week DATE NULL;
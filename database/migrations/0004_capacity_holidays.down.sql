@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS holiday;
+ALTER TABLE profile_account DROP COLUMN IF EXISTS capacity_hours;
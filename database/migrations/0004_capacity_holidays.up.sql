@@ -0,0 +1,12 @@
+-- Hours each member is expected to work in a week in the account, used for utilization
+ALTER TABLE profile_account ADD COLUMN IF NOT EXISTS capacity_hours NUMERIC(5, 2) NOT NULL DEFAULT 40;
+
+-- Account holidays reduce the capacity of the week they fall in
+CREATE TABLE IF NOT EXISTS holiday
+(
+    holiday_id   SERIAL PRIMARY KEY,
+    account_id   INT  NOT NULL,
+    day          DATE NOT NULL,
+    holiday_name TEXT NOT NULL,
+    UNIQUE (account_id, day)
+);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS time_report_idx;
@@ -0,0 +1,2 @@
+-- Matches the keyset order of the time entries report so each page is an index range scan
+CREATE INDEX IF NOT EXISTS time_report_idx ON time (account_id, day, profile_id, project_id, task_id);
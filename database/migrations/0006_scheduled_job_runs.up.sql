@@ -0,0 +1,6 @@
+-- Scheduled time of the last run of each scheduler job, so replicas with skewed clocks run each scheduled time once
+CREATE TABLE IF NOT EXISTS scheduled_job
+(
+    job_name TEXT PRIMARY KEY,
+    last_run TIMESTAMPTZ NOT NULL
+);
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -16,11 +17,31 @@ import (
 
 var NoRowAffectedError = errors.New("no rows affected")
 
+// Rows fetched from a cursor at a time by StreamRows
+const CursorFetchSize = 500
+
 func timeTrack(start time.Time, name string) {
 	logger.Log.Info(name, logger.Duration("duration", time.Since(start)))
 }
 
+// Connect to Postgres and, when postgres.migrateOnStart is set, apply any pending schema migrations
 func InitPostgres() *sqlx.DB {
+	db := Connect()
+
+	if viper.GetBool("postgres.migrateOnStart") {
+		count, err := MigrateUp(db)
+		if err != nil {
+			log.Panicf("Database migration failed: %s", err.Error())
+			return nil
+		}
+
+		logger.Log.Info("Postgres Migrations", logger.Int("applied", count))
+	}
+
+	return db
+}
+
+func Connect() *sqlx.DB {
 	start := time.Now()
 	defer timeTrack(start, "Postgres Startup")
 
@@ -82,3 +103,46 @@ func RollbackTransaction(tx *sql.Tx) {
 		logger.Log.Error("Failed to rollback transaction: " + err.Error())
 	}
 }
+
+// Run the query through a server side cursor in a read only transaction, calling fn for each row. Only CursorFetchSize
+// rows are read at a time so large results use constant memory. Stops at the first error from the query or from fn
+func StreamRows(db *sqlx.DB, query string, args []interface{}, fn func(rows *sqlx.Rows) error) error {
+	tx, err := db.BeginTxx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+
+	// The cursor is closed when the transaction ends
+	defer RollbackTransaction(tx.Tx)
+
+	if _, err := tx.Exec("DECLARE stream_cursor NO SCROLL CURSOR FOR "+query, args...); err != nil {
+		return err
+	}
+
+	fetchSql := fmt.Sprintf("FETCH FORWARD %d FROM stream_cursor", CursorFetchSize)
+	for {
+		rows, err := tx.Queryx(fetchSql)
+		if err != nil {
+			return err
+		}
+
+		count := 0
+		for rows.Next() {
+			count++
+			if err := fn(rows); err != nil {
+				CloseRows(rows)
+				return err
+			}
+		}
+
+		err = rows.Err()
+		CloseRows(rows)
+		if err != nil {
+			return err
+		}
+
+		if count < CursorFetchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package database
+
+import (
+	"github.com/jmoiron/sqlx"
+)
+
+// The ping table holds a single row with an id of 1
+func SeedPing(db *sqlx.DB) (bool, error) {
+	result, err := db.Exec(`INSERT INTO ping (ping_id) VALUES (1) ON CONFLICT DO NOTHING`)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows > 0, nil
+}
@@ -0,0 +1,79 @@
+package emails
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// OutboxSender keeps sent messages in memory for local development and tests. When a directory is set each message is
+// also written to it as an .eml file
+type OutboxSender struct {
+	directory string
+	mutex     sync.Mutex
+	messages  []*Message
+}
+
+func NewOutboxSender(directory string) *OutboxSender {
+	return &OutboxSender{
+		directory: directory,
+	}
+}
+
+func (o *OutboxSender) Send(message *Message) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.directory != "" {
+		body, err := message.Bytes()
+		if err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(o.directory, 0755); err != nil {
+			return err
+		}
+
+		name := fmt.Sprintf("%s-%04d.eml", time.Now().UTC().Format("20060102T150405"), len(o.messages)+1)
+		if err := os.WriteFile(filepath.Join(o.directory, name), body, 0644); err != nil {
+			return err
+		}
+	}
+
+	o.messages = append(o.messages, message)
+	return nil
+}
+
+// Returns the messages sent to the email address, oldest first
+func (o *OutboxSender) MessagesTo(email string) []*Message {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	var messages []*Message
+	for _, message := range o.messages {
+		for _, to := range message.To {
+			if to.Address == email {
+				messages = append(messages, message)
+				break
+			}
+		}
+	}
+
+	return messages
+}
+
+func (o *OutboxSender) Messages() []*Message {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	return append([]*Message{}, o.messages...)
+}
+
+func (o *OutboxSender) Clear() {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.messages = nil
+}
@@ -0,0 +1,17 @@
+package emails
+
+import (
+	"fmt"
+)
+
+func SendBudgetAlertEmail(to Recipient, branding Branding, projectName string, threshold int, percentUsed float64, projectUrl string) error {
+	data := &BudgetAlertData{
+		Layout:      NewLayout(to.Name, branding),
+		ProjectName: projectName,
+		Threshold:   threshold,
+		PercentUsed: fmt.Sprintf("%0.0f%%", percentUsed),
+		ProjectUrl:  projectUrl,
+	}
+
+	return send(BudgetAlertTemplate, "Budget Alert", to, data)
+}
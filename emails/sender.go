@@ -0,0 +1,190 @@
+package emails
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/bryanmorgan/time-tracking-api/logger"
+)
+
+// Email drivers selected with email.driver
+const (
+	SendGridDriver = "sendgrid"
+	SmtpDriver     = "smtp"
+	OutboxDriver   = "outbox"
+)
+
+type Address struct {
+	Name    string
+	Address string
+}
+
+type Message struct {
+	From       Address
+	To         []Address
+	Subject    string
+	Text       string
+	Html       string
+	Categories []string
+}
+
+// Sender delivers email messages
+type Sender interface {
+	Send(message *Message) error
+}
+
+var sender Sender
+
+// Create the email Sender for the configured driver
+func InitSender() {
+	driver := viper.GetString("email.driver")
+	switch driver {
+	case "", SendGridDriver:
+		sender = NewSendGridSender(viper.GetString("email.sendGrid.apiKey"))
+	case SmtpDriver:
+		sender = NewSmtpSender(
+			viper.GetString("email.smtp.host"),
+			viper.GetInt("email.smtp.port"),
+			viper.GetString("email.smtp.username"),
+			viper.GetString("email.smtp.password"),
+			viper.GetBool("email.smtp.startTLS"),
+		)
+	case OutboxDriver:
+		sender = NewOutboxSender(viper.GetString("email.outbox.directory"))
+	default:
+		logger.Log.Fatal("Unknown email driver: " + driver)
+	}
+}
+
+func SetSender(s Sender) {
+	sender = s
+}
+
+func GetSender() Sender {
+	return sender
+}
+
+type Recipient struct {
+	Name     string
+	Email    string
+	Language string
+}
+
+// Render the template in the recipient's language and send it
+func send(templateName string, category string, to Recipient, data interface{}) error {
+	m, err := Render(templateName, to.Language, data)
+	if err != nil {
+		logger.Log.Error("Failed to render " + templateName + " email: " + err.Error())
+		return err
+	}
+
+	m.From = Address{
+		Name:    viper.GetString("email.fromName"),
+		Address: viper.GetString("email.fromAddress"),
+	}
+	m.To = []Address{{Name: to.Name, Address: to.Email}}
+	m.Categories = []string{category}
+
+	// In test mode all messages go to email.testToEmail
+	if viper.GetBool("email.testMode") {
+		logger.Log.Warn("|Email Test Mode| : " + to.Email)
+		m.To[0].Address = viper.GetString("email.testToEmail")
+	}
+
+	err = sendEmail(m)
+	if err != nil {
+		logger.Log.Error(category + " email failed: " + err.Error())
+		return err
+	}
+
+	logger.Log.Info("Sent " + templateName + " email to " + to.Email)
+	return nil
+}
+
+func sendEmail(m *Message) error {
+	if !viper.GetBool("email.enabled") {
+		if len(m.To) > 0 {
+			logger.Log.Warn("Email sending disabled. Email not sent: " + fmt.Sprintf("%s [%s]", m.To[0].Address, m.To[0].Name))
+		}
+		return nil
+	}
+
+	if sender == nil {
+		return errors.New("email sender not initialized")
+	}
+
+	return sender.Send(m)
+}
+
+func (a Address) String() string {
+	address := mail.Address{Name: a.Name, Address: a.Address}
+	return address.String()
+}
+
+// Encode the message as a multipart/alternative MIME message with text and HTML parts
+func (m *Message) Bytes() ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := multipart.NewWriter(&buffer)
+
+	var to []string
+	for _, address := range m.To {
+		to = append(to, address.String())
+	}
+
+	headers := []string{
+		"From: " + m.From.String(),
+		"To: " + strings.Join(to, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", m.Subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + writer.Boundary(),
+	}
+	buffer.WriteString(strings.Join(headers, "\r\n") + "\r\n\r\n")
+
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", m.Text},
+		{"text/html; charset=utf-8", m.Html},
+	}
+
+	for _, part := range parts {
+		if part.content == "" {
+			continue
+		}
+
+		partWriter, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		encoder := quotedprintable.NewWriter(partWriter)
+		if _, err := encoder.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
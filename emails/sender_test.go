@@ -0,0 +1,51 @@
+package emails
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOutboxSender(t *testing.T) {
+	t.Parallel()
+
+	directory := t.TempDir()
+	outbox := NewOutboxSender(directory)
+
+	messages := []*Message{
+		{To: []Address{{Name: "One", Address: "one@example.com"}}, Subject: "First", Text: "Hello one", Html: "<b>Hello one</b>"},
+		{To: []Address{{Name: "Two", Address: "two@example.com"}}, Subject: "Second", Text: "Hello two"},
+	}
+
+	for _, message := range messages {
+		if err := outbox.Send(message); err != nil {
+			t.Fatalf("Send failed: %s", err)
+		}
+	}
+
+	if sent := outbox.MessagesTo("one@example.com"); len(sent) != 1 || sent[0].Subject != "First" {
+		t.Errorf("Unexpected messages to one@example.com: [%v]", sent)
+	}
+
+	files, err := filepath.Glob(filepath.Join(directory, "*.eml"))
+	if err != nil || len(files) != 2 {
+		t.Fatalf("Got %d message files, wanted 2", len(files))
+	}
+
+	body, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("Could not read message file: %s", err)
+	}
+
+	for _, expected := range []string{"To: \"One\" <one@example.com>", "Subject: First", "text/plain", "text/html", "<b>Hello one</b>"} {
+		if !strings.Contains(string(body), expected) {
+			t.Errorf("Message file missing [%s]: [%s]", expected, body)
+		}
+	}
+
+	outbox.Clear()
+	if len(outbox.Messages()) != 0 {
+		t.Errorf("Outbox not cleared")
+	}
+}
@@ -0,0 +1,57 @@
+package emails
+
+import (
+	"fmt"
+	"strconv"
+
+	sendgrid "github.com/sendgrid/sendgrid-go"
+	"github.com/sendgrid/sendgrid-go/helpers/mail"
+
+	"github.com/bryanmorgan/time-tracking-api/logger"
+)
+
+// SendGridSender sends email with the SendGrid v3 HTTP API
+type SendGridSender struct {
+	apiKey string
+}
+
+func NewSendGridSender(apiKey string) *SendGridSender {
+	return &SendGridSender{
+		apiKey: apiKey,
+	}
+}
+
+func (s *SendGridSender) Send(message *Message) error {
+	m := mail.NewV3Mail()
+	m.SetFrom(mail.NewEmail(message.From.Name, message.From.Address))
+	m.Subject = message.Subject
+
+	p := mail.NewPersonalization()
+	for _, to := range message.To {
+		p.AddTos(mail.NewEmail(to.Name, to.Address))
+	}
+	m.AddPersonalizations(p)
+
+	if message.Text != "" {
+		m.AddContent(mail.NewContent("text/plain", message.Text))
+	}
+	if message.Html != "" {
+		m.AddContent(mail.NewContent("text/html", message.Html))
+	}
+	m.AddCategories(message.Categories...)
+
+	request := sendgrid.GetRequest(s.apiKey, "/v3/mail/send", "https://api.sendgrid.com")
+	request.Method = "POST"
+	request.Body = mail.GetRequestBody(m)
+	response, err := sendgrid.API(request)
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode >= 400 {
+		return fmt.Errorf("sendgrid request failed [%d]: %s", response.StatusCode, response.Body)
+	}
+
+	logger.Log.Debug("SendGrid response [" + strconv.Itoa(response.StatusCode) + "]: " + response.Body)
+	return nil
+}
@@ -0,0 +1,83 @@
+package emails
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/smtp"
+	"strconv"
+)
+
+// SmtpSender sends email through an SMTP server. When startTLS is set the connection must be upgraded with STARTTLS
+// before authenticating
+type SmtpSender struct {
+	host     string
+	port     int
+	username string
+	password string
+	startTLS bool
+}
+
+func NewSmtpSender(host string, port int, username string, password string, startTLS bool) *SmtpSender {
+	return &SmtpSender{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+		startTLS: startTLS,
+	}
+}
+
+func (s *SmtpSender) Send(message *Message) error {
+	body, err := message.Bytes()
+	if err != nil {
+		return err
+	}
+
+	client, err := smtp.Dial(net.JoinHostPort(s.host, strconv.Itoa(s.port)))
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if s.startTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server does not support STARTTLS")
+		}
+
+		if err := client.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+			return err
+		}
+	}
+
+	if s.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.username, s.password, s.host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(message.From.Address); err != nil {
+		return err
+	}
+
+	for _, to := range message.To {
+		if err := client.Rcpt(to.Address); err != nil {
+			return err
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := writer.Write(body); err != nil {
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
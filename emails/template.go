@@ -0,0 +1,189 @@
+package emails
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+
+	"github.com/spf13/viper"
+)
+
+const DefaultLanguage = "en"
+
+// Languages with a template for every email. Each has a directory under templates/
+var Languages = []string{"en", "es"}
+
+// Email templates. Each language directory has <name>.txt, which also defines the subject, and <name>.html
+const (
+	ForgotPasswordTemplate = "forgot_password"
+	InvitationTemplate     = "invitation"
+	AddedToAccountTemplate = "added_to_account"
+	BudgetAlertTemplate    = "budget_alert"
+
+	TimesheetReminderTemplate = "timesheet_reminder"
+	TimesheetDigestTemplate   = "timesheet_digest"
+)
+
+//go:embed templates
+var templateFiles embed.FS
+
+// Account level overrides for the shared layout. Empty values use the email config
+type Branding struct {
+	Signature string
+	LogoUrl   string
+}
+
+// Values used by the shared layout in every email
+type Layout struct {
+	Name      string
+	Signature string
+	LogoUrl   string
+}
+
+type ForgotPasswordData struct {
+	Layout
+	ResetUrl string
+}
+
+type InvitationData struct {
+	Layout
+	Company  string
+	SetupUrl string
+	Expires  string
+}
+
+type AddedToAccountData struct {
+	Layout
+	Company  string
+	LoginUrl string
+}
+
+type BudgetAlertData struct {
+	Layout
+	ProjectName string
+	Threshold   int
+	PercentUsed string
+	ProjectUrl  string
+}
+
+type TimesheetReminderData struct {
+	Layout
+	Company       string
+	WeekOf        string
+	Hours         string
+	ExpectedHours string
+	TimesheetUrl  string
+}
+
+type TimesheetDigestData struct {
+	Layout
+	Company       string
+	WeekOf        string
+	ExpectedHours string
+	Missing       []*MissingTimesheet
+	ReportUrl     string
+}
+
+// A person listed in the timesheet digest with the hours they logged
+type MissingTimesheet struct {
+	Name  string
+	Hours string
+}
+
+type emailTemplate struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+var (
+	templateCache      = map[string]*emailTemplate{}
+	templateCacheMutex sync.Mutex
+)
+
+func IsLanguage(language string) bool {
+	for _, l := range Languages {
+		if l == language {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Build the layout values for the recipient name, using the configured signature and logo unless the account overrides them
+func NewLayout(name string, branding Branding) Layout {
+	layout := Layout{
+		Name:      name,
+		Signature: viper.GetString("email.emailSignatureName"),
+		LogoUrl:   viper.GetString("email.logoUrl"),
+	}
+
+	if branding.Signature != "" {
+		layout.Signature = branding.Signature
+	}
+
+	if branding.LogoUrl != "" {
+		layout.LogoUrl = branding.LogoUrl
+	}
+
+	return layout
+}
+
+// Render the subject, text and HTML content of an email template. Unknown languages use the DefaultLanguage
+func Render(name string, language string, data interface{}) (*Message, error) {
+	if !IsLanguage(language) {
+		language = DefaultLanguage
+	}
+
+	t, err := loadTemplate(name, language)
+	if err != nil {
+		return nil, err
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := t.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, err
+	}
+
+	if err := t.text.ExecuteTemplate(&text, "layout", data); err != nil {
+		return nil, err
+	}
+
+	if err := t.html.ExecuteTemplate(&html, "layout", data); err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    text.String(),
+		Html:    html.String(),
+	}, nil
+}
+
+func loadTemplate(name string, language string) (*emailTemplate, error) {
+	templateCacheMutex.Lock()
+	defer templateCacheMutex.Unlock()
+
+	key := language + "/" + name
+	if t, ok := templateCache[key]; ok {
+		return t, nil
+	}
+
+	common := "templates/" + language + "/common.tmpl"
+	html, err := htmltemplate.ParseFS(templateFiles, "templates/layout.html", common, "templates/"+key+".html")
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := texttemplate.ParseFS(templateFiles, "templates/layout.txt", common, "templates/"+key+".txt")
+	if err != nil {
+		return nil, err
+	}
+
+	t := &emailTemplate{html: html, text: text}
+	templateCache[key] = t
+	return t, nil
+}
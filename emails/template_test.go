@@ -0,0 +1,97 @@
+package emails
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderTemplates(t *testing.T) {
+	t.Parallel()
+
+	layout := Layout{Name: "Ada", Signature: "The Team"}
+	templates := []struct {
+		name string
+		data interface{}
+		want string
+	}{
+		{ForgotPasswordTemplate, &ForgotPasswordData{Layout: layout, ResetUrl: "http://localhost/reset-password?verify-token=abc"}, "verify-token=abc"},
+		{InvitationTemplate, &InvitationData{Layout: layout, Company: "ACME", SetupUrl: "http://localhost/setup?verify-token=abc", Expires: "2020-03-01"}, "2020-03-01"},
+		{AddedToAccountTemplate, &AddedToAccountData{Layout: layout, Company: "ACME", LoginUrl: "http://localhost/login"}, "http://localhost/login"},
+		{BudgetAlertTemplate, &BudgetAlertData{Layout: layout, ProjectName: "Website", Threshold: 80, PercentUsed: "82%", ProjectUrl: "http://localhost/projects/1"}, "82%"},
+		{TimesheetReminderTemplate, &TimesheetReminderData{Layout: layout, Company: "ACME", WeekOf: "2020-03-02", Hours: "12.5", ExpectedHours: "40", TimesheetUrl: "http://localhost/time"}, "12.5"},
+		{TimesheetDigestTemplate, &TimesheetDigestData{Layout: layout, Company: "ACME", WeekOf: "2020-03-02", ExpectedHours: "40", Missing: []*MissingTimesheet{{Name: "Grace Hopper", Hours: "0"}}, ReportUrl: "http://localhost/reports"}, "Grace Hopper"},
+	}
+
+	for _, language := range Languages {
+		for _, template := range templates {
+			t.Run(language+"/"+template.name, func(t *testing.T) {
+				m, err := Render(template.name, language, template.data)
+				if err != nil {
+					t.Fatalf("Render failed: %s", err)
+				}
+
+				if m.Subject == "" || strings.Contains(m.Subject, "\n") {
+					t.Errorf("Invalid subject: [%s]", m.Subject)
+				}
+
+				for _, content := range []string{m.Text, m.Html} {
+					if !strings.Contains(content, "Ada") || !strings.Contains(content, "The Team") || !strings.Contains(content, template.want) {
+						t.Errorf("Content missing name, signature or [%s]: [%s]", template.want, content)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestRenderLanguage(t *testing.T) {
+	t.Parallel()
+
+	data := &ForgotPasswordData{Layout: Layout{Name: "Ada"}, ResetUrl: "http://localhost"}
+
+	m, err := Render(ForgotPasswordTemplate, "es", data)
+	if err != nil {
+		t.Fatalf("Render failed: %s", err)
+	}
+
+	if !strings.HasPrefix(m.Text, "Hola Ada:") {
+		t.Errorf("Expected Spanish greeting: [%s]", m.Text)
+	}
+
+	// Unknown languages use the default
+	m, err = Render(ForgotPasswordTemplate, "xx", data)
+	if err != nil {
+		t.Fatalf("Render failed: %s", err)
+	}
+
+	if !strings.HasPrefix(m.Text, "Dear Ada,") || m.Subject != "Reset Your Password" {
+		t.Errorf("Expected English email: [%s] [%s]", m.Subject, m.Text)
+	}
+}
+
+func TestRenderLayout(t *testing.T) {
+	t.Parallel()
+
+	data := &AddedToAccountData{
+		Layout:   NewLayout("<Ada>", Branding{Signature: "ACME Payroll", LogoUrl: "https://example.com/logo.png"}),
+		Company:  "Tom & Jerry",
+		LoginUrl: "http://localhost/login",
+	}
+
+	m, err := Render(AddedToAccountTemplate, DefaultLanguage, data)
+	if err != nil {
+		t.Fatalf("Render failed: %s", err)
+	}
+
+	if !strings.Contains(m.Html, `<img src="https://example.com/logo.png"`) || !strings.Contains(m.Html, "ACME Payroll") {
+		t.Errorf("Account logo and signature not used: [%s]", m.Html)
+	}
+
+	if !strings.Contains(m.Html, "Dear &lt;Ada&gt;,") || !strings.Contains(m.Html, "Tom &amp; Jerry") {
+		t.Errorf("HTML values not escaped: [%s]", m.Html)
+	}
+
+	if !strings.Contains(m.Text, "Dear <Ada>,") || strings.Contains(m.Text, "<img") || m.Subject != "You have been added to Tom & Jerry" {
+		t.Errorf("Text content escaped or includes the logo: [%s] [%s]", m.Subject, m.Text)
+	}
+}
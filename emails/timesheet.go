@@ -0,0 +1,40 @@
+package emails
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/bryanmorgan/time-tracking-api/config"
+)
+
+func SendTimesheetReminderEmail(to Recipient, branding Branding, company string, weekOf time.Time, hours float64, expectedHours float64, timesheetUrl string) error {
+	data := &TimesheetReminderData{
+		Layout:        NewLayout(to.Name, branding),
+		Company:       company,
+		WeekOf:        weekOf.Format(config.ISOShortDateFormat),
+		Hours:         FormatHours(hours),
+		ExpectedHours: FormatHours(expectedHours),
+		TimesheetUrl:  timesheetUrl,
+	}
+
+	return send(TimesheetReminderTemplate, "Timesheet Reminder", to, data)
+}
+
+func SendTimesheetDigestEmail(to Recipient, branding Branding, company string, weekOf time.Time, expectedHours float64, missing []*MissingTimesheet, reportUrl string) error {
+	data := &TimesheetDigestData{
+		Layout:        NewLayout(to.Name, branding),
+		Company:       company,
+		WeekOf:        weekOf.Format(config.ISOShortDateFormat),
+		ExpectedHours: FormatHours(expectedHours),
+		Missing:       missing,
+		ReportUrl:     reportUrl,
+	}
+
+	return send(TimesheetDigestTemplate, "Timesheet Digest", to, data)
+}
+
+// Format hours to at most 2 decimal places without trailing zeros, e.g. 37.5 or 40
+func FormatHours(hours float64) string {
+	return strconv.FormatFloat(math.Round(hours*100)/100, 'f', -1, 64)
+}
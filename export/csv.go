@@ -0,0 +1,57 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+)
+
+// CsvRenderer writes a header row then the rows, without a title or totals so the file can be imported into other
+// tools. Numbers are written with two decimals
+type CsvRenderer struct{}
+
+type csvRowWriter struct {
+	writer *csv.Writer
+	report *Report
+}
+
+func (CsvRenderer) ContentType() string {
+	return "text/csv"
+}
+
+func (CsvRenderer) Extension() string {
+	return "csv"
+}
+
+func (CsvRenderer) NewWriter(w io.Writer, report *Report) (RowWriter, error) {
+	writer := csv.NewWriter(w)
+
+	header := make([]string, len(report.Columns))
+	for i, column := range report.Columns {
+		header[i] = column.Name
+	}
+
+	if err := writer.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &csvRowWriter{writer: writer, report: report}, nil
+}
+
+func (c *csvRowWriter) Write(row []Cell) error {
+	record := make([]string, len(row))
+	for i, cell := range row {
+		if i < len(c.report.Columns) && c.report.Columns[i].Type != TextColumn {
+			record[i] = fmt.Sprintf(" %0.2f", cell.Number)
+		} else {
+			record[i] = cell.Text
+		}
+	}
+
+	return c.writer.Write(record)
+}
+
+func (c *csvRowWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
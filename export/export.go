@@ -0,0 +1,183 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bryanmorgan/time-tracking-api/config"
+)
+
+type ColumnType int
+
+const (
+	TextColumn ColumnType = iota
+	HoursColumn
+	CurrencyColumn
+)
+
+// Used when the format parameter is not set
+const DefaultFormat = "csv"
+
+// Symbol added to currency values in formatted exports
+const CurrencySymbol = "$"
+
+var unsafeFilenameCharacters = regexp.MustCompile("[^a-zA-Z0-9]+")
+
+var renderers = map[string]Renderer{
+	"csv":  CsvRenderer{},
+	"xlsx": XlsxRenderer{},
+	"pdf":  PdfRenderer{},
+}
+
+type Column struct {
+	Name string
+	Type ColumnType
+}
+
+// Describes an export. Formatted exports show the company, title and date range above the rows and add a totals row
+// for the hours and currency columns
+type Report struct {
+	Title   string
+	Company string
+	From    time.Time
+	To      time.Time
+	Columns []Column
+}
+
+// A cell uses Text for text columns and Number for hours and currency columns
+type Cell struct {
+	Text   string
+	Number float64
+}
+
+// Renderer writes a report in one file format
+type Renderer interface {
+	ContentType() string
+	Extension() string
+
+	// Start the report on w. Rows are added with Write and the report is finished by Close
+	NewWriter(w io.Writer, report *Report) (RowWriter, error)
+}
+
+type RowWriter interface {
+	Write(row []Cell) error
+	Close() error
+}
+
+func TextCell(text string) Cell {
+	return Cell{Text: text}
+}
+
+func NumberCell(number float64) Cell {
+	return Cell{Number: number}
+}
+
+// Add or replace the renderer for a format
+func Register(format string, renderer Renderer) {
+	renderers[strings.ToLower(format)] = renderer
+}
+
+// Returns the renderer for the format, or false if the format is not supported
+func GetRenderer(format string) (Renderer, bool) {
+	renderer, ok := renderers[strings.ToLower(format)]
+	return renderer, ok
+}
+
+// Returns the supported formats in alphabetical order
+func Formats() []string {
+	var formats []string
+	for format := range renderers {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+
+	return formats
+}
+
+// Returns the attachment name, for example export_ACME_2019-01-01_to_2019-01-31.csv
+func Filename(report *Report, renderer Renderer) string {
+	return fmt.Sprintf("export_%s_%s_to_%s.%s",
+		unsafeFilenameCharacters.ReplaceAllString(report.Company, "-"),
+		report.From.Format(config.ISOShortDateFormat),
+		report.To.Format(config.ISOShortDateFormat),
+		renderer.Extension())
+}
+
+// Set the content type and attachment headers for the report
+func SetHeaders(w http.ResponseWriter, report *Report, renderer Renderer) {
+	header := w.Header()
+	header.Set("Content-Type", renderer.ContentType())
+	header.Set("Content-Disposition", "attachment;filename="+Filename(report, renderer))
+}
+
+// Write every row of the report with the renderer
+func Render(w io.Writer, renderer Renderer, report *Report, rows [][]Cell) error {
+	rowWriter, err := renderer.NewWriter(w, report)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := rowWriter.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return rowWriter.Close()
+}
+
+// Returns the "from to" line shown under the report title
+func dateRange(report *Report) string {
+	return report.From.Format(config.ISOShortDateFormat) + " to " + report.To.Format(config.ISOShortDateFormat)
+}
+
+// Keeps a running total of the hours and currency columns
+type totals []float64
+
+func newTotals(report *Report) totals {
+	return make(totals, len(report.Columns))
+}
+
+func (t totals) add(report *Report, row []Cell) {
+	for i, column := range report.Columns {
+		if column.Type != TextColumn && i < len(row) {
+			t[i] += row[i].Number
+		}
+	}
+}
+
+// Format a value with two decimals and thousands separators, for example 1,234.50
+func formatNumber(value float64) string {
+	text := fmt.Sprintf("%0.2f", value)
+	sign := ""
+	if strings.HasPrefix(text, "-") {
+		sign, text = "-", text[1:]
+	}
+
+	whole, decimals := text[:len(text)-3], text[len(text)-3:]
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(digit)
+	}
+
+	return sign + grouped.String() + decimals
+}
+
+func formatCell(column Column, cell Cell) string {
+	switch column.Type {
+	case HoursColumn:
+		return formatNumber(cell.Number)
+	case CurrencyColumn:
+		return CurrencySymbol + formatNumber(cell.Number)
+	}
+
+	return cell.Text
+}
@@ -0,0 +1,247 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testReport() (*Report, [][]Cell) {
+	report := &Report{
+		Title:   "Time by Client",
+		Company: "ACME & Sons, Inc.",
+		From:    time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:      time.Date(2019, 1, 31, 0, 0, 0, 0, time.UTC),
+		Columns: []Column{
+			{Name: "Client Name"},
+			{Name: "Billable Hours", Type: HoursColumn},
+			{Name: "Billable Total", Type: CurrencyColumn},
+		},
+	}
+
+	rows := [][]Cell{
+		{TextCell("Globex (East)"), NumberCell(10.5), NumberCell(1050)},
+		{TextCell("Initech"), NumberCell(2.25), NumberCell(225.75)},
+	}
+
+	return report, rows
+}
+
+func TestCsvRenderer(t *testing.T) {
+	t.Parallel()
+
+	report, rows := testReport()
+	var output bytes.Buffer
+	if err := Render(&output, CsvRenderer{}, report, rows); err != nil {
+		t.Fatalf("Failed to render CSV: [%s]", err)
+	}
+
+	expected := "Client Name,Billable Hours,Billable Total\n" +
+		"Globex (East),\" 10.50\",\" 1050.00\"\n" +
+		"Initech,\" 2.25\",\" 225.75\"\n"
+	if output.String() != expected {
+		t.Errorf("Invalid CSV. Got [%s] expected [%s]", output.String(), expected)
+	}
+}
+
+func TestXlsxRenderer(t *testing.T) {
+	t.Parallel()
+
+	report, rows := testReport()
+	var output bytes.Buffer
+	if err := Render(&output, XlsxRenderer{}, report, rows); err != nil {
+		t.Fatalf("Failed to render XLSX: [%s]", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(output.Bytes()), int64(output.Len()))
+	if err != nil {
+		t.Fatalf("XLSX is not a valid zip file: [%s]", err)
+	}
+
+	var sheet string
+	for _, file := range archive.File {
+		if file.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+
+		reader, err := file.Open()
+		if err != nil {
+			t.Fatalf("Failed to open sheet: [%s]", err)
+		}
+		content, _ := ioutil.ReadAll(reader)
+		sheet = string(content)
+	}
+
+	for _, expected := range []string{
+		"ACME &amp; Sons, Inc.",
+		"2019-01-01 to 2019-01-31",
+		"Globex (East)",
+		`<c r="B6" s="2"><v>10.5</v></c>`,
+		`<c r="C7" s="3"><v>225.75</v></c>`,
+		"<f>SUM(B6:B7)</f><v>12.75</v>",
+		"<f>SUM(C6:C7)</f><v>1275.75</v>",
+	} {
+		if !strings.Contains(sheet, expected) {
+			t.Errorf("Sheet is missing [%s]: [%s]", expected, sheet)
+		}
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestXlsxWriteError(t *testing.T) {
+	t.Parallel()
+
+	report, _ := testReport()
+	writer, err := XlsxRenderer{}.NewWriter(failingWriter{}, report)
+	if err != nil {
+		t.Fatalf("Failed to create XLSX writer: [%s]", err)
+	}
+
+	// Rows are buffered, so the error is returned once the sheet is flushed to the archive
+	for i := 0; i < 10000; i++ {
+		if err = writer.Write([]Cell{TextCell(fmt.Sprintf("Client %x", i*7919)), NumberCell(float64(i)), NumberCell(float64(i * 100))}); err != nil {
+			break
+		}
+	}
+
+	if err == nil {
+		t.Errorf("Expected a write error")
+	}
+}
+
+func TestPdfRenderer(t *testing.T) {
+	t.Parallel()
+
+	report, rows := testReport()
+	for i := 0; i < 100; i++ {
+		rows = append(rows, rows[i%2])
+	}
+
+	var output bytes.Buffer
+	if err := Render(&output, PdfRenderer{}, report, rows); err != nil {
+		t.Fatalf("Failed to render PDF: [%s]", err)
+	}
+
+	pdf := output.String()
+	if !strings.HasPrefix(pdf, "%PDF-1.4") || !strings.HasSuffix(pdf, "%%EOF\n") {
+		t.Fatalf("Invalid PDF: [%s]", pdf)
+	}
+
+	for _, expected := range []string{"(ACME & Sons, Inc.)", `(Globex \(East\))`, "($1,050.00)", "(Total)", "(Page 2)", "/Count 3"} {
+		if !strings.Contains(pdf, expected) {
+			t.Errorf("PDF is missing [%s]", expected)
+		}
+	}
+}
+
+func TestPdfTruncate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		value    string
+		width    float64
+		expected string
+	}{
+		{"Initech", 100, "Initech"},
+		{"1234567890", 51, "1234567890"},
+		{"1234567890", 30, "1234..."},
+		{"1234567890", 27.5, "123..."},
+		{"Café Crème", 40, "Café Cr..."},
+		{"Globex", 0, "..."},
+	}
+
+	for _, testCase := range testCases {
+		if truncated := pdfTruncate(testCase.value, testCase.width); truncated != testCase.expected {
+			t.Errorf("Invalid truncation for [%s] at [%f]. Got [%s] expected [%s]", testCase.value, testCase.width, truncated, testCase.expected)
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	t.Parallel()
+
+	report, rows := testReport()
+	w := httptest.NewRecorder()
+	stream := NewStream(w, CsvRenderer{}, report)
+	if stream.Started() || w.Header().Get("Content-Type") != "" || w.Body.Len() != 0 {
+		t.Fatalf("Stream wrote to the response before the first row")
+	}
+
+	for _, row := range rows {
+		if err := stream.Write(row); err != nil {
+			t.Fatalf("Failed to write row: [%s]", err)
+		}
+	}
+
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Failed to close stream: [%s]", err)
+	}
+
+	if !stream.Started() || w.Header().Get("Content-Type") != "text/csv" || strings.Count(w.Body.String(), "\n") != 3 {
+		t.Errorf("Invalid stream output. Type [%s] body [%s]", w.Header().Get("Content-Type"), w.Body.String())
+	}
+
+	// A report without rows still has its header
+	w = httptest.NewRecorder()
+	if err := NewStream(w, CsvRenderer{}, report).Close(); err != nil {
+		t.Fatalf("Failed to close empty stream: [%s]", err)
+	}
+
+	if w.Body.String() != "Client Name,Billable Hours,Billable Total\n" {
+		t.Errorf("Invalid empty stream output: [%s]", w.Body.String())
+	}
+}
+
+func TestFormatNumber(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		value    float64
+		expected string
+	}{
+		{0, "0.00"},
+		{1.005, "1.00"},
+		{999.999, "1,000.00"},
+		{1234.5, "1,234.50"},
+		{1234567.891, "1,234,567.89"},
+		{-98765.4, "-98,765.40"},
+	}
+
+	for _, testCase := range testCases {
+		if formatted := formatNumber(testCase.value); formatted != testCase.expected {
+			t.Errorf("Invalid format for [%f]. Got [%s] expected [%s]", testCase.value, formatted, testCase.expected)
+		}
+	}
+}
+
+func TestGetRenderer(t *testing.T) {
+	t.Parallel()
+
+	report, _ := testReport()
+	for _, format := range []string{"csv", "XLSX", "pdf"} {
+		renderer, ok := GetRenderer(format)
+		if !ok {
+			t.Fatalf("No renderer for [%s]", format)
+		}
+
+		expected := "export_ACME-Sons-Inc-_2019-01-01_to_2019-01-31." + strings.ToLower(format)
+		if filename := Filename(report, renderer); filename != expected {
+			t.Errorf("Invalid filename. Got [%s] expected [%s]", filename, expected)
+		}
+	}
+
+	if _, ok := GetRenderer("docx"); ok {
+		t.Errorf("Unsupported format has a renderer")
+	}
+}
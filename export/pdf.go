@@ -0,0 +1,305 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// US Letter portrait, in points
+const (
+	pdfPageWidth    = 612.0
+	pdfPageHeight   = 792.0
+	pdfMargin       = 40.0
+	pdfFontSize     = 9.0
+	pdfTitleSize    = 14.0
+	pdfRowHeight    = 14.0
+	pdfNumberWidth  = 70.0
+	pdfCellPadding  = 4.0
+	pdfFooterHeight = 20.0
+)
+
+// Objects written before the pages: the catalog, the page tree and the regular and bold fonts
+const (
+	pdfCatalogObject  = 1
+	pdfPagesObject    = 2
+	pdfFontObject     = 3
+	pdfBoldFontObject = 4
+)
+
+// PdfRenderer writes a PDF table using the standard Helvetica fonts, so no fonts are embedded. The column header is
+// repeated on every page and the last row totals the hours and currency columns
+type PdfRenderer struct{}
+
+type pdfRowWriter struct {
+	out     *pdfCountingWriter
+	report  *Report
+	widths  []float64
+	offsets []int64 // Offset of each object, by object number - 1
+	pages   []int   // Page object numbers
+	content bytes.Buffer
+	y       float64
+	totals  totals
+}
+
+type pdfCountingWriter struct {
+	writer io.Writer
+	count  int64
+	err    error
+}
+
+func (PdfRenderer) ContentType() string {
+	return "application/pdf"
+}
+
+func (PdfRenderer) Extension() string {
+	return "pdf"
+}
+
+func (PdfRenderer) NewWriter(w io.Writer, report *Report) (RowWriter, error) {
+	p := &pdfRowWriter{
+		out:     &pdfCountingWriter{writer: w},
+		report:  report,
+		widths:  pdfColumnWidths(report),
+		offsets: make([]int64, pdfBoldFontObject),
+		totals:  newTotals(report),
+	}
+
+	p.out.printf("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+	p.writeObject(pdfCatalogObject, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pdfPagesObject))
+	p.writeObject(pdfFontObject, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	p.writeObject(pdfBoldFontObject, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	p.startPage()
+
+	return p, p.out.err
+}
+
+func (p *pdfRowWriter) Write(row []Cell) error {
+	p.totals.add(p.report, row)
+
+	values := make([]string, len(p.report.Columns))
+	for i, column := range p.report.Columns {
+		if i < len(row) {
+			values[i] = formatCell(column, row[i])
+		}
+	}
+	p.writeRow(values, false)
+
+	return p.out.err
+}
+
+// Add the totals row, then write the last page, the page tree and the cross reference table
+func (p *pdfRowWriter) Close() error {
+	values := make([]string, len(p.report.Columns))
+	for i, column := range p.report.Columns {
+		if column.Type != TextColumn {
+			values[i] = formatCell(column, NumberCell(p.totals[i]))
+		}
+	}
+	values[0] = "Total"
+
+	if p.y-pdfRowHeight < pdfMargin+pdfFooterHeight {
+		p.finishPage()
+		p.startPage()
+	}
+	p.line(p.y + pdfRowHeight - 3)
+	p.writeRow(values, true)
+	p.finishPage()
+
+	kids := make([]string, len(p.pages))
+	for i, page := range p.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", page)
+	}
+	p.writeObject(pdfPagesObject, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(p.pages)))
+
+	xref := p.out.count
+	p.out.printf("xref\n0 %d\n0000000000 65535 f \n", len(p.offsets)+1)
+	for _, offset := range p.offsets {
+		p.out.printf("%010d 00000 n \n", offset)
+	}
+	p.out.printf("trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(p.offsets)+1, pdfCatalogObject, xref)
+
+	return p.out.err
+}
+
+// Start a page with the column header. The first page also shows the company, title and date range
+func (p *pdfRowWriter) startPage() {
+	p.content.Reset()
+	p.y = pdfPageHeight - pdfMargin - pdfTitleSize
+
+	if len(p.pages) == 0 {
+		p.text(pdfMargin, p.y, true, pdfTitleSize, p.report.Company)
+		p.y -= pdfTitleSize + 4
+		p.text(pdfMargin, p.y, false, pdfFontSize+1, p.report.Title)
+		p.y -= pdfRowHeight
+		p.text(pdfMargin, p.y, false, pdfFontSize, dateRange(p.report))
+		p.y -= pdfRowHeight * 2
+	}
+
+	header := make([]string, len(p.report.Columns))
+	for i, column := range p.report.Columns {
+		header[i] = column.Name
+	}
+	p.writeRow(header, true)
+	p.line(p.y + pdfRowHeight - 3)
+
+	p.text(pdfMargin, pdfMargin, false, pdfFontSize, fmt.Sprintf("Page %d", len(p.pages)+1))
+}
+
+// Write the page's content stream and page objects
+func (p *pdfRowWriter) finishPage() {
+	contentObject := len(p.offsets) + 1
+	pageObject := contentObject + 1
+
+	p.writeObject(contentObject, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", p.content.Len(), p.content.String()))
+	p.writeObject(pageObject, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %g %g] "+
+		"/Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> /Contents %d 0 R >>",
+		pdfPagesObject, pdfPageWidth, pdfPageHeight, pdfFontObject, pdfBoldFontObject, contentObject))
+	p.pages = append(p.pages, pageObject)
+}
+
+func (p *pdfRowWriter) writeRow(values []string, bold bool) {
+	if p.y < pdfMargin+pdfFooterHeight {
+		p.finishPage()
+		p.startPage()
+	}
+
+	x := pdfMargin
+	for i, column := range p.report.Columns {
+		width := p.widths[i]
+		value := ""
+		if i < len(values) {
+			value = pdfTruncate(values[i], width-pdfCellPadding)
+		}
+
+		if column.Type == TextColumn {
+			p.text(x, p.y, bold, pdfFontSize, value)
+		} else {
+			p.text(x+width-pdfCellPadding-pdfTextWidth(value, pdfFontSize), p.y, bold, pdfFontSize, value)
+		}
+		x += width
+	}
+	p.y -= pdfRowHeight
+}
+
+func (p *pdfRowWriter) text(x float64, y float64, bold bool, size float64, value string) {
+	font := 1
+	if bold {
+		font = 2
+	}
+
+	fmt.Fprintf(&p.content, "BT /F%d %g Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, pdfEscape(value))
+}
+
+// Draw a rule across the table
+func (p *pdfRowWriter) line(y float64) {
+	fmt.Fprintf(&p.content, "0.5 w %.2f %.2f m %.2f %.2f l S\n", pdfMargin, y, pdfPageWidth-pdfMargin, y)
+}
+
+func (p *pdfRowWriter) writeObject(number int, body string) {
+	for len(p.offsets) < number {
+		p.offsets = append(p.offsets, 0)
+	}
+	p.offsets[number-1] = p.out.count
+	p.out.printf("%d 0 obj\n%s\nendobj\n", number, body)
+}
+
+func (c *pdfCountingWriter) printf(format string, args ...interface{}) {
+	if c.err != nil {
+		return
+	}
+
+	n, err := fmt.Fprintf(c.writer, format, args...)
+	c.count += int64(n)
+	c.err = err
+}
+
+// Hours and currency columns have a fixed width and text columns share the rest of the page
+func pdfColumnWidths(report *Report) []float64 {
+	textColumns := 0
+	for _, column := range report.Columns {
+		if column.Type == TextColumn {
+			textColumns++
+		}
+	}
+
+	available := pdfPageWidth - 2*pdfMargin
+	textWidth := 0.0
+	if textColumns > 0 {
+		textWidth = (available - pdfNumberWidth*float64(len(report.Columns)-textColumns)) / float64(textColumns)
+	}
+
+	widths := make([]float64, len(report.Columns))
+	for i, column := range report.Columns {
+		widths[i] = pdfNumberWidth
+		if column.Type == TextColumn {
+			widths[i] = textWidth
+		}
+	}
+
+	return widths
+}
+
+// Approximate width of the text in Helvetica, using the font's widths for digits and punctuation
+func pdfTextWidth(value string, size float64) float64 {
+	width := 0.0
+	for _, r := range value {
+		width += pdfRuneWidth(r)
+	}
+
+	return width * size
+}
+
+// Width of a Helvetica character at a font size of one
+func pdfRuneWidth(r rune) float64 {
+	switch {
+	case r == '.' || r == ',' || r == ' ' || r == 'i' || r == 'l' || r == 'j' || r == 'I':
+		return 0.278
+	case r == '-' || r == 'r' || r == 't' || r == 'f':
+		return 0.333
+	case r == 'm' || r == 'w' || r == 'M' || r == 'W':
+		return 0.833
+	case r >= 'A' && r <= 'Z':
+		return 0.667
+	default:
+		return 0.556
+	}
+}
+
+// Shorten the text to fit the width, ending it with an ellipsis. The widths are summed in a single pass and the
+// text is cut before the first character that leaves no room for the ellipsis
+func pdfTruncate(value string, width float64) string {
+	if pdfTextWidth(value, pdfFontSize) <= width {
+		return value
+	}
+
+	available := width - pdfTextWidth("...", pdfFontSize)
+	used := 0.0
+	for i, r := range value {
+		used += pdfRuneWidth(r) * pdfFontSize
+		if used > available {
+			return value[:i] + "..."
+		}
+	}
+
+	return value + "..."
+}
+
+// Escape a PDF string. The standard fonts use WinAnsi encoding, so characters outside Latin-1 are replaced
+func pdfEscape(value string) string {
+	var escaped bytes.Buffer
+	for _, r := range value {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			escaped.WriteByte('\\')
+			escaped.WriteByte(byte(r))
+		case r < 32 || (r >= 127 && r < 160) || r > 255:
+			escaped.WriteByte('?')
+		default:
+			escaped.WriteByte(byte(r))
+		}
+	}
+
+	return escaped.String()
+}
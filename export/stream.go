@@ -0,0 +1,53 @@
+package export
+
+import (
+	"net/http"
+)
+
+// Stream renders a report to a response as its rows are read. The headers and the start of the report are only
+// written with the first row, or by Close when there are no rows, so an error before then can still be sent as a normal
+// error response
+type Stream struct {
+	w        http.ResponseWriter
+	renderer Renderer
+	report   *Report
+	started  bool
+	rows     RowWriter
+	err      error // Error starting the report
+}
+
+func NewStream(w http.ResponseWriter, renderer Renderer, report *Report) *Stream {
+	return &Stream{w: w, renderer: renderer, report: report}
+}
+
+// Returns true once any part of the report may have been written to the response
+func (s *Stream) Started() bool {
+	return s.started
+}
+
+func (s *Stream) Write(row []Cell) error {
+	if err := s.start(); err != nil {
+		return err
+	}
+
+	return s.rows.Write(row)
+}
+
+// Finish the report. A report without rows still has its header and totals
+func (s *Stream) Close() error {
+	if err := s.start(); err != nil {
+		return err
+	}
+
+	return s.rows.Close()
+}
+
+func (s *Stream) start() error {
+	if !s.started {
+		s.started = true
+		SetHeaders(s.w, s.report, s.renderer)
+		s.rows, s.err = s.renderer.NewWriter(s.w, s.report)
+	}
+
+	return s.err
+}
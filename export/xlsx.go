@@ -0,0 +1,248 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Styles in xlsxStyles: default, bold, hours, currency, bold hours and bold currency
+const (
+	xlsxStyleBold         = 1
+	xlsxStyleHours        = 2
+	xlsxStyleCurrency     = 3
+	xlsxStyleBoldHours    = 4
+	xlsxStyleBoldCurrency = 5
+)
+
+// Rows above the column header: company, title and date range, then a blank row
+const xlsxHeaderRow = 5
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`</Types>`
+
+const xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="Report" sheetId="1" r:id="rId1"/></sheets>` +
+	`</workbook>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`</Relationships>`
+
+// Number format 2 is the built in 0.00 format
+const xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="1"><numFmt numFmtId="164" formatCode="&quot;%s&quot;#,##0.00"/></numFmts>` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="6">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="2" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="2" fontId="1" fillId="0" borderId="0" xfId="0" applyNumberFormat="1" applyFont="1"/>` +
+	`<xf numFmtId="164" fontId="1" fillId="0" borderId="0" xfId="0" applyNumberFormat="1" applyFont="1"/>` +
+	`</cellXfs>` +
+	`</styleSheet>`
+
+// XlsxRenderer writes an Excel workbook with a single sheet. Hours and currency cells are numbers with two decimals,
+// and the last row totals them with SUM formulas
+type XlsxRenderer struct{}
+
+type xlsxRowWriter struct {
+	archive *zip.Writer
+	sheet   *bufio.Writer
+	report  *Report
+	row     int
+	totals  totals
+}
+
+func (XlsxRenderer) ContentType() string {
+	return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+}
+
+func (XlsxRenderer) Extension() string {
+	return "xlsx"
+}
+
+func (XlsxRenderer) NewWriter(w io.Writer, report *Report) (RowWriter, error) {
+	archive := zip.NewWriter(w)
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", fmt.Sprintf(xlsxStyles, xmlEscape(CurrencySymbol))},
+	}
+
+	for _, part := range parts {
+		file, err := archive.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := io.WriteString(file, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	// The sheet is the last file in the archive so its rows can be written as they arrive
+	file, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	x := &xlsxRowWriter{archive: archive, sheet: bufio.NewWriter(file), report: report, totals: newTotals(report)}
+	x.sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	x.sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><cols>`)
+	for i, column := range report.Columns {
+		width := 16
+		if column.Type == TextColumn {
+			width = 30
+		}
+		fmt.Fprintf(x.sheet, `<col min="%d" max="%d" width="%d" customWidth="1"/>`, i+1, i+1, width)
+	}
+	x.sheet.WriteString(`</cols><sheetData>`)
+
+	x.writeTextRow([]string{report.Company}, xlsxStyleBold)
+	x.writeTextRow([]string{report.Title}, 0)
+	x.writeTextRow([]string{dateRange(report)}, 0)
+	x.writeTextRow(nil, 0)
+
+	header := make([]string, len(report.Columns))
+	for i, column := range report.Columns {
+		header[i] = column.Name
+	}
+	x.writeTextRow(header, xlsxStyleBold)
+
+	return x, nil
+}
+
+func (x *xlsxRowWriter) Write(row []Cell) error {
+	x.totals.add(x.report, row)
+
+	x.startRow()
+	for i, cell := range row {
+		if i >= len(x.report.Columns) {
+			break
+		}
+
+		switch x.report.Columns[i].Type {
+		case HoursColumn:
+			x.numberCell(i, cell.Number, xlsxStyleHours, "")
+		case CurrencyColumn:
+			x.numberCell(i, cell.Number, xlsxStyleCurrency, "")
+		default:
+			x.textCell(i, cell.Text, 0)
+		}
+	}
+
+	// The buffered sheet keeps the first error from the zip archive, so the last write returns it
+	_, err := x.sheet.WriteString("</row>")
+	return err
+}
+
+// Add the totals row and finish the workbook
+func (x *xlsxRowWriter) Close() error {
+	firstDataRow := xlsxHeaderRow + 1
+	lastDataRow := x.row
+
+	x.startRow()
+	for i, column := range x.report.Columns {
+		formula := ""
+		if lastDataRow >= firstDataRow {
+			formula = fmt.Sprintf("SUM(%s%d:%s%d)", xlsxColumnName(i), firstDataRow, xlsxColumnName(i), lastDataRow)
+		}
+
+		switch {
+		case i == 0:
+			x.textCell(i, "Total", xlsxStyleBold)
+		case column.Type == HoursColumn:
+			x.numberCell(i, x.totals[i], xlsxStyleBoldHours, formula)
+		case column.Type == CurrencyColumn:
+			x.numberCell(i, x.totals[i], xlsxStyleBoldCurrency, formula)
+		}
+	}
+	x.sheet.WriteString("</row></sheetData></worksheet>")
+
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+
+	return x.archive.Close()
+}
+
+func (x *xlsxRowWriter) startRow() {
+	x.row++
+	fmt.Fprintf(x.sheet, `<row r="%d">`, x.row)
+}
+
+func (x *xlsxRowWriter) writeTextRow(values []string, style int) {
+	x.startRow()
+	for i, value := range values {
+		x.textCell(i, value, style)
+	}
+	x.sheet.WriteString("</row>")
+}
+
+func (x *xlsxRowWriter) textCell(column int, text string, style int) {
+	fmt.Fprintf(x.sheet, `<c r="%s%d" t="inlineStr"%s><is><t xml:space="preserve">%s</t></is></c>`,
+		xlsxColumnName(column), x.row, xlsxStyleAttribute(style), xmlEscape(text))
+}
+
+// Write a number cell. When formula is set the number is the formula's cached value
+func (x *xlsxRowWriter) numberCell(column int, number float64, style int, formula string) {
+	fmt.Fprintf(x.sheet, `<c r="%s%d"%s>`, xlsxColumnName(column), x.row, xlsxStyleAttribute(style))
+	if formula != "" {
+		fmt.Fprintf(x.sheet, "<f>%s</f>", formula)
+	}
+	fmt.Fprintf(x.sheet, "<v>%s</v></c>", strconv.FormatFloat(number, 'f', -1, 64))
+}
+
+func xlsxStyleAttribute(style int) string {
+	if style == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(` s="%d"`, style)
+}
+
+// Returns the spreadsheet column name for a zero based index: A to Z, then AA, AB and so on
+func xlsxColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+
+	return name
+}
+
+func xmlEscape(text string) string {
+	var escaped strings.Builder
+	_ = xml.EscapeText(&escaped, []byte(text))
+	return escaped.String()
+}
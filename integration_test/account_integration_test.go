@@ -8,6 +8,8 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -277,3 +279,280 @@ func TestRemoveUserFromAccount(t *testing.T) {
 		})
 	}
 }
+
+// The user last used another account, so the removal must find the membership of the admin's account
+func TestRemoveUserInTwoAccounts(t *testing.T) {
+	const memberEmail = "two.accounts.member@example.com"
+	_, accountId := createDefaultUnitTestAccount()
+	memberId := createTestAccountMember(accountId, memberEmail, profile.User)
+	defer deleteDefaultUnitTestAccount()
+	defer deleteUnitTestProfileByEmail(memberEmail)
+
+	var otherAccountId int
+	if err := db.QueryRow("INSERT INTO account (company) VALUES ($1) RETURNING account_id", "Other "+TestCompany).Scan(&otherAccountId); err != nil {
+		t.Fatalf("Failed to create other account: %s", err)
+	}
+	defer db.Exec("DELETE FROM account WHERE account_id = $1", otherAccountId)
+
+	profileAccountSql := `INSERT INTO profile_account (profile_id, account_id, role, profile_account_status, last_used) VALUES ($1, $2, $3, $4, $5)`
+	if _, err := db.Exec(profileAccountSql, memberId, otherAccountId, profile.User, profile.ProfileAccountValid, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Failed to add member to other account: %s", err)
+	}
+	defer db.Exec("DELETE FROM profile_account WHERE account_id = $1", otherAccountId)
+
+	r, _ := http.NewRequest("DELETE", "/api/account/user", encodeJson(t, &map[string]interface{}{"email": memberEmail}))
+	AddAuthorizationHeaders(r)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Remove user status code: [%d] wanted: [%d]. Body: %s", w.Code, http.StatusOK, w.Body)
+	}
+
+	var accounts []int
+	if err := db.Select(&accounts, "SELECT account_id FROM profile_account WHERE profile_id = $1", memberId); err != nil {
+		t.Fatalf("Failed to get member accounts: %s", err)
+	}
+
+	if len(accounts) != 1 || accounts[0] != otherAccountId {
+		t.Errorf("Member accounts: %v wanted: [%d]", accounts, otherAccountId)
+	}
+}
+
+func TestRolePermissions(t *testing.T) {
+	testCases := []struct {
+		name       string
+		role       profile.AuthorizationRole
+		method     string
+		path       string
+		statusCode int
+	}{
+		{"User lists clients", profile.User, "GET", "/api/client/all", http.StatusOK},
+		{"User lists tasks", profile.User, "GET", "/api/task/all", http.StatusOK},
+		{"User gets own time", profile.User, "GET", "/api/time/week", http.StatusOK},
+		{"User creates client", profile.User, "POST", "/api/client", http.StatusUnauthorized},
+		{"User deletes project", profile.User, "DELETE", "/api/client/project", http.StatusUnauthorized},
+		{"User creates task", profile.User, "POST", "/api/task", http.StatusUnauthorized},
+		{"User reports on people", profile.User, "GET", "/api/report/time/person?from=2017-11-13", http.StatusUnauthorized},
+		{"User exports report", profile.User, "GET", "/api/report/time/export/client?from=2017-11-13", http.StatusUnauthorized},
+		{"Reporting reports on people", profile.Reporting, "GET", "/api/report/time/person?from=2017-11-13", http.StatusOK},
+		{"Reporting updates client", profile.Reporting, "PUT", "/api/client", http.StatusUnauthorized},
+		{"Reporting archives task", profile.Reporting, "PUT", "/api/task/archive", http.StatusUnauthorized},
+		{"Admin reports on people", profile.Admin, "GET", "/api/report/time/person?from=2017-11-13", http.StatusOK},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			createUnitTestAccount(TestEmail, TestFirstName, TestLastName, TestCompany, TestCompany2, testCase.role)
+			defer deleteDefaultUnitTestAccount()
+
+			r, _ := http.NewRequest(testCase.method, testCase.path, nil)
+			w := httptest.NewRecorder()
+			AddAuthorizationHeaders(r)
+			router.ServeHTTP(w, r)
+
+			if w.Code != testCase.statusCode {
+				t.Errorf("Wrong status code: [%d] wanted: [%d]", w.Code, testCase.statusCode)
+			}
+
+			if testCase.statusCode == http.StatusUnauthorized {
+				var output jsonResult
+				if err := json.NewDecoder(w.Body).Decode(&output); err != nil {
+					t.Fatalf("Could not decode to json: [%s]", err.Error())
+				}
+
+				if output.Code != api.NotAuthorized {
+					t.Errorf("Wrong error code: [%s] wanted: [%s]", output.Code, api.NotAuthorized)
+				}
+			}
+		})
+	}
+}
+
+func TestInvitations(t *testing.T) {
+	createDefaultUnitTestAccount()
+	defer deleteDefaultUnitTestAccount()
+
+	inviteEmail := "invite-" + TestEmail
+	revokeEmail := "revoke-" + TestEmail
+	defer deleteUnitTestProfileByEmail(inviteEmail)
+	defer deleteUnitTestProfileByEmail(revokeEmail)
+
+	addTestInvitation(t, inviteEmail)
+	if message := lastTestEmail(t, inviteEmail); !strings.Contains(message.Text, TestCompany) {
+		t.Errorf("Invitation email does not name the company: [%s]", message.Text)
+	}
+	setupToken := lastTestEmailToken(t, inviteEmail)
+
+	invitations := getTestInvitations(t)
+	if len(invitations) != 1 || invitations[0].Email != strings.ToLower(inviteEmail) || invitations[0].Expired {
+		t.Fatalf("Invalid invitations: [%+v]", invitations)
+	}
+
+	// Resending emails a new setup token and the old one stops working
+	invitationPath := "/api/account/invitations/" + strconv.Itoa(invitations[0].ProfileId)
+	w, _ := invitationRequest(t, "POST", invitationPath+"/resend", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Resend invitation status: [%d] wanted: [%d]", w.Code, http.StatusOK)
+	}
+
+	resentToken := lastTestEmailToken(t, inviteEmail)
+	if resentToken == setupToken {
+		t.Fatalf("Resend did not generate a new setup token")
+	}
+
+	w, _ = invitationRequest(t, "PUT", "/api/auth/setup", encodeJson(t, &map[string]interface{}{"token": setupToken, "password": TestPassword}))
+	if w.Code == http.StatusOK {
+		t.Errorf("Setup with replaced token status: [%d]", w.Code)
+	}
+
+	// Setting up the profile accepts the invitation
+	w, _ = invitationRequest(t, "PUT", "/api/auth/setup", encodeJson(t, &map[string]interface{}{"token": resentToken, "password": TestPassword}))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Setup status: [%d] wanted: [%d]", w.Code, http.StatusOK)
+	}
+
+	if invitations = getTestInvitations(t); len(invitations) != 0 {
+		t.Errorf("Accepted invitation still pending: [%+v]", invitations)
+	}
+
+	// Revoked invitations are removed from the account and the setup token no longer works
+	addTestInvitation(t, revokeEmail)
+	revokeToken := lastTestEmailToken(t, revokeEmail)
+
+	invitations = getTestInvitations(t)
+	if len(invitations) != 1 {
+		t.Fatalf("Wrong number of invitations: [%d] wanted: [1]", len(invitations))
+	}
+
+	invitationPath = "/api/account/invitations/" + strconv.Itoa(invitations[0].ProfileId)
+	w, _ = invitationRequest(t, "DELETE", invitationPath, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Revoke invitation status: [%d] wanted: [%d]", w.Code, http.StatusOK)
+	}
+
+	if invitations = getTestInvitations(t); len(invitations) != 0 {
+		t.Errorf("Invitation not revoked: [%+v]", invitations)
+	}
+
+	w, output := invitationRequest(t, "DELETE", invitationPath, nil)
+	if w.Code != http.StatusBadRequest || output.Code != api.ProfileNotFound {
+		t.Errorf("Revoke missing invitation status: [%d] code: [%s]", w.Code, output.Code)
+	}
+
+	w, _ = invitationRequest(t, "PUT", "/api/auth/setup", encodeJson(t, &map[string]interface{}{"token": revokeToken, "password": TestPassword}))
+	if w.Code == http.StatusOK {
+		t.Errorf("Setup with revoked invitation token status: [%d]", w.Code)
+	}
+}
+
+// A second account inviting the same user sends its own invitation, and the first account's setup link still works
+func TestInvitationFromTwoAccounts(t *testing.T) {
+	const otherAdminEmail = "other.admin@example.com"
+	const otherToken = "other-account-invitation-token"
+	createDefaultUnitTestAccount()
+	defer deleteDefaultUnitTestAccount()
+
+	inviteEmail := "invite-" + TestEmail
+	defer deleteUnitTestProfileByEmail(inviteEmail)
+
+	var otherAccountId int
+	if err := db.QueryRow("INSERT INTO account (company) VALUES ($1) RETURNING account_id", "Other "+TestCompany).Scan(&otherAccountId); err != nil {
+		t.Fatalf("Failed to create other account: %s", err)
+	}
+	defer db.Exec("DELETE FROM account WHERE account_id = $1", otherAccountId)
+
+	otherAdminId := createTestAccountMember(otherAccountId, otherAdminEmail, profile.Admin)
+	createTestSession(otherAdminId, otherAccountId, otherToken)
+	defer deleteUnitTestProfileByEmail(otherAdminEmail)
+
+	addTestInvitation(t, inviteEmail)
+	setupToken := lastTestEmailToken(t, inviteEmail)
+
+	body := encodeJson(t, &map[string]interface{}{
+		"firstName": TestFirstName,
+		"lastName":  TestLastName,
+		"email":     inviteEmail,
+		"role":      string(profile.User),
+	})
+	r, _ := http.NewRequest("POST", "/api/account/user", body)
+	AddRequestHeaders(r)
+	r.Header.Add("Authorization", "Bearer "+otherToken)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Add user to other account status: [%d] wanted: [%d]. Body: %s", w.Code, http.StatusOK, w.Body)
+	}
+
+	if message := lastTestEmail(t, inviteEmail); !strings.Contains(message.Text, "Other "+TestCompany) {
+		t.Errorf("Other account did not send an invitation: [%s]", message.Text)
+	}
+
+	otherSetupToken := lastTestEmailToken(t, inviteEmail)
+	if otherSetupToken == setupToken {
+		t.Fatalf("Other account reused the setup token")
+	}
+
+	w, _ = invitationRequest(t, "PUT", "/api/auth/setup", encodeJson(t, &map[string]interface{}{"token": setupToken, "password": TestPassword}))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Setup with the first account's token status: [%d] wanted: [%d]", w.Code, http.StatusOK)
+	}
+
+	// Setting up the profile disables every other invitation's setup link
+	w, _ = invitationRequest(t, "PUT", "/api/auth/setup", encodeJson(t, &map[string]interface{}{"token": otherSetupToken, "password": TestPassword}))
+	if w.Code == http.StatusOK {
+		t.Errorf("Setup with the other account's token after setup status: [%d]", w.Code)
+	}
+}
+
+func addTestInvitation(t *testing.T, email string) {
+	t.Helper()
+	body := encodeJson(t, &map[string]interface{}{
+		"firstName": TestFirstName,
+		"lastName":  TestLastName,
+		"email":     email,
+		"role":      string(profile.User),
+	})
+
+	w, _ := invitationRequest(t, "POST", "/api/account/user", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Add user status: [%d] wanted: [%d]", w.Code, http.StatusOK)
+	}
+}
+
+type invitationResponse struct {
+	ProfileId int
+	Email     string
+	Role      string
+	Expired   bool
+}
+
+func getTestInvitations(t *testing.T) []invitationResponse {
+	t.Helper()
+	w, output := invitationRequest(t, "GET", "/api/account/invitations", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Get invitations status: [%d] wanted: [%d]", w.Code, http.StatusOK)
+	}
+
+	var invitations []invitationResponse
+	if err := json.Unmarshal(output.Data, &invitations); err != nil {
+		t.Fatalf("Could not decode to json: %s", err.Error())
+	}
+
+	return invitations
+}
+
+func invitationRequest(t *testing.T, method string, path string, body io.Reader) (*httptest.ResponseRecorder, jsonResult) {
+	t.Helper()
+	r, _ := http.NewRequest(method, path, body)
+	w := httptest.NewRecorder()
+	AddAuthorizationHeaders(r)
+	router.ServeHTTP(w, r)
+
+	var output jsonResult
+	if err := json.NewDecoder(w.Body).Decode(&output); err != nil {
+		t.Fatalf("Could not decode to json: %s", err.Error())
+	}
+
+	return w, output
+}
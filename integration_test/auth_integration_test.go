@@ -6,11 +6,14 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/bryanmorgan/time-tracking-api/api"
 	_ "github.com/bryanmorgan/time-tracking-api/config"
+	"github.com/bryanmorgan/time-tracking-api/profile"
 )
 
 func TestLogin(t *testing.T) {
@@ -266,6 +269,30 @@ func TestLoginLockAccount(t *testing.T) {
 			t.Fatalf("account not locked in loop [%d]: wrong error code: [%s] wanted: [%s]", i, output.Code, api.ProfileLocked)
 		}
 	}
+
+	// Unlocking the profile, as done by "timetrack user unlock", allows the valid password again
+	profileService := profile.NewProfileService(profile.NewProfileAccountStore(db))
+	if appErr := profileService.UnlockProfile(TestEmail); appErr != nil {
+		t.Fatalf("Failed to unlock profile: %s", appErr.Error())
+	}
+
+	body := encodeJson(t, &map[string]interface{}{
+		"email":    TestEmail,
+		"password": TestPassword,
+	})
+
+	r, _ := http.NewRequest("POST", "/api/auth/login", body)
+	w := httptest.NewRecorder()
+	AddRequestHeaders(r)
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Login after unlock status code: [%d] wanted: [%d]", w.Code, http.StatusOK)
+	}
+
+	if appErr := profileService.UnlockProfile("noone@acme.test.me"); appErr == nil || appErr.Code != api.ProfileNotFound {
+		t.Errorf("Unlock missing profile error: [%v] wanted: [%s]", appErr, api.ProfileNotFound)
+	}
 }
 
 func TestForgotPassword(t *testing.T) {
@@ -312,6 +339,64 @@ func TestForgotPassword(t *testing.T) {
 	}
 }
 
+func TestForgotPasswordEmail(t *testing.T) {
+	createDefaultUnitTestAccount()
+	defer deleteDefaultUnitTestAccount()
+
+	body := encodeJson(t, &map[string]interface{}{"email": TestEmail})
+	r, _ := http.NewRequest("POST", "/api/auth/forgot", body)
+	w := httptest.NewRecorder()
+	AddRequestHeaders(r)
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Forgot password status: [%d] wanted: [%d]", w.Code, http.StatusOK)
+	}
+
+	message := lastTestEmail(t, TestEmail)
+	if message.Subject != "Reset Your Password" || !strings.Contains(message.Html, "/reset-password") {
+		t.Errorf("Invalid forgot password email: [%s] [%s]", message.Subject, message.Html)
+	}
+
+	// The emailed token validates once
+	for _, statusCode := range []int{http.StatusOK, http.StatusBadRequest} {
+		body = encodeJson(t, &map[string]interface{}{"forgotPasswordToken": lastTestEmailToken(t, TestEmail)})
+		r, _ = http.NewRequest("POST", "/api/auth/forgot/validate", body)
+		w = httptest.NewRecorder()
+		AddRequestHeaders(r)
+		router.ServeHTTP(w, r)
+
+		if w.Code != statusCode {
+			t.Errorf("Validate emailed token status: [%d] wanted: [%d]", w.Code, statusCode)
+		}
+	}
+
+	// Emails use the profile language and the account signature
+	r, _ = http.NewRequest("PUT", "/api/profile", encodeJson(t, &map[string]interface{}{"language": "es"}))
+	w = httptest.NewRecorder()
+	AddAuthorizationHeaders(r)
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Update profile language status: [%d] wanted: [%d]", w.Code, http.StatusOK)
+	}
+
+	_, err := db.Exec("UPDATE account SET email_signature = $1 WHERE company = $2", "ACME Payroll", TestCompany)
+	if err != nil {
+		t.Fatalf("Failed to update account signature: %s", err.Error())
+	}
+
+	r, _ = http.NewRequest("POST", "/api/auth/forgot", encodeJson(t, &map[string]interface{}{"email": TestEmail}))
+	w = httptest.NewRecorder()
+	AddRequestHeaders(r)
+	router.ServeHTTP(w, r)
+
+	message = lastTestEmail(t, TestEmail)
+	if message.Subject != "Restablece tu contraseña" || !strings.Contains(message.Text, "ACME Payroll") {
+		t.Errorf("Invalid localized forgot password email: [%s] [%s]", message.Subject, message.Text)
+	}
+}
+
 func TestForgotPasswordTokenValidation(t *testing.T) {
 	createDefaultUnitTestAccount()
 	defer deleteDefaultUnitTestAccount()
@@ -354,3 +439,417 @@ func TestForgotPasswordTokenValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestApiKeys(t *testing.T) {
+	createDefaultUnitTestAccount()
+	defer deleteDefaultUnitTestAccount()
+
+	newKey := map[string]interface{}{"name": "Payroll export", "scopes": []string{"time:read"}}
+	r, _ := http.NewRequest("POST", "/api/profile/keys", encodeJson(t, &newKey))
+	w := httptest.NewRecorder()
+	AddAuthorizationHeaders(r)
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Create API key status: [%d] wanted: [%d]", w.Code, http.StatusOK)
+	}
+
+	var output jsonResult
+	if err := json.NewDecoder(w.Body).Decode(&output); err != nil {
+		t.Fatalf("Could not decode to json: %s", err.Error())
+	}
+
+	var created struct {
+		Id      int
+		Key     string
+		Scopes  []string
+		Expires *time.Time
+	}
+	if err := json.Unmarshal(output.Data, &created); err != nil {
+		t.Fatalf("Could not decode to json: %s", err.Error())
+	}
+
+	if !strings.HasPrefix(created.Key, "ttk_") || len(created.Scopes) != 1 {
+		t.Fatalf("Invalid API key: [%+v]", created)
+	}
+
+	if created.Expires == nil || created.Expires.After(time.Now().AddDate(0, 0, profile.ApiKeyDefaultExpirationDays+1)) {
+		t.Errorf("Missing default API key expiration: [%v]", created.Expires)
+	}
+
+	// Scopes are required
+	unscoped := map[string]interface{}{"name": "Everything"}
+	r, _ = http.NewRequest("POST", "/api/profile/keys", encodeJson(t, &unscoped))
+	w = httptest.NewRecorder()
+	AddAuthorizationHeaders(r)
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Create API key without scopes status: [%d] wanted: [%d]", w.Code, http.StatusBadRequest)
+	}
+
+	// Only the hash of the key is stored
+	var stored int
+	if err := db.Get(&stored, "SELECT count(*) FROM session WHERE token = $1", created.Key); err != nil || stored != 0 {
+		t.Errorf("Plain API key stored: [%d] [%v]", stored, err)
+	}
+
+	testCases := []struct {
+		name       string
+		method     string
+		path       string
+		statusCode int
+	}{
+		{"Read time", "GET", "/api/time/week", http.StatusOK},
+		{"Write time outside scope", "PUT", "/api/time", http.StatusUnauthorized},
+		{"Reports outside scope", "GET", "/api/report/time/person?from=2017-11-13", http.StatusUnauthorized},
+		{"Keys cannot manage keys", "GET", "/api/profile/keys", http.StatusUnauthorized},
+		{"Keys cannot logout", "POST", "/api/auth/logout", http.StatusBadRequest},
+		{"Keys cannot close the account", "DELETE", "/api/account", http.StatusUnauthorized},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			r, _ := http.NewRequest(testCase.method, testCase.path, nil)
+			AddRequestHeaders(r)
+			r.Header.Add("Authorization", "Bearer "+created.Key)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != testCase.statusCode {
+				t.Errorf("Wrong status code: [%d] wanted: [%d]", w.Code, testCase.statusCode)
+			}
+
+			if w.Header().Get("Set-Cookie") != "" {
+				t.Errorf("API key set a session cookie")
+			}
+		})
+	}
+
+	// Last used is recorded and the key can be revoked
+	r, _ = http.NewRequest("GET", "/api/profile/keys", nil)
+	w = httptest.NewRecorder()
+	AddAuthorizationHeaders(r)
+	router.ServeHTTP(w, r)
+
+	if !strings.Contains(w.Body.String(), "lastUsed") {
+		t.Errorf("Missing API key last used: [%s]", w.Body.String())
+	}
+
+	r, _ = http.NewRequest("DELETE", "/api/profile/keys/"+strconv.Itoa(created.Id), nil)
+	w = httptest.NewRecorder()
+	AddAuthorizationHeaders(r)
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Revoke API key status: [%d] wanted: [%d]", w.Code, http.StatusOK)
+	}
+
+	r, _ = http.NewRequest("GET", "/api/time/week", nil)
+	AddRequestHeaders(r)
+	r.Header.Add("Authorization", "Bearer "+created.Key)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Revoked API key status: [%d] wanted: [%d]", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestResetPasswordRevokesApiKeys(t *testing.T) {
+	createDefaultUnitTestAccount()
+	defer deleteDefaultUnitTestAccount()
+
+	newKey := map[string]interface{}{"name": "Sync", "scopes": []string{"time:read"}}
+	r, _ := http.NewRequest("POST", "/api/profile/keys", encodeJson(t, &newKey))
+	w := httptest.NewRecorder()
+	AddAuthorizationHeaders(r)
+	router.ServeHTTP(w, r)
+
+	var output jsonResult
+	if err := json.NewDecoder(w.Body).Decode(&output); err != nil {
+		t.Fatalf("Could not decode to json: %s", err.Error())
+	}
+
+	var created struct {
+		Key string
+	}
+	if err := json.Unmarshal(output.Data, &created); err != nil || created.Key == "" {
+		t.Fatalf("Could not create API key: [%s] [%v]", w.Body.String(), err)
+	}
+
+	// Reset as done by "timetrack user reset-password"
+	profileService := profile.NewProfileService(profile.NewProfileAccountStore(db))
+	if appErr := profileService.ResetPassword(TestEmail, "Reset-Password-1"); appErr != nil {
+		t.Fatalf("Failed to reset password: %s", appErr.Error())
+	}
+
+	if appErr := profileService.ResetPassword(TestEmail, "short"); appErr == nil || appErr.Code != api.FieldSize {
+		t.Errorf("Short password error: [%v] wanted: [%s]", appErr, api.FieldSize)
+	}
+
+	r, _ = http.NewRequest("GET", "/api/time/week", nil)
+	AddRequestHeaders(r)
+	r.Header.Add("Authorization", "Bearer "+created.Key)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("API key after reset status: [%d] wanted: [%d]", w.Code, http.StatusUnauthorized)
+	}
+}
+
+type sessionResponse struct {
+	Id        int
+	Type      string
+	UserAgent string
+	Current   bool
+}
+
+func TestSessions(t *testing.T) {
+	profileId, accountId := createDefaultUnitTestAccount()
+	defer deleteDefaultUnitTestAccount()
+
+	createTestSession(profileId, accountId, TestToken+"-laptop")
+	createTestSession(profileId, accountId, TestToken+"-phone")
+
+	sessions := getTestSessions(t)
+	if len(sessions) != 3 {
+		t.Fatalf("Wrong number of sessions: [%d] wanted: [3]", len(sessions))
+	}
+
+	// Revoke one other session by id
+	for _, session := range sessions {
+		if session.Current {
+			continue
+		}
+
+		r, _ := http.NewRequest("DELETE", "/api/profile/sessions/"+strconv.Itoa(session.Id), nil)
+		w := httptest.NewRecorder()
+		AddAuthorizationHeaders(r)
+		router.ServeHTTP(w, r)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Revoke session status: [%d] wanted: [%d]", w.Code, http.StatusOK)
+		}
+		break
+	}
+
+	if sessions = getTestSessions(t); len(sessions) != 2 {
+		t.Fatalf("Wrong number of sessions after revoke: [%d] wanted: [2]", len(sessions))
+	}
+
+	// Revoke all except the current session
+	r, _ := http.NewRequest("DELETE", "/api/profile/sessions", nil)
+	w := httptest.NewRecorder()
+	AddAuthorizationHeaders(r)
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Revoke other sessions status: [%d] wanted: [%d]", w.Code, http.StatusOK)
+	}
+
+	if sessions = getTestSessions(t); len(sessions) != 1 || !sessions[0].Current {
+		t.Fatalf("Only the current session should remain: [%+v]", sessions)
+	}
+
+	// Changing the password signs out every other session and revokes API keys
+	createTestSession(profileId, accountId, TestToken+"-tablet")
+	newKey := map[string]interface{}{"name": "Sync", "scopes": []string{"time:read"}}
+	r, _ = http.NewRequest("POST", "/api/profile/keys", encodeJson(t, &newKey))
+	w = httptest.NewRecorder()
+	AddAuthorizationHeaders(r)
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Create API key status: [%d] wanted: [%d]", w.Code, http.StatusOK)
+	}
+
+	newPassword := TestPassword + "updated"
+	body := encodeJson(t, &map[string]interface{}{
+		"currentPassword": TestPassword,
+		"password":        newPassword,
+		"confirmPassword": newPassword,
+	})
+	r, _ = http.NewRequest("PUT", "/api/profile/password", body)
+	w = httptest.NewRecorder()
+	AddAuthorizationHeaders(r)
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Password update status: [%d] wanted: [%d]", w.Code, http.StatusOK)
+	}
+
+	if sessions = getTestSessions(t); len(sessions) != 1 || !sessions[0].Current {
+		t.Errorf("Password change did not revoke other sessions: [%+v]", sessions)
+	}
+
+	var apiKeys int
+	if err := db.Get(&apiKeys, "SELECT count(*) FROM session WHERE profile_id = $1 AND type = 'api'", profileId); err != nil || apiKeys != 0 {
+		t.Errorf("Password change did not revoke API keys: [%d] [%v]", apiKeys, err)
+	}
+}
+
+func getTestSessions(t *testing.T) []sessionResponse {
+	t.Helper()
+	r, _ := http.NewRequest("GET", "/api/profile/sessions", nil)
+	w := httptest.NewRecorder()
+	AddAuthorizationHeaders(r)
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Get sessions status: [%d] wanted: [%d]", w.Code, http.StatusOK)
+	}
+
+	var output jsonResult
+	if err := json.NewDecoder(w.Body).Decode(&output); err != nil {
+		t.Fatalf("Could not decode to json: %s", err.Error())
+	}
+
+	var sessions []sessionResponse
+	if err := json.Unmarshal(output.Data, &sessions); err != nil {
+		t.Fatalf("Could not decode to json: %s", err.Error())
+	}
+
+	return sessions
+}
+
+func TestSwitchAccount(t *testing.T) {
+	profileId, accountId := createDefaultUnitTestAccount()
+	defer deleteDefaultUnitTestAccount()
+
+	// The second test account membership is created as new, so only the first is listed until it is accepted
+	if memberships := getTestMemberships(t, TestToken); len(memberships) != 1 || memberships[0].AccountId != accountId {
+		t.Fatalf("Invalid memberships before accepting the second account: [%+v]", memberships)
+	}
+
+	var accountId2 int
+	err := db.QueryRow(`SELECT account_id FROM profile_account WHERE profile_id = $1 AND account_id != $2`, profileId, accountId).Scan(&accountId2)
+	if err != nil {
+		t.Fatalf("Failed to get second account: %s", err.Error())
+	}
+
+	_, err = db.Exec(`UPDATE profile_account SET profile_account_status = 'valid' WHERE profile_id = $1`, profileId)
+	if err != nil {
+		t.Fatalf("Failed to accept second account: %s", err.Error())
+	}
+
+	memberships := getTestMemberships(t, TestToken)
+	if len(memberships) != 2 {
+		t.Fatalf("Wrong number of memberships: [%d] wanted: [2]", len(memberships))
+	}
+
+	for _, membership := range memberships {
+		if membership.Current != (membership.AccountId == accountId) {
+			t.Errorf("Invalid current membership: [%+v]", membership)
+		}
+	}
+
+	// Accounts the profile does not belong to
+	w, output := switchTestAccount(t, TestToken, accountId2+1000)
+	if w.Code != http.StatusUnauthorized || output.Code != api.NotAuthorized {
+		t.Errorf("Switch to other account status: [%d] code: [%s]", w.Code, output.Code)
+	}
+
+	w, output = switchTestAccount(t, TestToken, accountId2)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Switch account status: [%d] wanted: [%d]", w.Code, http.StatusOK)
+	}
+
+	var auth struct {
+		Company string
+		Token   string
+	}
+	if err := json.Unmarshal(output.Data, &auth); err != nil {
+		t.Fatalf("Could not decode to json: %s", err.Error())
+	}
+
+	if auth.Company != TestCompany2 || auth.Token == "" {
+		t.Fatalf("Invalid switch response: [%+v]", auth)
+	}
+
+	// The old session is signed out and the new one is for the second account
+	r, _ := http.NewRequest("GET", "/api/account/memberships", nil)
+	w = httptest.NewRecorder()
+	AddAuthorizationHeaders(r)
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Old session status: [%d] wanted: [%d]", w.Code, http.StatusUnauthorized)
+	}
+
+	memberships = getTestMemberships(t, auth.Token)
+	if len(memberships) != 2 || memberships[0].AccountId != accountId2 || !memberships[0].Current {
+		t.Errorf("Switched account should be current and most recently used: [%+v]", memberships)
+	}
+
+	// Login picks the most recently used account
+	body := encodeJson(t, &map[string]interface{}{"email": TestEmail, "password": TestPassword})
+	r, _ = http.NewRequest("POST", "/api/auth/login", body)
+	w = httptest.NewRecorder()
+	AddRequestHeaders(r)
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Login status: [%d] wanted: [%d]", w.Code, http.StatusOK)
+	}
+
+	if err := json.NewDecoder(w.Body).Decode(&output); err != nil {
+		t.Fatalf("Could not decode to json: %s", err.Error())
+	}
+
+	if err := json.Unmarshal(output.Data, &auth); err != nil {
+		t.Fatalf("Could not decode to json: %s", err.Error())
+	}
+
+	if auth.Company != TestCompany2 {
+		t.Errorf("Login company: [%s] wanted: [%s]", auth.Company, TestCompany2)
+	}
+}
+
+type membershipResponse struct {
+	AccountId int
+	Company   string
+	Role      string
+	Current   bool
+}
+
+func getTestMemberships(t *testing.T, token string) []membershipResponse {
+	t.Helper()
+	r, _ := http.NewRequest("GET", "/api/account/memberships", nil)
+	w := httptest.NewRecorder()
+	AddRequestHeaders(r)
+	r.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Get memberships status: [%d] wanted: [%d]", w.Code, http.StatusOK)
+	}
+
+	var output jsonResult
+	if err := json.NewDecoder(w.Body).Decode(&output); err != nil {
+		t.Fatalf("Could not decode to json: %s", err.Error())
+	}
+
+	var memberships []membershipResponse
+	if err := json.Unmarshal(output.Data, &memberships); err != nil {
+		t.Fatalf("Could not decode to json: %s", err.Error())
+	}
+
+	return memberships
+}
+
+func switchTestAccount(t *testing.T, token string, accountId int) (*httptest.ResponseRecorder, jsonResult) {
+	t.Helper()
+	r, _ := http.NewRequest("POST", "/api/auth/switch", encodeJson(t, &map[string]interface{}{"accountId": accountId}))
+	w := httptest.NewRecorder()
+	AddRequestHeaders(r)
+	r.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(w, r)
+
+	var output jsonResult
+	if err := json.NewDecoder(w.Body).Decode(&output); err != nil {
+		t.Fatalf("Could not decode to json: %s", err.Error())
+	}
+
+	return w, output
+}
@@ -3,13 +3,16 @@
 package integration_test
 
 import (
+	"database/sql"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/bryanmorgan/time-tracking-api/api"
+	"github.com/bryanmorgan/time-tracking-api/client"
 	_ "github.com/bryanmorgan/time-tracking-api/config"
 	"github.com/bryanmorgan/time-tracking-api/valid"
 )
@@ -583,6 +586,84 @@ func TestUpdateProject(t *testing.T) {
 	}
 }
 
+func TestUpdateProjectBudget(t *testing.T) {
+	_, accountId := createDefaultUnitTestAccount()
+	clientId := createTestClient(accountId, TestClientName, TestClientAddress)
+	projectId := createTestProject(accountId, clientId, TestProjectName)
+	taskId := createTestTask(accountId)
+	createTestProjectTask(accountId, projectId, taskId, 100)
+	updateTestProjectBudget(projectId, 1000, 100000)
+	defer deleteDefaultUnitTestAccount()
+	defer deleteTestClient(clientId)
+	defer deleteTestProject(projectId)
+	defer deleteTestTask(taskId, accountId)
+	defer deleteTestProjectTask(projectId, taskId)
+
+	if _, err := db.Exec("UPDATE project SET budget_alert_percent=80 WHERE project_id=$1", projectId); err != nil {
+		t.Fatalf("Failed to set budget alert percent: %s", err)
+	}
+
+	if _, err := db.Exec("UPDATE project_task SET budget_hours=10 WHERE project_id=$1", projectId); err != nil {
+		t.Fatalf("Failed to set task budget: %s", err)
+	}
+
+	testCases := []struct {
+		name         string
+		budgetHours  interface{}
+		hours        sql.NullFloat64
+		amount       sql.NullFloat64
+		alertPercent int
+	}{
+		{"Budgets left out are unchanged", nil, sql.NullFloat64{Float64: 1000, Valid: true}, sql.NullFloat64{Float64: 100000, Valid: true}, 80},
+		{"Zero clears the budget", 0, sql.NullFloat64{}, sql.NullFloat64{Float64: 100000, Valid: true}, 0},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			request := map[string]interface{}{
+				"id":       projectId,
+				"name":     TestProjectName,
+				"clientId": clientId,
+				"tasks":    []map[string]interface{}{{"id": taskId, "billable": true, "rate": 100}},
+			}
+			if testCase.budgetHours != nil {
+				request["budgetHours"] = testCase.budgetHours
+			}
+
+			r, _ := http.NewRequest("PUT", "/api/client/project", encodeJson(t, &request))
+			w := httptest.NewRecorder()
+			AddAuthorizationHeaders(r)
+			router.ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status code: [%d] wanted: [%d]. Body: %s", w.Code, http.StatusOK, w.Body)
+			}
+
+			var project struct {
+				BudgetHours  sql.NullFloat64 `db:"budget_hours"`
+				BudgetAmount sql.NullFloat64 `db:"budget_amount"`
+				AlertPercent int             `db:"budget_alert_percent"`
+			}
+			if err := db.Get(&project, "SELECT budget_hours, budget_amount, budget_alert_percent FROM project WHERE project_id=$1", projectId); err != nil {
+				t.Fatalf("Failed to get project budget: %s", err)
+			}
+
+			if project.BudgetHours != testCase.hours || project.BudgetAmount != testCase.amount || project.AlertPercent != testCase.alertPercent {
+				t.Errorf("Invalid project budget: [%v] [%v] [%d] wanted: [%v] [%v] [%d]", project.BudgetHours, project.BudgetAmount, project.AlertPercent, testCase.hours, testCase.amount, testCase.alertPercent)
+			}
+
+			var taskHours sql.NullFloat64
+			if err := db.Get(&taskHours, "SELECT budget_hours FROM project_task WHERE project_id=$1 AND task_id=$2", projectId, taskId); err != nil {
+				t.Fatalf("Failed to get task budget: %s", err)
+			}
+
+			if !taskHours.Valid || taskHours.Float64 != 10 {
+				t.Errorf("Task budget changed: [%v] wanted: [10]", taskHours)
+			}
+		})
+	}
+}
+
 func TestDeleteProject(t *testing.T) {
 	_, accountId := createDefaultUnitTestAccount()
 	clientId := createTestClient(accountId, TestClientName, TestClientAddress)
@@ -682,3 +763,188 @@ func TestUpdateProjectActive(t *testing.T) {
 		})
 	}
 }
+
+func TestGetProjectBudget(t *testing.T) {
+	profileId, accountId := createDefaultUnitTestAccount()
+	clientId := createTestClient(accountId, TestClientName, TestClientAddress)
+	projectId := createTestProject(accountId, clientId, TestProjectName)
+	taskId := createTestTask(accountId)
+	createTestProjectTask(accountId, projectId, taskId, 100)
+	createTestTimeEntries("2017-11-13", 7, accountId, profileId, projectId, taskId)
+	updateTestProjectBudget(projectId, 1000, 100000)
+	defer deleteDefaultUnitTestAccount()
+	defer deleteTestClient(clientId)
+	defer deleteTestProject(projectId)
+	defer deleteTestTask(taskId, accountId)
+	defer deleteTestProjectTask(projectId, taskId)
+	defer deleteTestTimeEntries(accountId, profileId, projectId)
+
+	testCases := []struct {
+		name       string
+		projectId  int
+		statusCode int
+		errorCode  string
+	}{
+		{"Valid Project", projectId, http.StatusOK, ""},
+		{"Unknown Project", projectId + 1000, http.StatusBadRequest, api.InvalidProject},
+		{"Invalid Project", 0, http.StatusBadRequest, api.InvalidField},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			r, _ := http.NewRequest("GET", "/api/client/project/"+strconv.Itoa(testCase.projectId)+"/budget", nil)
+			w := httptest.NewRecorder()
+			AddAuthorizationHeaders(r)
+			router.ServeHTTP(w, r)
+
+			if w.Code != testCase.statusCode {
+				t.Errorf("status code: [%d] wanted: [%d]", w.Code, testCase.statusCode)
+			}
+
+			var output jsonResult
+			if err := json.NewDecoder(w.Body).Decode(&output); err != nil {
+				t.Fatalf("could not decode to json: [%s]", err)
+			}
+
+			if testCase.statusCode != http.StatusOK {
+				if output.Code != testCase.errorCode {
+					t.Errorf("wrong error code: [%s] wanted: [%s]", output.Code, testCase.errorCode)
+				}
+				return
+			}
+
+			var budget struct {
+				BudgetHours    float64
+				SpentHours     float64
+				SpentAmount    float64
+				RemainingHours float64
+				Status         string
+				Tasks          []struct {
+					Id         int
+					SpentHours float64
+				}
+			}
+			if err := json.Unmarshal(output.Data, &budget); err != nil {
+				t.Fatalf("could not decode to json: %s", err)
+			}
+
+			if budget.SpentHours <= 0 || budget.SpentAmount <= 0 {
+				t.Errorf("Invalid spent hours: [%g] or amount: [%g]", budget.SpentHours, budget.SpentAmount)
+			}
+
+			if budget.RemainingHours != budget.BudgetHours-budget.SpentHours {
+				t.Errorf("Invalid remaining hours: [%g] wanted: [%g]", budget.RemainingHours, budget.BudgetHours-budget.SpentHours)
+			}
+
+			if budget.Status != "onTrack" {
+				t.Errorf("Invalid budget status: [%s] wanted: [onTrack]", budget.Status)
+			}
+
+			if len(budget.Tasks) != 1 || budget.Tasks[0].Id != taskId || budget.Tasks[0].SpentHours != budget.SpentHours {
+				t.Errorf("Invalid task budgets: [%v]", budget.Tasks)
+			}
+		})
+	}
+}
+
+func TestImportClients(t *testing.T) {
+	_, accountId := createDefaultUnitTestAccount()
+	clientId := createTestClient(accountId, TestClientName, TestClientAddress)
+	projectId := createTestProject(accountId, clientId, TestProjectName)
+	taskId := createTestTask(accountId)
+	createTestProjectTask(accountId, projectId, taskId, 100)
+	defer deleteDefaultUnitTestAccount()
+	defer deleteTestCatalog(accountId)
+
+	jsonImport := `{"clients": [
+		{"name": "` + TestClientName + `", "projects": [
+			{"name": "` + TestProjectName + `", "code": "LAUNCH", "tasks": [
+				{"name": "Test Task", "rate": 125, "billable": true},
+				{"name": "Design", "rate": 90}
+			]}
+		]},
+		{"name": "Globex", "address": "1 Main St", "projects": [{"name": "Website", "tasks": [{"name": "Design"}]}]}
+	]}`
+
+	csvImport := "client,project,code,task,rate,billable\n" +
+		"Globex,Website,WEB,Design,95,no\n" +
+		"\"" + TestClientName + "\",Launch App v2,LAUNCH,Test Task,125,yes\n"
+
+	invalidImport := `{"clients": [{"name": "", "projects": [{"name": "Website", "tasks": [{"name": "Design", "rate": -1}]}]}]}`
+
+	counts := func(created int, updated int, skipped int) client.ImportCountsResponse {
+		return client.ImportCountsResponse{Created: created, Updated: updated, Skipped: skipped}
+	}
+
+	testCases := []struct {
+		name         string
+		query        string
+		contentType  string
+		body         string
+		statusCode   int
+		errorCode    string
+		clients      client.ImportCountsResponse
+		projects     client.ImportCountsResponse
+		tasks        client.ImportCountsResponse
+		projectTasks client.ImportCountsResponse
+		projectCount int
+	}{
+		{"Dry run", "?dryRun=true", "application/json", jsonImport, http.StatusOK, "", counts(1, 0, 1), counts(1, 1, 0), counts(1, 0, 1), counts(2, 1, 0), 1},
+		{"Invalid", "", "application/json", invalidImport, http.StatusBadRequest, api.InvalidImport, counts(0, 0, 0), counts(0, 0, 0), counts(0, 0, 0), counts(0, 0, 0), 1},
+		{"Invalid JSON", "", "application/json", "{", http.StatusBadRequest, api.InvalidJson, counts(0, 0, 0), counts(0, 0, 0), counts(0, 0, 0), counts(0, 0, 0), 1},
+		{"Import JSON", "", "application/json", jsonImport, http.StatusOK, "", counts(1, 0, 1), counts(1, 1, 0), counts(1, 0, 1), counts(2, 1, 0), 2},
+		{"Import again", "", "application/json", jsonImport, http.StatusOK, "", counts(0, 0, 2), counts(0, 0, 2), counts(0, 0, 2), counts(0, 0, 3), 2},
+		{"Import CSV", "", "text/csv; charset=utf-8", csvImport, http.StatusOK, "", counts(0, 0, 2), counts(0, 2, 0), counts(0, 0, 2), counts(0, 1, 1), 2},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			r, _ := http.NewRequest("POST", "/api/client/import"+testCase.query, strings.NewReader(testCase.body))
+			w := httptest.NewRecorder()
+			AddAuthorizationHeaders(r)
+			r.Header.Set("Content-Type", testCase.contentType)
+			router.ServeHTTP(w, r)
+
+			if w.Code != testCase.statusCode {
+				t.Fatalf("status code: [%d] wanted: [%d] body: [%s]", w.Code, testCase.statusCode, w.Body.String())
+			}
+
+			var output jsonResult
+			if err := json.NewDecoder(w.Body).Decode(&output); err != nil {
+				t.Fatalf("could not decode to json: [%s]", err)
+			}
+
+			if testCase.statusCode != http.StatusOK {
+				if output.Code != testCase.errorCode {
+					t.Errorf("wrong error code: [%s] wanted: [%s]", output.Code, testCase.errorCode)
+				}
+			} else {
+				var result client.ImportResponse
+				if err := json.Unmarshal(output.Data, &result); err != nil {
+					t.Fatalf("could not decode to json: %s", err)
+				}
+
+				if result.Clients != testCase.clients || result.Projects != testCase.projects || result.Tasks != testCase.tasks ||
+					result.ProjectTasks != testCase.projectTasks || len(result.Errors) != 0 {
+					t.Errorf("Invalid import result: [%+v]", result)
+				}
+			}
+
+			var projectCount int
+			if err := db.Get(&projectCount, "SELECT count(*) FROM project WHERE account_id=$1", accountId); err != nil {
+				t.Fatalf("Failed to count projects: [%s]", err)
+			}
+
+			if projectCount != testCase.projectCount {
+				t.Errorf("Wrong number of projects: [%d] wanted: [%d]", projectCount, testCase.projectCount)
+			}
+		})
+	}
+
+	var rate float64
+	var billable bool
+	err := db.QueryRow("SELECT rate, billable FROM project_task WHERE project_id=$1 AND task_id=$2", projectId, taskId).Scan(&rate, &billable)
+	if err != nil || rate != 125 || !billable {
+		t.Errorf("Project task not updated. Rate: [%f] billable: [%t] error: [%v]", rate, billable, err)
+	}
+}
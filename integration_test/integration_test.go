@@ -15,11 +15,14 @@ import (
 	"math/rand"
 	"net/http"
 	"os"
+	"regexp"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/bryanmorgan/time-tracking-api/app"
+	"github.com/bryanmorgan/time-tracking-api/database"
+	"github.com/bryanmorgan/time-tracking-api/emails"
 	"github.com/bryanmorgan/time-tracking-api/profile"
 )
 
@@ -59,6 +62,11 @@ func TestMain(m *testing.M) {
 	router = server.Router
 	db = server.DB
 
+	// Build the schema with the same migrations used by the server
+	if _, err := database.MigrateUp(db); err != nil {
+		log.Fatalf("Failed to migrate test database: %s", err.Error())
+	}
+
 	setupVariables()
 	code := m.Run()
 
@@ -370,3 +378,142 @@ func deleteTestTask(taskId int, accountId int) {
 }
 
 
+
+func deleteTestTimesheets(accountId int, profileId int) {
+	_, err := db.Exec("DELETE FROM timesheet WHERE account_id=$1 AND profile_id=$2", accountId, profileId)
+	if err != nil {
+		log.Panicf("Failed to delete timesheets for: [%d] [%d]: [%s]", accountId, profileId, err)
+		return
+	}
+}
+
+func deleteTestTimer(profileId int) {
+	_, err := db.Exec("DELETE FROM timer WHERE profile_id=$1", profileId)
+	if err != nil {
+		log.Panicf("Failed to delete timer for: [%d]: [%s]", profileId, err)
+		return
+	}
+}
+
+func createTestProjectTask(accountId int, projectId int, taskId int, rate float64) {
+	sql := `INSERT INTO project_task (account_id, project_id, task_id, rate, billable) VALUES ($1, $2, $3, $4, TRUE)`
+	_, err := db.Exec(sql, accountId, projectId, taskId, rate)
+	if err != nil {
+		log.Panicf("Failed to create project task: [%d] [%d]: [%s]", projectId, taskId, err)
+		return
+	}
+}
+
+func deleteTestProjectTask(projectId int, taskId int) {
+	_, err := db.Exec("DELETE FROM project_task WHERE project_id=$1 AND task_id=$2", projectId, taskId)
+	if err != nil {
+		log.Panicf("Failed to delete project task: [%d] [%d]: [%s]", projectId, taskId, err)
+		return
+	}
+}
+
+// Delete every client, project, task and project task in the account, e.g. ones created by an import
+func deleteTestCatalog(accountId int) {
+	for _, table := range []string{"project_task", "project", "task", "client"} {
+		_, err := db.Exec("DELETE FROM "+table+" WHERE account_id=$1", accountId)
+		if err != nil {
+			log.Panicf("Failed to delete %s rows for: [%d]: [%s]", table, accountId, err)
+			return
+		}
+	}
+}
+
+func deleteTestHolidays(accountId int) {
+	_, err := db.Exec("DELETE FROM holiday WHERE account_id=$1", accountId)
+	if err != nil {
+		log.Panicf("Failed to delete holidays for: [%d]: [%s]", accountId, err)
+		return
+	}
+}
+
+func deleteTestInvoices(accountId int) {
+	_, err := db.Exec("DELETE FROM invoice_line WHERE invoice_id IN (SELECT invoice_id FROM invoice WHERE account_id=$1)", accountId)
+	if err != nil {
+		log.Panicf("Failed to delete invoice lines for: [%d]: [%s]", accountId, err)
+		return
+	}
+
+	_, err = db.Exec("DELETE FROM invoice WHERE account_id=$1", accountId)
+	if err != nil {
+		log.Panicf("Failed to delete invoices for: [%d]: [%s]", accountId, err)
+		return
+	}
+
+	_, err = db.Exec("DELETE FROM invoice_sequence WHERE account_id=$1", accountId)
+	if err != nil {
+		log.Panicf("Failed to delete invoice sequence for: [%d]: [%s]", accountId, err)
+		return
+	}
+}
+
+func updateTestProjectBudget(projectId int, budgetHours float64, budgetAmount float64) {
+	_, err := db.Exec("UPDATE project SET budget_hours=$2, budget_amount=$3 WHERE project_id=$1", projectId, budgetHours, budgetAmount)
+	if err != nil {
+		log.Panicf("Failed to update project budget: [%d]: [%s]", projectId, err)
+		return
+	}
+}
+
+// Add a new profile to an existing account. Remove it with deleteUnitTestProfileByEmail
+func createTestAccountMember(accountId int, email string, role profile.AuthorizationRole) int {
+	var memberId int
+	profileSql := `INSERT INTO profile (email, password, first_name, last_name, profile_status) VALUES ($1, $2, $3, $4, $5) RETURNING profile_id`
+	err := db.QueryRow(profileSql, strings.ToLower(email), testPasswordEncrypted, TestFirstName, TestLastName, profile.ProfileValid).Scan(&memberId)
+	if err != nil {
+		log.Panicf("Failed to create account member: [%s]: [%s]", email, err)
+		return 0
+	}
+
+	profileAccountSql := `INSERT INTO profile_account (profile_id, account_id, role, profile_account_status) VALUES ($1, $2, $3, $4)`
+	_, err = db.Exec(profileAccountSql, memberId, accountId, role, profile.ProfileAccountValid)
+	if err != nil {
+		log.Panicf("Failed to add account member: [%d] [%d]: [%s]", accountId, memberId, err)
+		return 0
+	}
+
+	return memberId
+}
+
+func createTestSession(profileId int, accountId int, token string) {
+	sessionSql := `INSERT INTO session (profile_id, account_id, token, token_expiration, type, user_agent) VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err := db.Exec(sessionSql, profileId, accountId, token, time.Now().Add(time.Hour), TestSessionType, "Unit Test Browser")
+	if err != nil {
+		log.Panicf("Failed to create session: [%d]: [%s]", profileId, err)
+		return
+	}
+}
+
+var emailTokenPattern = regexp.MustCompile(`verify-token=([A-Za-z0-9_-]+)`)
+
+// Returns the last email sent to the address. The test config uses the outbox email driver
+func lastTestEmail(t *testing.T, email string) *emails.Message {
+	t.Helper()
+	outbox, ok := emails.GetSender().(*emails.OutboxSender)
+	if !ok {
+		t.Fatalf("Email driver is not the outbox")
+	}
+
+	messages := outbox.MessagesTo(strings.ToLower(email))
+	if len(messages) == 0 {
+		t.Fatalf("No email sent to [%s]", email)
+	}
+
+	return messages[len(messages)-1]
+}
+
+// Returns the verify-token in the last email sent to the address
+func lastTestEmailToken(t *testing.T, email string) string {
+	t.Helper()
+	message := lastTestEmail(t, email)
+	match := emailTokenPattern.FindStringSubmatch(message.Text)
+	if match == nil {
+		t.Fatalf("No token in email to [%s]: [%s]", email, message.Text)
+	}
+
+	return match[1]
+}
@@ -0,0 +1,147 @@
+// +build integration
+
+package integration_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bryanmorgan/time-tracking-api/api"
+	_ "github.com/bryanmorgan/time-tracking-api/config"
+)
+
+type invoiceResponse struct {
+	Id     int
+	Number int
+	Status string
+	BillTo string
+	Total  float64
+	Lines  []struct {
+		ProjectId int
+		TaskId    int
+		Hours     float64
+		Rate      float64
+		Amount    float64
+	}
+}
+
+func TestInvoiceLifecycle(t *testing.T) {
+	const entriesStartDate = "2017-11-13"
+	profileId, accountId := createDefaultUnitTestAccount()
+	clientId := createTestClient(accountId, TestClientName, TestClientAddress)
+	projectId := createTestProject(accountId, clientId, TestProjectName)
+	taskId := createTestTask(accountId)
+	createTestProjectTask(accountId, projectId, taskId, 100)
+	createTestTimeEntries(entriesStartDate, 7, accountId, profileId, projectId, taskId)
+	defer deleteDefaultUnitTestAccount()
+	defer deleteTestClient(clientId)
+	defer deleteTestProject(projectId)
+	defer deleteTestTask(taskId, accountId)
+	defer deleteTestProjectTask(projectId, taskId)
+	defer deleteTestTimeEntries(accountId, profileId, projectId)
+	defer deleteTestInvoices(accountId)
+
+	newInvoice := map[string]interface{}{"clientId": clientId, "from": "2017-11-13", "to": "2017-11-19"}
+	invalidClient := map[string]interface{}{"clientId": clientId + 1000, "from": "2017-11-13", "to": "2017-11-19"}
+
+	// Invalid client
+	w, output := invoiceRequest(t, "POST", "/api/invoice", &invalidClient)
+	if w.Code != http.StatusBadRequest || output.Code != api.InvalidClient {
+		t.Errorf("Invalid client status: [%d] code: [%s]", w.Code, output.Code)
+	}
+
+	// Create the first invoice for the account
+	w, output = invoiceRequest(t, "POST", "/api/invoice", &newInvoice)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Create invoice status: [%d] wanted: [%d]", w.Code, http.StatusOK)
+	}
+
+	var created invoiceResponse
+	if err := json.Unmarshal(output.Data, &created); err != nil {
+		t.Fatalf("could not decode to json: %s", err)
+	}
+
+	if created.Number != 1 || created.Status != "draft" || created.BillTo != TestClientAddress {
+		t.Errorf("Invalid invoice number: [%d] status: [%s] billTo: [%s]", created.Number, created.Status, created.BillTo)
+	}
+
+	if len(created.Lines) != 1 || created.Lines[0].Rate != 100 || created.Total != created.Lines[0].Amount {
+		t.Errorf("Invalid invoice lines: [%v] total: [%g]", created.Lines, created.Total)
+	}
+
+	// All time in the range is now invoiced
+	w, output = invoiceRequest(t, "POST", "/api/invoice", &newInvoice)
+	if w.Code != http.StatusBadRequest || output.Code != api.NoBillableTime {
+		t.Errorf("Duplicate invoice status: [%d] code: [%s]", w.Code, output.Code)
+	}
+
+	// Invoiced time cannot be edited
+	entryList := map[string]interface{}{
+		"entries": []interface{}{
+			map[string]interface{}{"day": "2017-11-14", "hours": 1.5, "taskId": taskId, "projectId": projectId},
+		},
+	}
+	w, output = invoiceRequest(t, "PUT", "/api/time", &entryList)
+	if w.Code != http.StatusBadRequest || output.Code != api.TimeInvoiced {
+		t.Errorf("Edit invoiced time status: [%d] code: [%s]", w.Code, output.Code)
+	}
+
+	// CSV download includes the bill-to address
+	w, _ = invoiceRequest(t, "GET", "/api/invoice/"+strconv.Itoa(created.Id)+"/export/csv", nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "1200 Main Street") {
+		t.Errorf("CSV export status: [%d] body: [%s]", w.Code, w.Body.String())
+	}
+
+	// Draft invoices must be sent before they are paid
+	paid := map[string]interface{}{"invoiceId": created.Id, "status": "paid"}
+	w, output = invoiceRequest(t, "PUT", "/api/invoice/status", &paid)
+	if w.Code != http.StatusBadRequest || output.Code != api.InvalidInvoiceStatus {
+		t.Errorf("Pay draft invoice status: [%d] code: [%s]", w.Code, output.Code)
+	}
+
+	// Voiding releases the time so it can be invoiced again
+	void := map[string]interface{}{"invoiceId": created.Id, "status": "void"}
+	w, _ = invoiceRequest(t, "PUT", "/api/invoice/status", &void)
+	if w.Code != http.StatusOK {
+		t.Errorf("Void invoice status: [%d] wanted: [%d]", w.Code, http.StatusOK)
+	}
+
+	w, output = invoiceRequest(t, "POST", "/api/invoice", &newInvoice)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Re-create invoice status: [%d] wanted: [%d]", w.Code, http.StatusOK)
+	}
+
+	var recreated invoiceResponse
+	if err := json.Unmarshal(output.Data, &recreated); err != nil {
+		t.Fatalf("could not decode to json: %s", err)
+	}
+
+	if recreated.Number != 2 {
+		t.Errorf("Invalid invoice number: [%d] wanted: [2]", recreated.Number)
+	}
+}
+
+func invoiceRequest(t *testing.T, method string, path string, body *map[string]interface{}) (*httptest.ResponseRecorder, jsonResult) {
+	var r *http.Request
+	if body != nil {
+		r, _ = http.NewRequest(method, path, encodeJson(t, body))
+	} else {
+		r, _ = http.NewRequest(method, path, nil)
+	}
+	w := httptest.NewRecorder()
+	AddAuthorizationHeaders(r)
+	router.ServeHTTP(w, r)
+
+	var output jsonResult
+	if strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(w.Body).Decode(&output); err != nil {
+			t.Fatalf("could not decode to json: [%s]", err)
+		}
+	}
+
+	return w, output
+}
@@ -0,0 +1,457 @@
+// +build integration
+
+package integration_test
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bryanmorgan/time-tracking-api/api"
+	"github.com/bryanmorgan/time-tracking-api/config"
+	"github.com/bryanmorgan/time-tracking-api/database"
+	"github.com/bryanmorgan/time-tracking-api/profile"
+	"github.com/bryanmorgan/time-tracking-api/reporting"
+)
+
+func TestCapacityAndHolidays(t *testing.T) {
+	profileId, accountId := createDefaultUnitTestAccount()
+	defer deleteDefaultUnitTestAccount()
+	defer deleteTestHolidays(accountId)
+
+	testCases := []struct {
+		name       string
+		profileId  int
+		hours      interface{}
+		statusCode int
+		errorCode  string
+	}{
+		{"Set capacity", profileId, 30, http.StatusOK, ""},
+		{"Too many hours", profileId, 200, http.StatusBadRequest, api.InvalidField},
+		{"Missing hours", profileId, nil, http.StatusBadRequest, api.MissingField},
+		{"Not a member", profileId + 1000000, 40, http.StatusBadRequest, api.ProfileNotFound},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			body := map[string]interface{}{"profileId": testCase.profileId}
+			if testCase.hours != nil {
+				body["hours"] = testCase.hours
+			}
+
+			w, output := invoiceRequest(t, "PUT", "/api/account/capacity", &body)
+			if w.Code != testCase.statusCode || output.Code != testCase.errorCode {
+				t.Errorf("Update capacity status: [%d] code: [%s] wanted: [%d] [%s]", w.Code, output.Code, testCase.statusCode, testCase.errorCode)
+			}
+		})
+	}
+
+	_, output := invoiceRequest(t, "GET", "/api/account/capacity", nil)
+	var capacities []*profile.CapacityResponse
+	if err := json.Unmarshal(output.Data, &capacities); err != nil {
+		t.Fatalf("Invalid capacity response: [%s]", err)
+	}
+
+	if len(capacities) != 1 || capacities[0].ProfileId != profileId || capacities[0].Hours != 30 {
+		t.Fatalf("Invalid capacities: [%+v]", capacities)
+	}
+
+	// Saving a holiday on the same day renames it
+	holidayId := saveTestHoliday(t, "2019-01-01", "New Year")
+	if renamedId := saveTestHoliday(t, "2019-01-01", "New Year's Day"); renamedId != holidayId {
+		t.Errorf("Holiday on the same day was not renamed: [%d] [%d]", holidayId, renamedId)
+	}
+
+	_, output = invoiceRequest(t, "GET", "/api/account/holidays?from=2019-01-01&to=2019-12-31", nil)
+	var holidays []*profile.HolidayResponse
+	if err := json.Unmarshal(output.Data, &holidays); err != nil {
+		t.Fatalf("Invalid holidays response: [%s]", err)
+	}
+
+	if len(holidays) != 1 || holidays[0].Day != "2019-01-01" || holidays[0].Name != "New Year's Day" {
+		t.Fatalf("Invalid holidays: [%+v]", holidays)
+	}
+
+	holidayPath := "/api/account/holidays/" + strconv.Itoa(holidayId)
+	if w, _ := invoiceRequest(t, "DELETE", holidayPath, nil); w.Code != http.StatusOK {
+		t.Fatalf("Delete holiday status: [%d] wanted: [%d]", w.Code, http.StatusOK)
+	}
+
+	if w, output := invoiceRequest(t, "DELETE", holidayPath, nil); w.Code != http.StatusBadRequest || output.Code != api.InvalidHoliday {
+		t.Errorf("Delete missing holiday status: [%d] code: [%s]", w.Code, output.Code)
+	}
+}
+
+func TestUtilizationReport(t *testing.T) {
+	profileId, accountId := createDefaultUnitTestAccount()
+	clientId := createTestClient(accountId, TestClientName, TestClientAddress)
+	projectId := createTestProject(accountId, clientId, TestProjectName)
+	taskId := createTestTask(accountId)
+	createTestProjectTask(accountId, projectId, taskId, 100)
+	defer deleteDefaultUnitTestAccount()
+	defer deleteTestClient(clientId)
+	defer deleteTestProject(projectId)
+	defer deleteTestTask(taskId, accountId)
+	defer deleteTestProjectTask(projectId, taskId)
+	defer deleteTestTimeEntries(accountId, profileId, projectId)
+	defer deleteTestHolidays(accountId)
+
+	body := map[string]interface{}{"profileId": profileId, "hours": 30}
+	if w, _ := invoiceRequest(t, "PUT", "/api/account/capacity", &body); w.Code != http.StatusOK {
+		t.Fatalf("Update capacity status: [%d] wanted: [%d]", w.Code, http.StatusOK)
+	}
+
+	// The report range moves to the profile's week start, so find the Monday the report will start on
+	from, _ := time.Parse(config.ISOShortDateFormat, "2019-01-06")
+	to, _ := time.Parse(config.ISOShortDateFormat, "2019-01-12")
+	weekStart, _ := reporting.AdjustForWeekStart(from, to, 1, time.Now())
+
+	// A holiday on Monday leaves four days of capacity
+	saveTestHoliday(t, weekStart.Format(config.ISOShortDateFormat), "Holiday")
+	_, err := db.Exec(`INSERT INTO time (account_id, profile_id, project_id, task_id, day, hours) VALUES ($1, $2, $3, $4, $5, 12)`,
+		accountId, profileId, projectId, taskId, weekStart.AddDate(0, 0, 1).Format(config.ISOShortDateFormat))
+	if err != nil {
+		t.Fatalf("Failed to create time entry: [%s]", err)
+	}
+
+	w, output := invoiceRequest(t, "GET", "/api/report/utilization?from=2019-01-06&to=2019-01-12", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Utilization status: [%d] wanted: [%d]", w.Code, http.StatusOK)
+	}
+
+	var rows []*reporting.UtilizationResponse
+	if err := json.Unmarshal(output.Data, &rows); err != nil {
+		t.Fatalf("Invalid utilization response: [%s]", err)
+	}
+
+	if len(rows) != 1 {
+		t.Fatalf("Wrong number of utilization rows: [%d] wanted: [1]", len(rows))
+	}
+
+	row := rows[0]
+	if row.ProfileId != profileId || row.WeekStart != weekStart.Format(config.ISOShortDateFormat) {
+		t.Errorf("Invalid utilization row: [%+v]", row)
+	}
+
+	if row.CapacityHours != 24 || row.LoggedHours != 12 || row.UtilizationPercent != 50 || row.BillablePercent != 50 {
+		t.Errorf("Invalid utilization: [%+v]", row)
+	}
+
+	w, output = invoiceRequest(t, "GET", "/api/report/utilization?from=2019-01-06&to=2020-03-01", nil)
+	if w.Code != http.StatusBadRequest || output.Code != api.InvalidField {
+		t.Errorf("Utilization over a year status: [%d] code: [%s]", w.Code, output.Code)
+	}
+}
+
+func saveTestHoliday(t *testing.T, day string, name string) int {
+	t.Helper()
+	body := map[string]interface{}{"day": day, "name": name}
+	w, output := invoiceRequest(t, "POST", "/api/account/holidays", &body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Save holiday status: [%d] wanted: [%d]", w.Code, http.StatusOK)
+	}
+
+	var holiday profile.HolidayResponse
+	if err := json.Unmarshal(output.Data, &holiday); err != nil {
+		t.Fatalf("Invalid holiday response: [%s]", err)
+	}
+
+	return holiday.Id
+}
+
+func TestTimeEntriesReport(t *testing.T) {
+	profileId, accountId := createDefaultUnitTestAccount()
+	clientId := createTestClient(accountId, TestClientName, TestClientAddress)
+	projectId := createTestProject(accountId, clientId, TestProjectName)
+	taskId := createTestTask(accountId)
+	createTestProjectTask(accountId, projectId, taskId, 100)
+	createTestTimeEntries("2019-01-07", 7, accountId, profileId, projectId, taskId)
+	defer deleteDefaultUnitTestAccount()
+	defer deleteTestClient(clientId)
+	defer deleteTestProject(projectId)
+	defer deleteTestTask(taskId, accountId)
+	defer deleteTestProjectTask(projectId, taskId)
+	defer deleteTestTimeEntries(accountId, profileId, projectId)
+
+	// Walk the pages with the cursor until there is no next page
+	path := "/api/report/time/entries?from=2019-01-01&to=2019-01-31&limit=3&projectId=" + strconv.Itoa(projectId)
+	var days []string
+	cursor := ""
+	for pages := 0; pages < 5; pages++ {
+		w, output := invoiceRequest(t, "GET", path+"&cursor="+cursor, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Time entries status: [%d] wanted: [%d]", w.Code, http.StatusOK)
+		}
+
+		var report reporting.TimeEntriesReportResponse
+		if err := json.Unmarshal(output.Data, &report); err != nil {
+			t.Fatalf("Invalid time entries response: [%s]", err)
+		}
+
+		for _, entry := range report.Entries {
+			if entry.ProfileId != profileId || entry.ClientId != clientId || entry.TaskId != taskId || !entry.Billable {
+				t.Errorf("Invalid time entry: [%+v]", entry)
+			}
+			days = append(days, entry.Day)
+		}
+
+		cursor = report.NextCursor
+		if cursor == "" {
+			break
+		}
+	}
+
+	if len(days) != 7 || days[0] != "2019-01-07" || days[6] != "2019-01-13" {
+		t.Fatalf("Invalid time entry days: %v", days)
+	}
+
+	testCases := []struct {
+		name       string
+		query      string
+		statusCode int
+		entries    int
+	}{
+		{"Client filter", "from=2019-01-01&clientId=" + strconv.Itoa(clientId), http.StatusOK, 7},
+		{"Person and task filter", "from=2019-01-01&profileId=" + strconv.Itoa(profileId) + "&taskId=" + strconv.Itoa(taskId), http.StatusOK, 7},
+		{"Non-billable filter", "from=2019-01-01&billable=false", http.StatusOK, 0},
+		{"Date range", "from=2019-01-10&to=2019-01-11", http.StatusOK, 2},
+		{"Invalid billable", "from=2019-01-01&billable=maybe", http.StatusBadRequest, 0},
+		{"Invalid limit", "from=2019-01-01&limit=5000", http.StatusBadRequest, 0},
+		{"Invalid cursor", "from=2019-01-01&cursor=invalid", http.StatusBadRequest, 0},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			w, output := invoiceRequest(t, "GET", "/api/report/time/entries?"+testCase.query, nil)
+			if w.Code != testCase.statusCode {
+				t.Fatalf("Time entries status: [%d] wanted: [%d]", w.Code, testCase.statusCode)
+			}
+
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var report reporting.TimeEntriesReportResponse
+			if err := json.Unmarshal(output.Data, &report); err != nil {
+				t.Fatalf("Invalid time entries response: [%s]", err)
+			}
+
+			if len(report.Entries) != testCase.entries {
+				t.Errorf("Wrong number of time entries: [%d] wanted: [%d]", len(report.Entries), testCase.entries)
+			}
+		})
+	}
+}
+
+func TestTimeEntriesExport(t *testing.T) {
+	profileId, accountId := createDefaultUnitTestAccount()
+	clientId := createTestClient(accountId, TestClientName, TestClientAddress)
+	projectId := createTestProject(accountId, clientId, TestProjectName)
+	taskId := createTestTask(accountId)
+	createTestProjectTask(accountId, projectId, taskId, 100)
+	defer deleteDefaultUnitTestAccount()
+	defer deleteTestClient(clientId)
+	defer deleteTestProject(projectId)
+	defer deleteTestTask(taskId, accountId)
+	defer deleteTestProjectTask(projectId, taskId)
+	defer deleteTestTimeEntries(accountId, profileId, projectId)
+
+	// More entries than a report page and than one cursor fetch
+	days := database.CursorFetchSize + reporting.ReportPaginationLimit
+	createTestTimeEntries("2018-01-01", days, accountId, profileId, projectId, taskId)
+
+	w, _ := invoiceRequest(t, "GET", "/api/report/time/export/entries?from=2018-01-01&to=2019-12-31", nil)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/csv" {
+		t.Fatalf("Time entries export status: [%d] type: [%s]", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	// Header and one row per entry
+	if lines := strings.Count(strings.TrimSpace(w.Body.String()), "\n"); lines != days {
+		t.Errorf("Wrong number of export rows: [%d] wanted: [%d]", lines, days)
+	}
+
+	w, _ = invoiceRequest(t, "GET", "/api/report/time/export/client?from=2018-01-01&to=2019-12-31&format=xlsx", nil)
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Body.String(), "PK") {
+		t.Errorf("Client export status: [%d] type: [%s]", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	// Errors found before the export starts are normal error responses
+	w, output := invoiceRequest(t, "GET", "/api/report/time/export/entries?from=2018-01-01&billable=maybe", nil)
+	if w.Code != http.StatusBadRequest || output.Code != api.InvalidField || w.Header().Get("Content-Disposition") != "" {
+		t.Errorf("Invalid filter export status: [%d] code: [%s]", w.Code, output.Code)
+	}
+}
+
+func TestGroupedTimeReport(t *testing.T) {
+	profileId, accountId := createDefaultUnitTestAccount()
+	clientId := createTestClient(accountId, TestClientName, TestClientAddress)
+	projectId := createTestProject(accountId, clientId, TestProjectName)
+	taskId := createTestTask(accountId)
+	createTestProjectTask(accountId, projectId, taskId, 100)
+	createTestTimeEntries("2019-01-07", 7, accountId, profileId, projectId, taskId)
+	defer deleteDefaultUnitTestAccount()
+	defer deleteTestClient(clientId)
+	defer deleteTestProject(projectId)
+	defer deleteTestTask(taskId, accountId)
+	defer deleteTestProjectTask(projectId, taskId)
+	defer deleteTestTimeEntries(accountId, profileId, projectId)
+
+	var totalHours float64
+	if err := db.Get(&totalHours, "SELECT sum(hours) FROM time WHERE account_id = $1", accountId); err != nil {
+		t.Fatalf("Failed to sum test hours: [%s]", err)
+	}
+
+	// The single dimension reports return the same totals as before
+	for _, path := range []string{"/api/report/time/client", "/api/report/time/project", "/api/report/time/task", "/api/report/time/person"} {
+		w, output := invoiceRequest(t, "GET", path+"?from=2019-01-01&to=2019-01-31", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Report [%s] status: [%d] wanted: [%d]", path, w.Code, http.StatusOK)
+		}
+
+		var rows []map[string]interface{}
+		if err := json.Unmarshal(output.Data, &rows); err != nil {
+			t.Fatalf("Invalid report [%s] response: [%s]", path, err)
+		}
+
+		if len(rows) != 1 || !sameHours(rows[0]["billableHours"].(float64), totalHours) || !sameHours(rows[0]["billableTotal"].(float64), totalHours*100) {
+			t.Errorf("Invalid report [%s] rows: [%+v] wanted hours: [%f]", path, rows, totalHours)
+		}
+	}
+
+	w, output := invoiceRequest(t, "GET", "/api/report/time/group?by=client,project,person,week&from=2019-01-01&to=2019-01-31", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Grouped report status: [%d] wanted: [%d]", w.Code, http.StatusOK)
+	}
+
+	var nodes []*reporting.GroupNodeResponse
+	if err := json.Unmarshal(output.Data, &nodes); err != nil {
+		t.Fatalf("Invalid grouped report response: [%s]", err)
+	}
+
+	node := nodes[0]
+	for _, expected := range []struct {
+		dimension string
+		id        int
+	}{{"client", clientId}, {"project", projectId}, {"person", profileId}, {"week", 0}} {
+		if len(nodes) != 1 || node.Dimension != expected.dimension || node.Id != expected.id || !sameHours(node.BillableHours, totalHours) {
+			t.Fatalf("Invalid [%s] level: [%+v]", expected.dimension, nodes)
+		}
+
+		nodes = node.Children
+		if len(nodes) > 0 {
+			node = nodes[0]
+		}
+	}
+
+	// Accounts start weeks on Monday
+	if node.Day != "2019-01-07" || node.Children != nil {
+		t.Errorf("Invalid week: [%+v]", node)
+	}
+
+	w, output = invoiceRequest(t, "GET", "/api/report/time/group?by=client,year&from=2019-01-01", nil)
+	if w.Code != http.StatusBadRequest || output.Code != api.InvalidDimension {
+		t.Errorf("Invalid dimension status: [%d] code: [%s]", w.Code, output.Code)
+	}
+}
+
+func sameHours(a float64, b float64) bool {
+	return math.Abs(a-b) < 0.001
+}
+
+func TestTimeSeriesReport(t *testing.T) {
+	profileId, accountId := createDefaultUnitTestAccount()
+	clientId := createTestClient(accountId, TestClientName, TestClientAddress)
+	projectId := createTestProject(accountId, clientId, TestProjectName)
+	taskId := createTestTask(accountId)
+	createTestProjectTask(accountId, projectId, taskId, 100)
+	createTestTimeEntries("2019-01-07", 7, accountId, profileId, projectId, taskId)
+	defer deleteDefaultUnitTestAccount()
+	defer deleteTestClient(clientId)
+	defer deleteTestProject(projectId)
+	defer deleteTestTask(taskId, accountId)
+	defer deleteTestProjectTask(projectId, taskId)
+	defer deleteTestTimeEntries(accountId, profileId, projectId)
+
+	var totalHours float64
+	if err := db.Get(&totalHours, "SELECT sum(hours) FROM time WHERE account_id = $1", accountId); err != nil {
+		t.Fatalf("Failed to sum test hours: [%s]", err)
+	}
+
+	// All the time is in the ISO week of Monday 2019-01-07 and the weeks either side are zero
+	w, output := invoiceRequest(t, "GET", "/api/report/time/series?interval=isoWeek&split=client&from=2019-01-01&to=2019-01-20", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Time series status: [%d] wanted: [%d]", w.Code, http.StatusOK)
+	}
+
+	var report reporting.TimeSeriesResponse
+	if err := json.Unmarshal(output.Data, &report); err != nil {
+		t.Fatalf("Invalid time series response: [%s]", err)
+	}
+
+	if len(report.Series) != 1 || report.Series[0].Id != clientId || len(report.Series[0].Points) != 3 {
+		t.Fatalf("Invalid time series: [%+v]", report.Series)
+	}
+
+	points := report.Series[0].Points
+	if points[1].Label != "2019-W02" || !sameHours(points[1].Hours, totalHours) || points[0].Hours != 0 || points[2].Hours != 0 {
+		t.Errorf("Invalid time series points: [%+v] [%+v] [%+v]", points[0], points[1], points[2])
+	}
+
+	w, _ = invoiceRequest(t, "GET", "/api/report/time/export/series?interval=day&from=2019-01-07&to=2019-01-13", nil)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/csv" {
+		t.Fatalf("Time series export status: [%d] type: [%s]", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	// Header and one row per day
+	if lines := strings.Count(strings.TrimSpace(w.Body.String()), "\n"); lines != 7 {
+		t.Errorf("Wrong number of export rows: [%d] wanted: [7]", lines)
+	}
+
+	formats := []struct {
+		format      string
+		contentType string
+		prefix      string
+	}{
+		{"xlsx", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "PK"},
+		{"pdf", "application/pdf", "%PDF"},
+	}
+
+	for _, format := range formats {
+		w, _ = invoiceRequest(t, "GET", "/api/report/time/export/series?interval=day&from=2019-01-07&to=2019-01-13&format="+format.format, nil)
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != format.contentType || !strings.HasPrefix(w.Body.String(), format.prefix) {
+			t.Errorf("Time series %s export status: [%d] type: [%s]", format.format, w.Code, w.Header().Get("Content-Type"))
+		}
+
+		if !strings.HasSuffix(w.Header().Get("Content-Disposition"), "_2019-01-07_to_2019-01-13."+format.format) {
+			t.Errorf("Invalid %s export file name: [%s]", format.format, w.Header().Get("Content-Disposition"))
+		}
+	}
+
+	w, output = invoiceRequest(t, "GET", "/api/report/time/export/client?from=2019-01-07&format=docx", nil)
+	if w.Code != http.StatusBadRequest || output.Code != api.InvalidField {
+		t.Errorf("Unsupported export format status: [%d] code: [%s]", w.Code, output.Code)
+	}
+
+	testCases := []struct {
+		name  string
+		query string
+		code  string
+	}{
+		{"Invalid interval", "interval=year&from=2019-01-01", api.InvalidField},
+		{"Invalid split", "split=week&from=2019-01-01", api.InvalidDimension},
+		{"Too many days", "interval=day&from=2018-01-01&to=2019-12-31", api.InvalidField},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			w, output := invoiceRequest(t, "GET", "/api/report/time/series?"+testCase.query, nil)
+			if w.Code != http.StatusBadRequest || output.Code != testCase.code {
+				t.Errorf("Time series status: [%d] code: [%s] wanted: [%s]", w.Code, output.Code, testCase.code)
+			}
+		})
+	}
+}
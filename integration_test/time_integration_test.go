@@ -3,14 +3,21 @@
 package integration_test
 
 import (
+	"context"
 	"encoding/json"
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/bryanmorgan/time-tracking-api/api"
 	_ "github.com/bryanmorgan/time-tracking-api/config"
+	"github.com/bryanmorgan/time-tracking-api/emails"
+	"github.com/bryanmorgan/time-tracking-api/profile"
+	"github.com/bryanmorgan/time-tracking-api/timesheet"
 )
 
 type timeEntryResponse struct {
@@ -21,6 +28,7 @@ type timeEntryResponse struct {
 	TaskName    string
 	ProjectId   int
 	TaskId      int
+	Notes       string
 }
 type timeDataResponse struct {
 	Entries []timeEntryResponse
@@ -231,6 +239,168 @@ func TestSaveTimeEntries(t *testing.T) {
 	}
 }
 
+func TestUpdateHoursKeepsNotes(t *testing.T) {
+	profileId, accountId := createDefaultUnitTestAccount()
+	clientId := createTestClient(accountId, TestClientName, TestClientAddress)
+	projectId := createTestProject(accountId, clientId, TestProjectName)
+	taskId := createTestTask(accountId)
+	defer deleteDefaultUnitTestAccount()
+	defer deleteTestClient(clientId)
+	defer deleteTestProject(projectId)
+	defer deleteTestTask(taskId, accountId)
+	defer deleteTestTimeEntries(accountId, profileId, projectId)
+
+	testCases := []struct {
+		name  string
+		entry map[string]interface{}
+		hours float64
+		notes string
+	}{
+		{"Save with notes", map[string]interface{}{"hours": 2.0, "notes": "Sprint planning"}, 2, "Sprint planning"},
+		{"Update hours only", map[string]interface{}{"hours": 3.5}, 3.5, "Sprint planning"},
+		{"Clear notes", map[string]interface{}{"hours": 3.5, "notes": ""}, 3.5, ""},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			entry := testCase.entry
+			entry["day"] = "2017-11-20"
+			entry["taskId"] = taskId
+			entry["projectId"] = projectId
+
+			entryList := map[string]interface{}{"entries": []interface{}{entry}}
+			body := encodeJson(t, &entryList)
+
+			r, _ := http.NewRequest("PUT", "/api/time", body)
+			w := httptest.NewRecorder()
+			AddAuthorizationHeaders(r)
+			router.ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status code: [%d] wanted: [%d]", w.Code, http.StatusOK)
+			}
+
+			r, _ = http.NewRequest("GET", "/api/time/week/2017-11-20", nil)
+			w = httptest.NewRecorder()
+			AddAuthorizationHeaders(r)
+			router.ServeHTTP(w, r)
+
+			var output jsonResult
+			if err := json.NewDecoder(w.Body).Decode(&output); err != nil {
+				t.Fatalf("could not decode to json: [%s]", err)
+			}
+
+			timeResponseJson := timeDataResponse{}
+			if err := json.Unmarshal(output.Data, &timeResponseJson); err != nil {
+				t.Fatalf("could not decode to json: %s", err)
+			}
+
+			if len(timeResponseJson.Entries) != 1 {
+				t.Fatalf("Expected 1 entry got: [%d]", len(timeResponseJson.Entries))
+			}
+
+			if timeResponseJson.Entries[0].Hours != testCase.hours || timeResponseJson.Entries[0].Notes != testCase.notes {
+				t.Errorf("Invalid entry: [%+v] wanted hours [%g] and notes [%s]", timeResponseJson.Entries[0], testCase.hours, testCase.notes)
+			}
+		})
+	}
+}
+
+func TestSearchTimeEntryNotes(t *testing.T) {
+	profileId, accountId := createDefaultUnitTestAccount()
+	clientId := createTestClient(accountId, TestClientName, TestClientAddress)
+	projectId := createTestProject(accountId, clientId, TestProjectName)
+	taskId := createTestTask(accountId)
+	defer deleteDefaultUnitTestAccount()
+	defer deleteTestClient(clientId)
+	defer deleteTestProject(projectId)
+	defer deleteTestTask(taskId, accountId)
+	defer deleteTestTimeEntries(accountId, profileId, projectId)
+
+	entryList := map[string]interface{}{
+		"entries": [2]interface{}{
+			map[string]interface{}{
+				"day":       "2017-11-20",
+				"hours":     2.5,
+				"taskId":    taskId,
+				"projectId": projectId,
+				"notes":     "Kickoff meeting with 100% of the team",
+			},
+			map[string]interface{}{
+				"day":       "2017-11-21",
+				"hours":     4.0,
+				"taskId":    taskId,
+				"projectId": projectId,
+				"notes":     "Design review",
+			},
+		},
+	}
+	body := encodeJson(t, &entryList)
+
+	r, _ := http.NewRequest("PUT", "/api/time", body)
+	w := httptest.NewRecorder()
+	AddAuthorizationHeaders(r)
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code: [%d] wanted: [%d]", w.Code, http.StatusOK)
+	}
+
+	testCases := []struct {
+		name       string
+		query      string
+		entries    int
+		statusCode int
+		errorCode  string
+	}{
+		{"Match one entry", "?q=kickoff&from=2017-11-20&to=2017-11-26", 1, http.StatusOK, ""},
+		{"Match is case insensitive", "?q=REVIEW&from=2017-11-20&to=2017-11-26", 1, http.StatusOK, ""},
+		{"Wildcard is literal", "?q=100%25&from=2017-11-20&to=2017-11-26", 1, http.StatusOK, ""},
+		{"No match", "?q=invoice&from=2017-11-20&to=2017-11-26", 0, http.StatusOK, ""},
+		{"Outside date range", "?q=kickoff&from=2017-11-21&to=2017-11-26", 0, http.StatusOK, ""},
+		{"Search too short", "?q=k&from=2017-11-20&to=2017-11-26", 0, http.StatusBadRequest, api.FieldSize},
+		{"Missing from", "?q=kickoff", 0, http.StatusBadRequest, api.InvalidField},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			r, _ := http.NewRequest("GET", "/api/time/search"+testCase.query, nil)
+			w := httptest.NewRecorder()
+			AddAuthorizationHeaders(r)
+			router.ServeHTTP(w, r)
+
+			if w.Code != testCase.statusCode {
+				t.Errorf("status code: [%d] wanted: [%d]", w.Code, testCase.statusCode)
+			}
+
+			var output jsonResult
+			if err := json.NewDecoder(w.Body).Decode(&output); err != nil {
+				t.Fatalf("could not decode to json: [%s]", err)
+			}
+
+			if testCase.statusCode != http.StatusOK {
+				if output.Code != testCase.errorCode {
+					t.Errorf("wrong error code: [%s] wanted: [%s]", output.Code, testCase.errorCode)
+				}
+				return
+			}
+
+			timeResponseJson := timeDataResponse{}
+			if err := json.Unmarshal(output.Data, &timeResponseJson); err != nil {
+				t.Fatalf("could not decode to json: %s", err)
+			}
+
+			if len(timeResponseJson.Entries) != testCase.entries {
+				t.Fatalf("Wrong result entry count: [%d] wanted: [%d]", len(timeResponseJson.Entries), testCase.entries)
+			}
+
+			if testCase.entries > 0 && timeResponseJson.Entries[0].Notes == "" {
+				t.Errorf("Missing notes on entry for day: [%s]", timeResponseJson.Entries[0].Day)
+			}
+		})
+	}
+}
+
 func TestDeleteProjectTimeEntries(t *testing.T) {
 	profileId, accountId := createDefaultUnitTestAccount()
 	clientId := createTestClient(accountId, TestClientName, TestClientAddress)
@@ -293,3 +463,533 @@ func TestDeleteProjectTimeEntries(t *testing.T) {
 		})
 	}
 }
+
+// The admin manages the member's week, since reviewers cannot review their own weeks
+func TestTimesheetApprovalWorkflow(t *testing.T) {
+	const weekStartDate = "2017-11-20"
+	const memberEmail = "approval.member@example.com"
+	profileId, accountId := createDefaultUnitTestAccount()
+	memberId := createTestAccountMember(accountId, memberEmail, profile.User)
+	clientId := createTestClient(accountId, TestClientName, TestClientAddress)
+	projectId := createTestProject(accountId, clientId, TestProjectName)
+	taskId := createTestTask(accountId)
+	createTestTimeEntries(weekStartDate, 7, accountId, memberId, projectId, taskId)
+	defer deleteDefaultUnitTestAccount()
+	defer deleteUnitTestProfileByEmail(memberEmail)
+	defer deleteTestClient(clientId)
+	defer deleteTestProject(projectId)
+	defer deleteTestTask(taskId, accountId)
+	defer deleteTestTimeEntries(accountId, memberId, projectId)
+	defer deleteTestTimesheets(accountId, memberId)
+	defer deleteTestTimesheets(accountId, profileId)
+
+	member := "?profileId=" + strconv.Itoa(memberId)
+	review := map[string]interface{}{"profileId": memberId, "reason": "Missing Friday hours"}
+	selfReview := map[string]interface{}{"profileId": profileId, "reason": "Missing Friday hours"}
+	entries := map[string]interface{}{
+		"entries": [1]interface{}{
+			map[string]interface{}{
+				"day":       "2017-11-22",
+				"hours":     3.0,
+				"taskId":    taskId,
+				"projectId": projectId,
+			},
+		},
+	}
+
+	testCases := []struct {
+		name       string
+		method     string
+		path       string
+		body       *map[string]interface{}
+		status     string
+		statusCode int
+		errorCode  string
+	}{
+		{"Week starts as draft", "GET", "/api/time/week/" + weekStartDate + member, nil, "draft", http.StatusOK, ""},
+		{"Approve draft fails", "POST", "/api/time/week/" + weekStartDate + "/approve", &review, "", http.StatusBadRequest, api.InvalidTimesheetStatus},
+		{"Submit mid-week date", "POST", "/api/time/week/2017-11-23/submit" + member, nil, "", http.StatusOK, ""},
+		{"Week is submitted", "GET", "/api/time/week/" + weekStartDate + member, nil, "submitted", http.StatusOK, ""},
+		{"Submit twice fails", "POST", "/api/time/week/" + weekStartDate + "/submit" + member, nil, "", http.StatusBadRequest, api.InvalidTimesheetStatus},
+		{"Reject", "POST", "/api/time/week/" + weekStartDate + "/reject", &review, "", http.StatusOK, ""},
+		{"Week is rejected", "GET", "/api/time/week/" + weekStartDate + member, nil, "rejected", http.StatusOK, ""},
+		{"Resubmit", "POST", "/api/time/week/" + weekStartDate + "/submit" + member, nil, "", http.StatusOK, ""},
+		{"Reopen submitted week fails", "POST", "/api/time/week/" + weekStartDate + "/reopen", &review, "", http.StatusBadRequest, api.InvalidTimesheetStatus},
+		{"Approve", "POST", "/api/time/week/" + weekStartDate + "/approve", &review, "", http.StatusOK, ""},
+		{"Week is approved", "GET", "/api/time/week/" + weekStartDate + member, nil, "approved", http.StatusOK, ""},
+		{"Update approved week fails", "PUT", "/api/time" + member, &entries, "", http.StatusBadRequest, api.TimesheetApproved},
+		{"Reopen own week fails", "POST", "/api/time/week/" + weekStartDate + "/reopen", &selfReview, "", http.StatusBadRequest, api.InvalidField},
+		{"Reopen", "POST", "/api/time/week/" + weekStartDate + "/reopen", &review, "", http.StatusOK, ""},
+		{"Week is draft again", "GET", "/api/time/week/" + weekStartDate + member, nil, "draft", http.StatusOK, ""},
+		{"Update reopened week", "PUT", "/api/time" + member, &entries, "", http.StatusOK, ""},
+		{"Submit own week", "POST", "/api/time/week/" + weekStartDate + "/submit", nil, "", http.StatusOK, ""},
+		{"Approve own week fails", "POST", "/api/time/week/" + weekStartDate + "/approve", &selfReview, "", http.StatusBadRequest, api.InvalidField},
+		{"Reject own week fails", "POST", "/api/time/week/" + weekStartDate + "/reject", &selfReview, "", http.StatusBadRequest, api.InvalidField},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var r *http.Request
+			if testCase.body != nil {
+				r, _ = http.NewRequest(testCase.method, testCase.path, encodeJson(t, testCase.body))
+			} else {
+				r, _ = http.NewRequest(testCase.method, testCase.path, nil)
+			}
+			w := httptest.NewRecorder()
+			AddAuthorizationHeaders(r)
+			router.ServeHTTP(w, r)
+
+			if w.Code != testCase.statusCode {
+				t.Errorf("status code: [%d] wanted: [%d]", w.Code, testCase.statusCode)
+			}
+
+			var output jsonResult
+			if err := json.NewDecoder(w.Body).Decode(&output); err != nil {
+				t.Fatalf("could not decode to json: [%s]", err)
+			}
+
+			if testCase.statusCode != http.StatusOK {
+				if output.Code != testCase.errorCode {
+					t.Errorf("wrong error code: [%s] wanted: [%s]", output.Code, testCase.errorCode)
+				}
+				return
+			}
+
+			if testCase.status != "" {
+				var weekResponse struct {
+					Status string
+				}
+				if err := json.Unmarshal(output.Data, &weekResponse); err != nil {
+					t.Fatalf("could not decode to json: %s", err)
+				}
+
+				if weekResponse.Status != testCase.status {
+					t.Errorf("Invalid timesheet status: [%s] wanted: [%s]", weekResponse.Status, testCase.status)
+				}
+			}
+		})
+	}
+}
+
+// The project has no budget, so only the task budget alerts
+func TestTaskBudgetAlert(t *testing.T) {
+	profileId, accountId := createDefaultUnitTestAccount()
+	clientId := createTestClient(accountId, TestClientName, TestClientAddress)
+	projectId := createTestProject(accountId, clientId, TestProjectName)
+	taskId := createTestTask(accountId)
+	createTestProjectTask(accountId, projectId, taskId, 100)
+	defer deleteDefaultUnitTestAccount()
+	defer deleteTestClient(clientId)
+	defer deleteTestProject(projectId)
+	defer deleteTestTask(taskId, accountId)
+	defer deleteTestProjectTask(projectId, taskId)
+	defer deleteTestTimeEntries(accountId, profileId, projectId)
+
+	if _, err := db.Exec("UPDATE project_task SET budget_hours=10 WHERE project_id=$1 AND task_id=$2", projectId, taskId); err != nil {
+		t.Fatalf("Failed to set task budget: %s", err)
+	}
+
+	entries := map[string]interface{}{
+		"entries": [1]interface{}{
+			map[string]interface{}{
+				"day":       "2017-11-20",
+				"hours":     9.0,
+				"taskId":    taskId,
+				"projectId": projectId,
+			},
+		},
+	}
+
+	r, _ := http.NewRequest("PUT", "/api/time", encodeJson(t, &entries))
+	w := httptest.NewRecorder()
+	AddAuthorizationHeaders(r)
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code: [%d] wanted: [%d]. Body: %s", w.Code, http.StatusOK, w.Body)
+	}
+
+	var alertPercent int
+	if err := db.Get(&alertPercent, "SELECT budget_alert_percent FROM project_task WHERE project_id=$1 AND task_id=$2", projectId, taskId); err != nil {
+		t.Fatalf("Failed to get task budget alert: %s", err)
+	}
+
+	if alertPercent != 80 {
+		t.Errorf("Invalid task budget alert percent: [%d] wanted: [80]", alertPercent)
+	}
+
+	alert := lastTestEmail(t, TestEmail)
+	if !strings.HasPrefix(alert.Subject, TestProjectName+" / ") || !strings.Contains(alert.Subject, "80%") {
+		t.Errorf("Invalid task budget alert: [%s]", alert.Subject)
+	}
+}
+
+func TestTimer(t *testing.T) {
+	profileId, accountId := createDefaultUnitTestAccount()
+	clientId := createTestClient(accountId, TestClientName, TestClientAddress)
+	projectId := createTestProject(accountId, clientId, TestProjectName)
+	taskId := createTestTask(accountId)
+	defer deleteDefaultUnitTestAccount()
+	defer deleteTestClient(clientId)
+	defer deleteTestProject(projectId)
+	defer deleteTestTask(taskId, accountId)
+	defer deleteTestTimeEntries(accountId, profileId, projectId)
+	defer deleteTestTimer(profileId)
+
+	start := map[string]interface{}{"projectId": projectId, "taskId": taskId, "notes": "Pairing session"}
+	invalidProject := map[string]interface{}{"projectId": projectId + 1000, "taskId": taskId}
+
+	testCases := []struct {
+		name       string
+		method     string
+		path       string
+		body       *map[string]interface{}
+		running    bool
+		statusCode int
+		errorCode  string
+	}{
+		{"No current timer", "GET", "/api/time/timer/current", nil, false, http.StatusOK, ""},
+		{"Stop without timer", "POST", "/api/time/timer/stop", nil, false, http.StatusBadRequest, api.TimerNotRunning},
+		{"Start invalid project", "POST", "/api/time/timer/start", &invalidProject, false, http.StatusBadRequest, api.InvalidProject},
+		{"Start", "POST", "/api/time/timer/start", &start, true, http.StatusOK, ""},
+		{"Start twice", "POST", "/api/time/timer/start", &start, false, http.StatusBadRequest, api.TimerRunning},
+		{"Current timer", "GET", "/api/time/timer/current", nil, true, http.StatusOK, ""},
+		{"Discard", "POST", "/api/time/timer/discard", nil, false, http.StatusOK, ""},
+		{"Discard twice", "POST", "/api/time/timer/discard", nil, false, http.StatusBadRequest, api.TimerNotRunning},
+		{"Restart", "POST", "/api/time/timer/start", &start, true, http.StatusOK, ""},
+		{"Stop", "POST", "/api/time/timer/stop", nil, false, http.StatusOK, ""},
+		{"No timer after stop", "GET", "/api/time/timer/current", nil, false, http.StatusOK, ""},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var r *http.Request
+			if testCase.body != nil {
+				r, _ = http.NewRequest(testCase.method, testCase.path, encodeJson(t, testCase.body))
+			} else {
+				r, _ = http.NewRequest(testCase.method, testCase.path, nil)
+			}
+			w := httptest.NewRecorder()
+			AddAuthorizationHeaders(r)
+			router.ServeHTTP(w, r)
+
+			if w.Code != testCase.statusCode {
+				t.Errorf("status code: [%d] wanted: [%d]", w.Code, testCase.statusCode)
+			}
+
+			var output jsonResult
+			if err := json.NewDecoder(w.Body).Decode(&output); err != nil {
+				t.Fatalf("could not decode to json: [%s]", err)
+			}
+
+			if testCase.statusCode != http.StatusOK {
+				if output.Code != testCase.errorCode {
+					t.Errorf("wrong error code: [%s] wanted: [%s]", output.Code, testCase.errorCode)
+				}
+				return
+			}
+
+			if testCase.running {
+				var timerResponse struct {
+					ProjectId int
+					TaskId    int
+					Started   string
+				}
+				if err := json.Unmarshal(output.Data, &timerResponse); err != nil {
+					t.Fatalf("could not decode to json: %s", err)
+				}
+
+				if timerResponse.ProjectId != projectId || timerResponse.TaskId != taskId {
+					t.Errorf("Invalid timer projectId: [%d] or taskId: [%d]", timerResponse.ProjectId, timerResponse.TaskId)
+				}
+
+				if timerResponse.Started == "" {
+					t.Errorf("Missing timer start time")
+				}
+			}
+		})
+	}
+}
+
+func TestTimeOnBehalfOfMember(t *testing.T) {
+	const weekStartDate = "2017-11-20"
+	const memberEmail = "Unit.Test.Member@example.com"
+	_, accountId := createDefaultUnitTestAccount()
+	memberId := createTestAccountMember(accountId, memberEmail, profile.User)
+	clientId := createTestClient(accountId, TestClientName, TestClientAddress)
+	projectId := createTestProject(accountId, clientId, TestProjectName)
+	taskId := createTestTask(accountId)
+	defer deleteDefaultUnitTestAccount()
+	defer deleteUnitTestProfileByEmail(memberEmail)
+	defer deleteTestClient(clientId)
+	defer deleteTestProject(projectId)
+	defer deleteTestTask(taskId, accountId)
+	defer deleteTestTimeEntries(accountId, memberId, projectId)
+	defer deleteTestTimesheets(accountId, memberId)
+
+	entries := map[string]interface{}{
+		"entries": [1]interface{}{
+			map[string]interface{}{
+				"day":       "2017-11-22",
+				"hours":     6.5,
+				"taskId":    taskId,
+				"projectId": projectId,
+			},
+		},
+	}
+	memberQuery := "?profileId=" + strconv.Itoa(memberId)
+
+	testCases := []struct {
+		name       string
+		method     string
+		path       string
+		body       *map[string]interface{}
+		statusCode int
+		errorCode  string
+	}{
+		{"Enter member time", "PUT", "/api/time" + memberQuery, &entries, http.StatusOK, ""},
+		{"Submit member week", "POST", "/api/time/week/" + weekStartDate + "/submit" + memberQuery, nil, http.StatusOK, ""},
+		{"Invalid profile id", "GET", "/api/time/week/" + weekStartDate + "?profileId=abc", nil, http.StatusBadRequest, api.InvalidField},
+		{"Profile outside account", "GET", "/api/time/week/" + weekStartDate + "?profileId=2147483647", nil, http.StatusBadRequest, api.ProfileNotFound},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var r *http.Request
+			if testCase.body != nil {
+				r, _ = http.NewRequest(testCase.method, testCase.path, encodeJson(t, testCase.body))
+			} else {
+				r, _ = http.NewRequest(testCase.method, testCase.path, nil)
+			}
+			w := httptest.NewRecorder()
+			AddAuthorizationHeaders(r)
+			router.ServeHTTP(w, r)
+
+			if w.Code != testCase.statusCode {
+				t.Errorf("status code: [%d] wanted: [%d]", w.Code, testCase.statusCode)
+			}
+
+			var output jsonResult
+			if err := json.NewDecoder(w.Body).Decode(&output); err != nil {
+				t.Fatalf("could not decode to json: [%s]", err)
+			}
+
+			if testCase.statusCode != http.StatusOK && output.Code != testCase.errorCode {
+				t.Errorf("wrong error code: [%s] wanted: [%s]", output.Code, testCase.errorCode)
+			}
+		})
+	}
+
+	// The member's week has the admin's entry and submission
+	r, _ := http.NewRequest("GET", "/api/time/week/"+weekStartDate+memberQuery, nil)
+	w := httptest.NewRecorder()
+	AddAuthorizationHeaders(r)
+	router.ServeHTTP(w, r)
+
+	var output jsonResult
+	if err := json.NewDecoder(w.Body).Decode(&output); err != nil {
+		t.Fatalf("could not decode to json: [%s]", err)
+	}
+
+	var weekResponse struct {
+		Status  string
+		Entries []struct {
+			Hours     float64
+			UpdatedBy int
+		}
+	}
+	if err := json.Unmarshal(output.Data, &weekResponse); err != nil {
+		t.Fatalf("could not decode to json: %s", err)
+	}
+
+	if weekResponse.Status != "submitted" || len(weekResponse.Entries) != 1 || weekResponse.Entries[0].Hours != 6.5 {
+		t.Fatalf("Invalid member week: [%+v]", weekResponse)
+	}
+
+	if weekResponse.Entries[0].UpdatedBy == 0 || weekResponse.Entries[0].UpdatedBy == memberId {
+		t.Errorf("Invalid updatedBy: [%d] wanted the admin profile", weekResponse.Entries[0].UpdatedBy)
+	}
+}
+
+func TestTimeOnBehalfRequiresAdmin(t *testing.T) {
+	const memberEmail = "Unit.Test.Member@example.com"
+	_, accountId := createUnitTestAccount(TestEmail, TestFirstName, TestLastName, TestCompany, TestCompany2, profile.User)
+	memberId := createTestAccountMember(accountId, memberEmail, profile.User)
+	defer deleteDefaultUnitTestAccount()
+	defer deleteUnitTestProfileByEmail(memberEmail)
+
+	r, _ := http.NewRequest("GET", "/api/time/week?profileId="+strconv.Itoa(memberId), nil)
+	w := httptest.NewRecorder()
+	AddAuthorizationHeaders(r)
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status code: [%d] wanted: [%d]", w.Code, http.StatusUnauthorized)
+	}
+
+	var output jsonResult
+	if err := json.NewDecoder(w.Body).Decode(&output); err != nil {
+		t.Fatalf("could not decode to json: [%s]", err)
+	}
+
+	if output.Code != api.NotAuthorized {
+		t.Errorf("wrong error code: [%s] wanted: [%s]", output.Code, api.NotAuthorized)
+	}
+}
+
+func TestTimesheetReminders(t *testing.T) {
+	const memberEmail = "reminder.member@example.com"
+	const adminEmail = "reminder.admin@example.com"
+	_, accountId := createDefaultUnitTestAccount()
+	memberId := createTestAccountMember(accountId, memberEmail, profile.User)
+	adminId := createTestAccountMember(accountId, adminEmail, profile.Admin)
+	defer deleteDefaultUnitTestAccount()
+	defer deleteUnitTestProfileByEmail(memberEmail)
+	defer deleteUnitTestProfileByEmail(adminEmail)
+
+	// The member and admin opt out of their own reminders but are still listed in the digest, which the admin still gets
+	if _, err := db.Exec("UPDATE profile SET timesheet_reminders = false WHERE profile_id IN ($1, $2)", memberId, adminId); err != nil {
+		t.Fatalf("Failed to opt out of reminders: %s", err)
+	}
+
+	timeService := timesheet.NewTimeService(timesheet.NewTimeStore(db))
+	subjects := func(email string) []string {
+		var found []string
+		for _, message := range emails.GetSender().(*emails.OutboxSender).MessagesTo(email) {
+			if strings.Contains(message.Subject, "2017-11-13") {
+				found = append(found, message.Subject)
+			}
+		}
+		return found
+	}
+
+	// Accounts default to weeks starting Monday in America/New_York. 7am on Monday is before the reminder hour
+	if _, appErr := timeService.SendTimesheetReminders(context.Background(), time.Date(2017, 11, 20, 12, 0, 0, 0, time.UTC)); appErr != nil {
+		t.Fatalf("Send reminders failed: %s", appErr)
+	}
+
+	if sent := subjects(strings.ToLower(TestEmail)); len(sent) != 0 {
+		t.Fatalf("Reminders sent before the reminder hour: %v", sent)
+	}
+
+	// 10am on Monday reminds everyone for the week of 2017-11-13
+	if _, appErr := timeService.SendTimesheetReminders(context.Background(), time.Date(2017, 11, 20, 15, 0, 0, 0, time.UTC)); appErr != nil {
+		t.Fatalf("Send reminders failed: %s", appErr)
+	}
+
+	sent := subjects(strings.ToLower(TestEmail))
+	if len(sent) != 2 {
+		t.Fatalf("Expected a reminder and a digest for the admin: %v", sent)
+	}
+
+	digest := lastTestEmail(t, TestEmail)
+	if !strings.HasPrefix(digest.Subject, "3 incomplete timesheets") || !strings.Contains(digest.Text, TestFirstName+" "+TestLastName) {
+		t.Errorf("Invalid digest: [%s] [%s]", digest.Subject, digest.Text)
+	}
+
+	if sent := subjects(memberEmail); len(sent) != 0 {
+		t.Errorf("Reminder sent to a member who opted out: %v", sent)
+	}
+
+	if sent := subjects(adminEmail); len(sent) != 1 || !strings.HasPrefix(sent[0], "3 incomplete timesheets") {
+		t.Errorf("Expected only the digest for the admin who opted out of reminders: %v", sent)
+	}
+
+	// The week is only reminded once
+	if _, appErr := timeService.SendTimesheetReminders(context.Background(), time.Date(2017, 11, 20, 16, 0, 0, 0, time.UTC)); appErr != nil {
+		t.Fatalf("Send reminders failed: %s", appErr)
+	}
+
+	if sent := subjects(strings.ToLower(TestEmail)); len(sent) != 2 {
+		t.Errorf("Reminders sent twice for the week: %v", sent)
+	}
+}
+
+func TestImportTimeEntries(t *testing.T) {
+	const memberEmail = "Unit.Test.Member@example.com"
+	profileId, accountId := createDefaultUnitTestAccount()
+	memberId := createTestAccountMember(accountId, memberEmail, profile.User)
+	clientId := createTestClient(accountId, TestClientName, TestClientAddress)
+	projectId := createTestProject(accountId, clientId, TestProjectName)
+	taskId := createTestTask(accountId)
+	createTestProjectTask(accountId, projectId, taskId, 100)
+	defer deleteDefaultUnitTestAccount()
+	defer deleteUnitTestProfileByEmail(memberEmail)
+	defer deleteTestClient(clientId)
+	defer deleteTestProject(projectId)
+	defer deleteTestTask(taskId, accountId)
+	defer deleteTestProjectTask(projectId, taskId)
+	defer deleteTestTimeEntries(accountId, profileId, projectId)
+	defer deleteTestTimeEntries(accountId, memberId, projectId)
+
+	file := "date,email,client,project,task,hours,notes\n" +
+		"2019-01-07," + TestEmail + ",\"" + TestClientName + "\"," + TestProjectName + ",Test Task,2.5,Kickoff\n" +
+		"2019-01-08," + TestEmail + ",\"" + TestClientName + "\"," + TestProjectName + ",Test Task,4,\n" +
+		"2019-01-07," + memberEmail + ",\"" + TestClientName + "\"," + TestProjectName + ",Test Task,1.5,\n"
+	invalidFile := file + "2019-01-09,nobody@example.com,\"" + TestClientName + "\"," + TestProjectName + ",Test Task,1,\n"
+
+	countEntries := func() int {
+		var count int
+		if err := db.Get(&count, "SELECT count(*) FROM time WHERE account_id = $1", accountId); err != nil {
+			t.Fatalf("Failed to count time entries: [%s]", err)
+		}
+		return count
+	}
+
+	testCases := []struct {
+		name       string
+		query      string
+		file       string
+		statusCode int
+		errorCode  string
+		imported   int
+		errors     int
+		entries    int
+	}{
+		{"Dry run", "?dryRun=true", file, http.StatusOK, "", 0, 0, 0},
+		{"Dry run with errors", "?dryRun=true", invalidFile, http.StatusOK, "", 0, 1, 0},
+		{"Import with errors", "", invalidFile, http.StatusBadRequest, api.InvalidImport, 0, 0, 0},
+		{"Missing column", "", "date,email,client,project,hours\n", http.StatusBadRequest, api.InvalidImport, 0, 0, 0},
+		{"Invalid dry run", "?dryRun=maybe", file, http.StatusBadRequest, api.InvalidField, 0, 0, 0},
+		{"Import", "", file, http.StatusOK, "", 3, 0, 3},
+		{"Import again", "", file, http.StatusOK, "", 3, 0, 3},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			r, _ := http.NewRequest("POST", "/api/time/import"+testCase.query, strings.NewReader(testCase.file))
+			w := httptest.NewRecorder()
+			AddAuthorizationHeaders(r)
+			r.Header.Set("Content-Type", "text/csv")
+			router.ServeHTTP(w, r)
+
+			if w.Code != testCase.statusCode {
+				t.Fatalf("status code: [%d] wanted: [%d] body: [%s]", w.Code, testCase.statusCode, w.Body.String())
+			}
+
+			var output jsonResult
+			if err := json.NewDecoder(w.Body).Decode(&output); err != nil {
+				t.Fatalf("could not decode to json: [%s]", err)
+			}
+
+			if testCase.statusCode != http.StatusOK {
+				if output.Code != testCase.errorCode {
+					t.Errorf("wrong error code: [%s] wanted: [%s]", output.Code, testCase.errorCode)
+				}
+			} else {
+				var result timesheet.TimeImportResponse
+				if err := json.Unmarshal(output.Data, &result); err != nil {
+					t.Fatalf("could not decode to json: %s", err)
+				}
+
+				if result.Imported != testCase.imported || len(result.Errors) != testCase.errors {
+					t.Errorf("Invalid import result: [%+v]", result)
+				}
+			}
+
+			if count := countEntries(); count != testCase.entries {
+				t.Errorf("Wrong number of time entries: [%d] wanted: [%d]", count, testCase.entries)
+			}
+		})
+	}
+}
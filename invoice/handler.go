@@ -0,0 +1,337 @@
+package invoice
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bryanmorgan/time-tracking-api/api"
+	"github.com/bryanmorgan/time-tracking-api/config"
+	"github.com/bryanmorgan/time-tracking-api/logger"
+	"github.com/bryanmorgan/time-tracking-api/profile"
+	"github.com/bryanmorgan/time-tracking-api/valid"
+
+	"github.com/go-chi/chi"
+)
+
+type InvoiceRequest struct {
+	ClientId int
+	From     string
+	To       string
+}
+
+type InvoiceStatusRequest struct {
+	InvoiceId int
+	Status    string
+}
+
+type InvoiceLineResponse struct {
+	ProjectId   int     `json:"projectId"`
+	TaskId      int     `json:"taskId"`
+	ProjectName string  `json:"projectName"`
+	TaskName    string  `json:"taskName"`
+	Hours       float64 `json:"hours"`
+	Rate        float64 `json:"rate"`
+	Amount      float64 `json:"amount"`
+}
+
+type InvoiceResponse struct {
+	InvoiceId     int                    `json:"id"`
+	InvoiceNumber int                    `json:"number"`
+	Status        string                 `json:"status"`
+	ClientId      int                    `json:"clientId"`
+	ClientName    string                 `json:"clientName"`
+	BillTo        string                 `json:"billTo,omitempty"`
+	From          string                 `json:"from"`
+	To            string                 `json:"to"`
+	Total         float64                `json:"total"`
+	Created       time.Time              `json:"created"`
+	Updated       time.Time              `json:"updated"`
+	Lines         []*InvoiceLineResponse `json:"lines,omitempty"`
+}
+
+func (a *InvoiceRouter) getInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	invoiceData, ok := a.getInvoiceFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	api.Json(w, r, NewInvoiceResponse(invoiceData))
+}
+
+func (a *InvoiceRouter) getInvoicesHandler(w http.ResponseWriter, r *http.Request) {
+	offsetString := r.URL.Query().Get("page")
+
+	offset := 0
+	if !valid.IsNull(offsetString) {
+		var err error
+		offset, err = strconv.Atoi(offsetString)
+		if err != nil || offset < 0 {
+			api.ErrorJson(w, api.NewFieldError(err, "Invalid page offset", api.InvalidField, offsetString), http.StatusBadRequest)
+			return
+		}
+	}
+
+	userProfile, ok := r.Context().Value(config.ProfileContextKey).(*profile.Profile)
+	if !ok || userProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid profile context", api.SystemError), http.StatusInternalServerError)
+		return
+	}
+
+	invoices, err := a.invoiceService.GetInvoices(userProfile.AccountId, offset)
+	if err != nil {
+		api.ErrorJson(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	api.Json(w, r, NewInvoicesResponse(invoices))
+}
+
+func (a *InvoiceRouter) createInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Body == nil {
+		api.ErrorJson(w, api.NewError(nil, "Empty Body", api.InvalidJson), http.StatusBadRequest)
+		return
+	}
+
+	var request InvoiceRequest
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&request); err != nil {
+		api.ErrorJson(w, api.NewError(err, "Invalid JSON", api.InvalidJson), http.StatusBadRequest)
+		return
+	}
+	defer api.CloseBody(r.Body)
+
+	if request.ClientId <= 0 {
+		api.ErrorJson(w, api.NewFieldError(nil, "Invalid or missing clientId", api.InvalidField, "clientId"), http.StatusBadRequest)
+		return
+	}
+
+	fromDate, err := time.Parse(config.ISOShortDateFormat, request.From)
+	if err != nil {
+		api.ErrorJson(w, api.NewFieldError(err, "Invalid format. Use ISO8061: YYYY-MM-DD", api.InvalidField, "from"), http.StatusBadRequest)
+		return
+	}
+
+	toDate, err := time.Parse(config.ISOShortDateFormat, request.To)
+	if err != nil {
+		api.ErrorJson(w, api.NewFieldError(err, "Invalid format. Use ISO8061: YYYY-MM-DD", api.InvalidField, "to"), http.StatusBadRequest)
+		return
+	}
+
+	if toDate.Before(fromDate) {
+		api.ErrorJson(w, api.NewFieldError(nil, "To date must be on or after the from date", api.InvalidField, "to"), http.StatusBadRequest)
+		return
+	}
+
+	userProfile, ok := r.Context().Value(config.ProfileContextKey).(*profile.Profile)
+	if !ok || userProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid profile context", api.SystemError), http.StatusInternalServerError)
+		return
+	}
+
+	newInvoice, appErr := a.invoiceService.CreateInvoice(userProfile.AccountId, request.ClientId, fromDate, toDate, userProfile.ProfileId)
+	if appErr != nil {
+		if appErr.Code == api.SystemError {
+			api.ErrorJson(w, appErr, http.StatusInternalServerError)
+		} else {
+			api.ErrorJson(w, appErr, http.StatusBadRequest)
+		}
+		return
+	}
+
+	api.Json(w, r, NewInvoiceResponse(newInvoice))
+}
+
+func (a *InvoiceRouter) updateInvoiceStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Body == nil {
+		api.ErrorJson(w, api.NewError(nil, "Empty Body", api.InvalidJson), http.StatusBadRequest)
+		return
+	}
+
+	var request InvoiceStatusRequest
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&request); err != nil {
+		api.ErrorJson(w, api.NewError(err, "Invalid JSON", api.InvalidJson), http.StatusBadRequest)
+		return
+	}
+	defer api.CloseBody(r.Body)
+
+	if request.InvoiceId <= 0 {
+		api.ErrorJson(w, api.NewFieldError(nil, "Invalid or missing invoiceId", api.InvalidField, "invoiceId"), http.StatusBadRequest)
+		return
+	}
+
+	if !IsInvoiceStatus(request.Status) {
+		api.ErrorJson(w, api.NewFieldError(nil, "Status must be one of: draft, sent, paid, void", api.InvalidField, "status"), http.StatusBadRequest)
+		return
+	}
+
+	userProfile, ok := r.Context().Value(config.ProfileContextKey).(*profile.Profile)
+	if !ok || userProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid profile context", api.SystemError), http.StatusInternalServerError)
+		return
+	}
+
+	invoiceData, appErr := a.invoiceService.UpdateInvoiceStatus(request.InvoiceId, userProfile.AccountId, InvoiceStatus(request.Status))
+	if appErr != nil {
+		if appErr.Code == api.SystemError {
+			api.ErrorJson(w, appErr, http.StatusInternalServerError)
+		} else {
+			api.ErrorJson(w, appErr, http.StatusBadRequest)
+		}
+		return
+	}
+
+	api.Json(w, r, NewInvoiceResponse(invoiceData))
+}
+
+func (a *InvoiceRouter) exportInvoiceJsonHandler(w http.ResponseWriter, r *http.Request) {
+	invoiceData, ok := a.getInvoiceFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment;filename=invoice_%d.json", invoiceData.InvoiceNumber))
+	api.Json(w, r, NewInvoiceResponse(invoiceData))
+}
+
+func (a *InvoiceRouter) exportInvoiceCsvHandler(w http.ResponseWriter, r *http.Request) {
+	invoiceData, ok := a.getInvoiceFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	WriteExportInvoiceResponse(w, invoiceData)
+}
+
+// Reads the invoiceId path parameter and loads the invoice with its lines. Writes the error response and returns
+// false if the invoice could not be found
+func (a *InvoiceRouter) getInvoiceFromPath(w http.ResponseWriter, r *http.Request) (*Invoice, bool) {
+	invoiceIdString := chi.URLParam(r, "invoiceId")
+	invoiceId, err := strconv.Atoi(invoiceIdString)
+	if err != nil || invoiceId <= 0 {
+		api.ErrorJson(w, api.NewFieldError(err, "Invoice id not valid", api.InvalidField, "invoiceId"), http.StatusBadRequest)
+		return nil, false
+	}
+
+	userProfile, ok := r.Context().Value(config.ProfileContextKey).(*profile.Profile)
+	if !ok || userProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid profile context", api.SystemError), http.StatusInternalServerError)
+		return nil, false
+	}
+
+	invoiceData, appErr := a.invoiceService.GetInvoice(invoiceId, userProfile.AccountId)
+	if appErr != nil {
+		api.ErrorJson(w, appErr, http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if invoiceData == nil {
+		api.ErrorJson(w, api.NewError(nil, "No invoice matching id", api.InvalidInvoice), http.StatusBadRequest)
+		return nil, false
+	}
+
+	return invoiceData, true
+}
+
+func WriteExportInvoiceResponse(w http.ResponseWriter, invoiceData *Invoice) {
+	header := w.Header()
+	header.Set("Content-Type", "text/csv")
+	header.Set("Content-Disposition", fmt.Sprintf("attachment;filename=invoice_%d.csv", invoiceData.InvoiceNumber))
+
+	wr := csv.NewWriter(w)
+	for _, row := range ExportInvoiceRows(invoiceData) {
+		err := wr.Write(row)
+		if err != nil {
+			logger.Log.Error("Failed to write row: " + err.Error())
+		}
+	}
+	wr.Flush()
+}
+
+// Returns the CSV rows for an invoice: the invoice details and bill-to address, the line items, then the total
+func ExportInvoiceRows(invoiceData *Invoice) [][]string {
+	if invoiceData == nil {
+		return [][]string{}
+	}
+
+	rows := [][]string{
+		{"Invoice Number", strconv.Itoa(invoiceData.InvoiceNumber)},
+		{"Status", string(invoiceData.InvoiceStatus)},
+		{"Bill To", invoiceData.ClientName},
+	}
+
+	if invoiceData.BillTo.Valid && invoiceData.BillTo.String != "" {
+		rows = append(rows, []string{"Address", invoiceData.BillTo.String})
+	}
+
+	rows = append(rows,
+		[]string{"From", invoiceData.FromDate.Format(config.ISOShortDateFormat)},
+		[]string{"To", invoiceData.ToDate.Format(config.ISOShortDateFormat)},
+		[]string{},
+		[]string{"Project", "Task", "Hours", "Rate", "Amount"},
+	)
+
+	for _, line := range invoiceData.Lines {
+		rows = append(rows, []string{
+			line.ProjectName,
+			line.TaskName,
+			fmt.Sprintf("%0.2f", line.Hours),
+			fmt.Sprintf("%0.2f", line.Rate),
+			fmt.Sprintf("%0.2f", line.Amount),
+		})
+	}
+
+	rows = append(rows, []string{"Total", "", "", "", fmt.Sprintf("%0.2f", invoiceData.Total)})
+	return rows
+}
+
+func NewInvoiceResponse(invoiceData *Invoice) *InvoiceResponse {
+	if invoiceData == nil {
+		return nil
+	}
+
+	var lines []*InvoiceLineResponse
+	for _, line := range invoiceData.Lines {
+		lines = append(lines, &InvoiceLineResponse{
+			ProjectId:   line.ProjectId,
+			TaskId:      line.TaskId,
+			ProjectName: line.ProjectName,
+			TaskName:    line.TaskName,
+			Hours:       line.Hours,
+			Rate:        line.Rate,
+			Amount:      line.Amount,
+		})
+	}
+
+	return &InvoiceResponse{
+		InvoiceId:     invoiceData.InvoiceId,
+		InvoiceNumber: invoiceData.InvoiceNumber,
+		Status:        string(invoiceData.InvoiceStatus),
+		ClientId:      invoiceData.ClientId,
+		ClientName:    invoiceData.ClientName,
+		BillTo:        invoiceData.BillTo.String,
+		From:          invoiceData.FromDate.Format(config.ISOShortDateFormat),
+		To:            invoiceData.ToDate.Format(config.ISOShortDateFormat),
+		Total:         invoiceData.Total,
+		Created:       invoiceData.Created,
+		Updated:       invoiceData.Updated,
+		Lines:         lines,
+	}
+}
+
+func NewInvoicesResponse(invoices []*Invoice) []*InvoiceResponse {
+	if invoices == nil {
+		return nil
+	}
+
+	var result []*InvoiceResponse
+	for _, invoiceData := range invoices {
+		result = append(result, NewInvoiceResponse(invoiceData))
+	}
+
+	return result
+}
@@ -0,0 +1,66 @@
+package invoice
+
+import (
+	"database/sql"
+	"time"
+)
+
+type InvoiceStatus string
+
+const (
+	InvoiceDraft InvoiceStatus = "draft"
+	InvoiceSent  InvoiceStatus = "sent"
+	InvoicePaid  InvoiceStatus = "paid"
+	InvoiceVoid  InvoiceStatus = "void"
+)
+
+const InvoicePaginationLimit = 100
+
+type Invoice struct {
+	InvoiceId     int            `json:"-" db:"invoice_id"`
+	AccountId     int            `json:"-" db:"account_id"`
+	ClientId      int            `json:"-" db:"client_id"`
+	InvoiceNumber int            `json:"-" db:"invoice_number"`
+	InvoiceStatus InvoiceStatus  `json:"-" db:"invoice_status"`
+	FromDate      time.Time      `json:"-" db:"from_date"`
+	ToDate        time.Time      `json:"-" db:"to_date"`
+	ClientName    string         `json:"-" db:"client_name"`
+	BillTo        sql.NullString `json:"-" db:"bill_to"`
+	Total         float64        `json:"-"`
+	CreatedBy     int            `json:"-" db:"created_by"`
+	Created       time.Time      `json:"-"`
+	Updated       time.Time      `json:"-"`
+	Lines         []*InvoiceLine `json:"-" db:"-"`
+}
+
+type InvoiceLine struct {
+	InvoiceId   int     `json:"-" db:"invoice_id"`
+	ProjectId   int     `json:"-" db:"project_id"`
+	TaskId      int     `json:"-" db:"task_id"`
+	ProjectName string  `json:"-" db:"project_name"`
+	TaskName    string  `json:"-" db:"task_name"`
+	Hours       float64 `json:"-"`
+	Rate        float64 `json:"-"`
+	Amount      float64 `json:"-"`
+}
+
+func IsInvoiceStatus(status string) bool {
+	switch InvoiceStatus(status) {
+	case InvoiceDraft, InvoiceSent, InvoicePaid, InvoiceVoid:
+		return true
+	default:
+		return false
+	}
+}
+
+// Returns true if an invoice can move from one status to the next: draft -> sent -> paid. Draft and sent invoices can be voided
+func IsStatusTransition(from InvoiceStatus, to InvoiceStatus) bool {
+	switch from {
+	case InvoiceDraft:
+		return to == InvoiceSent || to == InvoiceVoid
+	case InvoiceSent:
+		return to == InvoicePaid || to == InvoiceVoid
+	default:
+		return false
+	}
+}
@@ -0,0 +1,73 @@
+package invoice
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInvoiceStatusTransition(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		from    InvoiceStatus
+		to      InvoiceStatus
+		allowed bool
+	}{
+		{"Draft to sent", InvoiceDraft, InvoiceSent, true},
+		{"Draft to void", InvoiceDraft, InvoiceVoid, true},
+		{"Draft to paid", InvoiceDraft, InvoicePaid, false},
+		{"Sent to paid", InvoiceSent, InvoicePaid, true},
+		{"Sent to void", InvoiceSent, InvoiceVoid, true},
+		{"Sent to draft", InvoiceSent, InvoiceDraft, false},
+		{"Paid to void", InvoicePaid, InvoiceVoid, false},
+		{"Void to draft", InvoiceVoid, InvoiceDraft, false},
+		{"Draft to draft", InvoiceDraft, InvoiceDraft, false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			allowed := IsStatusTransition(testCase.from, testCase.to)
+			if allowed != testCase.allowed {
+				t.Errorf("Transition %s -> %s: got %t, wanted %t", testCase.from, testCase.to, allowed, testCase.allowed)
+			}
+		})
+	}
+}
+
+func TestExportInvoiceRows(t *testing.T) {
+	t.Parallel()
+
+	invoiceData := &Invoice{
+		InvoiceNumber: 12,
+		InvoiceStatus: InvoiceSent,
+		ClientName:    "ACME",
+		BillTo:        sql.NullString{String: "1 Main St", Valid: true},
+		FromDate:      time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC),
+		ToDate:        time.Date(2020, 3, 31, 0, 0, 0, 0, time.UTC),
+		Total:         1250,
+		Lines: []*InvoiceLine{
+			{ProjectName: "Website", TaskName: "Design", Hours: 10, Rate: 50, Amount: 500},
+			{ProjectName: "Website", TaskName: "Development", Hours: 7.5, Rate: 100, Amount: 750},
+		},
+	}
+
+	rows := ExportInvoiceRows(invoiceData)
+	if len(rows) != 11 {
+		t.Fatalf("Got %d rows, wanted 11", len(rows))
+	}
+
+	if strings.Join(rows[3], ",") != "Address,1 Main St" {
+		t.Errorf("Unexpected bill-to address row: %v", rows[3])
+	}
+
+	if strings.Join(rows[9], ",") != "Website,Development,7.50,100.00,750.00" {
+		t.Errorf("Unexpected line row: %v", rows[9])
+	}
+
+	if strings.Join(rows[10], ",") != "Total,,,,1250.00" {
+		t.Errorf("Unexpected total row: %v", rows[10])
+	}
+}
@@ -0,0 +1,40 @@
+package invoice
+
+import (
+	"github.com/bryanmorgan/time-tracking-api/profile"
+
+	"github.com/go-chi/chi"
+)
+
+type InvoiceRouter struct {
+	invoiceService InvoiceService
+	profileRouter  *profile.ProfileRouter
+}
+
+func NewRouter(store InvoiceStore, profileRouter *profile.ProfileRouter) *InvoiceRouter {
+	return &InvoiceRouter{
+		invoiceService: NewInvoiceService(store),
+		profileRouter:  profileRouter,
+	}
+}
+
+func (a *InvoiceRouter) Router() *chi.Mux {
+	r := chi.NewRouter()
+
+	// Require authorization/token and admin permissions
+	r.Group(func(r chi.Router) {
+		r.Use(profile.TokenHandler)
+		r.Use(a.profileRouter.ValidateProfileHandler)
+		r.Use(a.profileRouter.ValidateSessionHandler)
+		r.Use(a.profileRouter.AdminPermissionHandler)
+
+		r.Post("/", a.createInvoiceHandler)
+		r.Get("/all", a.getInvoicesHandler)
+		r.Get("/{invoiceId}", a.getInvoiceHandler)
+		r.Get("/{invoiceId}/export/json", a.exportInvoiceJsonHandler)
+		r.Get("/{invoiceId}/export/csv", a.exportInvoiceCsvHandler)
+		r.Put("/status", a.updateInvoiceStatusHandler)
+	})
+
+	return r
+}
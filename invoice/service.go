@@ -0,0 +1,108 @@
+package invoice
+
+import (
+	"time"
+
+	"github.com/bryanmorgan/time-tracking-api/api"
+	"github.com/bryanmorgan/time-tracking-api/database"
+)
+
+// Compile Only: ensure interface is implemented
+var _ InvoiceService = &InvoiceResource{}
+
+type InvoiceService interface {
+	GetInvoice(invoiceId int, accountId int) (*Invoice, *api.Error)
+	GetInvoices(accountId int, offset int) ([]*Invoice, *api.Error)
+
+	CreateInvoice(accountId int, clientId int, from time.Time, to time.Time, createdBy int) (*Invoice, *api.Error)
+	UpdateInvoiceStatus(invoiceId int, accountId int, status InvoiceStatus) (*Invoice, *api.Error)
+}
+
+type InvoiceResource struct {
+	store InvoiceStore
+}
+
+func NewInvoiceService(store InvoiceStore) InvoiceService {
+	return &InvoiceResource{
+		store: store,
+	}
+}
+
+func (c *InvoiceResource) GetInvoice(invoiceId int, accountId int) (*Invoice, *api.Error) {
+	invoice, err := c.store.GetInvoice(invoiceId, accountId)
+	if err != nil {
+		return nil, api.NewError(err, "Could not get invoice", api.SystemError)
+	}
+
+	if invoice == nil {
+		return nil, nil
+	}
+
+	invoice.Lines, err = c.store.GetInvoiceLines(invoiceId)
+	if err != nil {
+		return nil, api.NewError(err, "Could not get invoice lines", api.SystemError)
+	}
+
+	return invoice, nil
+}
+
+func (c *InvoiceResource) GetInvoices(accountId int, offset int) ([]*Invoice, *api.Error) {
+	invoices, err := c.store.GetInvoices(accountId, offset)
+	if err != nil {
+		return nil, api.NewError(err, "Could not get invoices", api.SystemError)
+	}
+
+	return invoices, nil
+}
+
+func (c *InvoiceResource) CreateInvoice(accountId int, clientId int, from time.Time, to time.Time, createdBy int) (*Invoice, *api.Error) {
+	newInvoice := Invoice{
+		AccountId: accountId,
+		ClientId:  clientId,
+		FromDate:  from,
+		ToDate:    to,
+		CreatedBy: createdBy,
+	}
+
+	invoiceId, err := c.store.CreateInvoice(&newInvoice)
+	if err == InvalidClientError {
+		return nil, api.NewError(err, "No client matching id", api.InvalidClient)
+	}
+
+	if err == NoBillableTimeError {
+		return nil, api.NewError(err, "No uninvoiced billable time found for the client and dates", api.NoBillableTime)
+	}
+
+	if err != nil {
+		return nil, api.NewError(err, "Could not create invoice", api.SystemError)
+	}
+
+	return c.GetInvoice(invoiceId, accountId)
+}
+
+func (c *InvoiceResource) UpdateInvoiceStatus(invoiceId int, accountId int, status InvoiceStatus) (*Invoice, *api.Error) {
+	existing, apiErr := c.GetInvoice(invoiceId, accountId)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	if existing == nil {
+		return nil, api.NewError(nil, "No invoice matching id", api.InvalidInvoice)
+	}
+
+	if !IsStatusTransition(existing.InvoiceStatus, status) {
+		return nil, api.NewError(nil, "Invoice cannot move from "+string(existing.InvoiceStatus)+" to "+string(status), api.InvalidInvoiceStatus)
+	}
+
+	err := c.store.UpdateInvoiceStatus(invoiceId, accountId, existing.InvoiceStatus, status)
+	if err == database.NoRowAffectedError {
+		// Status changed by another request since it was read
+		return nil, api.NewError(err, "Invoice status has changed", api.InvalidInvoiceStatus)
+	}
+
+	if err != nil {
+		return nil, api.NewError(err, "Could not update invoice status", api.SystemError)
+	}
+
+	return c.GetInvoice(invoiceId, accountId)
+}
@@ -0,0 +1,294 @@
+package invoice
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/bryanmorgan/time-tracking-api/config"
+	"github.com/bryanmorgan/time-tracking-api/database"
+	"github.com/bryanmorgan/time-tracking-api/logger"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Compile Only: ensure interface is implemented
+var _ InvoiceStore = &InvoiceData{}
+
+var InvalidClientError = errors.New("client not found in account")
+var NoBillableTimeError = errors.New("no billable time to invoice")
+
+type InvoiceStore interface {
+	GetInvoice(invoiceId int, accountId int) (*Invoice, error)
+	GetInvoices(accountId int, offset int) ([]*Invoice, error)
+	GetInvoiceLines(invoiceId int) ([]*InvoiceLine, error)
+
+	CreateInvoice(invoice *Invoice) (int, error)
+	UpdateInvoiceStatus(invoiceId int, accountId int, from InvoiceStatus, to InvoiceStatus) error
+}
+
+type InvoiceData struct {
+	db *sqlx.DB
+}
+
+func NewInvoiceStore(db *sqlx.DB) InvoiceStore {
+	return &InvoiceData{
+		db: db,
+	}
+}
+
+func (c *InvoiceData) GetInvoice(invoiceId int, accountId int) (*Invoice, error) {
+	sqlStatement := `
+		SELECT invoice_id, account_id, client_id, invoice_number, invoice_status, from_date, to_date,
+		       client_name, bill_to, total, created_by, created, updated
+		FROM invoice
+		WHERE invoice_id = $1
+		  AND account_id = $2`
+
+	var invoice Invoice
+	err := c.db.Get(&invoice, sqlStatement, invoiceId, accountId)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &invoice, nil
+}
+
+func (c *InvoiceData) GetInvoices(accountId int, offset int) ([]*Invoice, error) {
+	sqlStatement := `
+		SELECT invoice_id, account_id, client_id, invoice_number, invoice_status, from_date, to_date,
+		       client_name, bill_to, total, created_by, created, updated
+		FROM invoice
+		WHERE account_id = $1
+		ORDER BY invoice_number DESC
+		LIMIT $2
+		OFFSET $3`
+
+	rows, err := c.db.Queryx(sqlStatement, accountId, InvoicePaginationLimit, offset*InvoicePaginationLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer database.CloseRows(rows)
+
+	var invoices []*Invoice
+	for rows.Next() {
+		var invoice Invoice
+		err := rows.StructScan(&invoice)
+		if err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, &invoice)
+	}
+
+	return invoices, nil
+}
+
+func (c *InvoiceData) GetInvoiceLines(invoiceId int) ([]*InvoiceLine, error) {
+	sqlStatement := `
+		SELECT invoice_id, project_id, task_id, project_name, task_name, hours, rate, amount
+		FROM invoice_line
+		WHERE invoice_id = $1
+		ORDER BY project_name, task_name`
+
+	rows, err := c.db.Queryx(sqlStatement, invoiceId)
+	if err != nil {
+		return nil, err
+	}
+	defer database.CloseRows(rows)
+
+	var lines []*InvoiceLine
+	for rows.Next() {
+		var line InvoiceLine
+		err := rows.StructScan(&line)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, &line)
+	}
+
+	return lines, nil
+}
+
+// Create an invoice from the uninvoiced billable time for the client and date range. The time rows are locked to
+// the invoice and a line item is added for each project/task, all in a single transaction
+func (c *InvoiceData) CreateInvoice(invoice *Invoice) (int, error) {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	clientSql := `SELECT client_name, address FROM client WHERE client_id = $1 AND account_id = $2`
+	err = tx.QueryRow(clientSql, invoice.ClientId, invoice.AccountId).Scan(&invoice.ClientName, &invoice.BillTo)
+	if err == sql.ErrNoRows {
+		database.RollbackTransaction(tx)
+		return 0, InvalidClientError
+	}
+
+	if err != nil {
+		database.RollbackTransaction(tx)
+		return 0, err
+	}
+
+	// Running invoice number per account
+	sequenceSql := `
+		INSERT INTO invoice_sequence (account_id, last_number) VALUES ($1, 1)
+		ON CONFLICT (account_id) DO UPDATE SET last_number = invoice_sequence.last_number + 1
+		RETURNING last_number`
+	err = tx.QueryRow(sequenceSql, invoice.AccountId).Scan(&invoice.InvoiceNumber)
+	if err != nil {
+		database.RollbackTransaction(tx)
+		return 0, err
+	}
+
+	invoiceSql := `
+		INSERT INTO invoice (account_id, client_id, invoice_number, invoice_status, from_date, to_date, client_name, bill_to, created_by)
+		             VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING invoice_id`
+	err = tx.QueryRow(invoiceSql,
+		invoice.AccountId,
+		invoice.ClientId,
+		invoice.InvoiceNumber,
+		InvoiceDraft,
+		invoice.FromDate.Format(config.ISOShortDateFormat),
+		invoice.ToDate.Format(config.ISOShortDateFormat),
+		invoice.ClientName,
+		invoice.BillTo,
+		invoice.CreatedBy).Scan(&invoice.InvoiceId)
+	if err != nil {
+		database.RollbackTransaction(tx)
+		return 0, err
+	}
+
+	// Lock the billable time rows to this invoice
+	lockSql := `
+		UPDATE time t
+		SET invoice_id = $1
+		FROM project_task pt,
+		     project p
+		WHERE t.account_id = $2
+		  AND t.account_id = pt.account_id
+		  AND t.project_id = pt.project_id
+		  AND t.task_id = pt.task_id
+		  AND t.project_id = p.project_id
+		  AND p.client_id = $3
+		  AND pt.billable
+		  AND t.hours > 0.0
+		  AND t.invoice_id IS NULL
+		  AND t.day >= $4
+		  AND t.day <= $5`
+	results, err := tx.Exec(lockSql,
+		invoice.InvoiceId,
+		invoice.AccountId,
+		invoice.ClientId,
+		invoice.FromDate.Format(config.ISOShortDateFormat),
+		invoice.ToDate.Format(config.ISOShortDateFormat))
+	if err != nil {
+		database.RollbackTransaction(tx)
+		return 0, err
+	}
+
+	rows, err := results.RowsAffected()
+	if err != nil {
+		database.RollbackTransaction(tx)
+		return 0, err
+	}
+
+	if rows == 0 {
+		database.RollbackTransaction(tx)
+		return 0, NoBillableTimeError
+	}
+
+	linesSql := `
+		INSERT INTO invoice_line (invoice_id, project_id, task_id, project_name, task_name, hours, rate, amount)
+		SELECT t.invoice_id,
+		       t.project_id,
+		       t.task_id,
+		       p.project_name,
+		       k.task_name,
+		       sum(t.hours),
+		       COALESCE(pt.rate, 0),
+		       sum(t.hours * COALESCE(pt.rate, 0))
+		FROM time t,
+		     project_task pt,
+		     project p,
+		     task k
+		WHERE t.invoice_id = $1
+		  AND t.account_id = pt.account_id
+		  AND t.project_id = pt.project_id
+		  AND t.task_id = pt.task_id
+		  AND t.project_id = p.project_id
+		  AND t.task_id = k.task_id
+		GROUP BY t.invoice_id, t.project_id, t.task_id, p.project_name, k.task_name, pt.rate`
+	_, err = tx.Exec(linesSql, invoice.InvoiceId)
+	if err != nil {
+		database.RollbackTransaction(tx)
+		return 0, err
+	}
+
+	totalSql := `
+		UPDATE invoice
+		SET total = (SELECT COALESCE(sum(amount), 0) FROM invoice_line WHERE invoice_id = $1)
+		WHERE invoice_id = $1`
+	_, err = tx.Exec(totalSql, invoice.InvoiceId)
+	if err != nil {
+		database.RollbackTransaction(tx)
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Log.Error("Error in commit transaction: " + err.Error())
+		return 0, err
+	}
+
+	return invoice.InvoiceId, nil
+}
+
+// Move the invoice between statuses. Voiding an invoice releases the time rows so they can be edited and invoiced again
+func (c *InvoiceData) UpdateInvoiceStatus(invoiceId int, accountId int, from InvoiceStatus, to InvoiceStatus) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	updateSql := `
+		UPDATE invoice
+		SET invoice_status = $3,
+		    updated = CURRENT_TIMESTAMP
+		WHERE invoice_id = $1
+		  AND account_id = $2
+		  AND invoice_status = $4`
+	results, err := tx.Exec(updateSql, invoiceId, accountId, to, from)
+	if err != nil {
+		database.RollbackTransaction(tx)
+		return err
+	}
+
+	rows, err := results.RowsAffected()
+	if err != nil {
+		database.RollbackTransaction(tx)
+		return err
+	}
+
+	if rows == 0 {
+		database.RollbackTransaction(tx)
+		return database.NoRowAffectedError
+	}
+
+	if to == InvoiceVoid {
+		_, err = tx.Exec(`UPDATE time SET invoice_id = NULL WHERE invoice_id = $1`, invoiceId)
+		if err != nil {
+			database.RollbackTransaction(tx)
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Log.Error("Error in commit transaction: " + err.Error())
+		return err
+	}
+
+	return nil
+}
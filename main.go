@@ -1,9 +1,53 @@
 package main
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/bryanmorgan/time-tracking-api/app"
 )
 
+const usage = `Usage: timetrack [command]
+
+Commands:
+  serve                Start the API server (the default when no command is given)
+  migrate              Apply, revert or list schema migrations
+  account create       Create an account and its owner profile
+  user reset-password  Set a new password for a profile and sign it out
+  user unlock          Unlock a profile locked by failed logins
+  sessions purge       Delete expired sessions and API keys
+  seed                 Add the ping row and, with -example, the example account
+
+The environment is selected with GO_ENV, the same as the server.`
+
 func main() {
-	app.NewApp().Run()
+	command := "serve"
+	if len(os.Args) > 1 {
+		command = os.Args[1]
+	}
+
+	var args []string
+	if len(os.Args) > 2 {
+		args = os.Args[2:]
+	}
+
+	switch command {
+	case "serve":
+		app.NewApp().Run()
+	case "migrate":
+		os.Exit(migrateCommand(args))
+	case "account":
+		os.Exit(accountCommand(args))
+	case "user":
+		os.Exit(userCommand(args))
+	case "sessions":
+		os.Exit(sessionsCommand(args))
+	case "seed":
+		os.Exit(seedCommand(args))
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
 }
@@ -12,6 +12,11 @@ func PanicHandler(next http.Handler) http.Handler {
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
+				// Let the server close the connection for handlers that abort a response they have started
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+
 				debug.PrintStack()
 				api.ErrorJson(w, api.NewError(fmt.Sprintf("%+v", err), r.RequestURI, api.PANIC), http.StatusInternalServerError)
 			}
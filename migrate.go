@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/bryanmorgan/time-tracking-api/database"
+)
+
+const migrateUsage = `Usage: timetrack migrate <command>
+
+Commands:
+  up            Apply all pending migrations
+  down [steps]  Revert the last applied migration, or the last <steps> migrations
+  status        List migrations and when they were applied`
+
+// Run a migrate subcommand and return the process exit code
+func migrateCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	db := initCommand()
+	defer db.Close()
+
+	switch args[0] {
+	case "up":
+		count, err := database.MigrateUp(db)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Migrate up failed: "+err.Error())
+			return 1
+		}
+		fmt.Printf("Applied %d migrations\n", count)
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				fmt.Fprintln(os.Stderr, "Steps must be a positive number: "+args[1])
+				return 2
+			}
+		}
+
+		count, err := database.MigrateDown(db, steps)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Migrate down failed: "+err.Error())
+			return 1
+		}
+		fmt.Printf("Reverted %d migrations\n", count)
+
+	case "status":
+		status, err := database.GetMigrationStatus(db)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Migrate status failed: "+err.Error())
+			return 1
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, migration := range status {
+			applied := "pending"
+			if migration.Applied.Valid {
+				applied = migration.Applied.Time.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", migration.Version, migration.Name, applied)
+		}
+		w.Flush()
+
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	return 0
+}
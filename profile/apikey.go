@@ -0,0 +1,136 @@
+package profile
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type ApiKeyScope string
+
+const (
+	ScopeTimeRead  ApiKeyScope = "time:read"
+	ScopeTimeWrite ApiKeyScope = "time:write"
+	ScopeReports   ApiKeyScope = "reports"
+)
+
+const (
+	WebSessionType    = "web"
+	ApiKeySessionType = "api"
+
+	// API keys start with the prefix so they can be told apart from web session tokens
+	ApiKeyPrefix     = "ttk_"
+	ApiKeyByteLength = 32
+
+	ApiKeyNameMaxLength = 64
+
+	// Keys created without an expiration date expire after this many days
+	ApiKeyDefaultExpirationDays = 90
+)
+
+// Expiration of API keys created before keys had a default expiration
+var ApiKeyNeverExpires = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// Path prefixes no API key can use, whatever its scopes. When writesOnly is set GET requests are still allowed.
+// Credentials, sessions, account membership and invoices can only be changed from a web session
+var apiKeyDeniedRoutes = []struct {
+	prefix     string
+	writesOnly bool
+}{
+	{"/api/profile/keys", false},
+	{"/api/profile/sessions", false},
+	{"/api/profile", true},
+	{"/api/auth/switch", false},
+	{"/api/account/user", false},
+	{"/api/account/invitations", false},
+	{"/api/account", true},
+	{"/api/invoice", true},
+}
+
+// Path prefixes each scope allows. Read scopes only allow GET requests
+var apiKeyScopeRoutes = []struct {
+	scope    ApiKeyScope
+	prefix   string
+	readOnly bool
+}{
+	{ScopeTimeRead, "/api/time", true},
+	{ScopeTimeWrite, "/api/time", false},
+	{ScopeReports, "/api/report", true},
+}
+
+type ApiKey struct {
+	ApiKeyId   int            `json:"-" db:"session_id"`
+	ProfileId  int            `json:"-" db:"profile_id"`
+	AccountId  int            `json:"-" db:"account_id"`
+	Name       string         `json:"-" db:"key_name"`
+	Scopes     pq.StringArray `json:"-"`
+	Created    time.Time      `json:"-"`
+	Expiration time.Time      `json:"-" db:"token_expiration"`
+	LastUsed   pq.NullTime    `json:"-" db:"last_used"`
+}
+
+func IsApiKeyScope(scope string) bool {
+	switch ApiKeyScope(scope) {
+	case ScopeTimeRead, ScopeTimeWrite, ScopeReports:
+		return true
+	}
+
+	return false
+}
+
+func IsApiKey(token string) bool {
+	return strings.HasPrefix(token, ApiKeyPrefix)
+}
+
+// Only the SHA-256 hash of an API key is stored in the session token column
+func HashApiKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+func generateApiKey() (string, error) {
+	b, err := generateRandomBytes(ApiKeyByteLength)
+	if err != nil {
+		return "", err
+	}
+
+	return ApiKeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Returns the value stored in the session token column for a web token or API key
+func sessionToken(token string) string {
+	if IsApiKey(token) {
+		return HashApiKey(token)
+	}
+
+	return token
+}
+
+// Returns true if an API key with the scopes can make the request. A key can only use the routes its scopes list, so
+// keys without scopes are denied everything, and the denied routes are never allowed whatever the scopes
+func IsApiKeyRequestAllowed(scopes []string, method string, path string) bool {
+	for _, route := range apiKeyDeniedRoutes {
+		if strings.HasPrefix(path, route.prefix) && (!route.writesOnly || method != http.MethodGet) {
+			return false
+		}
+	}
+
+	for _, scope := range scopes {
+		for _, route := range apiKeyScopeRoutes {
+			if ApiKeyScope(scope) != route.scope || !strings.HasPrefix(path, route.prefix) {
+				continue
+			}
+
+			if !route.readOnly || method == http.MethodGet {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,69 @@
+package profile
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApiKeyRequestAllowed(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		scopes  []string
+		method  string
+		path    string
+		allowed bool
+	}{
+		{"No scopes", nil, "DELETE", "/api/client", false},
+		{"No scopes read time", nil, "GET", "/api/time/week", false},
+		{"Manage keys", []string{"time:write"}, "GET", "/api/profile/keys", false},
+		{"Manage sessions", []string{"time:write"}, "DELETE", "/api/profile/sessions", false},
+		{"Switch account", []string{"time:write"}, "POST", "/api/auth/switch", false},
+		{"Change password", []string{"time:write"}, "PUT", "/api/profile/password", false},
+		{"Update profile", []string{"time:write"}, "PUT", "/api/profile/", false},
+		{"Close account", []string{"time:write", "reports"}, "DELETE", "/api/account/", false},
+		{"Remove user", []string{"time:write", "reports"}, "DELETE", "/api/account/user", false},
+		{"Resend invitation", []string{"time:write", "reports"}, "POST", "/api/account/invitations/4/resend", false},
+		{"Create invoice", []string{"time:write", "reports"}, "POST", "/api/invoice", false},
+		{"Read time", []string{"time:read"}, "GET", "/api/time/week", true},
+		{"Read scope writes time", []string{"time:read"}, "PUT", "/api/time/", false},
+		{"Write time", []string{"time:write"}, "PUT", "/api/time/", true},
+		{"Write scope reads time", []string{"time:write"}, "GET", "/api/time/week", true},
+		{"Time scope reads reports", []string{"time:read", "time:write"}, "GET", "/api/report/time/client", false},
+		{"Reports", []string{"reports"}, "GET", "/api/report/time/client", true},
+		{"Reports scope creates client", []string{"reports"}, "POST", "/api/client", false},
+		{"Unknown scope", []string{"admin"}, "GET", "/api/time/week", false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			allowed := IsApiKeyRequestAllowed(testCase.scopes, testCase.method, testCase.path)
+			if allowed != testCase.allowed {
+				t.Errorf("%s %s with %v: got %t, wanted %t", testCase.method, testCase.path, testCase.scopes, allowed, testCase.allowed)
+			}
+		})
+	}
+}
+
+func TestApiKeyHash(t *testing.T) {
+	t.Parallel()
+
+	key, err := generateApiKey()
+	if err != nil {
+		t.Fatalf("Could not generate API key: %s", err)
+	}
+
+	if !IsApiKey(key) || !strings.HasPrefix(key, ApiKeyPrefix) {
+		t.Errorf("Missing API key prefix: %s", key)
+	}
+
+	hash := HashApiKey(key)
+	if hash == key || len(hash) != 64 || hash != HashApiKey(key) {
+		t.Errorf("Invalid API key hash: %s", hash)
+	}
+
+	if sessionToken(key) != hash || sessionToken("web-token") != "web-token" {
+		t.Errorf("Session token lookup should only hash API keys")
+	}
+}
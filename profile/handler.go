@@ -5,14 +5,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/bryanmorgan/time-tracking-api/api"
 	"github.com/bryanmorgan/time-tracking-api/config"
+	"github.com/bryanmorgan/time-tracking-api/emails"
 	"github.com/bryanmorgan/time-tracking-api/logger"
 	"github.com/bryanmorgan/time-tracking-api/valid"
 
+	"github.com/go-chi/chi"
 	"github.com/spf13/viper"
 )
 
@@ -28,6 +31,10 @@ type ProfileRequest struct {
 	Email     string
 	Password  string
 	Timezone  string
+	Language  string
+
+	TimesheetReminders *bool
+	TimesheetDigest    *bool
 }
 
 type PasswordChangeRequest struct {
@@ -43,6 +50,10 @@ type ProfileResponse struct {
 	Email     string `json:"email"`
 	Phone     string `json:"phone,omitempty"`
 	Timezone  string `json:"timezone,omitempty"`
+	Language  string `json:"language,omitempty"`
+
+	TimesheetReminders bool `json:"timesheetReminders"`
+	TimesheetDigest    bool `json:"timesheetDigest"`
 }
 
 type CompanyResponse struct {
@@ -61,18 +72,26 @@ type AccountRequest struct {
 }
 
 type AccountResponse struct {
-	Company   string `json:"company"`
-	WeekStart int    `json:"weekStart"`
-	Timezone  string `json:"timezone,omitempty"`
-	Created   string `json:"created,omitempty"`
-	Updated   string `json:"updated,omitempty"`
+	Company           string `json:"company"`
+	WeekStart         int    `json:"weekStart"`
+	Timezone          string `json:"timezone,omitempty"`
+	TimerRounding     int    `json:"timerRounding"`
+	TimerRoundingMode string `json:"timerRoundingMode"`
+	EmailSignature    string `json:"emailSignature,omitempty"`
+	EmailLogoUrl      string `json:"emailLogoUrl,omitempty"`
+	Created           string `json:"created,omitempty"`
+	Updated           string `json:"updated,omitempty"`
 }
 
 type AccountUpdateRequest struct {
-    Company   string
-    Phone     string
-    Timezone  string
-    WeekStart int
+    Company           string
+    Phone             string
+    Timezone          string
+    WeekStart         int
+    TimerRounding     int
+    TimerRoundingMode string
+    EmailSignature    *string
+    EmailLogoUrl      *string
 }
 
 type AddUserRequest struct {
@@ -80,6 +99,7 @@ type AddUserRequest struct {
     LastName  string
     Email     string
     Role      string
+    Language  string
 }
 
 type RemoveUserRequest struct {
@@ -110,12 +130,82 @@ type AuthResponse struct {
 	LastName  string `json:"lastName"`
 	Company   string `json:"company"`
 	WeekStart int    `json:"weekStart"`
+	Token     string `json:"token,omitempty"`
+}
+
+type MembershipResponse struct {
+	AccountId int       `json:"accountId"`
+	Company   string    `json:"company"`
+	Role      string    `json:"role"`
+	LastUsed  time.Time `json:"lastUsed"`
+	Current   bool      `json:"current"`
 }
 
 type AccountIdRequest struct {
 	AccountId int
 }
 
+type ApiKeyRequest struct {
+	Name    string
+	Scopes  []string
+	Expires string
+}
+
+type ApiKeyResponse struct {
+	Id       int        `json:"id"`
+	Name     string     `json:"name"`
+	Key      string     `json:"key,omitempty"`
+	Scopes   []string   `json:"scopes"`
+	Created  time.Time  `json:"created"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	LastUsed *time.Time `json:"lastUsed,omitempty"`
+}
+
+type SessionResponse struct {
+	Id        int        `json:"id"`
+	Type      string     `json:"type"`
+	Created   time.Time  `json:"created"`
+	Expires   time.Time  `json:"expires"`
+	LastUsed  *time.Time `json:"lastUsed,omitempty"`
+	IpAddress string     `json:"ipAddress,omitempty"`
+	UserAgent string     `json:"userAgent,omitempty"`
+	Current   bool       `json:"current"`
+}
+
+type CapacityRequest struct {
+	ProfileId int
+	Hours     *float64
+}
+
+type HolidayRequest struct {
+	Day  string
+	Name string
+}
+
+type CapacityResponse struct {
+	ProfileId int     `json:"profileId"`
+	FirstName string  `json:"firstName"`
+	LastName  string  `json:"lastName"`
+	Hours     float64 `json:"hours"`
+}
+
+type HolidayResponse struct {
+	Id   int    `json:"id"`
+	Day  string `json:"day"`
+	Name string `json:"name"`
+}
+
+type InvitationResponse struct {
+	ProfileId int        `json:"profileId"`
+	Email     string     `json:"email"`
+	FirstName string     `json:"firstName"`
+	LastName  string     `json:"lastName"`
+	Role      string     `json:"role"`
+	Invited   *time.Time `json:"invited,omitempty"`
+	Expires   *time.Time `json:"expires,omitempty"`
+	Expired   bool       `json:"expired"`
+}
+
 func (pr *ProfileRouter) loginHandler(w http.ResponseWriter, r *http.Request) {
 	var loginRequest LoginRequest
 
@@ -147,7 +237,7 @@ func (pr *ProfileRouter) loginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userProfile, appErr := pr.profileService.Login(loginRequest.Email, loginRequest.Password, r.RemoteAddr)
+	userProfile, appErr := pr.profileService.Login(loginRequest.Email, loginRequest.Password, GetRequestIpAddress(r), GetRequestUserAgent(r))
 	if appErr != nil {
 		api.ErrorJson(w, appErr, http.StatusUnauthorized)
 		return
@@ -157,6 +247,52 @@ func (pr *ProfileRouter) loginHandler(w http.ResponseWriter, r *http.Request) {
 	api.Json(w, r, NewAuthResponse(userProfile))
 }
 
+func (pr *ProfileRouter) switchAccountHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Body == nil {
+		api.ErrorJson(w, api.NewError(nil, "Empty Body", api.InvalidJson), http.StatusBadRequest)
+		return
+	}
+
+	var request AccountIdRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		api.ErrorJson(w, api.NewError(err, "Invalid JSON", api.InvalidJson), http.StatusBadRequest)
+		return
+	}
+	defer api.CloseBody(r.Body)
+
+	if request.AccountId <= 0 {
+		api.BadInputs(w, "Invalid or missing accountId", api.InvalidField, "accountId")
+		return
+	}
+
+	userProfile, ok := r.Context().Value(config.ProfileContextKey).(*Profile)
+	if !ok || userProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid session profile", api.SystemError), http.StatusUnauthorized)
+		return
+	}
+
+	// Work on a copy so the request context keeps the original session
+	switchProfile := *userProfile
+	appErr := pr.profileService.SwitchAccount(&switchProfile, request.AccountId, GetRequestIpAddress(r), GetRequestUserAgent(r))
+	if appErr != nil {
+		switch appErr.Code {
+		case api.SystemError, api.TokenCreationFailed:
+			api.ErrorJson(w, appErr, http.StatusInternalServerError)
+		case api.NotAuthorized:
+			api.ErrorJson(w, appErr, http.StatusUnauthorized)
+		default:
+			api.ErrorJson(w, appErr, http.StatusBadRequest)
+		}
+		return
+	}
+
+	response := NewAuthResponse(&switchProfile)
+	response.Token = switchProfile.Token.String
+
+	AddSessionCookie(w, switchProfile.Token.String, viper.GetString("application.applicationDomain"))
+	api.Json(w, r, response)
+}
+
 func (pr *ProfileRouter) logoutHandler(w http.ResponseWriter, r *http.Request) {
 	token, ok := r.Context().Value(config.TokenContextKey).(string)
 	if !ok {
@@ -301,8 +437,8 @@ func TokenHandler(next http.Handler) http.Handler {
 			return
 		}
 
-		// Add/UpdateAccount the session cookie
-		if !strings.Contains(r.URL.Path, "/api/auth/logout") {
+		// Add/UpdateAccount the session cookie. API keys are sent on every request and never become a cookie
+		if !strings.Contains(r.URL.Path, "/api/auth/logout") && !IsApiKey(token) {
 			AddSessionCookie(w, token, viper.GetString("application.applicationDomain"))
 		}
 
@@ -412,6 +548,22 @@ func (pr *ProfileRouter) updateProfileHandler(w http.ResponseWriter, r *http.Req
 		updatedProfile.Timezone = profileRequest.Timezone
 	}
 
+	if !valid.IsNull(profileRequest.Language) {
+		if !emails.IsLanguage(profileRequest.Language) {
+			api.BadInputs(w, "Language must be one of: "+strings.Join(emails.Languages, ", "), api.InvalidField, "language")
+			return
+		}
+		updatedProfile.Language = profileRequest.Language
+	}
+
+	if profileRequest.TimesheetReminders != nil {
+		updatedProfile.TimesheetReminders = *profileRequest.TimesheetReminders
+	}
+
+	if profileRequest.TimesheetDigest != nil {
+		updatedProfile.TimesheetDigest = *profileRequest.TimesheetDigest
+	}
+
 	appErr := pr.profileService.UpdateProfile(&updatedProfile, existingUserProfile)
 	if appErr != nil {
 		api.ErrorJson(w, appErr, http.StatusBadRequest)
@@ -451,7 +603,7 @@ func (pr *ProfileRouter) updatePasswordHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	err := pr.profileService.UpdatePassword(userProfile.ProfileId, request.CurrentPassword, request.Password, request.ConfirmPassword)
+	err := pr.profileService.UpdatePassword(userProfile.ProfileId, request.CurrentPassword, request.Password, request.ConfirmPassword, userProfile.Token.String)
 	if err != nil {
 		api.ErrorJson(w, err, http.StatusBadRequest)
 		return
@@ -486,6 +638,17 @@ func (pr *ProfileRouter) ValidateSessionHandler(next http.Handler) http.Handler
 			return
 		}
 
+		// API keys have a fixed expiration and are limited to the routes their scopes allow
+		if userProfile.SessionType == ApiKeySessionType {
+			if !IsApiKeyRequestAllowed(userProfile.Scopes, r.Method, r.URL.Path) {
+				api.ErrorJson(w, api.NewError(nil, "API key scope does not allow this request", api.NotAuthorized), http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		// If we're within 50% of the time to expiration, update the token expiration
 		durationUntilExpiration := time.Until(tokenExpiration)
 		if durationUntilExpiration.Minutes() < float64(GetCookieExpirationMinutes())/2 {
@@ -514,35 +677,11 @@ func (pr *ProfileRouter) createAccountHandler(w http.ResponseWriter, r *http.Req
 	}
 	defer api.CloseBody(r.Body)
 
-	if !valid.IsEmail(accountRequest.Email) {
-		api.BadInputs(w, "Invalid email", api.InvalidEmail, "email")
-		return
-	}
-
-	if !valid.IsLength(accountRequest.Password, PasswordMinLength, PasswordMaxLength) {
-		api.BadInputs(w, "Password must be between 6 and 64 characters", api.FieldSize, "password")
-		return
-	}
-
-	if !valid.IsLength(accountRequest.FirstName, NameMinLength, NameMaxLength) {
-		api.BadInputs(w, "First name must be between 1 and 64 characters", api.FieldSize, "firstName")
-		return
-	}
-
-	if !valid.IsLength(accountRequest.LastName, NameMinLength, NameMaxLength) {
-		api.BadInputs(w, "Last name must be between 1 and 64 characters", api.FieldSize, "lastName")
-		return
-	}
-
-	if !valid.IsLength(accountRequest.Company, CompanyNameMinLength, CompanyNameMaxLength) {
-		api.BadInputs(w, "Company name must be between 1 and 64 characters", api.FieldSize, "company")
+	if appErr := ValidateAccountRequest(&accountRequest); appErr != nil {
+		api.ErrorJson(w, appErr, http.StatusBadRequest)
 		return
 	}
 
-	if valid.IsNull(accountRequest.Timezone) {
-		accountRequest.Timezone = "America/New_York"
-	}
-
 	_, newUser, appError := pr.profileService.Create(&accountRequest)
 	if appError != nil {
 		api.ErrorJson(w, appError, http.StatusBadRequest)
@@ -581,6 +720,26 @@ func (pr *ProfileRouter) updateAccountHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	if !IsTimerRounding(accountRequest.TimerRounding) {
+		api.BadInputs(w, "Timer rounding must be 0, 1, 5, 6, 10, 15, 30 or 60 minutes", api.InvalidField, "timerRounding")
+		return
+	}
+
+	if !valid.IsNull(accountRequest.TimerRoundingMode) && !IsTimerRoundingMode(accountRequest.TimerRoundingMode) {
+		api.BadInputs(w, "Timer rounding mode must be nearest, up or down", api.InvalidField, "timerRoundingMode")
+		return
+	}
+
+	if accountRequest.EmailSignature != nil && !valid.IsLength(*accountRequest.EmailSignature, 0, EmailSignatureMaxLength) {
+		api.BadInputs(w, "Email signature must be at most 128 characters", api.FieldSize, "emailSignature")
+		return
+	}
+
+	if accountRequest.EmailLogoUrl != nil && *accountRequest.EmailLogoUrl != "" && !IsEmailLogoUrl(*accountRequest.EmailLogoUrl) {
+		api.BadInputs(w, "Email logo must be an https URL", api.InvalidField, "emailLogoUrl")
+		return
+	}
+
 	accountProfile, ok := r.Context().Value(config.ProfileContextKey).(*Profile)
 	if !ok || accountProfile == nil {
 		api.ErrorJson(w, api.NewError(nil, "Invalid session accountProfile", api.SystemError), http.StatusUnauthorized)
@@ -697,6 +856,11 @@ func (pr *ProfileRouter) addUserHandler(w http.ResponseWriter, r *http.Request)
 		newUserRequest.Role = "user"
 	}
 
+	if !valid.IsNull(newUserRequest.Language) && !emails.IsLanguage(newUserRequest.Language) {
+		api.BadInputs(w, "Language must be one of: "+strings.Join(emails.Languages, ", "), api.InvalidField, "language")
+		return
+	}
+
 	newUser, appError := pr.profileService.AddUser(&newUserRequest, &accountProfile.Account)
 	if appError != nil {
 		if appError.Code == EmailExistsInAccount {
@@ -726,6 +890,250 @@ func (pr *ProfileRouter) getUsersHandler(w http.ResponseWriter, r *http.Request)
 	api.Json(w, r, NewProfileListResponse(profiles))
 }
 
+func (pr *ProfileRouter) getInvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	accountProfile, ok := r.Context().Value(config.ProfileContextKey).(*Profile)
+	if !ok || accountProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid session profile", api.SystemError), http.StatusUnauthorized)
+		return
+	}
+
+	invitations, appErr := pr.profileService.GetInvitations(accountProfile.AccountId)
+	if appErr != nil {
+		api.ErrorJson(w, appErr, http.StatusInternalServerError)
+		return
+	}
+
+	api.Json(w, r, NewInvitationListResponse(invitations))
+}
+
+func (pr *ProfileRouter) resendInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	profileId, err := strconv.Atoi(chi.URLParam(r, "profileId"))
+	if err != nil || profileId <= 0 {
+		api.BadInputs(w, "Invalid profile id", api.InvalidField, "profileId")
+		return
+	}
+
+	accountProfile, ok := r.Context().Value(config.ProfileContextKey).(*Profile)
+	if !ok || accountProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid session profile", api.SystemError), http.StatusUnauthorized)
+		return
+	}
+
+	appErr := pr.profileService.ResendInvitation(&accountProfile.Account, profileId)
+	if appErr != nil {
+		if appErr.Code == api.SystemError {
+			api.ErrorJson(w, appErr, http.StatusInternalServerError)
+		} else {
+			api.ErrorJson(w, appErr, http.StatusBadRequest)
+		}
+		return
+	}
+
+	api.Json(w, r, nil)
+}
+
+func (pr *ProfileRouter) revokeInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	profileId, err := strconv.Atoi(chi.URLParam(r, "profileId"))
+	if err != nil || profileId <= 0 {
+		api.BadInputs(w, "Invalid profile id", api.InvalidField, "profileId")
+		return
+	}
+
+	accountProfile, ok := r.Context().Value(config.ProfileContextKey).(*Profile)
+	if !ok || accountProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid session profile", api.SystemError), http.StatusUnauthorized)
+		return
+	}
+
+	appErr := pr.profileService.RevokeInvitation(accountProfile.AccountId, profileId)
+	if appErr != nil {
+		if appErr.Code == api.SystemError {
+			api.ErrorJson(w, appErr, http.StatusInternalServerError)
+		} else {
+			api.ErrorJson(w, appErr, http.StatusBadRequest)
+		}
+		return
+	}
+
+	api.Json(w, r, nil)
+}
+
+func (pr *ProfileRouter) getCapacityHandler(w http.ResponseWriter, r *http.Request) {
+	accountProfile, ok := r.Context().Value(config.ProfileContextKey).(*Profile)
+	if !ok || accountProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid session profile", api.SystemError), http.StatusUnauthorized)
+		return
+	}
+
+	capacities, appErr := pr.profileService.GetCapacities(accountProfile.AccountId)
+	if appErr != nil {
+		api.ErrorJson(w, appErr, http.StatusInternalServerError)
+		return
+	}
+
+	api.Json(w, r, NewCapacityListResponse(capacities))
+}
+
+func (pr *ProfileRouter) updateCapacityHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Body == nil {
+		api.ErrorJson(w, api.NewError(nil, "Empty Body", api.InvalidJson), http.StatusBadRequest)
+		return
+	}
+
+	var request CapacityRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		api.ErrorJson(w, api.NewError(err, "Invalid capacity JSON", api.InvalidJson), http.StatusBadRequest)
+		return
+	}
+	defer api.CloseBody(r.Body)
+
+	if request.ProfileId <= 0 {
+		api.BadInputs(w, "Invalid profile id", api.InvalidField, "profileId")
+		return
+	}
+
+	if request.Hours == nil {
+		api.BadInputs(w, "Missing hours", api.MissingField, "hours")
+		return
+	}
+
+	accountProfile, ok := r.Context().Value(config.ProfileContextKey).(*Profile)
+	if !ok || accountProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid session profile", api.SystemError), http.StatusUnauthorized)
+		return
+	}
+
+	appErr := pr.profileService.UpdateCapacity(accountProfile.AccountId, request.ProfileId, *request.Hours)
+	if appErr != nil {
+		if appErr.Code == api.SystemError {
+			api.ErrorJson(w, appErr, http.StatusInternalServerError)
+		} else {
+			api.ErrorJson(w, appErr, http.StatusBadRequest)
+		}
+		return
+	}
+
+	api.Json(w, r, nil)
+}
+
+// Holidays between the from and to dates, defaulting to the current year
+func (pr *ProfileRouter) getHolidaysHandler(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	from := time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(now.Year(), time.December, 31, 0, 0, 0, 0, time.UTC)
+
+	var err error
+	if fromParam := r.URL.Query().Get("from"); fromParam != "" {
+		from, err = time.Parse(config.ISOShortDateFormat, fromParam)
+		if err != nil {
+			api.BadInputs(w, "Invalid from date. Use ISO8061: YYYY-MM-DD", api.InvalidField, "from")
+			return
+		}
+	}
+
+	if toParam := r.URL.Query().Get("to"); toParam != "" {
+		to, err = time.Parse(config.ISOShortDateFormat, toParam)
+		if err != nil || to.Before(from) {
+			api.BadInputs(w, "Invalid to date. Use ISO8061: YYYY-MM-DD", api.InvalidField, "to")
+			return
+		}
+	}
+
+	accountProfile, ok := r.Context().Value(config.ProfileContextKey).(*Profile)
+	if !ok || accountProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid session profile", api.SystemError), http.StatusUnauthorized)
+		return
+	}
+
+	holidays, appErr := pr.profileService.GetHolidays(accountProfile.AccountId, from, to)
+	if appErr != nil {
+		api.ErrorJson(w, appErr, http.StatusInternalServerError)
+		return
+	}
+
+	api.Json(w, r, NewHolidayListResponse(holidays))
+}
+
+func (pr *ProfileRouter) saveHolidayHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Body == nil {
+		api.ErrorJson(w, api.NewError(nil, "Empty Body", api.InvalidJson), http.StatusBadRequest)
+		return
+	}
+
+	var request HolidayRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		api.ErrorJson(w, api.NewError(err, "Invalid holiday JSON", api.InvalidJson), http.StatusBadRequest)
+		return
+	}
+	defer api.CloseBody(r.Body)
+
+	day, err := time.Parse(config.ISOShortDateFormat, request.Day)
+	if err != nil {
+		api.BadInputs(w, "Invalid day. Use ISO8061: YYYY-MM-DD", api.InvalidField, "day")
+		return
+	}
+
+	accountProfile, ok := r.Context().Value(config.ProfileContextKey).(*Profile)
+	if !ok || accountProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid session profile", api.SystemError), http.StatusUnauthorized)
+		return
+	}
+
+	holiday, appErr := pr.profileService.SaveHoliday(accountProfile.AccountId, day, request.Name)
+	if appErr != nil {
+		if appErr.Code == api.SystemError {
+			api.ErrorJson(w, appErr, http.StatusInternalServerError)
+		} else {
+			api.ErrorJson(w, appErr, http.StatusBadRequest)
+		}
+		return
+	}
+
+	api.Json(w, r, NewHolidayResponse(holiday))
+}
+
+func (pr *ProfileRouter) deleteHolidayHandler(w http.ResponseWriter, r *http.Request) {
+	holidayId, err := strconv.Atoi(chi.URLParam(r, "holidayId"))
+	if err != nil || holidayId <= 0 {
+		api.BadInputs(w, "Invalid holiday id", api.InvalidField, "holidayId")
+		return
+	}
+
+	accountProfile, ok := r.Context().Value(config.ProfileContextKey).(*Profile)
+	if !ok || accountProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid session profile", api.SystemError), http.StatusUnauthorized)
+		return
+	}
+
+	appErr := pr.profileService.DeleteHoliday(accountProfile.AccountId, holidayId)
+	if appErr != nil {
+		if appErr.Code == api.SystemError {
+			api.ErrorJson(w, appErr, http.StatusInternalServerError)
+		} else {
+			api.ErrorJson(w, appErr, http.StatusBadRequest)
+		}
+		return
+	}
+
+	api.Json(w, r, nil)
+}
+
+func (pr *ProfileRouter) getMembershipsHandler(w http.ResponseWriter, r *http.Request) {
+	userProfile, ok := r.Context().Value(config.ProfileContextKey).(*Profile)
+	if !ok || userProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid session profile", api.SystemError), http.StatusUnauthorized)
+		return
+	}
+
+	memberships, appErr := pr.profileService.GetMemberships(userProfile.ProfileId)
+	if appErr != nil {
+		api.ErrorJson(w, appErr, http.StatusInternalServerError)
+		return
+	}
+
+	api.Json(w, r, NewMembershipListResponse(memberships, userProfile.AccountId))
+}
+
 func (pr *ProfileRouter) getProfileHandler(w http.ResponseWriter, r *http.Request) {
 	userProfile, ok := r.Context().Value(config.ProfileContextKey).(*Profile)
 	if !ok || userProfile == nil {
@@ -769,21 +1177,178 @@ func (pr *ProfileRouter) removeUserHandler(w http.ResponseWriter, r *http.Reques
 	api.Json(w, r, nil)
 }
 
-func (pr *ProfileRouter) AdminPermissionHandler(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		accountProfile, ok := r.Context().Value(config.ProfileContextKey).(*Profile)
-		if !ok || accountProfile == nil {
-			api.ErrorJson(w, api.NewError(nil, "Invalid profile account", api.SystemError), http.StatusUnauthorized)
+func (pr *ProfileRouter) getApiKeysHandler(w http.ResponseWriter, r *http.Request) {
+	userProfile, ok := r.Context().Value(config.ProfileContextKey).(*Profile)
+	if !ok || userProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid session profile", api.SystemError), http.StatusUnauthorized)
+		return
+	}
+
+	apiKeys, appErr := pr.profileService.GetApiKeys(userProfile.ProfileId, userProfile.AccountId)
+	if appErr != nil {
+		api.ErrorJson(w, appErr, http.StatusInternalServerError)
+		return
+	}
+
+	api.Json(w, r, NewApiKeyListResponse(apiKeys))
+}
+
+func (pr *ProfileRouter) createApiKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Body == nil {
+		api.ErrorJson(w, api.NewError(nil, "Empty Body", api.InvalidJson), http.StatusBadRequest)
+		return
+	}
+
+	var request ApiKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		api.ErrorJson(w, api.NewError(err, "Invalid API key JSON", api.InvalidJson), http.StatusBadRequest)
+		return
+	}
+	defer api.CloseBody(r.Body)
+
+	name := strings.TrimSpace(request.Name)
+	if !valid.IsLength(name, 1, ApiKeyNameMaxLength) {
+		api.BadInputs(w, "Name must be between 1 and 64 characters", api.FieldSize, "name")
+		return
+	}
+
+	expiration := time.Now().AddDate(0, 0, ApiKeyDefaultExpirationDays)
+	if !valid.IsNull(request.Expires) {
+		expires, err := time.Parse(config.ISOShortDateFormat, request.Expires)
+		if err != nil || !expires.After(time.Now()) {
+			api.BadInputs(w, "Expires must be a future date. Use ISO8061: YYYY-MM-DD", api.InvalidField, "expires")
 			return
 		}
+		expiration = expires
+	}
 
-		if !IsAdmin(accountProfile.Role) {
-			api.ErrorJson(w, api.NewError(nil, "Not permitted", api.NotAuthorized), http.StatusUnauthorized)
-			return
+	userProfile, ok := r.Context().Value(config.ProfileContextKey).(*Profile)
+	if !ok || userProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid session profile", api.SystemError), http.StatusUnauthorized)
+		return
+	}
+
+	apiKey, key, appErr := pr.profileService.CreateApiKey(userProfile, name, request.Scopes, expiration)
+	if appErr != nil {
+		if appErr.Code == api.InvalidField {
+			api.ErrorJson(w, appErr, http.StatusBadRequest)
+		} else {
+			api.ErrorJson(w, appErr, http.StatusInternalServerError)
 		}
+		return
+	}
 
-		next.ServeHTTP(w, r)
-	})
+	response := NewApiKeyResponse(apiKey)
+	response.Key = key
+	api.Json(w, r, response)
+}
+
+func (pr *ProfileRouter) deleteApiKeyHandler(w http.ResponseWriter, r *http.Request) {
+	apiKeyId, err := strconv.Atoi(chi.URLParam(r, "keyId"))
+	if err != nil || apiKeyId <= 0 {
+		api.BadInputs(w, "Invalid key id", api.InvalidField, "keyId")
+		return
+	}
+
+	userProfile, ok := r.Context().Value(config.ProfileContextKey).(*Profile)
+	if !ok || userProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid session profile", api.SystemError), http.StatusUnauthorized)
+		return
+	}
+
+	appErr := pr.profileService.DeleteApiKey(userProfile.ProfileId, apiKeyId)
+	if appErr != nil {
+		if appErr.Code == api.SystemError {
+			api.ErrorJson(w, appErr, http.StatusInternalServerError)
+		} else {
+			api.ErrorJson(w, appErr, http.StatusBadRequest)
+		}
+		return
+	}
+
+	api.Json(w, r, nil)
+}
+
+func (pr *ProfileRouter) getSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userProfile, ok := r.Context().Value(config.ProfileContextKey).(*Profile)
+	if !ok || userProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid session profile", api.SystemError), http.StatusUnauthorized)
+		return
+	}
+
+	sessions, appErr := pr.profileService.GetSessions(userProfile.ProfileId, userProfile.Token.String)
+	if appErr != nil {
+		api.ErrorJson(w, appErr, http.StatusInternalServerError)
+		return
+	}
+
+	api.Json(w, r, NewSessionListResponse(sessions))
+}
+
+func (pr *ProfileRouter) revokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	sessionId, err := strconv.Atoi(chi.URLParam(r, "sessionId"))
+	if err != nil || sessionId <= 0 {
+		api.BadInputs(w, "Invalid session id", api.InvalidField, "sessionId")
+		return
+	}
+
+	userProfile, ok := r.Context().Value(config.ProfileContextKey).(*Profile)
+	if !ok || userProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid session profile", api.SystemError), http.StatusUnauthorized)
+		return
+	}
+
+	appErr := pr.profileService.RevokeSession(userProfile.ProfileId, sessionId)
+	if appErr != nil {
+		if appErr.Code == api.SystemError {
+			api.ErrorJson(w, appErr, http.StatusInternalServerError)
+		} else {
+			api.ErrorJson(w, appErr, http.StatusBadRequest)
+		}
+		return
+	}
+
+	api.Json(w, r, nil)
+}
+
+func (pr *ProfileRouter) revokeOtherSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userProfile, ok := r.Context().Value(config.ProfileContextKey).(*Profile)
+	if !ok || userProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid session profile", api.SystemError), http.StatusUnauthorized)
+		return
+	}
+
+	appErr := pr.profileService.RevokeOtherSessions(userProfile.ProfileId, userProfile.Token.String)
+	if appErr != nil {
+		api.ErrorJson(w, appErr, http.StatusInternalServerError)
+		return
+	}
+
+	api.Json(w, r, nil)
+}
+
+func (pr *ProfileRouter) AdminPermissionHandler(next http.Handler) http.Handler {
+	return pr.RolePermissionHandler(AdminRoles...)(next)
+}
+
+// Returns a middleware that only allows profiles with one of the roles in the current account
+func (pr *ProfileRouter) RolePermissionHandler(roles ...AuthorizationRole) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			accountProfile, ok := r.Context().Value(config.ProfileContextKey).(*Profile)
+			if !ok || accountProfile == nil {
+				api.ErrorJson(w, api.NewError(nil, "Invalid profile account", api.SystemError), http.StatusUnauthorized)
+				return
+			}
+
+			if !HasRole(accountProfile.Role, roles) {
+				api.ErrorJson(w, api.NewError(nil, "Not permitted", api.NotAuthorized), http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
 }
 
 func (pr *ProfileRouter) ValidateProfileHandler(next http.Handler) http.Handler {
@@ -853,11 +1418,15 @@ func NewAccountCompaniesResponse(accounts []*Account) []*CompanyResponse {
 
 func NewAccountResponse(accountData *Account) *AccountResponse {
 	return &AccountResponse{
-		Company:   accountData.Company,
-		Timezone:  accountData.AccountTimezone,
-		WeekStart: accountData.WeekStart,
-		Created:   accountData.Created.Format(time.RFC3339),
-		Updated:   accountData.Updated.Format(time.RFC3339),
+		Company:           accountData.Company,
+		Timezone:          accountData.AccountTimezone,
+		WeekStart:         accountData.WeekStart,
+		TimerRounding:     accountData.TimerRounding,
+		TimerRoundingMode: string(accountData.TimerRoundingMode),
+		EmailSignature:    accountData.EmailSignature.String,
+		EmailLogoUrl:      accountData.EmailLogoUrl.String,
+		Created:           accountData.Created.Format(time.RFC3339),
+		Updated:           accountData.Updated.Format(time.RFC3339),
 	}
 }
 
@@ -877,5 +1446,140 @@ func NewProfileResponse(user *Profile) *ProfileResponse {
 		Email:     user.Email,
 		Phone:     user.Phone.String,
 		Timezone:  user.Timezone,
+		Language:  user.Language,
+
+		TimesheetReminders: user.TimesheetReminders,
+		TimesheetDigest:    user.TimesheetDigest,
+	}
+}
+
+func NewApiKeyListResponse(apiKeys []*ApiKey) []*ApiKeyResponse {
+	response := []*ApiKeyResponse{}
+	for _, apiKey := range apiKeys {
+		response = append(response, NewApiKeyResponse(apiKey))
+	}
+
+	return response
+}
+
+func NewApiKeyResponse(apiKey *ApiKey) *ApiKeyResponse {
+	response := &ApiKeyResponse{
+		Id:      apiKey.ApiKeyId,
+		Name:    apiKey.Name,
+		Scopes:  apiKey.Scopes,
+		Created: apiKey.Created,
+	}
+
+	if response.Scopes == nil {
+		response.Scopes = []string{}
+	}
+
+	if !apiKey.Expiration.Equal(ApiKeyNeverExpires) {
+		response.Expires = &apiKey.Expiration
+	}
+
+	if apiKey.LastUsed.Valid {
+		response.LastUsed = &apiKey.LastUsed.Time
+	}
+
+	return response
+}
+
+func NewSessionListResponse(sessions []*ActiveSession) []*SessionResponse {
+	response := []*SessionResponse{}
+	for _, session := range sessions {
+		response = append(response, NewSessionResponse(session))
+	}
+
+	return response
+}
+
+func NewSessionResponse(session *ActiveSession) *SessionResponse {
+	response := &SessionResponse{
+		Id:        session.SessionId,
+		Type:      session.SessionType,
+		Created:   session.Created,
+		Expires:   session.TokenExpiration,
+		IpAddress: session.IpAddress.String,
+		UserAgent: session.UserAgent.String,
+		Current:   session.Current,
+	}
+
+	if session.LastUsed.Valid {
+		response.LastUsed = &session.LastUsed.Time
 	}
+
+	return response
+}
+
+func NewMembershipListResponse(memberships []*Membership, currentAccountId int) []*MembershipResponse {
+	response := []*MembershipResponse{}
+	for _, membership := range memberships {
+		response = append(response, &MembershipResponse{
+			AccountId: membership.AccountId,
+			Company:   membership.Company,
+			Role:      string(membership.Role),
+			LastUsed:  membership.LastUsed,
+			Current:   membership.AccountId == currentAccountId,
+		})
+	}
+
+	return response
+}
+
+func NewInvitationListResponse(invitations []*Invitation) []*InvitationResponse {
+	response := []*InvitationResponse{}
+	for _, invitation := range invitations {
+		invitationResponse := &InvitationResponse{
+			ProfileId: invitation.ProfileId,
+			Email:     invitation.Email,
+			FirstName: invitation.FirstName,
+			LastName:  invitation.LastName,
+			Role:      string(invitation.Role),
+			Expired:   invitation.IsExpired(),
+		}
+
+		if invitation.Invited.Valid {
+			invitationResponse.Invited = &invitation.Invited.Time
+		}
+
+		if invitation.Expiration.Valid {
+			invitationResponse.Expires = &invitation.Expiration.Time
+		}
+
+		response = append(response, invitationResponse)
+	}
+
+	return response
+}
+
+func NewCapacityListResponse(capacities []*Capacity) []*CapacityResponse {
+	response := []*CapacityResponse{}
+	for _, capacity := range capacities {
+		response = append(response, &CapacityResponse{
+			ProfileId: capacity.ProfileId,
+			FirstName: capacity.FirstName,
+			LastName:  capacity.LastName,
+			Hours:     capacity.CapacityHours,
+		})
+	}
+
+	return response
+}
+
+func NewHolidayResponse(holiday *Holiday) *HolidayResponse {
+	return &HolidayResponse{
+		Id:   holiday.HolidayId,
+		Day:  holiday.Day.Format(config.ISOShortDateFormat),
+		Name: holiday.Name,
+	}
+}
+
+func NewHolidayListResponse(holidays []*Holiday) []*HolidayResponse {
+	response := []*HolidayResponse{}
+	for _, holiday := range holidays {
+		response = append(response, NewHolidayResponse(holiday))
+	}
+
+	return response
 }
@@ -5,12 +5,15 @@ import (
 	"time"
 
 	"github.com/lib/pq"
+
+	"github.com/bryanmorgan/time-tracking-api/emails"
 )
 
 type ProfileStatus string
 type ProfileAccountStatus string
 type AuthorizationRole string
 type AccountStatus string
+type TimerRoundingMode string
 
 const (
 	ProfileNew         ProfileStatus = "new"
@@ -30,6 +33,16 @@ const (
 	AccountArchived AccountStatus = "archived"
 )
 
+// Language for profiles that have not chosen one
+const DefaultLanguage = emails.DefaultLanguage
+
+// Timer rounding direction used when a running timer is stopped
+const (
+	RoundNearest TimerRoundingMode = "nearest"
+	RoundUp      TimerRoundingMode = "up"
+	RoundDown    TimerRoundingMode = "down"
+)
+
 const (
 	Owner     AuthorizationRole = "owner"
 	Admin     AuthorizationRole = "admin"
@@ -37,6 +50,13 @@ const (
 	User      AuthorizationRole = "user"
 )
 
+// Roles allowed to use a route with RolePermissionHandler
+var (
+	AdminRoles     = []AuthorizationRole{Owner, Admin}
+	ReportingRoles = []AuthorizationRole{Owner, Admin, Reporting}
+	AllRoles       = []AuthorizationRole{Owner, Admin, Reporting, User}
+)
+
 // Profile Field Lengths
 const (
 	EmailMinLength       = 5
@@ -47,23 +67,34 @@ const (
 	NameMaxLength        = 64
 	CompanyNameMinLength = 1
 	CompanyNameMaxLength = 64
+
+	EmailSignatureMaxLength = 128
+
+	CapacityMaxHours     = 168 // 24 * 7
+	HolidayNameMaxLength = 64
 )
 
 type Account struct {
-	AccountId       int           `json:"-" db:"account_id"`
-	Company         string        `json:"-"`
-	AccountStatus   AccountStatus `json:"-" db:"account_status"`
-	WeekStart       int           `json:"-" db:"week_start"`
-	AccountTimezone string        `json:"-" db:"account_timezone"`
-	Created         time.Time     `json:"-"`
-	Updated         time.Time     `json:"-"`
-	CloseReason     string        `json:"-" db:"close_reason"`
+	AccountId         int               `json:"-" db:"account_id"`
+	Company           string            `json:"-"`
+	AccountStatus     AccountStatus     `json:"-" db:"account_status"`
+	WeekStart         int               `json:"-" db:"week_start"`
+	AccountTimezone   string            `json:"-" db:"account_timezone"`
+	Created           time.Time         `json:"-"`
+	Updated           time.Time         `json:"-"`
+	CloseReason       string            `json:"-" db:"close_reason"`
+	TimerRounding     int               `json:"-" db:"timer_rounding"`
+	TimerRoundingMode TimerRoundingMode `json:"-" db:"timer_rounding_mode"`
+	EmailSignature    sql.NullString    `json:"-" db:"email_signature"`
+	EmailLogoUrl      sql.NullString    `json:"-" db:"email_logo_url"`
+	ReminderWeek      pq.NullTime       `json:"-" db:"timesheet_reminder_week"`
 }
 
 type Session struct {
 	Token           sql.NullString `json:"-"`
 	TokenExpiration pq.NullTime    `json:"-" db:"token_expiration"`
 	SessionType     string         `json:"-" db:"type"`
+	Scopes          pq.StringArray `json:"-"`
 }
 
 type Profile struct {
@@ -82,10 +113,54 @@ type Profile struct {
 	Role                     AuthorizationRole    `json:"-"`
 	ProfileAccountStatus     ProfileAccountStatus `json:"-" db:"profile_account_status"`
 	Timezone                 string               `json:"-"`
+	Language                 string               `json:"-"`
+	TimesheetReminders       bool                 `json:"-" db:"timesheet_reminders"`
+	TimesheetDigest          bool                 `json:"-" db:"timesheet_digest"`
 	ForgotPasswordToken      pq.NullTime          `json:"-" db:"forgot_password_token"`
 	ForgotPasswordExpiration pq.NullTime          `json:"-" db:"forgot_password_expiration"`
 }
 
+// An account the profile belongs to
+type Membership struct {
+	AccountId     int               `json:"-" db:"account_id"`
+	Company       string            `json:"-"`
+	AccountStatus AccountStatus     `json:"-" db:"account_status"`
+	Role          AuthorizationRole `json:"-"`
+	LastUsed      time.Time         `json:"-" db:"last_used"`
+}
+
+// The hours a member is expected to work each week in the account
+type Capacity struct {
+	ProfileId     int     `json:"-" db:"profile_id"`
+	FirstName     string  `json:"-" db:"first_name"`
+	LastName      string  `json:"-" db:"last_name"`
+	CapacityHours float64 `json:"-" db:"capacity_hours"`
+}
+
+// A day the account does not expect anyone to work
+type Holiday struct {
+	HolidayId int       `json:"-" db:"holiday_id"`
+	AccountId int       `json:"-" db:"account_id"`
+	Day       time.Time `json:"-"`
+	Name      string    `json:"-" db:"holiday_name"`
+}
+
+// A user added to an account who has not set up their profile yet
+type Invitation struct {
+	ProfileId  int               `json:"-" db:"profile_id"`
+	Email      string            `json:"-"`
+	FirstName  string            `json:"-" db:"first_name"`
+	LastName   string            `json:"-" db:"last_name"`
+	Role       AuthorizationRole `json:"-"`
+	Language   string            `json:"-"`
+	Invited    pq.NullTime       `json:"-" db:"invited"`
+	Expiration pq.NullTime       `json:"-" db:"invitation_expiration"`
+}
+
+func (i *Invitation) IsExpired() bool {
+	return !i.Expiration.Valid || i.Expiration.Time.Before(time.Now())
+}
+
 type ForgotPassword struct {
 	ProfileId                int            `json:"-" db:"profile_id"`
 	ForgotPasswordToken      sql.NullString `json:"-" db:"forgot_password_token"`
@@ -95,3 +170,19 @@ type ForgotPassword struct {
 const (
 	MissingIpAddress = "0.0.0.0"
 )
+
+// Account overrides for the email signature and logo
+func (a *Account) EmailBranding() emails.Branding {
+	return emails.Branding{
+		Signature: a.EmailSignature.String,
+		LogoUrl:   a.EmailLogoUrl.String,
+	}
+}
+
+func (p *Profile) EmailRecipient() emails.Recipient {
+	return emails.Recipient{
+		Name:     p.FirstName,
+		Email:    p.Email,
+		Language: p.Language,
+	}
+}
@@ -33,6 +33,8 @@ func (pr *ProfileRouter) AuthenticationRouter() *chi.Mux {
 		r.Group(func(r chi.Router) {
 			r.Use(pr.ValidateProfileHandler)
 			r.Use(pr.ValidateSessionHandler)
+
+			r.Post("/switch", pr.switchAccountHandler)
 		})
 	})
 
@@ -52,6 +54,16 @@ func (pr *ProfileRouter) ProfileRouter() *chi.Mux {
 		r.Put("/", pr.updateProfileHandler)
 		r.Put("/password", pr.updatePasswordHandler)
 
+		// Personal API keys
+		r.Get("/keys", pr.getApiKeysHandler)
+		r.Post("/keys", pr.createApiKeyHandler)
+		r.Delete("/keys/{keyId}", pr.deleteApiKeyHandler)
+
+		// Signed in sessions
+		r.Get("/sessions", pr.getSessionsHandler)
+		r.Delete("/sessions", pr.revokeOtherSessionsHandler)
+		r.Delete("/sessions/{sessionId}", pr.revokeSessionHandler)
+
 	})
 
 	return r
@@ -71,6 +83,12 @@ func (pr *ProfileRouter) AccountRouter() *chi.Mux {
 			r.Use(pr.ValidateProfileHandler)
 			r.Use(pr.ValidateSessionHandler)
 
+			// Every account the profile belongs to
+			r.Get("/memberships", pr.getMembershipsHandler)
+
+			// Days off for the whole account
+			r.Get("/holidays", pr.getHolidaysHandler)
+
 			// Admin-level access
 			r.Group(func(r chi.Router) {
 				r.Use(pr.AdminPermissionHandler)
@@ -85,6 +103,17 @@ func (pr *ProfileRouter) AccountRouter() *chi.Mux {
 					r.Get("/users", pr.getUsersHandler)
 					r.Post("/user", pr.addUserHandler)
 					r.Delete("/user", pr.removeUserHandler)
+
+					// Users added to the account who have not set up their profile
+					r.Get("/invitations", pr.getInvitationsHandler)
+					r.Post("/invitations/{profileId}/resend", pr.resendInvitationHandler)
+					r.Delete("/invitations/{profileId}", pr.revokeInvitationHandler)
+
+					// Weekly hours each member is expected to work, and the account holidays
+					r.Get("/capacity", pr.getCapacityHandler)
+					r.Put("/capacity", pr.updateCapacityHandler)
+					r.Post("/holidays", pr.saveHolidayHandler)
+					r.Delete("/holidays/{holidayId}", pr.deleteHolidayHandler)
 				})
 			})
 		})
@@ -1,6 +1,7 @@
 package profile
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"github.com/bryanmorgan/time-tracking-api/emails"
@@ -13,6 +14,7 @@ import (
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/bryanmorgan/time-tracking-api/api"
+	"github.com/bryanmorgan/time-tracking-api/database"
 	"github.com/bryanmorgan/time-tracking-api/logger"
 	"github.com/bryanmorgan/time-tracking-api/valid"
 )
@@ -29,7 +31,7 @@ type ProfileResource struct {
 }
 
 type ProfileService interface {
-	Login(email string, password string, ipAddress string) (*Profile, *api.Error)
+	Login(email string, password string, ipAddress string, userAgent string) (*Profile, *api.Error)
 	Logout(token string) *api.Error
 	GetProfile(token string) (*Profile, *api.Error)
 	ForgotPassword(email string) *api.Error
@@ -43,12 +45,33 @@ type ProfileService interface {
 	RemoveUser(email string, account *Account) *api.Error
 	UpdateProfile(updatedProfile *Profile, existingProfile *Profile) *api.Error
 	UpdateTokenExpiration(token string, expiration time.Time) *api.Error
-	UpdatePassword(profileId int, currentPassword string, password string, confirmPassword string) *api.Error
+	UpdatePassword(profileId int, currentPassword string, password string, confirmPassword string, currentToken string) *api.Error
 	UpdateAccount(accountId int, request *AccountUpdateRequest) (*Account, *api.Error)
 	CloseAccount(accountId int, reason string) *api.Error
+	CreateApiKey(profile *Profile, name string, scopes []string, expiration time.Time) (*ApiKey, string, *api.Error)
+	GetApiKeys(profileId int, accountId int) ([]*ApiKey, *api.Error)
+	DeleteApiKey(profileId int, apiKeyId int) *api.Error
+	GetSessions(profileId int, currentToken string) ([]*ActiveSession, *api.Error)
+	RevokeSession(profileId int, sessionId int) *api.Error
+	RevokeOtherSessions(profileId int, currentToken string) *api.Error
+	GetMemberships(profileId int) ([]*Membership, *api.Error)
+	SwitchAccount(profile *Profile, accountId int, ipAddress string, userAgent string) *api.Error
+	GetInvitations(accountId int) ([]*Invitation, *api.Error)
+	ResendInvitation(account *Account, profileId int) *api.Error
+	RevokeInvitation(accountId int, profileId int) *api.Error
+	ResetPassword(email string, password string) *api.Error
+	UnlockProfile(email string) *api.Error
+	PurgeExpiredSessions(ctx context.Context) (int64, *api.Error)
+	PurgeLoginAttempts(ctx context.Context, before time.Time) (int64, *api.Error)
+	PurgeForgotPasswordTokens(ctx context.Context) (int64, *api.Error)
+	GetCapacities(accountId int) ([]*Capacity, *api.Error)
+	UpdateCapacity(accountId int, profileId int, hours float64) *api.Error
+	GetHolidays(accountId int, from time.Time, to time.Time) ([]*Holiday, *api.Error)
+	SaveHoliday(accountId int, day time.Time, name string) (*Holiday, *api.Error)
+	DeleteHoliday(accountId int, holidayId int) *api.Error
 }
 
-func (pr *ProfileResource) Login(email string, password string, ipAddress string) (*Profile, *api.Error) {
+func (pr *ProfileResource) Login(email string, password string, ipAddress string, userAgent string) (*Profile, *api.Error) {
 	// Convert email to all lower case
 	email = strings.ToLower(email)
 
@@ -104,8 +127,26 @@ func (pr *ProfileResource) Login(email string, password string, ipAddress string
 		return nil, api.NewError(nil, "Incorrect password", api.IncorrectPassword, api.NewErrorDetail("field", "password"))
 	}
 
+	// Sign in to the most recently used account
+	memberships, err := pr.store.GetMemberships(user.ProfileId)
+	if err != nil {
+		return nil, api.NewError(err, "Failed to get profile accounts", api.SystemError)
+	}
+
+	if len(memberships) > 0 && memberships[0].AccountId != user.AccountId {
+		account, err := pr.store.GetAccount(memberships[0].AccountId)
+		if err != nil {
+			return nil, api.NewError(err, "Failed to get account", api.SystemError)
+		}
+
+		if account != nil {
+			user.Account = *account
+			user.Role = memberships[0].Role
+		}
+	}
+
 	// UpdateAccount token
-	appErr := pr.GenerateNewTokenForProfile(user)
+	appErr := pr.GenerateNewTokenForProfile(user, ipAddress, userAgent)
 	if appErr != nil {
 		return nil, appErr
 	}
@@ -140,7 +181,7 @@ func (pr *ProfileResource) ForgotPassword(email string) *api.Error {
 	// Build reset URL with token
 	resetUrl := config.CreateUrl("/reset-password", "verify-token="+forgotPasswordToken)
 
-	err = emails.SendForgotPasswordEmail(authProfile.FirstName, authProfile.Email, resetUrl)
+	err = emails.SendForgotPasswordEmail(authProfile.EmailRecipient(), authProfile.EmailBranding(), resetUrl)
 	if err != nil {
 		return api.NewError(err, "Failed to send email", api.SystemError)
 	}
@@ -175,21 +216,21 @@ func (pr *ProfileResource) ValidateForgotPasswordToken(token string) *api.Error
 }
 
 func (pr *ProfileResource) SetupNewUser(token string, password string) *api.Error {
-	newUserTokenData, err := pr.store.GetForgotPasswordToken(token)
+	invitation, err := pr.store.GetInvitationByToken(token)
 	if err != nil {
 		return api.NewError(err, "Failed to get new user token", api.SystemError)
 	}
 
-	if newUserTokenData == nil {
+	if invitation == nil {
 		return api.NewError(nil, "Missing new user token", api.InvalidToken)
 	}
 
-	if !newUserTokenData.ForgotPasswordExpiration.Valid || newUserTokenData.ForgotPasswordExpiration.Time.Before(time.Now()) {
+	if invitation.IsExpired() {
 		return api.NewError(err, "Expired or invalid new user token", api.TokenExpired)
 	}
 
-	// Successful, now clear out the values so it cannot be used again
-	err = pr.store.UpdateForgotPassword(newUserTokenData.ProfileId, "", -1)
+	// Successful, now clear out the tokens from every invitation so they cannot be used again
+	err = pr.store.ClearInvitationTokens(invitation.ProfileId)
 	if err != nil {
 		return api.NewError(err, "Failed to new user token", api.SystemError)
 	}
@@ -200,12 +241,12 @@ func (pr *ProfileResource) SetupNewUser(token string, password string) *api.Erro
 		return api.NewError(err, "Failed to encrypt password", api.EncryptionFailed)
 	}
 
-	err = pr.store.UpdatePassword(newUserTokenData.ProfileId, encryptedPassword)
+	err = pr.store.UpdatePassword(invitation.ProfileId, encryptedPassword)
 	if err != nil {
 		return api.NewError(nil, "Failed to update password", api.SystemError)
 	}
 
-	err = pr.store.UpdateProfileState(newUserTokenData.ProfileId, ProfileValid)
+	err = pr.store.UpdateProfileState(invitation.ProfileId, ProfileValid)
 	if err != nil {
 		return api.NewError(nil, "Failed to update profile state", api.SystemError)
 	}
@@ -286,7 +327,8 @@ func (pr *ProfileResource) UpdateTokenExpiration(token string, expiration time.T
 	return nil
 }
 
-func (pr *ProfileResource) UpdatePassword(profileId int, currentPassword string, password string, confirmPassword string) *api.Error {
+// Change the password and sign out every other web session for the profile
+func (pr *ProfileResource) UpdatePassword(profileId int, currentPassword string, password string, confirmPassword string, currentToken string) *api.Error {
 	if valid.IsNull(password) {
 		return api.NewError(nil, "Empty password", api.InvalidPassword)
 	}
@@ -315,9 +357,10 @@ func (pr *ProfileResource) UpdatePassword(profileId int, currentPassword string,
 		return api.NewError(err, "Failed to encrypt password", api.EncryptionFailed)
 	}
 
-	err = pr.store.UpdatePassword(profileId, encryptedPassword)
+	// Keys created with the old password stop working, as they do after a reset
+	err = pr.store.ChangePassword(profileId, encryptedPassword, currentToken)
 	if err != nil {
-		return api.NewError(nil, "Failed to update password", api.SystemError)
+		return api.NewError(err, "Failed to update password", api.SystemError)
 	}
 
 	return nil
@@ -344,6 +387,11 @@ func (pr *ProfileResource) UpdateProfile(profile *Profile, existingProfile *Prof
 }
 
 func (pr *ProfileResource) Logout(token string) *api.Error {
+	// API keys are revoked from the profile keys, so a script logging out cannot delete its own key by mistake
+	if IsApiKey(token) {
+		return api.NewError(nil, "API keys cannot logout", api.InvalidApiKey)
+	}
+
 	err := pr.store.DeleteSessionByToken(token)
 	if err != nil {
 		return api.NewError(err, "Failed to logout", api.SystemError)
@@ -353,7 +401,7 @@ func (pr *ProfileResource) Logout(token string) *api.Error {
 }
 
 func (pr *ProfileResource) GetProfile(token string) (*Profile, *api.Error) {
-	profile, err := pr.store.GetProfileByToken(token)
+	profile, err := pr.store.GetProfileByToken(sessionToken(token))
 	if err != nil {
 		return nil, api.NewError(err, "Failed to get profile", api.SystemError)
 	}
@@ -393,6 +441,7 @@ func (pr *ProfileResource) Create(accountRequest *AccountRequest) (*Account, *Pr
 	if err != nil {
 		return nil, nil, api.NewError(err, "Failed to create account", api.AccountCreateFailed)
 	}
+	newAccount.AccountId = accountId
 
 	if user == nil {
 		user = &Profile{
@@ -438,7 +487,8 @@ func (pr *ProfileResource) AddUser(request *AddUserRequest, account *Account) (*
 		return nil, api.NewFieldError(err, "Failed to check for existing email: "+request.Email, api.SystemError, "email")
 	}
 
-	if user == nil {
+	newUser := user == nil
+	if newUser {
 		// Generate placeholder token for password - never used by user
 		tempPassword, err := GenerateToken()
 		if err != nil {
@@ -450,6 +500,7 @@ func (pr *ProfileResource) AddUser(request *AddUserRequest, account *Account) (*
 			Password:  tempPassword,
 			FirstName: request.FirstName,
 			LastName:  request.LastName,
+			Language:  request.Language,
 		}
 
 		// Create new user profile
@@ -458,24 +509,6 @@ func (pr *ProfileResource) AddUser(request *AddUserRequest, account *Account) (*
 			return nil, api.NewError(err, "Failed to add user", api.ProfileCreateFailed)
 		}
 		user.ProfileId = profileId
-
-		forgotPasswordToken, err := generateForgotPasswordToken()
-		if err != nil {
-			return nil, api.NewError(err, "Failed to generate add user login token", api.SystemError)
-		}
-
-		forgotPasswordExpirationMinutes := viper.GetInt("session.addUserTokenExpirationInMinutes")
-		err = pr.store.UpdateForgotPassword(profileId, forgotPasswordToken, forgotPasswordExpirationMinutes)
-		if err != nil {
-			return nil, api.NewError(err, "Failed to update add user login expiration", api.SystemError)
-		}
-
-		// TODO: send user user email and create page for '/new-user' experience, where we validate email,
-		// token and ask for password, then update the state to ProfileValid
-		//firstNameEncoded := base64.RawURLEncoding.EncodeToString([]byte(user.FirstName))
-		//resetUrl := config.CreateUrl("/new-user/"+firstNameEncoded+"/"+forgotPasswordToken, "")
-		//
-		//logger.Log.Debug(fmt.Sprintf("TODO: Send email for user %d URL: %s", profileId, resetUrl))
 	}
 
 	// Associate user profile to account with an initial role and status
@@ -488,6 +521,19 @@ func (pr *ProfileResource) AddUser(request *AddUserRequest, account *Account) (*
 		}
 	}
 
+	// The user is in the account now, so a failed email is logged and the admin can resend the invitation. Users who
+	// have not set up their profile from another account's invitation are invited to this account too
+	if newUser || user.ProfileStatus == ProfileNotVerified {
+		if appErr := pr.sendInvitation(account, user.ProfileId, user.EmailRecipient()); appErr != nil {
+			logger.Log.Error("Failed to send invitation: " + appErr.Error())
+		}
+	} else {
+		loginUrl := config.CreateUrl("/login", "")
+		if err := emails.SendAddedToAccountEmail(user.EmailRecipient(), account.EmailBranding(), account.Company, loginUrl); err != nil {
+			logger.Log.Error("Failed to send added to account email: " + err.Error())
+		}
+	}
+
 	return user, nil
 }
 
@@ -508,6 +554,18 @@ func (pr *ProfileResource) UpdateAccount(accountId int, request *AccountUpdateRe
 	if request.WeekStart >= 0 {
 		updatedAccountData.WeekStart = request.WeekStart
 	}
+	if request.TimerRounding >= 0 {
+		updatedAccountData.TimerRounding = request.TimerRounding
+	}
+	if !valid.IsNull(request.TimerRoundingMode) {
+		updatedAccountData.TimerRoundingMode = TimerRoundingMode(request.TimerRoundingMode)
+	}
+	if request.EmailSignature != nil {
+		updatedAccountData.EmailSignature = valid.ToNullString(*request.EmailSignature)
+	}
+	if request.EmailLogoUrl != nil {
+		updatedAccountData.EmailLogoUrl = valid.ToNullString(*request.EmailLogoUrl)
+	}
 
 	err = pr.store.UpdateAccount(&updatedAccountData)
 	if err != nil {
@@ -545,7 +603,7 @@ func (pr *ProfileResource) GetAllProfiles(accountId int) ([]*Profile, *api.Error
 }
 
 func (pr *ProfileResource) GetProfileByToken(token string) (*Profile, *api.Error) {
-	profileAccount, err := pr.store.GetProfileByToken(token)
+	profileAccount, err := pr.store.GetProfileByToken(sessionToken(token))
 
 	if err != nil {
 		return nil, api.NewError(err, "Failed to get profile and account", api.SystemError)
@@ -555,12 +613,16 @@ func (pr *ProfileResource) GetProfileByToken(token string) (*Profile, *api.Error
 		return nil, api.NewError(err, "No matching profile and account", api.ProfileNotFound)
 	}
 
+	if err := pr.store.UpdateSessionLastUsed(profileAccount.Token.String); err != nil {
+		logger.Log.Error("Failed to update session last used: " + err.Error())
+	}
+
 	return profileAccount, nil
 }
 
 func (pr *ProfileResource) RemoveUser(email string, account *Account) *api.Error {
 	email = strings.ToLower(email)
-	user, err := pr.store.GetByEmail(email)
+	user, err := pr.store.GetAccountProfileByEmail(account.AccountId, email)
 	if err != nil {
 		return api.NewError(err, "Failed to find user in account", api.SystemError)
 	}
@@ -569,10 +631,6 @@ func (pr *ProfileResource) RemoveUser(email string, account *Account) *api.Error
 		return api.NewError(err, "Failed to find user in account", api.ProfileNotFound)
 	}
 
-	if user.AccountId != account.AccountId {
-		return api.NewError(err, "Failed to find user in account", api.ProfileNotFound)
-	}
-
 	err = pr.store.RemoveUser(account.AccountId, user.ProfileId)
 	if err != nil {
 		return api.NewError(err, "Failed to remove user from account", api.SystemError)
@@ -581,7 +639,7 @@ func (pr *ProfileResource) RemoveUser(email string, account *Account) *api.Error
 	return nil
 }
 
-func (pr *ProfileResource) GenerateNewTokenForProfile(profile *Profile) *api.Error {
+func (pr *ProfileResource) GenerateNewTokenForProfile(profile *Profile, ipAddress string, userAgent string) *api.Error {
 	if profile == nil {
 		return api.NewError(nil, "Invalid profile", api.ProfileNotFound)
 	}
@@ -596,10 +654,349 @@ func (pr *ProfileResource) GenerateNewTokenForProfile(profile *Profile) *api.Err
 	profile.Token = valid.ToNullString(token)
 	profile.TokenExpiration = valid.ToNullTime(tokenExpirationMinutes)
 
-	err = pr.store.AddToken(profile.ProfileId, profile.AccountId, token, tokenExpirationMinutes)
+	err = pr.store.AddToken(profile.ProfileId, profile.AccountId, token, tokenExpirationMinutes, ipAddress, userAgent)
 	if err != nil {
 		return api.NewError(err, "Failed to add new token", api.TokenCreationFailed)
 	}
 
 	return nil
 }
+
+// Create a new API key for the profile in the current account. The key is only returned here; the store keeps its hash
+func (pr *ProfileResource) CreateApiKey(profile *Profile, name string, scopes []string, expiration time.Time) (*ApiKey, string, *api.Error) {
+	if len(scopes) == 0 {
+		return nil, "", api.NewFieldError(nil, "At least one scope is required", api.InvalidField, "scopes")
+	}
+
+	for _, scope := range scopes {
+		if !IsApiKeyScope(scope) {
+			return nil, "", api.NewFieldError(nil, "Invalid scope: "+scope, api.InvalidField, "scopes")
+		}
+	}
+
+	key, err := generateApiKey()
+	if err != nil {
+		return nil, "", api.NewError(err, "Failed to generate API key", api.TokenCreationFailed)
+	}
+
+	apiKey := &ApiKey{
+		ProfileId:  profile.ProfileId,
+		AccountId:  profile.AccountId,
+		Name:       name,
+		Scopes:     scopes,
+		Expiration: expiration,
+	}
+
+	_, err = pr.store.CreateApiKey(apiKey, HashApiKey(key))
+	if err != nil {
+		return nil, "", api.NewError(err, "Failed to create API key", api.TokenCreationFailed)
+	}
+
+	return apiKey, key, nil
+}
+
+func (pr *ProfileResource) GetApiKeys(profileId int, accountId int) ([]*ApiKey, *api.Error) {
+	apiKeys, err := pr.store.GetApiKeys(profileId, accountId)
+	if err != nil {
+		return nil, api.NewError(err, "Failed to get API keys", api.SystemError)
+	}
+
+	return apiKeys, nil
+}
+
+func (pr *ProfileResource) DeleteApiKey(profileId int, apiKeyId int) *api.Error {
+	err := pr.store.DeleteApiKey(profileId, apiKeyId)
+	if err == database.NoRowAffectedError {
+		return api.NewError(err, "API key not found", api.InvalidApiKey)
+	}
+
+	if err != nil {
+		return api.NewError(err, "Failed to delete API key", api.SystemError)
+	}
+
+	return nil
+}
+
+func (pr *ProfileResource) GetSessions(profileId int, currentToken string) ([]*ActiveSession, *api.Error) {
+	sessions, err := pr.store.GetSessions(profileId, currentToken)
+	if err != nil {
+		return nil, api.NewError(err, "Failed to get sessions", api.SystemError)
+	}
+
+	return sessions, nil
+}
+
+func (pr *ProfileResource) RevokeSession(profileId int, sessionId int) *api.Error {
+	err := pr.store.DeleteSession(profileId, sessionId)
+	if err == database.NoRowAffectedError {
+		return api.NewFieldError(err, "Session not found", api.InvalidField, "sessionId")
+	}
+
+	if err != nil {
+		return api.NewError(err, "Failed to revoke session", api.SystemError)
+	}
+
+	return nil
+}
+
+func (pr *ProfileResource) RevokeOtherSessions(profileId int, currentToken string) *api.Error {
+	err := pr.store.DeleteOtherSessions(profileId, currentToken)
+	if err != nil {
+		return api.NewError(err, "Failed to revoke other sessions", api.SystemError)
+	}
+
+	return nil
+}
+
+func (pr *ProfileResource) GetMemberships(profileId int) ([]*Membership, *api.Error) {
+	memberships, err := pr.store.GetMemberships(profileId)
+	if err != nil {
+		return nil, api.NewError(err, "Failed to get profile accounts", api.SystemError)
+	}
+
+	return memberships, nil
+}
+
+// Re-issue the session for another account the profile belongs to. The current session token is removed and the
+// profile is updated with the new account and token
+func (pr *ProfileResource) SwitchAccount(profile *Profile, accountId int, ipAddress string, userAgent string) *api.Error {
+	memberships, appErr := pr.GetMemberships(profile.ProfileId)
+	if appErr != nil {
+		return appErr
+	}
+
+	var membership *Membership
+	for _, m := range memberships {
+		if m.AccountId == accountId {
+			membership = m
+			break
+		}
+	}
+
+	if membership == nil {
+		return api.NewFieldError(nil, "Profile is not a member of the account", api.NotAuthorized, "accountId")
+	}
+
+	account, err := pr.store.GetAccount(accountId)
+	if err != nil {
+		return api.NewError(err, "Failed to get account", api.SystemError)
+	}
+
+	if account == nil {
+		return api.NewFieldError(nil, "Account not found", api.AccountInactive, "accountId")
+	}
+
+	currentToken := profile.Token.String
+	profile.Account = *account
+	profile.Role = membership.Role
+
+	appErr = pr.GenerateNewTokenForProfile(profile, ipAddress, userAgent)
+	if appErr != nil {
+		return appErr
+	}
+
+	if err := pr.store.DeleteSessionByToken(currentToken); err != nil {
+		logger.Log.Error("Failed to delete session after switching accounts: " + err.Error())
+	}
+
+	return nil
+}
+
+func (pr *ProfileResource) GetInvitations(accountId int) ([]*Invitation, *api.Error) {
+	invitations, err := pr.store.GetInvitations(accountId)
+	if err != nil {
+		return nil, api.NewError(err, "Failed to get invitations", api.SystemError)
+	}
+
+	return invitations, nil
+}
+
+// Send the invitation again with a new setup token and expiration
+func (pr *ProfileResource) ResendInvitation(account *Account, profileId int) *api.Error {
+	invitation, err := pr.store.GetInvitation(account.AccountId, profileId)
+	if err != nil {
+		return api.NewError(err, "Failed to get invitation", api.SystemError)
+	}
+
+	if invitation == nil {
+		return api.NewFieldError(nil, "Invitation not found", api.ProfileNotFound, "profileId")
+	}
+
+	to := emails.Recipient{Name: invitation.FirstName, Email: invitation.Email, Language: invitation.Language}
+	return pr.sendInvitation(account, invitation.ProfileId, to)
+}
+
+func (pr *ProfileResource) RevokeInvitation(accountId int, profileId int) *api.Error {
+	err := pr.store.DeleteInvitation(accountId, profileId)
+	if err == database.NoRowAffectedError {
+		return api.NewFieldError(nil, "Invitation not found", api.ProfileNotFound, "profileId")
+	}
+
+	if err != nil {
+		return api.NewError(err, "Failed to revoke invitation", api.SystemError)
+	}
+
+	return nil
+}
+
+// Set a new password without the current one, sign the profile out of its web sessions and revoke its API keys
+func (pr *ProfileResource) ResetPassword(email string, password string) *api.Error {
+	email = strings.ToLower(email)
+
+	if !valid.IsLength(password, PasswordMinLength, PasswordMaxLength) {
+		return api.NewFieldError(nil, "Password must be between "+strconv.Itoa(PasswordMinLength)+" and "+strconv.Itoa(PasswordMaxLength)+" characters", api.FieldSize, "password")
+	}
+
+	user, err := pr.store.GetByEmail(email)
+	if err != nil {
+		return api.NewFieldError(err, "Failed to find profile by email", api.SystemError, "email")
+	}
+
+	if user == nil {
+		return api.NewFieldError(nil, "No user found for email: "+email, api.ProfileNotFound, "email")
+	}
+
+	encryptedPassword, err := EncryptPassword(password)
+	if err != nil {
+		return api.NewError(err, "Failed to encrypt password", api.EncryptionFailed)
+	}
+
+	// A reset is usually done because the account may be compromised, so keys created with the old password stop working
+	err = pr.store.ChangePassword(user.ProfileId, encryptedPassword, "")
+	if err != nil {
+		return api.NewError(err, "Failed to update password", api.SystemError)
+	}
+
+	return nil
+}
+
+// Clear a profile locked by failed login attempts
+func (pr *ProfileResource) UnlockProfile(email string) *api.Error {
+	email = strings.ToLower(email)
+
+	err := pr.store.ClearProfileLock(email)
+	if err == database.NoRowAffectedError {
+		return api.NewFieldError(nil, "No user found for email: "+email, api.ProfileNotFound, "email")
+	}
+
+	if err != nil {
+		return api.NewError(err, "Failed to unlock profile", api.SystemError)
+	}
+
+	return nil
+}
+
+func (pr *ProfileResource) PurgeExpiredSessions(ctx context.Context) (int64, *api.Error) {
+	count, err := pr.store.DeleteExpiredSessions(ctx)
+	if err != nil {
+		return 0, api.NewError(err, "Failed to delete expired sessions", api.SystemError)
+	}
+
+	return count, nil
+}
+
+func (pr *ProfileResource) PurgeLoginAttempts(ctx context.Context, before time.Time) (int64, *api.Error) {
+	count, err := pr.store.DeleteLoginAttempts(ctx, before)
+	if err != nil {
+		return 0, api.NewError(err, "Failed to delete login attempts", api.SystemError)
+	}
+
+	return count, nil
+}
+
+func (pr *ProfileResource) PurgeForgotPasswordTokens(ctx context.Context) (int64, *api.Error) {
+	count, err := pr.store.ClearExpiredForgotPasswordTokens(ctx)
+	if err != nil {
+		return 0, api.NewError(err, "Failed to clear forgot password tokens", api.SystemError)
+	}
+
+	return count, nil
+}
+
+func (pr *ProfileResource) GetCapacities(accountId int) ([]*Capacity, *api.Error) {
+	capacities, err := pr.store.GetCapacities(accountId)
+	if err != nil {
+		return nil, api.NewError(err, "Failed to get capacities", api.SystemError)
+	}
+
+	return capacities, nil
+}
+
+func (pr *ProfileResource) UpdateCapacity(accountId int, profileId int, hours float64) *api.Error {
+	if hours < 0 || hours > CapacityMaxHours {
+		return api.NewFieldError(nil, "Hours must be between 0 and 168", api.InvalidField, "hours")
+	}
+
+	err := pr.store.UpdateCapacity(accountId, profileId, hours)
+	if err == database.NoRowAffectedError {
+		return api.NewFieldError(nil, "Profile is not a member of the account", api.ProfileNotFound, "profileId")
+	}
+
+	if err != nil {
+		return api.NewError(err, "Failed to update capacity", api.SystemError)
+	}
+
+	return nil
+}
+
+func (pr *ProfileResource) GetHolidays(accountId int, from time.Time, to time.Time) ([]*Holiday, *api.Error) {
+	holidays, err := pr.store.GetHolidays(accountId, from, to)
+	if err != nil {
+		return nil, api.NewError(err, "Failed to get holidays", api.SystemError)
+	}
+
+	return holidays, nil
+}
+
+// Add a holiday to the account. A holiday already on the same day is renamed
+func (pr *ProfileResource) SaveHoliday(accountId int, day time.Time, name string) (*Holiday, *api.Error) {
+	name = strings.TrimSpace(name)
+	if !valid.IsLength(name, 1, HolidayNameMaxLength) {
+		return nil, api.NewFieldError(nil, "Name must be between 1 and 64 characters", api.FieldSize, "name")
+	}
+
+	holiday := &Holiday{AccountId: accountId, Day: day, Name: name}
+	holidayId, err := pr.store.SaveHoliday(holiday)
+	if err != nil {
+		return nil, api.NewError(err, "Failed to save holiday", api.SystemError)
+	}
+
+	holiday.HolidayId = holidayId
+	return holiday, nil
+}
+
+func (pr *ProfileResource) DeleteHoliday(accountId int, holidayId int) *api.Error {
+	err := pr.store.DeleteHoliday(accountId, holidayId)
+	if err == database.NoRowAffectedError {
+		return api.NewFieldError(nil, "Holiday not found", api.InvalidHoliday, "holidayId")
+	}
+
+	if err != nil {
+		return api.NewError(err, "Failed to delete holiday", api.SystemError)
+	}
+
+	return nil
+}
+
+// Generate a setup token that expires after session.addUserTokenExpirationInMinutes and email the link to the user
+func (pr *ProfileResource) sendInvitation(account *Account, profileId int, to emails.Recipient) *api.Error {
+	setupToken, err := generateForgotPasswordToken()
+	if err != nil {
+		return api.NewError(err, "Failed to generate invitation token", api.SystemError)
+	}
+
+	expirationMinutes := viper.GetInt("session.addUserTokenExpirationInMinutes")
+	err = pr.store.UpdateInvitation(account.AccountId, profileId, setupToken, expirationMinutes)
+	if err != nil {
+		return api.NewError(err, "Failed to update invitation", api.SystemError)
+	}
+
+	setupUrl := config.CreateUrl("/setup", "verify-token="+setupToken)
+	expiration := time.Now().Add(time.Minute * time.Duration(expirationMinutes))
+	err = emails.SendInvitationEmail(to, account.EmailBranding(), account.Company, setupUrl, expiration)
+	if err != nil {
+		return api.NewError(err, "Failed to send invitation email", api.SystemError)
+	}
+
+	return nil
+}
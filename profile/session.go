@@ -0,0 +1,43 @@
+package profile
+
+import (
+	"database/sql"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const UserAgentMaxLength = 512
+
+// A signed in session or API key as shown to the profile that owns it. The token itself is never returned
+type ActiveSession struct {
+	SessionId       int            `json:"-" db:"session_id"`
+	SessionType     string         `json:"-" db:"type"`
+	Created         time.Time      `json:"-"`
+	TokenExpiration time.Time      `json:"-" db:"token_expiration"`
+	LastUsed        pq.NullTime    `json:"-" db:"last_used"`
+	IpAddress       sql.NullString `json:"-" db:"ip_address"`
+	UserAgent       sql.NullString `json:"-" db:"user_agent"`
+	Current         bool           `json:"-"`
+}
+
+// Returns the client IP address of the request without the port
+func GetRequestIpAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+func GetRequestUserAgent(r *http.Request) string {
+	userAgent := r.UserAgent()
+	if len(userAgent) > UserAgentMaxLength {
+		return userAgent[:UserAgentMaxLength]
+	}
+
+	return userAgent
+}
@@ -1,11 +1,12 @@
 package profile
 
 import (
+	"context"
 	"database/sql"
 	"errors"
-	"fmt"
 	"time"
 
+	"github.com/bryanmorgan/time-tracking-api/config"
 	"github.com/bryanmorgan/time-tracking-api/database"
 	"github.com/bryanmorgan/time-tracking-api/logger"
 	"github.com/bryanmorgan/time-tracking-api/valid"
@@ -30,12 +31,16 @@ type ProfileStore interface {
 	UpdateProfile(*Profile) error
 	UpdateTokenExpiration(string, time.Time) error
 	UpdatePassword(profileId int, password string) error
+	ChangePassword(profileId int, password string, currentToken string) error
 	UpdateProfileState(profileId int, profileStatus ProfileStatus) error
 	SetProfileLocked(email string) error
+	ClearProfileLock(email string) error
 	DeleteSessionByToken(token string) error
-	AddToken(profileId int, accountId int, token string, expiration time.Time) error
+	AddToken(profileId int, accountId int, token string, expiration time.Time, ipAddress string, userAgent string) error
 	UpdateForgotPassword(profileId int, forgotPasswordToken string, forgotPasswordExpirationMinutes int) error
 	AddFailedLoginAttempt(email string, ipAddress string) error
+	DeleteLoginAttempts(ctx context.Context, before time.Time) (int64, error)
+	ClearExpiredForgotPasswordTokens(ctx context.Context) (int64, error)
 	GetForgotPasswordToken(token string) (*ForgotPassword, error)
 
 	// Account
@@ -43,10 +48,35 @@ type ProfileStore interface {
 	GetAccount(accountId int) (*Account, error)
 	GetProfiles(accountId int) ([]*Profile, error)
 	GetProfileByToken(token string) (*Profile, error)
+	GetAccountProfileByEmail(accountId int, email string) (*Profile, error)
+	GetMemberships(profileId int) ([]*Membership, error)
+	GetInvitations(accountId int) ([]*Invitation, error)
+	GetInvitation(accountId int, profileId int) (*Invitation, error)
+	GetInvitationByToken(token string) (*Invitation, error)
+	UpdateInvitation(accountId int, profileId int, token string, expirationMinutes int) error
+	ClearInvitationTokens(profileId int) error
+	DeleteInvitation(accountId int, profileId int) error
+	GetCapacities(accountId int) ([]*Capacity, error)
+	UpdateCapacity(accountId int, profileId int, hours float64) error
+	GetHolidays(accountId int, from time.Time, to time.Time) ([]*Holiday, error)
+	SaveHoliday(holiday *Holiday) (int, error)
+	DeleteHoliday(accountId int, holidayId int) error
 	UpdateAccount(account *Account) error
 	CloseAccount(accountId int, reason string) error
 	AddUser(accountId int, userId int, email string, role AuthorizationRole, status ProfileAccountStatus) error
 	RemoveUser(accountId int, userId int) error
+
+	// API Keys
+	CreateApiKey(apiKey *ApiKey, keyHash string) (int, error)
+	GetApiKeys(profileId int, accountId int) ([]*ApiKey, error)
+	DeleteApiKey(profileId int, apiKeyId int) error
+	UpdateSessionLastUsed(token string) error
+
+	// Sessions
+	GetSessions(profileId int, currentToken string) ([]*ActiveSession, error)
+	DeleteSession(profileId int, sessionId int) error
+	DeleteOtherSessions(profileId int, currentToken string) error
+	DeleteExpiredSessions(ctx context.Context) (int64, error)
 }
 
 // ProfileData implements database operations for user profiles
@@ -77,14 +107,15 @@ func (pa *ProfileData) GetForgotPasswordToken(token string) (*ForgotPassword, er
 	return &forgotPassword, nil
 }
 
-func (pa *ProfileData) AddToken(profileId int, accountId int, token string, expiration time.Time) error {
+func (pa *ProfileData) AddToken(profileId int, accountId int, token string, expiration time.Time, ipAddress string, userAgent string) error {
 
 	upsertSql := `
-		INSERT INTO session (token, token_expiration, profile_id, account_id, type) VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO session (token, token_expiration, profile_id, account_id, type, last_used, ip_address, user_agent)
+		VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP, $6, $7)
 		ON CONFLICT (token)
 		DO UPDATE SET token_expiration=$2
 		WHERE session.token=$1`
-	_, err := pa.db.Exec(upsertSql, token, expiration, profileId, accountId, "web")
+	_, err := pa.db.Exec(upsertSql, token, expiration, profileId, accountId, WebSessionType, valid.ToNullString(ipAddress), valid.ToNullString(userAgent))
 	if err != nil {
 		logger.Log.Error("Failed to upsert token into session", logger.Error(err))
 		return err
@@ -173,10 +204,15 @@ func (pa *ProfileData) CreateProfile(user *Profile, accountId int, newSession bo
 	}
 
 	sqlStatement := `
-		INSERT INTO profile (email, password, first_name, last_name, phone, profile_status, timezone)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO profile (email, password, first_name, last_name, phone, profile_status, timezone, language)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING profile_id`
 
+	language := user.Language
+	if language == "" {
+		language = DefaultLanguage
+	}
+
 	var userId int
 	err = tx.QueryRow(sqlStatement,
 		user.Email,
@@ -185,7 +221,8 @@ func (pa *ProfileData) CreateProfile(user *Profile, accountId int, newSession bo
 		user.LastName,
 		user.Phone,
 		status,
-		user.Timezone).Scan(&userId)
+		user.Timezone,
+		language).Scan(&userId)
 	if err != nil {
 		if rollbackErr := tx.Rollback(); rollbackErr != nil {
 			logger.Log.Error("Failed to rollback transaction: " + rollbackErr.Error())
@@ -242,10 +279,38 @@ func (pa *ProfileData) SetProfileLocked(email string) error {
 	return nil
 }
 
+// Unlock the profile and clear its failed login attempts. Returns NoRowAffectedError if there is no profile for the email
+func (pa *ProfileData) ClearProfileLock(email string) error {
+	tx, err := pa.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`UPDATE profile SET locked_until=NULL WHERE email=$1`, email)
+	if err != nil {
+		database.RollbackTransaction(tx)
+		return err
+	}
+
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		database.RollbackTransaction(tx)
+		return database.NoRowAffectedError
+	}
+
+	_, err = tx.Exec(`DELETE FROM login_attempts WHERE email=$1`, email)
+	if err != nil {
+		database.RollbackTransaction(tx)
+		return err
+	}
+
+	return tx.Commit()
+}
+
 func (pa *ProfileData) UpdateProfile(profile *Profile) error {
 	updateSql := `UPDATE profile
-				  SET email=$1, first_name=$2, last_name=$3, phone=$4, timezone=$5, updated=CURRENT_TIMESTAMP
-				  WHERE profile_id=$6`
+				  SET email=$1, first_name=$2, last_name=$3, phone=$4, timezone=$5, language=$6, timesheet_reminders=$7,
+				      timesheet_digest=$8, updated=CURRENT_TIMESTAMP
+				  WHERE profile_id=$9`
 
 	result, err := pa.db.Exec(updateSql,
 		profile.Email,
@@ -253,6 +318,9 @@ func (pa *ProfileData) UpdateProfile(profile *Profile) error {
 		profile.LastName,
 		profile.Phone,
 		profile.Timezone,
+		profile.Language,
+		profile.TimesheetReminders,
+		profile.TimesheetDigest,
 		profile.ProfileId)
 
 	if err != nil {
@@ -291,6 +359,43 @@ func (pa *ProfileData) UpdatePassword(profileId int, encryptedPassword string) e
 	return nil
 }
 
+// Set a new password, delete the profile's web sessions other than the current token and revoke its API keys in all
+// of its accounts. Done in one transaction so credentials from the old password never outlive it
+func (pa *ProfileData) ChangePassword(profileId int, encryptedPassword string, currentToken string) error {
+	tx, err := pa.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`UPDATE profile SET password=$1, updated=CURRENT_TIMESTAMP WHERE profile_id=$2`, encryptedPassword, profileId)
+	if err != nil {
+		database.RollbackTransaction(tx)
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		database.RollbackTransaction(tx)
+		return err
+	}
+
+	if n == 0 {
+		database.RollbackTransaction(tx)
+		return errors.New("no values in profile were updated")
+	}
+
+	deleteSql := `
+		DELETE FROM session
+		WHERE profile_id = $1
+		  AND ((type = $2 AND token != $3) OR type = $4)`
+	if _, err := tx.Exec(deleteSql, profileId, WebSessionType, currentToken, ApiKeySessionType); err != nil {
+		database.RollbackTransaction(tx)
+		return err
+	}
+
+	return tx.Commit()
+}
+
 func (pa *ProfileData) UpdateProfileState(profileId int, profileStatus ProfileStatus) error {
 	updateSql := `UPDATE profile SET profile_status=$1, updated=CURRENT_TIMESTAMP WHERE profile_id=$2`
 	result, err := pa.db.Exec(updateSql, profileStatus, profileId)
@@ -358,6 +463,31 @@ func (pa *ProfileData) AddFailedLoginAttempt(email string, ipAddress string) err
 	return nil
 }
 
+// Delete failed login attempts made before the time, returning the number deleted
+func (pa *ProfileData) DeleteLoginAttempts(ctx context.Context, before time.Time) (int64, error) {
+	result, err := pa.db.ExecContext(ctx, `DELETE FROM login_attempts WHERE login_attempt_time < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// Clear expired forgot password tokens, returning the number cleared. Invitation tokens are kept with the account
+// membership so an expired invitation still shows and can be resent
+func (pa *ProfileData) ClearExpiredForgotPasswordTokens(ctx context.Context) (int64, error) {
+	updateSql := `
+		UPDATE profile
+		SET forgot_password_token=NULL, forgot_password_expiration=NULL
+		WHERE forgot_password_expiration <= CURRENT_TIMESTAMP`
+	result, err := pa.db.ExecContext(ctx, updateSql)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
 func (pa *ProfileData) GetFailedLoginCount(email string, currentTime time.Time) (int, error) {
 	var count int
 	window := viper.GetInt("session.loginFailureWindowMinutes")
@@ -382,7 +512,7 @@ func (pa *ProfileData) DeleteSessionByToken(token string) error {
 	}
 
 	if rows, _ := result.RowsAffected(); rows == 0 {
-		logger.Log.Warn(fmt.Sprintf("No rows deleted using token [%s]", token))
+		logger.Log.Warn("No session deleted for token")
 	}
 
 	return nil
@@ -442,7 +572,7 @@ func (pa *ProfileData) GetProfiles(accountId int) ([]*Profile, error) {
 func (pa *ProfileData) GetProfileByToken(token string) (*Profile, error) {
 	userProfile := Profile{}
 	query := `	
-		SELECT a.*, p.*, pa.profile_account_status, pa.role, s.token, s.token_expiration, s.type
+		SELECT a.*, p.*, pa.profile_account_status, pa.role, s.token, s.token_expiration, s.type, s.scopes
 		FROM profile p,
      		session s,
      		account a,
@@ -467,6 +597,31 @@ func (pa *ProfileData) GetProfileByToken(token string) (*Profile, error) {
 	return &userProfile, nil
 }
 
+// Get the profile with the email and its membership of the account. Returns nil if the profile is not in the account
+func (pa *ProfileData) GetAccountProfileByEmail(accountId int, email string) (*Profile, error) {
+	user := Profile{}
+	query := `
+		SELECT p.*, a.*
+		FROM profile p,
+		     profile_account pa,
+		     account a
+		WHERE p.email = $1
+		  AND pa.profile_id = p.profile_id
+		  AND pa.account_id = $2
+		  AND a.account_id = pa.account_id`
+
+	err := pa.db.Get(&user, query, email, accountId)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
 func (pa *ProfileData) CreateAccount(newAccount *Account) (int, error) {
 	tx, err := pa.db.Begin()
 	if err != nil {
@@ -496,12 +651,20 @@ func (pa *ProfileData) UpdateAccount(updateAccount *Account) error {
 				  SET company=$1,
 				  	  week_start=$2,
 				  	  account_timezone=$3,
+				  	  timer_rounding=$4,
+				  	  timer_rounding_mode=$5,
+				  	  email_signature=$6,
+				  	  email_logo_url=$7,
 				  	  updated=CURRENT_TIMESTAMP
-				  WHERE account_id=$4`
+				  WHERE account_id=$8`
 	result, err := pa.db.Exec(updateSql,
 		updateAccount.Company,
 		updateAccount.WeekStart,
 		updateAccount.AccountTimezone,
+		updateAccount.TimerRounding,
+		updateAccount.TimerRoundingMode,
+		updateAccount.EmailSignature,
+		updateAccount.EmailLogoUrl,
 		updateAccount.AccountId)
 
 	if err != nil {
@@ -600,3 +763,358 @@ func (pa *ProfileData) CloseAccount(accountId int, reason string) error {
 
 	return nil
 }
+
+// Store a new API key session. Only the hash of the key is saved
+func (pa *ProfileData) CreateApiKey(apiKey *ApiKey, keyHash string) (int, error) {
+	insertSql := `
+		INSERT INTO session (token, token_expiration, profile_id, account_id, type, key_name, scopes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING session_id, created`
+
+	err := pa.db.QueryRow(insertSql, keyHash, apiKey.Expiration, apiKey.ProfileId, apiKey.AccountId, ApiKeySessionType, apiKey.Name, apiKey.Scopes).Scan(&apiKey.ApiKeyId, &apiKey.Created)
+	if err != nil {
+		return 0, err
+	}
+
+	return apiKey.ApiKeyId, nil
+}
+
+func (pa *ProfileData) GetApiKeys(profileId int, accountId int) ([]*ApiKey, error) {
+	query := `
+		SELECT session_id, profile_id, account_id, key_name, scopes, created, token_expiration, last_used
+		FROM session
+		WHERE profile_id = $1
+		  AND account_id = $2
+		  AND type = $3
+		ORDER BY created`
+
+	var apiKeys []*ApiKey
+	err := pa.db.Select(&apiKeys, query, profileId, accountId, ApiKeySessionType)
+	if err != nil {
+		return nil, err
+	}
+
+	return apiKeys, nil
+}
+
+// Revoke an API key owned by the profile. Returns NoRowAffectedError if the profile has no matching key
+func (pa *ProfileData) DeleteApiKey(profileId int, apiKeyId int) error {
+	result, err := pa.db.Exec("DELETE FROM session WHERE session_id = $1 AND profile_id = $2 AND type = $3", apiKeyId, profileId, ApiKeySessionType)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return database.NoRowAffectedError
+	}
+
+	return nil
+}
+
+// Record when a session was last used. Updates at most once a minute to limit writes on busy sessions
+func (pa *ProfileData) UpdateSessionLastUsed(token string) error {
+	updateSql := `
+		UPDATE session
+		SET last_used = CURRENT_TIMESTAMP
+		WHERE token = $1
+		  AND (last_used IS NULL OR last_used < CURRENT_TIMESTAMP - INTERVAL '1 minute')`
+
+	_, err := pa.db.Exec(updateSql, token)
+	return err
+}
+
+// Get the unexpired sessions and API keys for the profile, newest first, flagging the session using the current token
+func (pa *ProfileData) GetSessions(profileId int, currentToken string) ([]*ActiveSession, error) {
+	query := `
+		SELECT session_id, type, created, token_expiration, last_used, ip_address, user_agent, token = $2 AS current
+		FROM session
+		WHERE profile_id = $1
+		  AND token_expiration > CURRENT_TIMESTAMP
+		ORDER BY created DESC`
+
+	var sessions []*ActiveSession
+	err := pa.db.Select(&sessions, query, profileId, currentToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// Revoke one of the profile's sessions. Returns NoRowAffectedError if the profile has no matching session
+func (pa *ProfileData) DeleteSession(profileId int, sessionId int) error {
+	result, err := pa.db.Exec("DELETE FROM session WHERE session_id = $1 AND profile_id = $2", sessionId, profileId)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return database.NoRowAffectedError
+	}
+
+	return nil
+}
+
+// Revoke every web session for the profile except the current one. API keys are kept
+func (pa *ProfileData) DeleteOtherSessions(profileId int, currentToken string) error {
+	deleteSql := `DELETE FROM session WHERE profile_id = $1 AND token != $2 AND type = $3`
+	_, err := pa.db.Exec(deleteSql, profileId, currentToken, WebSessionType)
+	return err
+}
+
+// Delete the web sessions and API keys that have expired, returning the number deleted
+func (pa *ProfileData) DeleteExpiredSessions(ctx context.Context) (int64, error) {
+	result, err := pa.db.ExecContext(ctx, `DELETE FROM session WHERE token_expiration <= CURRENT_TIMESTAMP`)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// Get the valid accounts the profile belongs to, most recently used first
+func (pa *ProfileData) GetMemberships(profileId int) ([]*Membership, error) {
+	query := `
+		SELECT a.account_id, a.company, a.account_status, pa.role, pa.last_used
+		FROM profile_account pa,
+		     account a
+		WHERE pa.profile_id = $1
+		  AND pa.account_id = a.account_id
+		  AND pa.profile_account_status = $2
+		  AND a.account_status != $3
+		ORDER BY pa.last_used DESC`
+
+	var memberships []*Membership
+	err := pa.db.Select(&memberships, query, profileId, ProfileAccountValid, AccountArchived)
+	if err != nil {
+		return nil, err
+	}
+
+	return memberships, nil
+}
+
+const invitationColumns = `
+		SELECT p.profile_id, p.email, p.first_name, p.last_name, pa.role, p.language, pa.invited, pa.invitation_expiration
+		FROM profile p,
+		     profile_account pa`
+
+const invitationQuery = invitationColumns + `
+		WHERE pa.account_id = $1
+		  AND p.profile_id = pa.profile_id
+		  AND p.profile_status = $2`
+
+// Get the users added to the account who have not set up their profile
+func (pa *ProfileData) GetInvitations(accountId int) ([]*Invitation, error) {
+	var invitations []*Invitation
+	err := pa.db.Select(&invitations, invitationQuery+" ORDER BY p.email", accountId, ProfileNotVerified)
+	if err != nil {
+		return nil, err
+	}
+
+	return invitations, nil
+}
+
+func (pa *ProfileData) GetInvitation(accountId int, profileId int) (*Invitation, error) {
+	var invitation Invitation
+	err := pa.db.Get(&invitation, invitationQuery+" AND p.profile_id = $3", accountId, ProfileNotVerified, profileId)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &invitation, nil
+}
+
+// Get the invitation with the setup token. Returns nil once the profile is set up
+func (pa *ProfileData) GetInvitationByToken(token string) (*Invitation, error) {
+	query := invitationColumns + `
+		WHERE pa.invitation_token = $1
+		  AND p.profile_id = pa.profile_id
+		  AND p.profile_status = $2`
+
+	var invitation Invitation
+	err := pa.db.Get(&invitation, query, token, ProfileNotVerified)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &invitation, nil
+}
+
+// Set a new setup token and expiration for the profile's invitation to the account and record when it was sent
+func (pa *ProfileData) UpdateInvitation(accountId int, profileId int, token string, expirationMinutes int) error {
+	tokenExpiration := time.Now().Add(time.Minute * time.Duration(expirationMinutes))
+	updateSql := `
+		UPDATE profile_account pa
+		SET invitation_token=$1, invitation_expiration=$2, invited=CURRENT_TIMESTAMP
+		FROM profile p
+		WHERE pa.account_id = $3
+		  AND pa.profile_id = $4
+		  AND p.profile_id = pa.profile_id
+		  AND p.profile_status = $5`
+	result, err := pa.db.Exec(updateSql, token, tokenExpiration, accountId, profileId, ProfileNotVerified)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return database.NoRowAffectedError
+	}
+
+	return nil
+}
+
+// Clear the setup tokens of every account that invited the profile, so none can be used once the profile is set up
+func (pa *ProfileData) ClearInvitationTokens(profileId int) error {
+	updateSql := `UPDATE profile_account SET invitation_token=NULL, invitation_expiration=NULL WHERE profile_id=$1`
+	_, err := pa.db.Exec(updateSql, profileId)
+	return err
+}
+
+// Remove an invited profile from the account, along with its setup token
+func (pa *ProfileData) DeleteInvitation(accountId int, profileId int) error {
+	deleteSql := `
+		DELETE FROM profile_account pa
+		USING profile p
+		WHERE pa.account_id = $1
+		  AND pa.profile_id = $2
+		  AND p.profile_id = pa.profile_id
+		  AND p.profile_status = $3`
+	result, err := pa.db.Exec(deleteSql, accountId, profileId, ProfileNotVerified)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return database.NoRowAffectedError
+	}
+
+	return nil
+}
+
+// Get the weekly capacity of each valid member of the account
+func (pa *ProfileData) GetCapacities(accountId int) ([]*Capacity, error) {
+	query := `
+		SELECT p.profile_id, p.first_name, p.last_name, pa.capacity_hours
+		FROM profile p,
+		     profile_account pa
+		WHERE pa.account_id = $1
+		  AND pa.profile_id = p.profile_id
+		  AND pa.profile_account_status = $2
+		ORDER BY p.last_name, p.first_name`
+
+	var capacities []*Capacity
+	err := pa.db.Select(&capacities, query, accountId, ProfileAccountValid)
+	if err != nil {
+		return nil, err
+	}
+
+	return capacities, nil
+}
+
+// Set a member's weekly capacity. Returns NoRowAffectedError if the profile is not a valid member of the account
+func (pa *ProfileData) UpdateCapacity(accountId int, profileId int, hours float64) error {
+	updateSql := `
+		UPDATE profile_account
+		SET capacity_hours = $3
+		WHERE account_id = $1
+		  AND profile_id = $2
+		  AND profile_account_status = $4`
+
+	result, err := pa.db.Exec(updateSql, accountId, profileId, hours, ProfileAccountValid)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return database.NoRowAffectedError
+	}
+
+	return nil
+}
+
+// Get the account holidays between the from and to dates, inclusive
+func (pa *ProfileData) GetHolidays(accountId int, from time.Time, to time.Time) ([]*Holiday, error) {
+	query := `
+		SELECT holiday_id, account_id, day, holiday_name
+		FROM holiday
+		WHERE account_id = $1
+		  AND day BETWEEN $2 AND $3
+		ORDER BY day`
+
+	var holidays []*Holiday
+	err := pa.db.Select(&holidays, query, accountId, from.Format(config.ISOShortDateFormat), to.Format(config.ISOShortDateFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	return holidays, nil
+}
+
+// Add a holiday, or rename the holiday already on the same day, and return its id
+func (pa *ProfileData) SaveHoliday(holiday *Holiday) (int, error) {
+	insertSql := `
+		INSERT INTO holiday (account_id, day, holiday_name)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (account_id, day) DO UPDATE SET holiday_name = EXCLUDED.holiday_name
+		RETURNING holiday_id`
+
+	var holidayId int
+	err := pa.db.Get(&holidayId, insertSql, holiday.AccountId, holiday.Day.Format(config.ISOShortDateFormat), holiday.Name)
+	if err != nil {
+		return 0, err
+	}
+
+	return holidayId, nil
+}
+
+// Returns NoRowAffectedError if the account has no matching holiday
+func (pa *ProfileData) DeleteHoliday(accountId int, holidayId int) error {
+	result, err := pa.db.Exec("DELETE FROM holiday WHERE holiday_id = $1 AND account_id = $2", holidayId, accountId)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return database.NoRowAffectedError
+	}
+
+	return nil
+}
@@ -1,5 +1,12 @@
 package profile
 
+import (
+	"net/url"
+
+	"github.com/bryanmorgan/time-tracking-api/api"
+	"github.com/bryanmorgan/time-tracking-api/valid"
+)
+
 // Returns true if the ProfileStatus is new or valid
 func IsProfileStatusValid(status ProfileStatus) bool {
 	if status == ProfileNew || status == ProfileValid {
@@ -34,6 +41,17 @@ func IsAdmin(role AuthorizationRole) bool {
 	return false
 }
 
+// Returns true if the AuthorizationRole is one of the allowed roles
+func HasRole(role AuthorizationRole, allowed []AuthorizationRole) bool {
+	for _, allowedRole := range allowed {
+		if role == allowedRole {
+			return true
+		}
+	}
+
+	return false
+}
+
 // Returns true if the AccountStatus is new or valid
 func IsAccountStatusValid(status AccountStatus) bool {
 	if status == AccountNew || status == AccountValid {
@@ -52,3 +70,61 @@ func IsProfileAccountStatusValid(status ProfileAccountStatus) bool {
 func IsWeekStart(weekStart int) bool {
 	return weekStart >= 0 && weekStart < 7
 }
+
+// Returns true if the timer rounding increment is one of the supported minute values (0 disables rounding)
+func IsTimerRounding(minutes int) bool {
+	switch minutes {
+	case 0, 1, 5, 6, 10, 15, 30, 60:
+		return true
+	default:
+		return false
+	}
+}
+
+func IsTimerRoundingMode(mode string) bool {
+	switch TimerRoundingMode(mode) {
+	case RoundNearest, RoundUp, RoundDown:
+		return true
+	default:
+		return false
+	}
+}
+
+// Returns true if the email logo is an absolute https URL so it loads in email clients without mixed content warnings
+func IsEmailLogoUrl(logoUrl string) bool {
+	u, err := url.Parse(logoUrl)
+	if err != nil {
+		return false
+	}
+
+	return u.Scheme == "https" && u.Host != ""
+}
+
+// Check the fields required to create a new account, defaulting the timezone when it is missing
+func ValidateAccountRequest(accountRequest *AccountRequest) *api.Error {
+	if !valid.IsEmail(accountRequest.Email) {
+		return api.NewFieldError("invalid input", "Invalid email", api.InvalidEmail, "email")
+	}
+
+	if !valid.IsLength(accountRequest.Password, PasswordMinLength, PasswordMaxLength) {
+		return api.NewFieldError("invalid input", "Password must be between 6 and 64 characters", api.FieldSize, "password")
+	}
+
+	if !valid.IsLength(accountRequest.FirstName, NameMinLength, NameMaxLength) {
+		return api.NewFieldError("invalid input", "First name must be between 1 and 64 characters", api.FieldSize, "firstName")
+	}
+
+	if !valid.IsLength(accountRequest.LastName, NameMinLength, NameMaxLength) {
+		return api.NewFieldError("invalid input", "Last name must be between 1 and 64 characters", api.FieldSize, "lastName")
+	}
+
+	if !valid.IsLength(accountRequest.Company, CompanyNameMinLength, CompanyNameMaxLength) {
+		return api.NewFieldError("invalid input", "Company name must be between 1 and 64 characters", api.FieldSize, "company")
+	}
+
+	if valid.IsNull(accountRequest.Timezone) {
+		accountRequest.Timezone = "America/New_York"
+	}
+
+	return nil
+}
@@ -2,6 +2,9 @@ package profile
 
 import (
 	"testing"
+	"time"
+
+	"github.com/lib/pq"
 
 	"github.com/bryanmorgan/time-tracking-api/api"
 )
@@ -77,6 +80,33 @@ func TestAdminRole(t *testing.T) {
 	}
 }
 
+func TestHasRole(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		role    AuthorizationRole
+		allowed []AuthorizationRole
+		valid   bool
+	}{
+		{"Owner in admin roles", Owner, AdminRoles, true},
+		{"Reporting not in admin roles", Reporting, AdminRoles, false},
+		{"Reporting in reporting roles", Reporting, ReportingRoles, true},
+		{"User not in reporting roles", User, ReportingRoles, false},
+		{"User in all roles", User, AllRoles, true},
+		{"Unknown role in all roles", "none", AllRoles, false},
+		{"No allowed roles", Owner, nil, false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if testCase.valid != HasRole(testCase.role, testCase.allowed) {
+				t.Errorf("Role: [%s] allowed: [%v] wanted: [%v]", testCase.role, testCase.allowed, testCase.valid)
+			}
+		})
+	}
+}
+
 func TestAccountStatusValid(t *testing.T) {
 	t.Parallel()
 
@@ -120,3 +150,53 @@ func TestProfileAccountStatusValid(t *testing.T) {
 		})
 	}
 }
+
+func TestTimerRoundingValid(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		minutes int
+		mode    string
+		valid   bool
+	}{
+		{"No rounding", 0, string(RoundNearest), true},
+		{"15 minutes up", 15, string(RoundUp), true},
+		{"6 minutes down", 6, string(RoundDown), true},
+		{"Invalid minutes", 7, string(RoundNearest), false},
+		{"Negative minutes", -15, string(RoundNearest), false},
+		{"Invalid mode", 15, "sideways", false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			valid := IsTimerRounding(testCase.minutes) && IsTimerRoundingMode(testCase.mode)
+			if testCase.valid != valid {
+				t.Errorf("Timer rounding check failed: [%d] [%s] wanted: [%v]", testCase.minutes, testCase.mode, testCase.valid)
+			}
+		})
+	}
+}
+
+func TestInvitationExpired(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name       string
+		expiration pq.NullTime
+		expired    bool
+	}{
+		{"Future", pq.NullTime{Time: time.Now().Add(time.Hour), Valid: true}, false},
+		{"Past", pq.NullTime{Time: time.Now().Add(-time.Hour), Valid: true}, true},
+		{"Cleared", pq.NullTime{}, true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			invitation := Invitation{Expiration: testCase.expiration}
+			if invitation.IsExpired() != testCase.expired {
+				t.Errorf("Invitation expired check failed: [%v] wanted: [%v]", invitation.Expiration, testCase.expired)
+			}
+		})
+	}
+}
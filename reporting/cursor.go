@@ -0,0 +1,66 @@
+package reporting
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bryanmorgan/time-tracking-api/config"
+)
+
+var InvalidCursorError = errors.New("invalid cursor")
+
+// Position of the last time entry on a page. Entries are ordered by day, profile, project and task, which is the
+// time table's primary key within an account, so the next page starts strictly after the cursor
+type EntryCursor struct {
+	Day       time.Time
+	ProfileId int
+	ProjectId int
+	TaskId    int
+}
+
+func NewEntryCursor(entry *TimeEntryReport) *EntryCursor {
+	return &EntryCursor{
+		Day:       entry.Day,
+		ProfileId: entry.ProfileId,
+		ProjectId: entry.ProjectId,
+		TaskId:    entry.TaskId,
+	}
+}
+
+// Encode the cursor as an opaque URL safe string
+func (c *EntryCursor) Encode() string {
+	value := fmt.Sprintf("%s:%d:%d:%d", c.Day.Format(config.ISOShortDateFormat), c.ProfileId, c.ProjectId, c.TaskId)
+	return base64.RawURLEncoding.EncodeToString([]byte(value))
+}
+
+// Decode a cursor created by Encode. Returns InvalidCursorError if the cursor was not created by Encode
+func ParseEntryCursor(encoded string) (*EntryCursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, InvalidCursorError
+	}
+
+	parts := strings.Split(string(decoded), ":")
+	if len(parts) != 4 {
+		return nil, InvalidCursorError
+	}
+
+	day, err := time.Parse(config.ISOShortDateFormat, parts[0])
+	if err != nil {
+		return nil, InvalidCursorError
+	}
+
+	ids := make([]int, 3)
+	for i, part := range parts[1:] {
+		ids[i], err = strconv.Atoi(part)
+		if err != nil || ids[i] <= 0 {
+			return nil, InvalidCursorError
+		}
+	}
+
+	return &EntryCursor{Day: day, ProfileId: ids[0], ProjectId: ids[1], TaskId: ids[2]}, nil
+}
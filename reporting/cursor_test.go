@@ -0,0 +1,39 @@
+package reporting
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestEntryCursor(t *testing.T) {
+	t.Parallel()
+
+	cursor := &EntryCursor{Day: getDate("2019-01-07"), ProfileId: 12, ProjectId: 34, TaskId: 56}
+	parsed, err := ParseEntryCursor(cursor.Encode())
+	if err != nil {
+		t.Fatalf("Failed to parse encoded cursor: [%s]", err)
+	}
+
+	if *parsed != *cursor {
+		t.Errorf("Cursor changed. Got [%+v] Expected: [%+v]", parsed, cursor)
+	}
+
+	testCases := []struct {
+		name   string
+		cursor string
+	}{
+		{"Not base64", "!!!"},
+		{"Missing parts", base64.RawURLEncoding.EncodeToString([]byte("2019-01-07:12:34"))},
+		{"Invalid day", base64.RawURLEncoding.EncodeToString([]byte("2019-13-07:12:34:56"))},
+		{"Invalid id", base64.RawURLEncoding.EncodeToString([]byte("2019-01-07:12:x:56"))},
+		{"Zero id", base64.RawURLEncoding.EncodeToString([]byte("2019-01-07:0:34:56"))},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if _, err := ParseEntryCursor(testCase.cursor); err != InvalidCursorError {
+				t.Errorf("Expected an invalid cursor error but got [%v]", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package reporting
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type Dimension string
+
+const (
+	DimensionClient  Dimension = "client"
+	DimensionProject Dimension = "project"
+	DimensionTask    Dimension = "task"
+	DimensionPerson  Dimension = "person"
+	DimensionDay     Dimension = "day"
+	DimensionWeek    Dimension = "week"
+	DimensionMonth   Dimension = "month"
+)
+
+// Most rows a grouped report can return before it has to be narrowed with filters or fewer dimensions
+const GroupMaxRows = 10000
+
+var InvalidDimensionError = errors.New("invalid report dimension")
+
+// SQL for one dimension of a grouped report. Every dimension selects the same four columns so rows can be scanned
+// without knowing the dimensions: an id, a date, a name and a second name
+type dimensionSql struct {
+	id      string
+	day     string
+	name    string
+	detail  string
+	groupBy string
+	orderBy string
+}
+
+// The week dimension's %[1]d is replaced with the week start, 0 for Sunday to 6 for Saturday
+var dimensionSqls = map[Dimension]dimensionSql{
+	DimensionClient: {
+		id: "c.client_id", day: "NULL::date", name: "c.client_name", detail: "NULL",
+		groupBy: "c.client_id, c.client_name",
+		orderBy: "c.client_name, c.client_id",
+	},
+	DimensionProject: {
+		id: "p.project_id", day: "NULL::date", name: "p.project_name", detail: "c.client_name",
+		groupBy: "p.project_id, p.project_name, c.client_name",
+		orderBy: "p.project_name, p.project_id",
+	},
+	DimensionTask: {
+		id: "k.task_id", day: "NULL::date", name: "k.task_name", detail: "NULL",
+		groupBy: "k.task_id, k.task_name",
+		orderBy: "k.task_name, k.task_id",
+	},
+	DimensionPerson: {
+		id: "pr.profile_id", day: "NULL::date", name: "pr.first_name", detail: "pr.last_name",
+		groupBy: "pr.profile_id, pr.first_name, pr.last_name",
+		orderBy: "pr.last_name, pr.first_name, pr.profile_id",
+	},
+	DimensionDay: {
+		id: "NULL::int", day: "t.day", name: "NULL", detail: "NULL",
+		groupBy: "t.day",
+		orderBy: "t.day",
+	},
+	DimensionWeek: {
+		id: "NULL::int", day: "t.day - ((EXTRACT(DOW FROM t.day)::int - %[1]d + 7) %% 7)", name: "NULL", detail: "NULL",
+		groupBy: "t.day - ((EXTRACT(DOW FROM t.day)::int - %[1]d + 7) %% 7)",
+		orderBy: "t.day - ((EXTRACT(DOW FROM t.day)::int - %[1]d + 7) %% 7)",
+	},
+	DimensionMonth: {
+		id: "NULL::int", day: "date_trunc('month', t.day)::date", name: "NULL", detail: "NULL",
+		groupBy: "date_trunc('month', t.day)::date",
+		orderBy: "date_trunc('month', t.day)::date",
+	},
+}
+
+// Time grouped by an ordered list of dimensions. A limit of 0 returns every row
+type GroupQuery struct {
+	Filter     TimeEntryFilter
+	Dimensions []Dimension
+	WeekStart  int
+	Limit      int
+	Offset     int
+	Notes      bool // Adds the distinct notes of each row's time, used by the CSV exports
+}
+
+// The value of one dimension for a row of a grouped report
+type GroupKey struct {
+	Dimension  Dimension
+	Id         int       // Client, project, task or profile id
+	Day        time.Time // The day, or the first day of the week or month
+	Name       string    // Client, project or task name, or the person's full name
+	ClientName string    // Set for projects
+	FirstName  string    // Set for people
+	LastName   string    // Set for people
+}
+
+// Totals for one combination of dimension values
+type GroupRow struct {
+	Keys             []*GroupKey
+	NonBillableHours sql.NullFloat64
+	BillableHours    sql.NullFloat64
+	BillableTotal    sql.NullFloat64
+	Notes            sql.NullString // Only set when the query includes notes
+}
+
+// Totals for a dimension value and the totals of the next dimension within it
+type GroupNode struct {
+	Key              *GroupKey
+	NonBillableHours float64
+	BillableHours    float64
+	BillableTotal    float64
+	Children         []*GroupNode
+}
+
+// Parse a comma separated list of dimensions. Each dimension can be used once and only one of day, week and month can
+// be used
+func ParseDimensions(value string) ([]Dimension, error) {
+	var dimensions []Dimension
+	seen := make(map[Dimension]bool)
+	period := false
+	for _, part := range strings.Split(value, ",") {
+		dimension := Dimension(strings.ToLower(strings.TrimSpace(part)))
+		if _, ok := dimensionSqls[dimension]; !ok || seen[dimension] {
+			return nil, InvalidDimensionError
+		}
+
+		if dimension.IsPeriod() {
+			if period {
+				return nil, InvalidDimensionError
+			}
+			period = true
+		}
+
+		seen[dimension] = true
+		dimensions = append(dimensions, dimension)
+	}
+
+	return dimensions, nil
+}
+
+// Returns true for the dimensions that group time by date
+func (d Dimension) IsPeriod() bool {
+	return d == DimensionDay || d == DimensionWeek || d == DimensionMonth
+}
+
+// Build the grouped report SQL for the dimensions. The parameters are the account id, from and to dates, the filters
+// in TimeEntryFilter order, then the limit and offset. Notes adds the distinct notes as the last column
+func groupSql(dimensions []Dimension, weekStart int, notes bool) string {
+	var columns, groupBy, orderBy []string
+	for i, dimension := range dimensions {
+		d := dimensionSqls[dimension]
+		if dimension == DimensionWeek {
+			d.day = fmt.Sprintf(d.day, weekStart)
+			d.groupBy = fmt.Sprintf(d.groupBy, weekStart)
+			d.orderBy = fmt.Sprintf(d.orderBy, weekStart)
+		}
+
+		columns = append(columns,
+			fmt.Sprintf("%s AS key_id_%d", d.id, i),
+			fmt.Sprintf("%s AS key_day_%d", d.day, i),
+			fmt.Sprintf("%s AS key_name_%d", d.name, i),
+			fmt.Sprintf("%s AS key_detail_%d", d.detail, i))
+		groupBy = append(groupBy, d.groupBy)
+		orderBy = append(orderBy, d.orderBy)
+	}
+
+	totals := `sum(t.hours) filter (where not pt.billable)       as non_billable_hours,
+		       sum(t.hours) filter (where pt.billable)           as billable_hours,
+		       sum(t.hours * pt.rate) filter (where pt.billable) as billable_total`
+	if notes {
+		totals += `,
+		       string_agg(DISTINCT t.notes, '; ') filter (where t.notes <> '') as notes`
+	}
+
+	return `
+		SELECT ` + strings.Join(columns, ",\n\t\t       ") + `,
+		       ` + totals + `
+		FROM time t,
+		     project_task pt,
+		     project p,
+		     client c,
+		     task k,
+		     profile pr
+		WHERE t.account_id = $1
+		  AND t.account_id = pt.account_id
+		  AND t.project_id = pt.project_id
+		  AND t.task_id = pt.task_id
+		  AND pt.project_id = p.project_id
+		  AND c.client_id = p.client_id
+		  AND k.task_id = t.task_id
+		  AND pr.profile_id = t.profile_id
+		  AND t.hours > 0.0
+		  AND t.day >= $2
+		  AND t.day <= $3
+		  AND ($4 = 0 OR c.client_id = $4)
+		  AND ($5 = 0 OR t.project_id = $5)
+		  AND ($6 = 0 OR t.task_id = $6)
+		  AND ($7 = 0 OR t.profile_id = $7)
+		  AND ($8::boolean IS NULL OR pt.billable = $8)
+		GROUP BY ` + strings.Join(groupBy, ", ") + `
+		ORDER BY ` + strings.Join(orderBy, ", ") + `
+		LIMIT $9
+		OFFSET $10
+`
+}
+
+func newGroupKey(dimension Dimension, id sql.NullInt64, day pq.NullTime, name sql.NullString, detail sql.NullString) *GroupKey {
+	key := &GroupKey{Dimension: dimension, Id: int(id.Int64), Day: day.Time, Name: name.String}
+	switch dimension {
+	case DimensionProject:
+		key.ClientName = detail.String
+	case DimensionPerson:
+		key.FirstName = name.String
+		key.LastName = detail.String
+		key.Name = strings.TrimSpace(name.String + " " + detail.String)
+	}
+
+	return key
+}
+
+func (k *GroupKey) matches(other *GroupKey) bool {
+	return k.Dimension == other.Dimension && k.Id == other.Id && k.Day.Equal(other.Day)
+}
+
+// Nest the rows of a grouped report by dimension, adding up the totals of each level. Rows must be in the order
+// returned by the grouped report so each dimension value's rows are together
+func BuildGroupTree(rows []*GroupRow) []*GroupNode {
+	var roots []*GroupNode
+	for _, row := range rows {
+		nodes := &roots
+		for _, key := range row.Keys {
+			var node *GroupNode
+			if count := len(*nodes); count > 0 && (*nodes)[count-1].Key.matches(key) {
+				node = (*nodes)[count-1]
+			} else {
+				node = &GroupNode{Key: key}
+				*nodes = append(*nodes, node)
+			}
+
+			node.NonBillableHours += row.NonBillableHours.Float64
+			node.BillableHours += row.BillableHours.Float64
+			node.BillableTotal += row.BillableTotal.Float64
+			nodes = &node.Children
+		}
+	}
+
+	return roots
+}
@@ -0,0 +1,101 @@
+package reporting
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+func TestParseDimensions(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name       string
+		value      string
+		dimensions []Dimension
+	}{
+		{"Single", "client", []Dimension{DimensionClient}},
+		{"Drill down", "client, Project,person,week", []Dimension{DimensionClient, DimensionProject, DimensionPerson, DimensionWeek}},
+		{"Unknown", "client,year", nil},
+		{"Repeated", "task,task", nil},
+		{"Two periods", "day,month", nil},
+		{"Empty", "", nil},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			dimensions, err := ParseDimensions(testCase.value)
+			if testCase.dimensions == nil {
+				if err != InvalidDimensionError {
+					t.Errorf("Expected an invalid dimension error but got [%v] [%v]", dimensions, err)
+				}
+				return
+			}
+
+			if err != nil || strings.Join(dimensionNames(dimensions), ",") != strings.Join(dimensionNames(testCase.dimensions), ",") {
+				t.Errorf("Dimensions incorrect. Got [%v] [%v] Expected: [%v]", dimensions, err, testCase.dimensions)
+			}
+		})
+	}
+}
+
+func TestGroupSqlWeekStart(t *testing.T) {
+	t.Parallel()
+
+	query := groupSql([]Dimension{DimensionPerson, DimensionWeek}, 3, false)
+	if !strings.Contains(query, "EXTRACT(DOW FROM t.day)::int - 3 + 7) % 7") {
+		t.Errorf("Week start not set in query: %s", query)
+	}
+
+	if strings.Contains(query, "string_agg") {
+		t.Errorf("Notes added to query without notes: %s", query)
+	}
+
+	if query := groupSql([]Dimension{DimensionClient}, 0, true); !strings.Contains(query, "string_agg(DISTINCT t.notes, '; ')") {
+		t.Errorf("Notes missing from query: %s", query)
+	}
+
+	if !strings.Contains(query, "ORDER BY pr.last_name, pr.first_name, pr.profile_id, t.day") {
+		t.Errorf("Dimensions not ordered in query: %s", query)
+	}
+}
+
+func TestBuildGroupTree(t *testing.T) {
+	t.Parallel()
+
+	client := func(id int) *GroupKey { return &GroupKey{Dimension: DimensionClient, Id: id} }
+	project := func(id int) *GroupKey { return &GroupKey{Dimension: DimensionProject, Id: id} }
+	hours := func(value float64) sql.NullFloat64 { return sql.NullFloat64{Float64: value, Valid: true} }
+
+	rows := []*GroupRow{
+		{Keys: []*GroupKey{client(1), project(10)}, BillableHours: hours(2), BillableTotal: hours(200)},
+		{Keys: []*GroupKey{client(1), project(11)}, BillableHours: hours(3), NonBillableHours: hours(1), BillableTotal: hours(150)},
+		{Keys: []*GroupKey{client(2), project(20)}, NonBillableHours: hours(4)},
+	}
+
+	tree := BuildGroupTree(rows)
+	if len(tree) != 2 || len(tree[0].Children) != 2 || len(tree[1].Children) != 1 {
+		t.Fatalf("Wrong tree shape: [%+v]", tree)
+	}
+
+	first := tree[0]
+	if first.Key.Id != 1 || first.BillableHours != 5 || first.NonBillableHours != 1 || first.BillableTotal != 350 {
+		t.Errorf("Client totals incorrect: [%+v]", first)
+	}
+
+	if first.Children[1].Key.Id != 11 || first.Children[1].BillableHours != 3 {
+		t.Errorf("Project totals incorrect: [%+v]", first.Children[1])
+	}
+
+	if tree[1].NonBillableHours != 4 || tree[1].Children[0].Children != nil {
+		t.Errorf("Second client incorrect: [%+v]", tree[1])
+	}
+}
+
+func dimensionNames(dimensions []Dimension) []string {
+	var names []string
+	for _, dimension := range dimensions {
+		names = append(names, string(dimension))
+	}
+	return names
+}
@@ -1,15 +1,14 @@
 package reporting
 
 import (
-	"encoding/csv"
-	"fmt"
 	"net/http"
-	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bryanmorgan/time-tracking-api/api"
 	"github.com/bryanmorgan/time-tracking-api/config"
+	"github.com/bryanmorgan/time-tracking-api/export"
 	"github.com/bryanmorgan/time-tracking-api/logger"
 	"github.com/bryanmorgan/time-tracking-api/profile"
 	"github.com/bryanmorgan/time-tracking-api/valid"
@@ -51,6 +50,78 @@ type PersonReportResponse struct {
 	BillableTotal    float64 `json:"billableTotal"`
 }
 
+type UtilizationResponse struct {
+	ProfileId          int     `json:"profileId"`
+	FirstName          string  `json:"firstName"`
+	LastName           string  `json:"lastName"`
+	WeekStart          string  `json:"weekStart"`
+	CapacityHours      float64 `json:"capacityHours"`
+	LoggedHours        float64 `json:"loggedHours"`
+	BillableHours      float64 `json:"billableHours"`
+	NonBillableHours   float64 `json:"nonBillableHours"`
+	UtilizationPercent float64 `json:"utilizationPercent"`
+	BillablePercent    float64 `json:"billablePercent"`
+}
+
+type TimeEntryReportResponse struct {
+	Day           string  `json:"day"`
+	ProfileId     int     `json:"profileId"`
+	FirstName     string  `json:"firstName"`
+	LastName      string  `json:"lastName"`
+	ClientId      int     `json:"clientId"`
+	ClientName    string  `json:"clientName"`
+	ProjectId     int     `json:"projectId"`
+	ProjectName   string  `json:"projectName"`
+	TaskId        int     `json:"taskId"`
+	TaskName      string  `json:"taskName"`
+	Hours         float64 `json:"hours"`
+	Billable      bool    `json:"billable"`
+	Rate          float64 `json:"rate"`
+	BillableTotal float64 `json:"billableTotal"`
+	Notes         string  `json:"notes,omitempty"`
+	Invoiced      bool    `json:"invoiced"`
+}
+
+type GroupNodeResponse struct {
+	Dimension        string               `json:"dimension"`
+	Id               int                  `json:"id,omitempty"`
+	Day              string               `json:"day,omitempty"`
+	Name             string               `json:"name,omitempty"`
+	ClientName       string               `json:"clientName,omitempty"`
+	FirstName        string               `json:"firstName,omitempty"`
+	LastName         string               `json:"lastName,omitempty"`
+	NonBillableHours float64              `json:"nonBillableHours"`
+	BillableHours    float64              `json:"billableHours"`
+	BillableTotal    float64              `json:"billableTotal"`
+	Children         []*GroupNodeResponse `json:"children,omitempty"`
+}
+
+type SeriesPointResponse struct {
+	Start            string  `json:"start"`
+	Label            string  `json:"label"`
+	Hours            float64 `json:"hours"`
+	NonBillableHours float64 `json:"nonBillableHours"`
+	BillableHours    float64 `json:"billableHours"`
+	BillableTotal    float64 `json:"billableTotal"`
+}
+
+type SeriesResponse struct {
+	Id     int                    `json:"id,omitempty"`
+	Name   string                 `json:"name,omitempty"`
+	Points []*SeriesPointResponse `json:"points"`
+}
+
+type TimeSeriesResponse struct {
+	Interval string            `json:"interval"`
+	Split    string            `json:"split,omitempty"`
+	Series   []*SeriesResponse `json:"series"`
+}
+
+type TimeEntriesReportResponse struct {
+	Entries    []*TimeEntryReportResponse `json:"entries"`
+	NextCursor string                     `json:"nextCursor,omitempty"`
+}
+
 func (a *ReportingRouter) getTimeByClient(w http.ResponseWriter, r *http.Request) {
 	fromDateString := r.URL.Query().Get("from")
 	toDateString := r.URL.Query().Get("to")
@@ -266,6 +337,11 @@ func (a *ReportingRouter) getTimeByPerson(w http.ResponseWriter, r *http.Request
 }
 
 func (a *ReportingRouter) exportTimeByClient(w http.ResponseWriter, r *http.Request) {
+	renderer, ok := getExportRenderer(w, r)
+	if !ok {
+		return
+	}
+
 	fromDateString := r.URL.Query().Get("from")
 	toDateString := r.URL.Query().Get("to")
 
@@ -299,16 +375,20 @@ func (a *ReportingRouter) exportTimeByClient(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	reportRows, apperr := a.ReportingService.GetTimeByClient(userProfile.AccountId, fromDate, toDate, 0)
-	if apperr != nil {
-		api.ErrorJson(w, apperr, http.StatusInternalServerError)
-		return
-	}
-
-	WriteExportClientReportsResponse(w, userProfile.Account.Company, fromDate, toDate, reportRows)
+	stream := export.NewStream(w, renderer, NewClientExportReport(userProfile.Account.Company, fromDate, toDate))
+	streamExport(w, stream, func() *api.Error {
+		return a.ReportingService.ExportTimeByClient(userProfile.AccountId, fromDate, toDate, func(report *ClientReport) error {
+			return stream.Write(ExportClientReportResponse(report))
+		})
+	})
 }
 
 func (a *ReportingRouter) exportTimeByProject(w http.ResponseWriter, r *http.Request) {
+	renderer, ok := getExportRenderer(w, r)
+	if !ok {
+		return
+	}
+
 	fromDateString := r.URL.Query().Get("from")
 	toDateString := r.URL.Query().Get("to")
 
@@ -342,16 +422,20 @@ func (a *ReportingRouter) exportTimeByProject(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	reportRows, apperr := a.ReportingService.GetTimeByProject(userProfile.AccountId, fromDate, toDate, 0)
-	if apperr != nil {
-		api.ErrorJson(w, apperr, http.StatusInternalServerError)
-		return
-	}
-
-	WriteExportProjectReportsResponse(w, userProfile.Account.Company, fromDate, toDate, reportRows)
+	stream := export.NewStream(w, renderer, NewProjectExportReport(userProfile.Account.Company, fromDate, toDate))
+	streamExport(w, stream, func() *api.Error {
+		return a.ReportingService.ExportTimeByProject(userProfile.AccountId, fromDate, toDate, func(report *ProjectReport) error {
+			return stream.Write(ExportProjectReportResponse(report))
+		})
+	})
 }
 
 func (a *ReportingRouter) exportTimeByTask(w http.ResponseWriter, r *http.Request) {
+	renderer, ok := getExportRenderer(w, r)
+	if !ok {
+		return
+	}
+
 	fromDateString := r.URL.Query().Get("from")
 	toDateString := r.URL.Query().Get("to")
 
@@ -385,16 +469,63 @@ func (a *ReportingRouter) exportTimeByTask(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	reportRows, apperr := a.ReportingService.GetTimeByTask(userProfile.AccountId, fromDate, toDate, 0)
-	if apperr != nil {
-		api.ErrorJson(w, apperr, http.StatusInternalServerError)
+	stream := export.NewStream(w, renderer, NewTaskExportReport(userProfile.Account.Company, fromDate, toDate))
+	streamExport(w, stream, func() *api.Error {
+		return a.ReportingService.ExportTimeByTask(userProfile.AccountId, fromDate, toDate, func(report *TaskReport) error {
+			return stream.Write(ExportTaskReportResponse(report))
+		})
+	})
+}
+
+func (a *ReportingRouter) exportTimeByPerson(w http.ResponseWriter, r *http.Request) {
+	renderer, ok := getExportRenderer(w, r)
+	if !ok {
+		return
+	}
+
+	fromDateString := r.URL.Query().Get("from")
+	toDateString := r.URL.Query().Get("to")
+
+	if valid.IsNull(fromDateString) {
+		api.ErrorJson(w, api.NewFieldError(nil, "No from parameter", api.InvalidField, "from"), http.StatusBadRequest)
+		return
+	}
+
+	var toDate time.Time
+	var fromDate time.Time
+	fromDate, err := time.Parse(config.ISOShortDateFormat, fromDateString)
+	if err != nil {
+		api.ErrorJson(w, api.NewFieldError(err, "Invalid format. Use ISO8061: YYYY-MM-DD", api.InvalidField, fromDateString), http.StatusBadRequest)
+		return
+	}
+
+	if !valid.IsNull(toDateString) {
+		// Make sure the date string is in a valid ISO 8061 format
+		toDate, err = time.Parse(config.ISOShortDateFormat, toDateString)
+		if err != nil {
+			api.ErrorJson(w, api.NewFieldError(err, "Invalid format. Use ISO8061: YYYY-MM-DD", api.InvalidField, toDateString), http.StatusBadRequest)
+			return
+		}
+	} else {
+		toDate = time.Now()
+	}
+
+	userProfile, ok := r.Context().Value(config.ProfileContextKey).(*profile.Profile)
+	if !ok || userProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid profile context", api.SystemError), http.StatusInternalServerError)
 		return
 	}
 
-	WriteExportTaskReportsResponse(w, userProfile.Account.Company, fromDate, toDate, reportRows)
+	stream := export.NewStream(w, renderer, NewPersonExportReport(userProfile.Account.Company, fromDate, toDate))
+	streamExport(w, stream, func() *api.Error {
+		return a.ReportingService.ExportTimeByPerson(userProfile.AccountId, fromDate, toDate, func(report *PersonReport) error {
+			return stream.Write(ExportPersonReportResponse(report))
+		})
+	})
 }
 
-func (a *ReportingRouter) exportTimeByPerson(w http.ResponseWriter, r *http.Request) {
+// Logged and billable hours against capacity for each person and week. Weeks start on the adjusted from date
+func (a *ReportingRouter) getUtilization(w http.ResponseWriter, r *http.Request) {
 	fromDateString := r.URL.Query().Get("from")
 	toDateString := r.URL.Query().Get("to")
 
@@ -422,19 +553,288 @@ func (a *ReportingRouter) exportTimeByPerson(w http.ResponseWriter, r *http.Requ
 		toDate = time.Now()
 	}
 
+	if toDate.Before(fromDate) || toDate.Sub(fromDate) > UtilizationMaxDays*24*time.Hour {
+		api.ErrorJson(w, api.NewFieldError(nil, "The to date must be within 366 days after the from date", api.InvalidField, "to"), http.StatusBadRequest)
+		return
+	}
+
 	userProfile, ok := r.Context().Value(config.ProfileContextKey).(*profile.Profile)
 	if !ok || userProfile == nil {
 		api.ErrorJson(w, api.NewError(nil, "Invalid profile context", api.SystemError), http.StatusInternalServerError)
 		return
 	}
 
-	reportRows, apperr := a.ReportingService.GetTimeByPerson(userProfile.AccountId, fromDate, toDate, 0)
+	fromDate, toDate = AdjustForWeekStart(fromDate, toDate, userProfile.WeekStart, time.Now())
+
+	rows, apperr := a.ReportingService.GetUtilization(userProfile.AccountId, fromDate, toDate)
 	if apperr != nil {
 		api.ErrorJson(w, apperr, http.StatusInternalServerError)
 		return
 	}
 
-	WriteExportPersonReportsResponse(w, userProfile.Account.Company, fromDate, toDate, reportRows)
+	api.Json(w, r, NewUtilizationListResponse(rows))
+}
+
+// Individual time entries matching the filters. Pass nextCursor from the response as the cursor parameter to get the
+// next page
+func (a *ReportingRouter) getTimeEntries(w http.ResponseWriter, r *http.Request) {
+	filter, apperr := parseTimeEntryFilter(r)
+	if apperr != nil {
+		api.ErrorJson(w, apperr, http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	var err error
+	limit := ReportPaginationLimit
+	if limitString := query.Get("limit"); !valid.IsNull(limitString) {
+		limit, err = strconv.Atoi(limitString)
+		if err != nil || limit <= 0 || limit > EntriesMaxLimit {
+			api.BadInputs(w, "Limit must be between 1 and 1000", api.InvalidField, "limit")
+			return
+		}
+	}
+
+	var after *EntryCursor
+	if cursor := query.Get("cursor"); !valid.IsNull(cursor) {
+		after, err = ParseEntryCursor(cursor)
+		if err != nil {
+			api.BadInputs(w, "Invalid cursor", api.InvalidField, "cursor")
+			return
+		}
+	}
+
+	userProfile, ok := r.Context().Value(config.ProfileContextKey).(*profile.Profile)
+	if !ok || userProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid profile context", api.SystemError), http.StatusInternalServerError)
+		return
+	}
+
+	entries, next, apperr := a.ReportingService.GetTimeEntries(userProfile.AccountId, filter, after, limit)
+	if apperr != nil {
+		api.ErrorJson(w, apperr, http.StatusInternalServerError)
+		return
+	}
+
+	api.Json(w, r, NewTimeEntriesReportResponse(entries, next))
+}
+
+// Time totals nested by the dimensions in the by parameter, for example by=client,project,person
+func (a *ReportingRouter) getGroupedTime(w http.ResponseWriter, r *http.Request) {
+	by := r.URL.Query().Get("by")
+	if valid.IsNull(by) {
+		api.BadInputs(w, "No by parameter", api.MissingField, "by")
+		return
+	}
+
+	dimensions, err := ParseDimensions(by)
+	if err != nil {
+		api.BadInputs(w, "Use each of client, project, task, person and one of day, week or month at most once", api.InvalidDimension, "by")
+		return
+	}
+
+	filter, apperr := parseTimeEntryFilter(r)
+	if apperr != nil {
+		api.ErrorJson(w, apperr, http.StatusBadRequest)
+		return
+	}
+
+	userProfile, ok := r.Context().Value(config.ProfileContextKey).(*profile.Profile)
+	if !ok || userProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid profile context", api.SystemError), http.StatusInternalServerError)
+		return
+	}
+
+	nodes, apperr := a.ReportingService.GetGroupedTime(userProfile.AccountId, filter, dimensions, userProfile.WeekStart)
+	if apperr != nil {
+		if apperr.Code == api.SystemError {
+			api.ErrorJson(w, apperr, http.StatusInternalServerError)
+		} else {
+			api.ErrorJson(w, apperr, http.StatusBadRequest)
+		}
+		return
+	}
+
+	api.Json(w, r, NewGroupNodesResponse(nodes))
+}
+
+// Every time entry matching the filters, streamed without the page limit of the time entries report
+func (a *ReportingRouter) exportTimeEntries(w http.ResponseWriter, r *http.Request) {
+	renderer, ok := getExportRenderer(w, r)
+	if !ok {
+		return
+	}
+
+	filter, apperr := parseTimeEntryFilter(r)
+	if apperr != nil {
+		api.ErrorJson(w, apperr, http.StatusBadRequest)
+		return
+	}
+
+	userProfile, ok := r.Context().Value(config.ProfileContextKey).(*profile.Profile)
+	if !ok || userProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid profile context", api.SystemError), http.StatusInternalServerError)
+		return
+	}
+
+	stream := export.NewStream(w, renderer, NewTimeEntriesExportReport(userProfile.Account.Company, filter.FromDate, filter.ToDate))
+	streamExport(w, stream, func() *api.Error {
+		return a.ReportingService.ExportTimeEntries(userProfile.AccountId, filter, func(entry *TimeEntryReport) error {
+			return stream.Write(ExportTimeEntryResponse(entry))
+		})
+	})
+}
+
+// Hours and billable totals per day, week or month, optionally split into a series per client, project, task or
+// person
+func (a *ReportingRouter) getTimeSeries(w http.ResponseWriter, r *http.Request) {
+	userProfile, filter, interval, split, ok := a.parseTimeSeriesRequest(w, r)
+	if !ok {
+		return
+	}
+
+	series, apperr := a.ReportingService.GetTimeSeries(userProfile.AccountId, filter, interval, split, userProfile.WeekStart)
+	if apperr != nil {
+		api.ErrorJson(w, apperr, http.StatusInternalServerError)
+		return
+	}
+
+	api.Json(w, r, NewTimeSeriesResponse(interval, split, series))
+}
+
+func (a *ReportingRouter) exportTimeSeries(w http.ResponseWriter, r *http.Request) {
+	renderer, ok := getExportRenderer(w, r)
+	if !ok {
+		return
+	}
+
+	userProfile, filter, interval, split, ok := a.parseTimeSeriesRequest(w, r)
+	if !ok {
+		return
+	}
+
+	series, apperr := a.ReportingService.GetTimeSeries(userProfile.AccountId, filter, interval, split, userProfile.WeekStart)
+	if apperr != nil {
+		api.ErrorJson(w, apperr, http.StatusInternalServerError)
+		return
+	}
+
+	WriteExportTimeSeriesResponse(w, renderer, userProfile.Account.Company, filter.FromDate, filter.ToDate, interval, series)
+}
+
+// Returns the renderer for the format parameter, csv by default. Writes the error response and returns false if the
+// format is not supported
+func getExportRenderer(w http.ResponseWriter, r *http.Request) (export.Renderer, bool) {
+	format := r.URL.Query().Get("format")
+	if valid.IsNull(format) {
+		format = export.DefaultFormat
+	}
+
+	renderer, ok := export.GetRenderer(format)
+	if !ok {
+		api.BadInputs(w, "Invalid format. Use one of: "+strings.Join(export.Formats(), ", "), api.InvalidField, "format")
+		return nil, false
+	}
+
+	return renderer, true
+}
+
+// Read the interval, split and filter parameters of a time series. Writes the error response and returns false if a
+// parameter is invalid
+func (a *ReportingRouter) parseTimeSeriesRequest(w http.ResponseWriter, r *http.Request) (*profile.Profile, *TimeEntryFilter, Interval, Dimension, bool) {
+	interval := IntervalWeek
+	if intervalString := r.URL.Query().Get("interval"); !valid.IsNull(intervalString) {
+		var err error
+		interval, err = ParseInterval(intervalString)
+		if err != nil {
+			api.BadInputs(w, "Interval must be day, week, isoWeek or month", api.InvalidField, "interval")
+			return nil, nil, "", "", false
+		}
+	}
+
+	var split Dimension
+	if splitString := r.URL.Query().Get("split"); !valid.IsNull(splitString) {
+		split = Dimension(splitString)
+		if _, ok := dimensionSqls[split]; !ok || split.IsPeriod() {
+			api.BadInputs(w, "Split must be client, project, task or person", api.InvalidDimension, "split")
+			return nil, nil, "", "", false
+		}
+	}
+
+	filter, apperr := parseTimeEntryFilter(r)
+	if apperr != nil {
+		api.ErrorJson(w, apperr, http.StatusBadRequest)
+		return nil, nil, "", "", false
+	}
+
+	userProfile, ok := r.Context().Value(config.ProfileContextKey).(*profile.Profile)
+	if !ok || userProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid profile context", api.SystemError), http.StatusInternalServerError)
+		return nil, nil, "", "", false
+	}
+
+	if filter.ToDate.Before(filter.FromDate) || len(interval.Buckets(filter.FromDate, filter.ToDate, userProfile.WeekStart)) > SeriesMaxBuckets {
+		api.BadInputs(w, "The to date must be after the from date with at most 400 intervals between them", api.InvalidField, "to")
+		return nil, nil, "", "", false
+	}
+
+	return userProfile, filter, interval, split, true
+}
+
+// Read the from and to dates and the optional clientId, projectId, taskId, profileId and billable filters
+func parseTimeEntryFilter(r *http.Request) (*TimeEntryFilter, *api.Error) {
+	query := r.URL.Query()
+	fromDateString := query.Get("from")
+	toDateString := query.Get("to")
+
+	if valid.IsNull(fromDateString) {
+		return nil, api.NewFieldError(nil, "No from parameter", api.InvalidField, "from")
+	}
+
+	filter := &TimeEntryFilter{}
+	var err error
+	filter.FromDate, err = time.Parse(config.ISOShortDateFormat, fromDateString)
+	if err != nil {
+		return nil, api.NewFieldError(err, "Invalid format. Use ISO8061: YYYY-MM-DD", api.InvalidField, "from")
+	}
+
+	if !valid.IsNull(toDateString) {
+		filter.ToDate, err = time.Parse(config.ISOShortDateFormat, toDateString)
+		if err != nil {
+			return nil, api.NewFieldError(err, "Invalid format. Use ISO8061: YYYY-MM-DD", api.InvalidField, "to")
+		}
+	} else {
+		filter.ToDate = time.Now()
+	}
+
+	idParams := []struct {
+		name string
+		id   *int
+	}{
+		{"clientId", &filter.ClientId},
+		{"projectId", &filter.ProjectId},
+		{"taskId", &filter.TaskId},
+		{"profileId", &filter.ProfileId},
+	}
+
+	for _, param := range idParams {
+		if value := query.Get(param.name); !valid.IsNull(value) {
+			*param.id, err = strconv.Atoi(value)
+			if err != nil || *param.id <= 0 {
+				return nil, api.NewFieldError(nil, "Invalid "+param.name, api.InvalidField, param.name)
+			}
+		}
+	}
+
+	if billable := query.Get("billable"); !valid.IsNull(billable) {
+		filter.Billable.Bool, err = strconv.ParseBool(billable)
+		if err != nil {
+			return nil, api.NewFieldError(nil, "Billable must be true or false", api.InvalidField, "billable")
+		}
+		filter.Billable.Valid = true
+	}
+
+	return filter, nil
 }
 
 /*
@@ -468,62 +868,62 @@ func NewClientReportResponse(report *ClientReport) *ClientReportResponse {
 	}
 }
 
-func ExportClientReportResponse(report *ClientReport) []string {
+func ExportClientReportResponse(report *ClientReport) []export.Cell {
 	if report == nil {
-		return []string{}
+		return []export.Cell{}
 	}
 
-	result := make([]string, 4)
-	result[0] = report.ClientName
-	result[1] = fmt.Sprintf(" %0.2f", report.NonBillableHours.Float64)
-	result[2] = fmt.Sprintf(" %0.2f", report.BillableHours.Float64)
-	result[3] = fmt.Sprintf(" %0.2f", report.BillableTotal.Float64)
-
-	return result
+	return []export.Cell{
+		export.TextCell(report.ClientName),
+		export.NumberCell(report.NonBillableHours.Float64),
+		export.NumberCell(report.BillableHours.Float64),
+		export.NumberCell(report.BillableTotal.Float64),
+		export.TextCell(report.Notes.String),
+	}
 }
 
-func ExportProjectReportResponse(report *ProjectReport) []string {
+func ExportProjectReportResponse(report *ProjectReport) []export.Cell {
 	if report == nil {
-		return []string{}
+		return []export.Cell{}
 	}
 
-	result := make([]string, 5)
-	result[0] = report.ClientName
-	result[1] = report.ProjectName
-	result[2] = fmt.Sprintf(" %0.2f", report.NonBillableHours.Float64)
-	result[3] = fmt.Sprintf(" %0.2f", report.BillableHours.Float64)
-	result[4] = fmt.Sprintf(" %0.2f", report.BillableTotal.Float64)
-
-	return result
+	return []export.Cell{
+		export.TextCell(report.ClientName),
+		export.TextCell(report.ProjectName),
+		export.NumberCell(report.NonBillableHours.Float64),
+		export.NumberCell(report.BillableHours.Float64),
+		export.NumberCell(report.BillableTotal.Float64),
+		export.TextCell(report.Notes.String),
+	}
 }
 
-func ExportTaskReportResponse(report *TaskReport) []string {
+func ExportTaskReportResponse(report *TaskReport) []export.Cell {
 	if report == nil {
-		return []string{}
+		return []export.Cell{}
 	}
 
-	result := make([]string, 5)
-	result[0] = report.TaskName
-	result[1] = fmt.Sprintf(" %0.2f", report.NonBillableHours.Float64)
-	result[2] = fmt.Sprintf(" %0.2f", report.BillableHours.Float64)
-	result[3] = fmt.Sprintf(" %0.2f", report.BillableTotal.Float64)
-
-	return result
+	return []export.Cell{
+		export.TextCell(report.TaskName),
+		export.NumberCell(report.NonBillableHours.Float64),
+		export.NumberCell(report.BillableHours.Float64),
+		export.NumberCell(report.BillableTotal.Float64),
+		export.TextCell(report.Notes.String),
+	}
 }
 
-func ExportPersonReportResponse(report *PersonReport) []string {
+func ExportPersonReportResponse(report *PersonReport) []export.Cell {
 	if report == nil {
-		return []string{}
+		return []export.Cell{}
 	}
 
-	result := make([]string, 5)
-	result[0] = report.LastName
-	result[1] = report.FirstName
-	result[2] = fmt.Sprintf(" %0.2f", report.NonBillableHours.Float64)
-	result[3] = fmt.Sprintf(" %0.2f", report.BillableHours.Float64)
-	result[4] = fmt.Sprintf(" %0.2f", report.BillableTotal.Float64)
-
-	return result
+	return []export.Cell{
+		export.TextCell(report.LastName),
+		export.TextCell(report.FirstName),
+		export.NumberCell(report.NonBillableHours.Float64),
+		export.NumberCell(report.BillableHours.Float64),
+		export.NumberCell(report.BillableTotal.Float64),
+		export.TextCell(report.Notes.String),
+	}
 }
 
 func NewClientReportsResponse(clientReportRows []*ClientReport) []*ClientReportResponse {
@@ -567,133 +967,109 @@ func NewProjectReportsResponse(reportRows []*ProjectReport) []*ProjectReportResp
 	return response
 }
 
-func writeExportCsvHeader(w http.ResponseWriter, fromDate time.Time, toDate time.Time, companyName string) {
-	reg, err := regexp.Compile("[^a-zA-Z0-9]+")
-	var cleanCompanyName string
-	if err != nil {
-		logger.Log.Error("Failed to compile regular expression: " + err.Error())
-		cleanCompanyName = companyName
-	} else {
-		cleanCompanyName = reg.ReplaceAllString(companyName, "-")
-	}
-
-	header := w.Header()
-	header.Set("Content-Type", "text/csv")
-	header.Set("Content-Disposition",
-		fmt.Sprintf("attachment;filename=export_%s_%s_to_%s.csv",
-			cleanCompanyName,
-			fromDate.Format(config.ISOShortDateFormat),
-			toDate.Format(config.ISOShortDateFormat)))
-
+// The hours, billable total and notes columns shared by the client, project, task and person exports
+var exportTotalsColumns = []export.Column{
+	{Name: "Non-Billable Hours", Type: export.HoursColumn},
+	{Name: "Billable Hours", Type: export.HoursColumn},
+	{Name: "Billable Total", Type: export.CurrencyColumn},
+	{Name: "Notes", Type: export.TextColumn},
 }
 
-func WriteExportClientReportsResponse(w http.ResponseWriter, companyName string, fromDate time.Time, toDate time.Time, clientReportRows []*ClientReport) {
-	writeExportCsvHeader(w, fromDate, toDate, companyName)
+func newExportReport(title string, companyName string, fromDate time.Time, toDate time.Time, columns ...export.Column) *export.Report {
+	return &export.Report{
+		Title:   title,
+		Company: companyName,
+		From:    fromDate,
+		To:      toDate,
+		Columns: columns,
+	}
+}
 
-	wr := csv.NewWriter(w)
-	if clientReportRows != nil {
-		header := []string{
-			"Client Name",
-			"Non-Billable Hours",
-			"Billable Hours",
-			"Billable Total",
+// Write an export as the service reads its rows. An error before any of the export is written is sent as a normal
+// error response. Once rows have been sent the status can't change, so the response is aborted rather than ending the
+// file early and making a partial export look complete
+func streamExport(w http.ResponseWriter, stream *export.Stream, readRows func() *api.Error) {
+	if apperr := readRows(); apperr != nil {
+		if !stream.Started() {
+			api.ErrorJson(w, apperr, http.StatusInternalServerError)
+			return
 		}
 
-		err := wr.Write(header)
-		if err != nil {
-			logger.Log.Error("Failed to write row: " + err.Error())
-		}
+		logger.Log.Error("Export failed after rows were written: " + apperr.Error())
+		panic(http.ErrAbortHandler)
+	}
 
-		for _, row := range clientReportRows {
-			err := wr.Write(ExportClientReportResponse(row))
-			if err != nil {
-				logger.Log.Error("Failed to write row: " + err.Error())
-			}
-		}
-		wr.Flush()
+	if err := stream.Close(); err != nil {
+		logger.Log.Error("Failed to finish export: " + err.Error())
+		panic(http.ErrAbortHandler)
 	}
 }
 
-func WriteExportProjectReportsResponse(w http.ResponseWriter, companyName string, fromDate time.Time, toDate time.Time, projectReportRows []*ProjectReport) {
-	writeExportCsvHeader(w, fromDate, toDate, companyName)
-
-	wr := csv.NewWriter(w)
-	if projectReportRows != nil {
-		header := []string{
-			"Client Name",
-			"Project Name",
-			"Non-Billable Hours",
-			"Billable Hours",
-			"Billable Total",
-		}
+func writeExport(w http.ResponseWriter, renderer export.Renderer, report *export.Report, rows [][]export.Cell) {
+	export.SetHeaders(w, report, renderer)
 
-		err := wr.Write(header)
-		if err != nil {
-			logger.Log.Error("Failed to write row: " + err.Error())
-		}
-
-		for _, row := range projectReportRows {
-			err := wr.Write(ExportProjectReportResponse(row))
-			if err != nil {
-				logger.Log.Error("Failed to write row: " + err.Error())
-			}
-		}
-		wr.Flush()
+	err := export.Render(w, renderer, report, rows)
+	if err != nil {
+		logger.Log.Error("Failed to write export: " + err.Error())
 	}
 }
 
-func WriteExportTaskReportsResponse(w http.ResponseWriter, companyName string, fromDate time.Time, toDate time.Time, taskReportRows []*TaskReport) {
-	writeExportCsvHeader(w, fromDate, toDate, companyName)
+func NewTimeEntriesExportReport(companyName string, fromDate time.Time, toDate time.Time) *export.Report {
+	return newExportReport("Time Entries", companyName, fromDate, toDate,
+		export.Column{Name: "Date"},
+		export.Column{Name: "Last Name"},
+		export.Column{Name: "First Name"},
+		export.Column{Name: "Client Name"},
+		export.Column{Name: "Project Name"},
+		export.Column{Name: "Task Name"},
+		export.Column{Name: "Billable"},
+		export.Column{Name: "Hours", Type: export.HoursColumn},
+		export.Column{Name: "Billable Total", Type: export.CurrencyColumn},
+		export.Column{Name: "Notes"})
+}
 
-	wr := csv.NewWriter(w)
-	if taskReportRows != nil {
-		header := []string{
-			"Task Name",
-			"Non-Billable Hours",
-			"Billable Hours",
-			"Billable Total",
-		}
+func ExportTimeEntryResponse(entry *TimeEntryReport) []export.Cell {
+	if entry == nil {
+		return []export.Cell{}
+	}
 
-		err := wr.Write(header)
-		if err != nil {
-			logger.Log.Error("Failed to write row: " + err.Error())
-		}
+	billable := "No"
+	if entry.Billable {
+		billable = "Yes"
+	}
 
-		for _, row := range taskReportRows {
-			err := wr.Write(ExportTaskReportResponse(row))
-			if err != nil {
-				logger.Log.Error("Failed to write row: " + err.Error())
-			}
-		}
-		wr.Flush()
+	return []export.Cell{
+		export.TextCell(entry.Day.Format(config.ISOShortDateFormat)),
+		export.TextCell(entry.LastName),
+		export.TextCell(entry.FirstName),
+		export.TextCell(entry.ClientName),
+		export.TextCell(entry.ProjectName),
+		export.TextCell(entry.TaskName),
+		export.TextCell(billable),
+		export.NumberCell(entry.Hours),
+		export.NumberCell(entry.BillableTotal()),
+		export.TextCell(entry.Notes.String),
 	}
 }
 
-func WriteExportPersonReportsResponse(w http.ResponseWriter, companyName string, fromDate time.Time, toDate time.Time, personReportRows []*PersonReport) {
-	writeExportCsvHeader(w, fromDate, toDate, companyName)
+func NewClientExportReport(companyName string, fromDate time.Time, toDate time.Time) *export.Report {
+	return newExportReport("Time by Client", companyName, fromDate, toDate,
+		append([]export.Column{{Name: "Client Name"}}, exportTotalsColumns...)...)
+}
 
-	wr := csv.NewWriter(w)
-	if personReportRows != nil {
-		header := []string{
-			"Last Name",
-			"First Name",
-			"Non-Billable Hours",
-			"Billable Hours",
-			"Billable Total",
-		}
+func NewProjectExportReport(companyName string, fromDate time.Time, toDate time.Time) *export.Report {
+	return newExportReport("Time by Project", companyName, fromDate, toDate,
+		append([]export.Column{{Name: "Client Name"}, {Name: "Project Name"}}, exportTotalsColumns...)...)
+}
 
-		err := wr.Write(header)
-		if err != nil {
-			logger.Log.Error("Failed to write row: " + err.Error())
-		}
-		for _, row := range personReportRows {
-			err := wr.Write(ExportPersonReportResponse(row))
-			if err != nil {
-				logger.Log.Error("Failed to write row: " + err.Error())
-			}
-		}
-		wr.Flush()
-	}
+func NewTaskExportReport(companyName string, fromDate time.Time, toDate time.Time) *export.Report {
+	return newExportReport("Time by Task", companyName, fromDate, toDate,
+		append([]export.Column{{Name: "Task Name"}}, exportTotalsColumns...)...)
+}
+
+func NewPersonExportReport(companyName string, fromDate time.Time, toDate time.Time) *export.Report {
+	return newExportReport("Time by Person", companyName, fromDate, toDate,
+		append([]export.Column{{Name: "Last Name"}, {Name: "First Name"}}, exportTotalsColumns...)...)
 }
 
 func NewTaskReportResponse(report *TaskReport) *TaskReportResponse {
@@ -751,3 +1127,145 @@ func NewPersonReportsResponse(reportRows []*PersonReport) []*PersonReportRespons
 
 	return response
 }
+
+func NewUtilizationListResponse(rows []*Utilization) []*UtilizationResponse {
+	response := []*UtilizationResponse{}
+	for _, row := range rows {
+		response = append(response, &UtilizationResponse{
+			ProfileId:          row.ProfileId,
+			FirstName:          row.FirstName,
+			LastName:           row.LastName,
+			WeekStart:          row.WeekStart.Format(config.ISOShortDateFormat),
+			CapacityHours:      row.CapacityHours,
+			LoggedHours:        row.LoggedHours(),
+			BillableHours:      row.BillableHours,
+			NonBillableHours:   row.NonBillableHours,
+			UtilizationPercent: row.UtilizationPercent(),
+			BillablePercent:    row.BillablePercent(),
+		})
+	}
+
+	return response
+}
+
+func NewTimeEntryReportResponse(entry *TimeEntryReport) *TimeEntryReportResponse {
+	return &TimeEntryReportResponse{
+		Day:           entry.Day.Format(config.ISOShortDateFormat),
+		ProfileId:     entry.ProfileId,
+		FirstName:     entry.FirstName,
+		LastName:      entry.LastName,
+		ClientId:      entry.ClientId,
+		ClientName:    entry.ClientName,
+		ProjectId:     entry.ProjectId,
+		ProjectName:   entry.ProjectName,
+		TaskId:        entry.TaskId,
+		TaskName:      entry.TaskName,
+		Hours:         entry.Hours,
+		Billable:      entry.Billable,
+		Rate:          entry.Rate.Float64,
+		BillableTotal: entry.BillableTotal(),
+		Notes:         entry.Notes.String,
+		Invoiced:      entry.InvoiceId.Valid,
+	}
+}
+
+func NewTimeEntriesReportResponse(entries []*TimeEntryReport, next *EntryCursor) *TimeEntriesReportResponse {
+	response := &TimeEntriesReportResponse{Entries: []*TimeEntryReportResponse{}}
+	for _, entry := range entries {
+		response.Entries = append(response.Entries, NewTimeEntryReportResponse(entry))
+	}
+
+	if next != nil {
+		response.NextCursor = next.Encode()
+	}
+
+	return response
+}
+
+func NewGroupNodesResponse(nodes []*GroupNode) []*GroupNodeResponse {
+	response := []*GroupNodeResponse{}
+	for _, node := range nodes {
+		nodeResponse := &GroupNodeResponse{
+			Dimension:        string(node.Key.Dimension),
+			Id:               node.Key.Id,
+			Name:             node.Key.Name,
+			ClientName:       node.Key.ClientName,
+			FirstName:        node.Key.FirstName,
+			LastName:         node.Key.LastName,
+			NonBillableHours: node.NonBillableHours,
+			BillableHours:    node.BillableHours,
+			BillableTotal:    node.BillableTotal,
+		}
+
+		if node.Key.Dimension.IsPeriod() {
+			nodeResponse.Day = node.Key.Day.Format(config.ISOShortDateFormat)
+		}
+
+		if len(node.Children) > 0 {
+			nodeResponse.Children = NewGroupNodesResponse(node.Children)
+		}
+
+		response = append(response, nodeResponse)
+	}
+
+	return response
+}
+
+func NewTimeSeriesResponse(interval Interval, split Dimension, seriesList []*Series) *TimeSeriesResponse {
+	response := &TimeSeriesResponse{Interval: string(interval), Split: string(split), Series: []*SeriesResponse{}}
+	for _, series := range seriesList {
+		seriesResponse := &SeriesResponse{Points: []*SeriesPointResponse{}}
+		if series.Key != nil {
+			seriesResponse.Id = series.Key.Id
+			seriesResponse.Name = series.Key.Name
+		}
+
+		for _, point := range series.Points {
+			seriesResponse.Points = append(seriesResponse.Points, &SeriesPointResponse{
+				Start:            point.Start.Format(config.ISOShortDateFormat),
+				Label:            interval.Label(point.Start),
+				Hours:            point.NonBillableHours + point.BillableHours,
+				NonBillableHours: point.NonBillableHours,
+				BillableHours:    point.BillableHours,
+				BillableTotal:    point.BillableTotal,
+			})
+		}
+
+		response.Series = append(response.Series, seriesResponse)
+	}
+
+	return response
+}
+
+func WriteExportTimeSeriesResponse(w http.ResponseWriter, renderer export.Renderer, companyName string, fromDate time.Time, toDate time.Time, interval Interval, seriesList []*Series) {
+	report := newExportReport("Time Series by "+string(interval), companyName, fromDate, toDate,
+		export.Column{Name: "Period"},
+		export.Column{Name: "Start"},
+		export.Column{Name: "Name"},
+		export.Column{Name: "Hours", Type: export.HoursColumn},
+		export.Column{Name: "Non-Billable Hours", Type: export.HoursColumn},
+		export.Column{Name: "Billable Hours", Type: export.HoursColumn},
+		export.Column{Name: "Billable Total", Type: export.CurrencyColumn})
+
+	var rows [][]export.Cell
+	for _, series := range seriesList {
+		name := ""
+		if series.Key != nil {
+			name = series.Key.Name
+		}
+
+		for _, point := range series.Points {
+			rows = append(rows, []export.Cell{
+				export.TextCell(interval.Label(point.Start)),
+				export.TextCell(point.Start.Format(config.ISOShortDateFormat)),
+				export.TextCell(name),
+				export.NumberCell(point.NonBillableHours + point.BillableHours),
+				export.NumberCell(point.NonBillableHours),
+				export.NumberCell(point.BillableHours),
+				export.NumberCell(point.BillableTotal),
+			})
+		}
+	}
+
+	writeExport(w, renderer, report, rows)
+}
@@ -1,6 +1,24 @@
 package reporting
 
-import "database/sql"
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/bryanmorgan/time-tracking-api/config"
+)
+
+const (
+	// Weekly capacity is spread evenly over Monday to Friday
+	UtilizationWorkDays = 5
+
+	// Longest from/to range allowed for the utilization report
+	UtilizationMaxDays = 366
+
+	// Most time entries returned in one page of the entries report
+	EntriesMaxLimit = 1000
+)
 
 type ClientReport struct {
 	ClientId         int             `json:"-" db:"client_id"`
@@ -8,6 +26,7 @@ type ClientReport struct {
 	NonBillableHours sql.NullFloat64 `json:"-" db:"non_billable_hours"`
 	BillableHours    sql.NullFloat64 `json:"-" db:"billable_hours"`
 	BillableTotal    sql.NullFloat64 `json:"-" db:"billable_total"`
+	Notes            sql.NullString  `json:"-" db:"notes"`
 }
 
 type ProjectReport struct {
@@ -17,6 +36,7 @@ type ProjectReport struct {
 	NonBillableHours sql.NullFloat64 `json:"-" db:"non_billable_hours"`
 	BillableHours    sql.NullFloat64 `json:"-" db:"billable_hours"`
 	BillableTotal    sql.NullFloat64 `json:"-" db:"billable_total"`
+	Notes            sql.NullString  `json:"-" db:"notes"`
 }
 
 type TaskReport struct {
@@ -26,6 +46,7 @@ type TaskReport struct {
 	NonBillableHours sql.NullFloat64 `json:"-" db:"non_billable_hours"`
 	BillableHours    sql.NullFloat64 `json:"-" db:"billable_hours"`
 	BillableTotal    sql.NullFloat64 `json:"-" db:"billable_total"`
+	Notes            sql.NullString  `json:"-" db:"notes"`
 }
 
 type PersonReport struct {
@@ -35,4 +56,145 @@ type PersonReport struct {
 	NonBillableHours sql.NullFloat64 `json:"-" db:"non_billable_hours"`
 	BillableHours    sql.NullFloat64 `json:"-" db:"billable_hours"`
 	BillableTotal    sql.NullFloat64 `json:"-" db:"billable_total"`
+	Notes            sql.NullString  `json:"-" db:"notes"`
+}
+
+// A single time entry with the names of its client, project, task and person
+type TimeEntryReport struct {
+	Day         time.Time       `json:"-"`
+	ProfileId   int             `json:"-" db:"profile_id"`
+	FirstName   string          `json:"-" db:"first_name"`
+	LastName    string          `json:"-" db:"last_name"`
+	ClientId    int             `json:"-" db:"client_id"`
+	ClientName  string          `json:"-" db:"client_name"`
+	ProjectId   int             `json:"-" db:"project_id"`
+	ProjectName string          `json:"-" db:"project_name"`
+	TaskId      int             `json:"-" db:"task_id"`
+	TaskName    string          `json:"-" db:"task_name"`
+	Hours       float64         `json:"-"`
+	Billable    bool            `json:"-"`
+	Rate        sql.NullFloat64 `json:"-"`
+	Notes       sql.NullString  `json:"-"`
+	InvoiceId   sql.NullInt64   `json:"-" db:"invoice_id"`
+}
+
+// Filters for the time entries report. Zero ids and a null billable flag match every entry
+type TimeEntryFilter struct {
+	FromDate  time.Time
+	ToDate    time.Time
+	ClientId  int
+	ProjectId int
+	TaskId    int
+	ProfileId int
+	Billable  sql.NullBool
+}
+
+func (e *TimeEntryReport) BillableTotal() float64 {
+	if !e.Billable {
+		return 0
+	}
+
+	return e.Hours * e.Rate.Float64
+}
+
+// Weekly capacity of a member, or of a former member who logged time in the report range
+type MemberCapacity struct {
+	ProfileId     int     `json:"-" db:"profile_id"`
+	FirstName     string  `json:"-" db:"first_name"`
+	LastName      string  `json:"-" db:"last_name"`
+	CapacityHours float64 `json:"-" db:"capacity_hours"`
+}
+
+type Utilization struct {
+	ProfileId        int
+	FirstName        string
+	LastName         string
+	WeekStart        time.Time
+	CapacityHours    float64
+	BillableHours    float64
+	NonBillableHours float64
+}
+
+func (u *Utilization) LoggedHours() float64 {
+	return u.BillableHours + u.NonBillableHours
+}
+
+// Percent of the capacity logged, or 0 when the week has no capacity
+func (u *Utilization) UtilizationPercent() float64 {
+	return capacityPercent(u.LoggedHours(), u.CapacityHours)
+}
+
+// Percent of the capacity logged as billable, or 0 when the week has no capacity
+func (u *Utilization) BillablePercent() float64 {
+	return capacityPercent(u.BillableHours, u.CapacityHours)
+}
+
+// Build a utilization row for every profile and week between the from and to dates. The weeks are time grouped by
+// person then week, with weeks starting on the from date's weekday. Weekly capacity is spread over Monday to Friday,
+// so weekend days, holidays and days outside the range reduce a week's capacity
+func BuildUtilization(fromDate time.Time, toDate time.Time, capacities []*MemberCapacity, weeks []*GroupRow, holidays []time.Time) []*Utilization {
+	holidaySet := make(map[string]bool)
+	for _, holiday := range holidays {
+		holidaySet[holiday.Format(config.ISOShortDateFormat)] = true
+	}
+
+	logged := make(map[string]*GroupRow)
+	for _, week := range weeks {
+		logged[utilizationKey(week.Keys[0].Id, week.Keys[1].Day)] = week
+	}
+
+	var rows []*Utilization
+	for _, capacity := range capacities {
+		for weekStart := fromDate; !weekStart.After(toDate); weekStart = weekStart.AddDate(0, 0, 7) {
+			weekEnd := weekStart.AddDate(0, 0, 6)
+			if weekEnd.After(toDate) {
+				weekEnd = toDate
+			}
+
+			row := &Utilization{
+				ProfileId:     capacity.ProfileId,
+				FirstName:     capacity.FirstName,
+				LastName:      capacity.LastName,
+				WeekStart:     weekStart,
+				CapacityHours: capacity.CapacityHours / UtilizationWorkDays * float64(workingDays(weekStart, weekEnd, holidaySet)),
+			}
+
+			if week, ok := logged[utilizationKey(capacity.ProfileId, weekStart)]; ok {
+				row.BillableHours = week.BillableHours.Float64
+				row.NonBillableHours = week.NonBillableHours.Float64
+			}
+
+			rows = append(rows, row)
+		}
+	}
+
+	return rows
+}
+
+// Count the weekdays between the from and to dates, inclusive, that are not holidays
+func workingDays(fromDate time.Time, toDate time.Time, holidays map[string]bool) int {
+	days := 0
+	for day := fromDate; !day.After(toDate); day = day.AddDate(0, 0, 1) {
+		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+			continue
+		}
+
+		if !holidays[day.Format(config.ISOShortDateFormat)] {
+			days++
+		}
+	}
+
+	return days
+}
+
+func utilizationKey(profileId int, weekStart time.Time) string {
+	return fmt.Sprintf("%d:%s", profileId, weekStart.Format(config.ISOShortDateFormat))
+}
+
+func capacityPercent(hours float64, capacity float64) float64 {
+	if capacity <= 0 {
+		return 0
+	}
+
+	return math.Round(hours/capacity*100*100) / 100
 }
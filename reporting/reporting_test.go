@@ -0,0 +1,72 @@
+package reporting
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestBuildUtilization(t *testing.T) {
+	t.Parallel()
+
+	capacities := []*MemberCapacity{
+		{ProfileId: 1, FirstName: "Jane", LastName: "Doe", CapacityHours: 40},
+		{ProfileId: 2, FirstName: "Former", LastName: "Member", CapacityHours: 0},
+	}
+
+	weeks := []*GroupRow{
+		{Keys: personWeekKeys(1, "2019-01-07"), BillableHours: sql.NullFloat64{Float64: 24, Valid: true}, NonBillableHours: sql.NullFloat64{Float64: 6, Valid: true}},
+		{Keys: personWeekKeys(1, "2019-01-14"), BillableHours: sql.NullFloat64{Float64: 16, Valid: true}},
+		{Keys: personWeekKeys(2, "2019-01-14"), NonBillableHours: sql.NullFloat64{Float64: 5, Valid: true}},
+	}
+
+	// Monday 2019-01-07 to Wednesday 2019-01-16 with a holiday on Monday 2019-01-14
+	rows := BuildUtilization(getDate("2019-01-07"), getDate("2019-01-16"), capacities, weeks, []time.Time{getDate("2019-01-14")})
+
+	testCases := []struct {
+		name               string
+		profileId          int
+		weekStart          string
+		capacityHours      float64
+		loggedHours        float64
+		utilizationPercent float64
+		billablePercent    float64
+	}{
+		{"Full week", 1, "2019-01-07", 40, 30, 75, 60},
+		{"Partial week with holiday", 1, "2019-01-14", 16, 16, 100, 100},
+		{"No capacity", 2, "2019-01-07", 0, 0, 0, 0},
+		{"No capacity with time", 2, "2019-01-14", 0, 5, 0, 0},
+	}
+
+	if len(rows) != len(testCases) {
+		t.Fatalf("Expected [%d] rows but got [%d]", len(testCases), len(rows))
+	}
+
+	for i, testCase := range testCases {
+		row := rows[i]
+		t.Run(testCase.name, func(t *testing.T) {
+			if row.ProfileId != testCase.profileId || row.WeekStart != getDate(testCase.weekStart) {
+				t.Fatalf("Unexpected row. Got [%d] [%s] Expected: [%d] [%s]", row.ProfileId, row.WeekStart, testCase.profileId, testCase.weekStart)
+			}
+
+			if row.CapacityHours != testCase.capacityHours {
+				t.Errorf("Capacity incorrect. Got [%f] Expected: [%f]", row.CapacityHours, testCase.capacityHours)
+			}
+
+			if row.LoggedHours() != testCase.loggedHours {
+				t.Errorf("Logged hours incorrect. Got [%f] Expected: [%f]", row.LoggedHours(), testCase.loggedHours)
+			}
+
+			if row.UtilizationPercent() != testCase.utilizationPercent || row.BillablePercent() != testCase.billablePercent {
+				t.Errorf("Percent incorrect. Got [%f] [%f] Expected: [%f] [%f]", row.UtilizationPercent(), row.BillablePercent(), testCase.utilizationPercent, testCase.billablePercent)
+			}
+		})
+	}
+}
+
+func personWeekKeys(profileId int, weekStart string) []*GroupKey {
+	return []*GroupKey{
+		{Dimension: DimensionPerson, Id: profileId},
+		{Dimension: DimensionWeek, Day: getDate(weekStart)},
+	}
+}
@@ -27,14 +27,23 @@ func (a *ReportingRouter) Router() *chi.Mux {
 		r.Use(a.profileRouter.ValidateProfileHandler)
 		r.Use(a.profileRouter.ValidateSessionHandler)
 
+		// Reports include every profile's time so are limited to admins and the read-only reporting role
+		r.Use(a.profileRouter.RolePermissionHandler(profile.ReportingRoles...))
+
 		r.Get("/time/client", a.getTimeByClient)
 		r.Get("/time/project", a.getTimeByProject)
 		r.Get("/time/task", a.getTimeByTask)
 		r.Get("/time/person", a.getTimeByPerson)
+		r.Get("/time/entries", a.getTimeEntries)
+		r.Get("/time/group", a.getGroupedTime)
+		r.Get("/time/series", a.getTimeSeries)
 		r.Get("/time/export/client", a.exportTimeByClient)
 		r.Get("/time/export/project", a.exportTimeByProject)
 		r.Get("/time/export/task", a.exportTimeByTask)
 		r.Get("/time/export/person", a.exportTimeByPerson)
+		r.Get("/time/export/series", a.exportTimeSeries)
+		r.Get("/time/export/entries", a.exportTimeEntries)
+		r.Get("/utilization", a.getUtilization)
 
 	})
 
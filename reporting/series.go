@@ -0,0 +1,152 @@
+package reporting
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bryanmorgan/time-tracking-api/config"
+)
+
+type Interval string
+
+const (
+	IntervalDay     Interval = "day"
+	IntervalWeek    Interval = "week"    // Weeks start on the account's week start
+	IntervalIsoWeek Interval = "isoWeek" // ISO 8601 weeks start on Monday
+	IntervalMonth   Interval = "month"
+)
+
+// Most buckets a time series can have, so a daily series can cover a little over a year
+const SeriesMaxBuckets = 400
+
+var InvalidIntervalError = errors.New("invalid time series interval")
+
+// Hours and billable total for one bucket of a time series
+type SeriesPoint struct {
+	Start            time.Time
+	NonBillableHours float64
+	BillableHours    float64
+	BillableTotal    float64
+}
+
+// A bucket for every interval in the report range. Key is the client, project, task or person the series is split
+// by and nil when the series is not split
+type Series struct {
+	Key    *GroupKey
+	Points []*SeriesPoint
+}
+
+func ParseInterval(value string) (Interval, error) {
+	switch Interval(value) {
+	case IntervalDay, IntervalWeek, IntervalIsoWeek, IntervalMonth:
+		return Interval(value), nil
+	}
+
+	return "", InvalidIntervalError
+}
+
+// Returns the dimension that groups time into the interval's buckets
+func (i Interval) Dimension() Dimension {
+	switch i {
+	case IntervalDay:
+		return DimensionDay
+	case IntervalMonth:
+		return DimensionMonth
+	}
+
+	return DimensionWeek
+}
+
+// Returns the weekday weeks start on, 0 for Sunday to 6 for Saturday
+func (i Interval) WeekStart(accountWeekStart int) int {
+	if i == IntervalIsoWeek {
+		return int(time.Monday)
+	}
+
+	return accountWeekStart
+}
+
+// Returns the first day of the bucket the day falls in
+func (i Interval) BucketStart(day time.Time, weekStart int) time.Time {
+	switch i {
+	case IntervalDay:
+		return day
+	case IntervalMonth:
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, day.Location())
+	}
+
+	return day.AddDate(0, 0, -((int(day.Weekday()) - i.WeekStart(weekStart) + 7) % 7))
+}
+
+func (i Interval) nextBucket(start time.Time) time.Time {
+	switch i {
+	case IntervalDay:
+		return start.AddDate(0, 0, 1)
+	case IntervalMonth:
+		return start.AddDate(0, 1, 0)
+	}
+
+	return start.AddDate(0, 0, 7)
+}
+
+// Returns a display label for the bucket, for example 2019-01-07, 2019-W02 or 2019-01
+func (i Interval) Label(start time.Time) string {
+	switch i {
+	case IntervalIsoWeek:
+		year, week := start.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case IntervalMonth:
+		return start.Format("2006-01")
+	}
+
+	return start.Format(config.ISOShortDateFormat)
+}
+
+// Returns the start of every bucket between the from and to dates. The first bucket can start before the from date
+func (i Interval) Buckets(fromDate time.Time, toDate time.Time, weekStart int) []time.Time {
+	var buckets []time.Time
+	for start := i.BucketStart(fromDate, weekStart); !start.After(toDate); start = i.nextBucket(start) {
+		buckets = append(buckets, start)
+	}
+
+	return buckets
+}
+
+// Build a series for each split value with a point for every bucket, filling the buckets without time with zeros.
+// The rows are time grouped by the split dimension, if any, then the interval's dimension
+func BuildTimeSeries(buckets []time.Time, rows []*GroupRow, split bool) []*Series {
+	var seriesList []*Series
+	var series *Series
+	var index map[string]*SeriesPoint
+
+	newSeries := func(key *GroupKey) {
+		series = &Series{Key: key}
+		index = make(map[string]*SeriesPoint)
+		for _, start := range buckets {
+			point := &SeriesPoint{Start: start}
+			series.Points = append(series.Points, point)
+			index[start.Format(config.ISOShortDateFormat)] = point
+		}
+		seriesList = append(seriesList, series)
+	}
+
+	if !split {
+		newSeries(nil)
+	}
+
+	for _, row := range rows {
+		periodKey := row.Keys[len(row.Keys)-1]
+		if split && (series == nil || !series.Key.matches(row.Keys[0])) {
+			newSeries(row.Keys[0])
+		}
+
+		if point, ok := index[periodKey.Day.Format(config.ISOShortDateFormat)]; ok {
+			point.NonBillableHours += row.NonBillableHours.Float64
+			point.BillableHours += row.BillableHours.Float64
+			point.BillableTotal += row.BillableTotal.Float64
+		}
+	}
+
+	return seriesList
+}
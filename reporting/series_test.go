@@ -0,0 +1,82 @@
+package reporting
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/bryanmorgan/time-tracking-api/config"
+)
+
+func TestIntervalBuckets(t *testing.T) {
+	t.Parallel()
+
+	// Wednesday 2019-01-02 to Tuesday 2019-01-15 with an account week starting Sunday
+	testCases := []struct {
+		name     string
+		interval Interval
+		count    int
+		first    string
+		label    string
+	}{
+		{"Day", IntervalDay, 14, "2019-01-02", "2019-01-02"},
+		{"Account week", IntervalWeek, 3, "2018-12-30", "2018-12-30"},
+		{"ISO week", IntervalIsoWeek, 3, "2018-12-31", "2019-W01"},
+		{"Month", IntervalMonth, 1, "2019-01-01", "2019-01"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			buckets := testCase.interval.Buckets(getDate("2019-01-02"), getDate("2019-01-15"), 0)
+			if len(buckets) != testCase.count {
+				t.Fatalf("Wrong number of buckets. Got [%d] Expected: [%d]", len(buckets), testCase.count)
+			}
+
+			if first := buckets[0].Format(config.ISOShortDateFormat); first != testCase.first {
+				t.Errorf("First bucket incorrect. Got [%s] Expected: [%s]", first, testCase.first)
+			}
+
+			if label := testCase.interval.Label(buckets[0]); label != testCase.label {
+				t.Errorf("Label incorrect. Got [%s] Expected: [%s]", label, testCase.label)
+			}
+		})
+	}
+}
+
+func TestBuildTimeSeries(t *testing.T) {
+	t.Parallel()
+
+	buckets := IntervalMonth.Buckets(getDate("2019-01-01"), getDate("2019-03-31"), 0)
+	month := func(day string) *GroupKey { return &GroupKey{Dimension: DimensionMonth, Day: getDate(day)} }
+	client := &GroupKey{Dimension: DimensionClient, Id: 1, Name: "Apple"}
+	hours := sql.NullFloat64{Float64: 5, Valid: true}
+
+	// Without a split there is always one series with every bucket
+	series := BuildTimeSeries(buckets, []*GroupRow{{Keys: []*GroupKey{month("2019-02-01")}, BillableHours: hours}}, false)
+	if len(series) != 1 || series[0].Key != nil || len(series[0].Points) != 3 {
+		t.Fatalf("Invalid series: [%+v]", series)
+	}
+
+	if series[0].Points[0].BillableHours != 0 || series[0].Points[1].BillableHours != 5 || series[0].Points[2].BillableHours != 0 {
+		t.Errorf("Buckets not zero filled: [%+v] [%+v] [%+v]", series[0].Points[0], series[0].Points[1], series[0].Points[2])
+	}
+
+	// A split series is added for each value with time
+	rows := []*GroupRow{
+		{Keys: []*GroupKey{client, month("2019-01-01")}, NonBillableHours: hours},
+		{Keys: []*GroupKey{client, month("2019-03-01")}, BillableHours: hours},
+		{Keys: []*GroupKey{{Dimension: DimensionClient, Id: 2}, month("2019-03-01")}, BillableHours: hours},
+	}
+
+	series = BuildTimeSeries(buckets, rows, true)
+	if len(series) != 2 || series[0].Key.Name != "Apple" || series[1].Key.Id != 2 {
+		t.Fatalf("Invalid split series: [%+v]", series)
+	}
+
+	if series[0].Points[0].NonBillableHours != 5 || series[0].Points[1].NonBillableHours != 0 || series[0].Points[2].BillableHours != 5 {
+		t.Errorf("Invalid split points: [%+v] [%+v] [%+v]", series[0].Points[0], series[0].Points[1], series[0].Points[2])
+	}
+
+	if series = BuildTimeSeries(buckets, nil, true); len(series) != 0 {
+		t.Errorf("Split series without time: [%+v]", series)
+	}
+}
@@ -14,6 +14,15 @@ type ReportingService interface {
 	GetTimeByProject(accountId int, fromDate time.Time, toDate time.Time, offset int) ([]*ProjectReport, *api.Error)
 	GetTimeByTask(accountId int, fromDate time.Time, toDate time.Time, offset int) ([]*TaskReport, *api.Error)
 	GetTimeByPerson(accountId int, fromDate time.Time, toDate time.Time, offset int) ([]*PersonReport, *api.Error)
+	GetGroupedTime(accountId int, filter *TimeEntryFilter, dimensions []Dimension, weekStart int) ([]*GroupNode, *api.Error)
+	GetTimeSeries(accountId int, filter *TimeEntryFilter, interval Interval, split Dimension, accountWeekStart int) ([]*Series, *api.Error)
+	GetUtilization(accountId int, fromDate time.Time, toDate time.Time) ([]*Utilization, *api.Error)
+	GetTimeEntries(accountId int, filter *TimeEntryFilter, after *EntryCursor, limit int) ([]*TimeEntryReport, *EntryCursor, *api.Error)
+	ExportTimeByClient(accountId int, fromDate time.Time, toDate time.Time, fn func(report *ClientReport) error) *api.Error
+	ExportTimeByProject(accountId int, fromDate time.Time, toDate time.Time, fn func(report *ProjectReport) error) *api.Error
+	ExportTimeByTask(accountId int, fromDate time.Time, toDate time.Time, fn func(report *TaskReport) error) *api.Error
+	ExportTimeByPerson(accountId int, fromDate time.Time, toDate time.Time, fn func(report *PersonReport) error) *api.Error
+	ExportTimeEntries(accountId int, filter *TimeEntryFilter, fn func(entry *TimeEntryReport) error) *api.Error
 }
 
 type ReportingResource struct {
@@ -25,37 +34,234 @@ func NewReportingService(store ReportingStore) ReportingService {
 }
 
 func (c *ReportingResource) GetTimeByClient(accountId int, fromDate time.Time, toDate time.Time, offset int) ([]*ClientReport, *api.Error) {
-	clientReportRows, err := c.store.GetTimeByClient(accountId, fromDate, toDate, offset)
+	groupRows, err := c.store.GetGroupedTime(accountId, newPageQuery(DimensionClient, fromDate, toDate, offset))
 	if err != nil {
 		return nil, api.NewError(err, "Failed to get time by client", api.SystemError)
 	}
 
+	var clientReportRows []*ClientReport
+	for _, row := range groupRows {
+		clientReportRows = append(clientReportRows, newClientReport(row))
+	}
+
 	return clientReportRows, nil
 }
 
 func (c *ReportingResource) GetTimeByProject(accountId int, fromDate time.Time, toDate time.Time, offset int) ([]*ProjectReport, *api.Error) {
-	projectReportRows, err := c.store.GetTimeByProject(accountId, fromDate, toDate, offset)
+	groupRows, err := c.store.GetGroupedTime(accountId, newPageQuery(DimensionProject, fromDate, toDate, offset))
 	if err != nil {
 		return nil, api.NewError(err, "Failed to get time by project", api.SystemError)
 	}
 
+	var projectReportRows []*ProjectReport
+	for _, row := range groupRows {
+		projectReportRows = append(projectReportRows, newProjectReport(row))
+	}
+
 	return projectReportRows, nil
 }
 
 func (c *ReportingResource) GetTimeByTask(accountId int, fromDate time.Time, toDate time.Time, offset int) ([]*TaskReport, *api.Error) {
-	taskReportRows, err := c.store.GetTimeByTask(accountId, fromDate, toDate, offset)
+	groupRows, err := c.store.GetGroupedTime(accountId, newPageQuery(DimensionTask, fromDate, toDate, offset))
 	if err != nil {
-		return nil, api.NewError(err, "Failed to get time by project", api.SystemError)
+		return nil, api.NewError(err, "Failed to get time by task", api.SystemError)
+	}
+
+	var taskReportRows []*TaskReport
+	for _, row := range groupRows {
+		taskReportRows = append(taskReportRows, newTaskReport(row))
 	}
 
 	return taskReportRows, nil
 }
 
 func (c *ReportingResource) GetTimeByPerson(accountId int, fromDate time.Time, toDate time.Time, offset int) ([]*PersonReport, *api.Error) {
-	personReportRows, err := c.store.GetTimeByPerson(accountId, fromDate, toDate, offset)
+	groupRows, err := c.store.GetGroupedTime(accountId, newPageQuery(DimensionPerson, fromDate, toDate, offset))
 	if err != nil {
 		return nil, api.NewError(err, "Failed to get time by person", api.SystemError)
 	}
 
+	var personReportRows []*PersonReport
+	for _, row := range groupRows {
+		personReportRows = append(personReportRows, newPersonReport(row))
+	}
+
 	return personReportRows, nil
 }
+
+// Call fn with the time for every client, without the page limit of GetTimeByClient
+func (c *ReportingResource) ExportTimeByClient(accountId int, fromDate time.Time, toDate time.Time, fn func(report *ClientReport) error) *api.Error {
+	return c.exportGroupedTime(accountId, DimensionClient, fromDate, toDate, func(row *GroupRow) error {
+		return fn(newClientReport(row))
+	})
+}
+
+func (c *ReportingResource) ExportTimeByProject(accountId int, fromDate time.Time, toDate time.Time, fn func(report *ProjectReport) error) *api.Error {
+	return c.exportGroupedTime(accountId, DimensionProject, fromDate, toDate, func(row *GroupRow) error {
+		return fn(newProjectReport(row))
+	})
+}
+
+func (c *ReportingResource) ExportTimeByTask(accountId int, fromDate time.Time, toDate time.Time, fn func(report *TaskReport) error) *api.Error {
+	return c.exportGroupedTime(accountId, DimensionTask, fromDate, toDate, func(row *GroupRow) error {
+		return fn(newTaskReport(row))
+	})
+}
+
+func (c *ReportingResource) ExportTimeByPerson(accountId int, fromDate time.Time, toDate time.Time, fn func(report *PersonReport) error) *api.Error {
+	return c.exportGroupedTime(accountId, DimensionPerson, fromDate, toDate, func(row *GroupRow) error {
+		return fn(newPersonReport(row))
+	})
+}
+
+func (c *ReportingResource) exportGroupedTime(accountId int, dimension Dimension, fromDate time.Time, toDate time.Time, fn func(row *GroupRow) error) *api.Error {
+	query := &GroupQuery{
+		Filter:     TimeEntryFilter{FromDate: fromDate, ToDate: toDate},
+		Dimensions: []Dimension{dimension},
+		Notes:      true,
+	}
+
+	if err := c.store.StreamGroupedTime(accountId, query, fn); err != nil {
+		return api.NewError(err, "Failed to export time by "+string(dimension), api.SystemError)
+	}
+
+	return nil
+}
+
+// Group time by the dimensions and nest the totals. Returns ReportTooLarge when there are more than GroupMaxRows
+// combinations of dimension values
+func (c *ReportingResource) GetGroupedTime(accountId int, filter *TimeEntryFilter, dimensions []Dimension, weekStart int) ([]*GroupNode, *api.Error) {
+	query := &GroupQuery{Filter: *filter, Dimensions: dimensions, WeekStart: weekStart, Limit: GroupMaxRows + 1}
+	groupRows, err := c.store.GetGroupedTime(accountId, query)
+	if err != nil {
+		return nil, api.NewError(err, "Failed to get grouped time", api.SystemError)
+	}
+
+	if len(groupRows) > GroupMaxRows {
+		return nil, api.NewError(nil, "Report has too many rows. Use filters or fewer dimensions", api.ReportTooLarge)
+	}
+
+	return BuildGroupTree(groupRows), nil
+}
+
+// A page of one of the single dimension reports
+func newPageQuery(dimension Dimension, fromDate time.Time, toDate time.Time, offset int) *GroupQuery {
+	return &GroupQuery{
+		Filter:     TimeEntryFilter{FromDate: fromDate, ToDate: toDate},
+		Dimensions: []Dimension{dimension},
+		Limit:      ReportPaginationLimit,
+		Offset:     offset * ReportPaginationLimit,
+	}
+}
+
+func (c *ReportingResource) GetUtilization(accountId int, fromDate time.Time, toDate time.Time) ([]*Utilization, *api.Error) {
+	capacities, err := c.store.GetMemberCapacities(accountId, fromDate, toDate)
+	if err != nil {
+		return nil, api.NewError(err, "Failed to get member capacities", api.SystemError)
+	}
+
+	// Start the weeks on the from date's weekday so they line up with the report range
+	weekRows, err := c.store.GetGroupedTime(accountId, &GroupQuery{
+		Filter:     TimeEntryFilter{FromDate: fromDate, ToDate: toDate},
+		Dimensions: []Dimension{DimensionPerson, DimensionWeek},
+		WeekStart:  int(fromDate.Weekday()),
+	})
+	if err != nil {
+		return nil, api.NewError(err, "Failed to get time by person and week", api.SystemError)
+	}
+
+	holidays, err := c.store.GetHolidays(accountId, fromDate, toDate)
+	if err != nil {
+		return nil, api.NewError(err, "Failed to get holidays", api.SystemError)
+	}
+
+	return BuildUtilization(fromDate, toDate, capacities, weekRows, holidays), nil
+}
+
+// Get a page of time entries and the cursor for the next page, which is nil on the last page
+func (c *ReportingResource) GetTimeEntries(accountId int, filter *TimeEntryFilter, after *EntryCursor, limit int) ([]*TimeEntryReport, *EntryCursor, *api.Error) {
+	// Read one extra entry to find out if there is another page
+	entries, err := c.store.GetTimeEntries(accountId, filter, after, limit+1)
+	if err != nil {
+		return nil, nil, api.NewError(err, "Failed to get time entries", api.SystemError)
+	}
+
+	if len(entries) <= limit {
+		return entries, nil, nil
+	}
+
+	entries = entries[:limit]
+	return entries, NewEntryCursor(entries[limit-1]), nil
+}
+
+// Call fn with every time entry matching the filter
+func (c *ReportingResource) ExportTimeEntries(accountId int, filter *TimeEntryFilter, fn func(entry *TimeEntryReport) error) *api.Error {
+	if err := c.store.StreamTimeEntries(accountId, filter, fn); err != nil {
+		return api.NewError(err, "Failed to export time entries", api.SystemError)
+	}
+
+	return nil
+}
+
+// Get the time in each interval between the filter dates, split into a series per client, project, task or person
+// when split is set
+func (c *ReportingResource) GetTimeSeries(accountId int, filter *TimeEntryFilter, interval Interval, split Dimension, accountWeekStart int) ([]*Series, *api.Error) {
+	weekStart := interval.WeekStart(accountWeekStart)
+	dimensions := []Dimension{interval.Dimension()}
+	if split != "" {
+		dimensions = []Dimension{split, interval.Dimension()}
+	}
+
+	groupRows, err := c.store.GetGroupedTime(accountId, &GroupQuery{Filter: *filter, Dimensions: dimensions, WeekStart: weekStart})
+	if err != nil {
+		return nil, api.NewError(err, "Failed to get time series", api.SystemError)
+	}
+
+	return BuildTimeSeries(interval.Buckets(filter.FromDate, filter.ToDate, weekStart), groupRows, split != ""), nil
+}
+
+func newClientReport(row *GroupRow) *ClientReport {
+	return &ClientReport{
+		ClientId:         row.Keys[0].Id,
+		ClientName:       row.Keys[0].Name,
+		NonBillableHours: row.NonBillableHours,
+		BillableHours:    row.BillableHours,
+		BillableTotal:    row.BillableTotal,
+		Notes:            row.Notes,
+	}
+}
+
+func newProjectReport(row *GroupRow) *ProjectReport {
+	return &ProjectReport{
+		ProjectId:        row.Keys[0].Id,
+		ProjectName:      row.Keys[0].Name,
+		ClientName:       row.Keys[0].ClientName,
+		NonBillableHours: row.NonBillableHours,
+		BillableHours:    row.BillableHours,
+		BillableTotal:    row.BillableTotal,
+		Notes:            row.Notes,
+	}
+}
+
+func newTaskReport(row *GroupRow) *TaskReport {
+	return &TaskReport{
+		TaskId:           row.Keys[0].Id,
+		TaskName:         row.Keys[0].Name,
+		NonBillableHours: row.NonBillableHours,
+		BillableHours:    row.BillableHours,
+		BillableTotal:    row.BillableTotal,
+		Notes:            row.Notes,
+	}
+}
+
+func newPersonReport(row *GroupRow) *PersonReport {
+	return &PersonReport{
+		ProfileId:        row.Keys[0].Id,
+		FirstName:        row.Keys[0].FirstName,
+		LastName:         row.Keys[0].LastName,
+		NonBillableHours: row.NonBillableHours,
+		BillableHours:    row.BillableHours,
+		BillableTotal:    row.BillableTotal,
+		Notes:            row.Notes,
+	}
+}
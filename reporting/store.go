@@ -6,7 +6,9 @@ import (
 
 	"github.com/bryanmorgan/time-tracking-api/config"
 	"github.com/bryanmorgan/time-tracking-api/database"
+	"github.com/bryanmorgan/time-tracking-api/profile"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 // Compile Only: ensure interface is implemented
@@ -15,10 +17,12 @@ var _ ReportingStore = &ReportingData{}
 const ReportPaginationLimit = 100
 
 type ReportingStore interface {
-	GetTimeByClient(accountId int, fromDate time.Time, toDate time.Time, offset int) ([]*ClientReport, error)
-	GetTimeByProject(accountId int, fromDate time.Time, toDate time.Time, offset int) ([]*ProjectReport, error)
-	GetTimeByTask(accountId int, fromDate time.Time, toDate time.Time, offset int) ([]*TaskReport, error)
-	GetTimeByPerson(accountId int, fromDate time.Time, toDate time.Time, offset int) ([]*PersonReport, error)
+	GetGroupedTime(accountId int, query *GroupQuery) ([]*GroupRow, error)
+	GetMemberCapacities(accountId int, fromDate time.Time, toDate time.Time) ([]*MemberCapacity, error)
+	GetHolidays(accountId int, fromDate time.Time, toDate time.Time) ([]time.Time, error)
+	GetTimeEntries(accountId int, filter *TimeEntryFilter, after *EntryCursor, limit int) ([]*TimeEntryReport, error)
+	StreamGroupedTime(accountId int, query *GroupQuery, fn func(row *GroupRow) error) error
+	StreamTimeEntries(accountId int, filter *TimeEntryFilter, fn func(entry *TimeEntryReport) error) error
 }
 
 type ReportingData struct {
@@ -31,230 +35,235 @@ func NewReportingStore(db *sqlx.DB) ReportingStore {
 	}
 }
 
-func (c *ReportingData) GetTimeByClient(accountId int, fromDate time.Time, toDate time.Time, offset int) ([]*ClientReport, error) {
-	sqlStatement := `
-		SELECT c.client_name,
-		       c.client_id,
-       		   sum(t.hours) filter (where not pt.billable)       as non_billable_hours,
-       		   sum(t.hours) filter (where pt.billable)           as billable_hours,
-       		   sum(t.hours * pt.rate) filter (where pt.billable) as billable_total
-		FROM time t,
-     		 project_task pt,
-       		 project p,
-     	  	 client c
-		WHERE t.account_id = $1
-  		  AND t.account_id = pt.account_id
-  		  AND t.project_id = pt.project_id
-  		  AND t.task_id = pt.task_id
-  		  AND pt.project_id = p.project_id
-  		  AND c.client_id = p.client_id
-		  AND t.hours > 0.0
-		  AND day >= $2
-		  AND day <= $3
-		GROUP BY c.client_id
-		ORDER BY c.client_name
-		LIMIT $4
-		OFFSET $5		
-`
-	rows, err := c.db.Queryx(sqlStatement,
-		accountId,
-		fromDate.Format(config.ISOShortDateFormat),
-		toDate.Format(config.ISOShortDateFormat),
-		ReportPaginationLimit,
-		offset*ReportPaginationLimit)
-
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
-
+// Get the time totals for each combination of the query's dimensions, in dimension order
+func (c *ReportingData) GetGroupedTime(accountId int, query *GroupQuery) ([]*GroupRow, error) {
+	rows, err := c.db.Queryx(groupSql(query.Dimensions, query.WeekStart, query.Notes), groupArgs(accountId, query)...)
 	if err != nil {
 		return nil, err
 	}
 
 	defer database.CloseRows(rows)
 
-	var clientRows []*ClientReport
+	var groupRows []*GroupRow
 	for rows.Next() {
-		var r ClientReport
-		err := rows.StructScan(&r)
+		row, err := scanGroupRow(rows, query)
 		if err != nil {
 			return nil, err
 		}
-		clientRows = append(clientRows, &r)
+		groupRows = append(groupRows, row)
 	}
 
-	return clientRows, nil
+	return groupRows, rows.Err()
 }
 
-func (c *ReportingData) GetTimeByProject(accountId int, fromDate time.Time, toDate time.Time, offset int) ([]*ProjectReport, error) {
-	sqlStatement := `
-	  SELECT p.project_id, 
-	         p.project_name, 
-	         c.client_name, 
-	         bt.non_billable_hours, 
-	         bt.billable_hours, 
-	         bt.billable_total
-      FROM (SELECT t.project_id,
-                   sum(t.hours) FILTER (WHERE NOT pt.billable)       AS non_billable_hours,
-                   sum(t.hours) FILTER (WHERE pt.billable)           AS billable_hours,
-                   sum(t.hours * pt.rate) FILTER (WHERE pt.billable) AS billable_total
-            FROM time t,
-                 project_task pt
-            WHERE t.account_id = $1
-              AND t.account_id = pt.account_id
-              AND t.project_id = pt.project_id
-              AND t.task_id = pt.task_id
-              AND t.hours > 0.0
-              AND day >= $2
-              AND day <= $3
-            GROUP BY t.project_id
-            ORDER BY t.project_id
-            LIMIT $4
-            OFFSET $5) bt,
-           project p,
-           client c
-      WHERE bt.project_id = p.project_id
-        AND p.client_id = c.client_id
-      ORDER BY p.project_name`
-
-	rows, err := c.db.Queryx(sqlStatement,
+// Call fn with each row of a grouped report, read through a cursor so every row can be exported without loading
+// them all
+func (c *ReportingData) StreamGroupedTime(accountId int, query *GroupQuery, fn func(row *GroupRow) error) error {
+	return database.StreamRows(c.db, groupSql(query.Dimensions, query.WeekStart, query.Notes), groupArgs(accountId, query), func(rows *sqlx.Rows) error {
+		row, err := scanGroupRow(rows, query)
+		if err != nil {
+			return err
+		}
+
+		return fn(row)
+	})
+}
+
+func groupArgs(accountId int, query *GroupQuery) []interface{} {
+	limit := sql.NullInt64{Int64: int64(query.Limit), Valid: query.Limit > 0}
+	return []interface{}{
 		accountId,
-		fromDate.Format(config.ISOShortDateFormat),
-		toDate.Format(config.ISOShortDateFormat),
-		ReportPaginationLimit,
-		offset*ReportPaginationLimit)
+		query.Filter.FromDate.Format(config.ISOShortDateFormat),
+		query.Filter.ToDate.Format(config.ISOShortDateFormat),
+		query.Filter.ClientId,
+		query.Filter.ProjectId,
+		query.Filter.TaskId,
+		query.Filter.ProfileId,
+		query.Filter.Billable,
+		limit,
+		query.Offset,
+	}
+}
 
-	if err == sql.ErrNoRows {
-		return nil, nil
+func scanGroupRow(rows *sqlx.Rows, query *GroupQuery) (*GroupRow, error) {
+	dimensions := query.Dimensions
+	count := len(dimensions)
+	ids := make([]sql.NullInt64, count)
+	days := make([]pq.NullTime, count)
+	names := make([]sql.NullString, count)
+	details := make([]sql.NullString, count)
+
+	var row GroupRow
+	columns := make([]interface{}, 0, count*4+4)
+	for i := 0; i < count; i++ {
+		columns = append(columns, &ids[i], &days[i], &names[i], &details[i])
+	}
+	columns = append(columns, &row.NonBillableHours, &row.BillableHours, &row.BillableTotal)
+	if query.Notes {
+		columns = append(columns, &row.Notes)
 	}
 
-	if err != nil {
+	if err := rows.Scan(columns...); err != nil {
 		return nil, err
 	}
 
-	defer database.CloseRows(rows)
-
-	var projectRows []*ProjectReport
-	for rows.Next() {
-		var p ProjectReport
-		err := rows.StructScan(&p)
-		if err != nil {
-			return nil, err
-		}
-		projectRows = append(projectRows, &p)
+	for i, dimension := range dimensions {
+		row.Keys = append(row.Keys, newGroupKey(dimension, ids[i], days[i], names[i], details[i]))
 	}
 
-	return projectRows, nil
+	return &row, nil
 }
 
-func (c *ReportingData) GetTimeByTask(accountId int, fromDate time.Time, toDate time.Time, offset int) ([]*TaskReport, error) {
+// Capacity of each valid member plus anyone removed from the account who logged time between the dates
+func (c *ReportingData) GetMemberCapacities(accountId int, fromDate time.Time, toDate time.Time) ([]*MemberCapacity, error) {
 	sqlStatement := `
-	  SELECT t.task_id,
-	         t.task_name, 
-	         bt.non_billable_hours, 
-	         bt.billable_hours, 
-	         bt.billable_total
-      FROM (SELECT t.task_id,
-                   sum(t.hours) FILTER (WHERE NOT pt.billable)       AS non_billable_hours,
-                   sum(t.hours) FILTER (WHERE pt.billable)           AS billable_hours,
-                   sum(t.hours * pt.rate) FILTER (WHERE pt.billable) AS billable_total
-            FROM time t,
-                 project_task pt
-            WHERE t.account_id = $1
-              AND t.account_id = pt.account_id
-              AND t.project_id = pt.project_id
-              AND t.task_id = pt.task_id
-              AND t.hours > 0.0
-              AND day >= $2
-              AND day <= $3
-            GROUP BY t.task_id
-            ORDER BY t.task_id
-            LIMIT $4
-            OFFSET $5) bt,
-           task t
-      WHERE bt.task_id = t.task_id
-      ORDER BY t.task_name;		
+		SELECT p.profile_id,
+		       p.first_name,
+		       p.last_name,
+		       COALESCE(pa.capacity_hours, 0) as capacity_hours
+		FROM profile p
+		LEFT JOIN profile_account pa ON pa.profile_id = p.profile_id AND pa.account_id = $1
+		WHERE pa.profile_account_status = $4
+		   OR p.profile_id IN (SELECT t.profile_id
+		                       FROM time t
+		                       WHERE t.account_id = $1
+		                         AND t.hours > 0.0
+		                         AND t.day >= $2
+		                         AND t.day <= $3)
+		ORDER BY p.last_name, p.first_name
 `
-	rows, err := c.db.Queryx(sqlStatement,
+	var capacities []*MemberCapacity
+	err := c.db.Select(&capacities, sqlStatement,
 		accountId,
 		fromDate.Format(config.ISOShortDateFormat),
 		toDate.Format(config.ISOShortDateFormat),
-		ReportPaginationLimit,
-		offset*ReportPaginationLimit)
-
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
+		profile.ProfileAccountValid)
 
 	if err != nil {
 		return nil, err
 	}
 
-	defer database.CloseRows(rows)
+	return capacities, nil
+}
 
-	var taskRows []*TaskReport
-	for rows.Next() {
-		var t TaskReport
-		err := rows.StructScan(&t)
-		if err != nil {
-			return nil, err
-		}
-		taskRows = append(taskRows, &t)
+func (c *ReportingData) GetHolidays(accountId int, fromDate time.Time, toDate time.Time) ([]time.Time, error) {
+	var holidays []time.Time
+	err := c.db.Select(&holidays, "SELECT day FROM holiday WHERE account_id = $1 AND day >= $2 AND day <= $3 ORDER BY day",
+		accountId,
+		fromDate.Format(config.ISOShortDateFormat),
+		toDate.Format(config.ISOShortDateFormat))
+
+	if err != nil {
+		return nil, err
 	}
 
-	return taskRows, nil
+	return holidays, nil
 }
 
-func (c *ReportingData) GetTimeByPerson(accountId int, fromDate time.Time, toDate time.Time, offset int) ([]*PersonReport, error) {
-	sqlStatement := `
-		SELECT p.profile_id,
-		       p.first_name,
-		       p.last_name,
-       		   sum(t.hours) filter (where not pt.billable)       as non_billable_hours,
-       		   sum(t.hours) filter (where pt.billable)           as billable_hours,
-       		   sum(t.hours * pt.rate) filter (where pt.billable) as billable_total
+// Time entries matching a TimeEntryFilter. Entries after the cursor in $9 to $12 are returned, or all entries when
+// $9 is null, up to the limit in $13
+const timeEntriesSql = `
+		SELECT t.day,
+		       t.profile_id,
+		       pr.first_name,
+		       pr.last_name,
+		       c.client_id,
+		       c.client_name,
+		       t.project_id,
+		       p.project_name,
+		       t.task_id,
+		       k.task_name,
+		       t.hours,
+		       pt.billable,
+		       pt.rate,
+		       t.notes,
+		       t.invoice_id
 		FROM time t,
-     		 project_task pt,
-       		 profile p
+		     project_task pt,
+		     project p,
+		     client c,
+		     task k,
+		     profile pr
 		WHERE t.account_id = $1
-  		  AND t.account_id = pt.account_id
-  		  AND t.project_id = pt.project_id
-  		  AND t.task_id = pt.task_id
-  		  AND t.profile_id = p.profile_id
+		  AND t.account_id = pt.account_id
+		  AND t.project_id = pt.project_id
+		  AND t.task_id = pt.task_id
+		  AND pt.project_id = p.project_id
+		  AND c.client_id = p.client_id
+		  AND k.task_id = t.task_id
+		  AND pr.profile_id = t.profile_id
 		  AND t.hours > 0.0
-		  AND day >= $2
-		  AND day <= $3
-		GROUP BY p.profile_id
-		ORDER BY p.last_name
-		LIMIT $4
-		OFFSET $5		
+		  AND t.day >= $2
+		  AND t.day <= $3
+		  AND ($4 = 0 OR c.client_id = $4)
+		  AND ($5 = 0 OR t.project_id = $5)
+		  AND ($6 = 0 OR t.task_id = $6)
+		  AND ($7 = 0 OR t.profile_id = $7)
+		  AND ($8::boolean IS NULL OR pt.billable = $8)
+		  AND ($9::date IS NULL OR (t.day, t.profile_id, t.project_id, t.task_id) > ($9::date, $10, $11, $12))
+		ORDER BY t.day, t.profile_id, t.project_id, t.task_id
+		LIMIT $13
 `
-	rows, err := c.db.Queryx(sqlStatement,
-		accountId,
-		fromDate.Format(config.ISOShortDateFormat),
-		toDate.Format(config.ISOShortDateFormat),
-		ReportPaginationLimit,
-		offset*ReportPaginationLimit)
 
-	if err == sql.ErrNoRows {
-		return nil, nil
+// Get up to limit time entries matching the filter, ordered by day, profile, project and task. When after is set only
+// entries that sort after the cursor are returned
+func (c *ReportingData) GetTimeEntries(accountId int, filter *TimeEntryFilter, after *EntryCursor, limit int) ([]*TimeEntryReport, error) {
+	var afterDay sql.NullString
+	var afterProfileId, afterProjectId, afterTaskId int
+	if after != nil {
+		afterDay = sql.NullString{String: after.Day.Format(config.ISOShortDateFormat), Valid: true}
+		afterProfileId = after.ProfileId
+		afterProjectId = after.ProjectId
+		afterTaskId = after.TaskId
 	}
 
+	var entries []*TimeEntryReport
+	err := c.db.Select(&entries, timeEntriesSql,
+		accountId,
+		filter.FromDate.Format(config.ISOShortDateFormat),
+		filter.ToDate.Format(config.ISOShortDateFormat),
+		filter.ClientId,
+		filter.ProjectId,
+		filter.TaskId,
+		filter.ProfileId,
+		filter.Billable,
+		afterDay,
+		afterProfileId,
+		afterProjectId,
+		afterTaskId,
+		limit)
+
 	if err != nil {
 		return nil, err
 	}
 
-	defer database.CloseRows(rows)
+	return entries, nil
+}
 
-	var personRows []*PersonReport
-	for rows.Next() {
-		var p PersonReport
-		err := rows.StructScan(&p)
-		if err != nil {
-			return nil, err
-		}
-		personRows = append(personRows, &p)
+// Call fn with every time entry matching the filter, in the same order as GetTimeEntries, read through a cursor
+func (c *ReportingData) StreamTimeEntries(accountId int, filter *TimeEntryFilter, fn func(entry *TimeEntryReport) error) error {
+	args := []interface{}{
+		accountId,
+		filter.FromDate.Format(config.ISOShortDateFormat),
+		filter.ToDate.Format(config.ISOShortDateFormat),
+		filter.ClientId,
+		filter.ProjectId,
+		filter.TaskId,
+		filter.ProfileId,
+		filter.Billable,
+		sql.NullString{},
+		0,
+		0,
+		0,
+		sql.NullInt64{},
 	}
 
-	return personRows, nil
+	return database.StreamRows(c.db, timeEntriesSql, args, func(rows *sqlx.Rows) error {
+		var entry TimeEntryReport
+		if err := rows.StructScan(&entry); err != nil {
+			return err
+		}
+
+		return fn(&entry)
+	})
 }
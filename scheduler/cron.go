@@ -0,0 +1,189 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedules are searched this far ahead for a matching time before giving up, e.g. for "0 0 30 2 *"
+const maxScheduleSearchYears = 5
+
+// Shorthand schedules accepted in place of the five cron fields
+var scheduleDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+type cronField struct {
+	name string
+	min  int
+	max  int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// Schedule is a parsed cron expression with the standard five fields: minute, hour, day of month, month and day of
+// week. Each field accepts *, numbers, ranges (1-5), lists (1,15) and steps (*/15 or 0-30/10). Sunday is 0 or 7
+type Schedule struct {
+	Spec     string
+	minute   uint64
+	hour     uint64
+	dom      uint64
+	month    uint64
+	dow      uint64
+	domStar  bool
+	dowStar  bool
+	location *time.Location
+}
+
+// Parse a cron expression or descriptor such as @daily. Times are matched in the location
+func ParseSchedule(spec string, location *time.Location) (*Schedule, error) {
+	expression := strings.TrimSpace(spec)
+	if descriptor, ok := scheduleDescriptors[expression]; ok {
+		expression = descriptor
+	}
+
+	fields := strings.Fields(expression)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("schedule [%s] must have %d fields", spec, len(cronFields))
+	}
+
+	bits := make([]uint64, len(cronFields))
+	for i, field := range fields {
+		var err error
+		bits[i], err = parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("schedule [%s]: %w", spec, err)
+		}
+	}
+
+	if location == nil {
+		location = time.UTC
+	}
+
+	// Sunday may be given as 7
+	dow := bits[4]
+	if dow&(1<<7) != 0 {
+		dow |= 1
+	}
+
+	return &Schedule{
+		Spec:     spec,
+		minute:   bits[0],
+		hour:     bits[1],
+		dom:      bits[2],
+		month:    bits[3],
+		dow:      dow,
+		domStar:  fields[2] == "*",
+		dowStar:  fields[4] == "*",
+		location: location,
+	}, nil
+}
+
+func parseCronField(field string, bounds cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if slash := strings.Index(part, "/"); slash >= 0 {
+			var err error
+			rangePart = part[:slash]
+			step, err = strconv.Atoi(part[slash+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %s [%s]", bounds.name, part)
+			}
+		}
+
+		start, end := bounds.min, bounds.max
+		if rangePart != "*" {
+			var err error
+			if dash := strings.Index(rangePart, "-"); dash >= 0 {
+				start, err = strconv.Atoi(rangePart[:dash])
+				if err == nil {
+					end, err = strconv.Atoi(rangePart[dash+1:])
+				}
+			} else {
+				start, err = strconv.Atoi(rangePart)
+				end = start
+				if step > 1 {
+					end = bounds.max
+				}
+			}
+
+			if err != nil {
+				return 0, fmt.Errorf("invalid %s [%s]", bounds.name, part)
+			}
+		}
+
+		if start < bounds.min || end > bounds.max || start > end {
+			return 0, fmt.Errorf("%s [%s] must be between %d and %d", bounds.name, part, bounds.min, bounds.max)
+		}
+
+		for value := start; value <= end; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+
+	if bits == 0 {
+		return 0, errors.New("empty " + bounds.name)
+	}
+
+	return bits, nil
+}
+
+// Returns the first time after t that matches the schedule, or the zero time if there is none
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.In(s.location).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxScheduleSearchYears, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.location)
+			continue
+		}
+
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.location)
+			continue
+		}
+
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, s.location)
+			continue
+		}
+
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+// As in cron, when both day of month and day of week are restricted a day matching either one is used
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+
+	return domMatch || dowMatch
+}
@@ -0,0 +1,95 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name  string
+		spec  string
+		valid bool
+	}{
+		{"Every minute", "* * * * *", true},
+		{"Steps and lists", "*/15 9-17 * * 1,3,5", true},
+		{"Range with step", "0-30/10 * * * *", true},
+		{"Sunday as seven", "0 0 * * 7", true},
+		{"Descriptor", "@daily", true},
+		{"Too few fields", "0 0 * *", false},
+		{"Minute out of range", "60 * * * *", false},
+		{"Reversed range", "0 5-1 * * *", false},
+		{"Zero step", "*/0 * * * *", false},
+		{"Not a number", "0 noon * * *", false},
+		{"Unknown descriptor", "@fortnightly", false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := ParseSchedule(testCase.spec, time.UTC)
+			if (err == nil) != testCase.valid {
+				t.Errorf("Parse [%s]: error [%v], wanted valid: %t", testCase.spec, err, testCase.valid)
+			}
+		})
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	t.Parallel()
+
+	// Wednesday
+	from := time.Date(2021, 3, 10, 10, 7, 30, 0, time.UTC)
+
+	testCases := []struct {
+		name string
+		spec string
+		next time.Time
+	}{
+		{"Every minute", "* * * * *", time.Date(2021, 3, 10, 10, 8, 0, 0, time.UTC)},
+		{"Quarter hour", "*/15 * * * *", time.Date(2021, 3, 10, 10, 15, 0, 0, time.UTC)},
+		{"Hourly", "@hourly", time.Date(2021, 3, 10, 11, 0, 0, 0, time.UTC)},
+		{"Daily rolls to tomorrow", "0 9 * * *", time.Date(2021, 3, 11, 9, 0, 0, 0, time.UTC)},
+		{"Weekly on Monday", "0 8 * * 1", time.Date(2021, 3, 15, 8, 0, 0, 0, time.UTC)},
+		{"Sunday as seven", "30 6 * * 7", time.Date(2021, 3, 14, 6, 30, 0, 0, time.UTC)},
+		{"Monthly", "@monthly", time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"Day of month or week", "0 0 20 * 5", time.Date(2021, 3, 12, 0, 0, 0, 0, time.UTC)},
+		{"Leap day", "0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"Never", "0 0 30 2 *", time.Time{}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			schedule, err := ParseSchedule(testCase.spec, time.UTC)
+			if err != nil {
+				t.Fatalf("Parse [%s]: %s", testCase.spec, err)
+			}
+
+			next := schedule.Next(from)
+			if !next.Equal(testCase.next) {
+				t.Errorf("Next [%s]: got %s, wanted %s", testCase.spec, next, testCase.next)
+			}
+		})
+	}
+}
+
+func TestScheduleLocation(t *testing.T) {
+	t.Parallel()
+
+	location, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("Timezone data not available")
+	}
+
+	schedule, err := ParseSchedule("0 9 * * *", location)
+	if err != nil {
+		t.Fatalf("Parse: %s", err)
+	}
+
+	// 9am Eastern is 14:00 UTC in winter
+	next := schedule.Next(time.Date(2021, 1, 4, 12, 0, 0, 0, time.UTC))
+	if !next.Equal(time.Date(2021, 1, 4, 14, 0, 0, 0, time.UTC)) {
+		t.Errorf("Next in location: got %s", next.UTC())
+	}
+}
@@ -0,0 +1,210 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/bryanmorgan/time-tracking-api/logger"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/spf13/viper"
+)
+
+// First key of the two-key advisory locks taken by scheduled jobs. The second key is a hash of the job name
+const jobLockClass = 7234002
+
+// JobFunc does the work of a scheduled job. The context is cancelled if the job is still running when shutdown times out
+type JobFunc func(ctx context.Context) error
+
+type Job struct {
+	Name     string
+	Schedule *Schedule
+	Run      JobFunc
+}
+
+// Scheduler runs jobs on cron schedules from config. Every replica runs the scheduler, but each run of a job first
+// takes a Postgres advisory lock for the job and records the scheduled time, so only one replica does the work even
+// when their clocks differ
+type Scheduler struct {
+	db         *sqlx.DB
+	location   *time.Location
+	jobs       []*Job
+	wg         sync.WaitGroup
+	stop       context.CancelFunc
+	cancelJobs context.CancelFunc
+	jobContext context.Context
+}
+
+// Create a scheduler that evaluates schedules in scheduler.timezone, or UTC when it is not set
+func NewScheduler(db *sqlx.DB) (*Scheduler, error) {
+	location := time.UTC
+	if timezone := viper.GetString("scheduler.timezone"); timezone != "" {
+		var err error
+		location, err = time.LoadLocation(timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scheduler timezone [%s]: %w", timezone, err)
+		}
+	}
+
+	return &Scheduler{db: db, location: location}, nil
+}
+
+// Add a job using the schedule in scheduler.jobs.<name>.schedule. Jobs without a schedule, or with enabled set to
+// false, are skipped. Returns false if the job was not added
+func (s *Scheduler) Register(name string, run JobFunc) (bool, error) {
+	key := "scheduler.jobs." + name
+	spec := viper.GetString(key + ".schedule")
+	if spec == "" || (viper.IsSet(key+".enabled") && !viper.GetBool(key+".enabled")) {
+		logger.Log.Info("Scheduled job disabled", logger.String("job", name))
+		return false, nil
+	}
+
+	schedule, err := ParseSchedule(spec, s.location)
+	if err != nil {
+		return false, fmt.Errorf("job [%s]: %w", name, err)
+	}
+
+	s.jobs = append(s.jobs, &Job{Name: name, Schedule: schedule, Run: run})
+	return true, nil
+}
+
+func (s *Scheduler) Jobs() []*Job {
+	return s.jobs
+}
+
+// Start a goroutine for each registered job
+func (s *Scheduler) Start() {
+	var stopContext context.Context
+	stopContext, s.stop = context.WithCancel(context.Background())
+	s.jobContext, s.cancelJobs = context.WithCancel(context.Background())
+
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go s.schedule(stopContext, job)
+	}
+
+	logger.Log.Info("Scheduler started", logger.Int("jobs", len(s.jobs)))
+}
+
+// Stop scheduling new runs and wait for running jobs to finish. If the context ends first the running jobs are
+// cancelled and the context error is returned
+func (s *Scheduler) Stop(ctx context.Context) error {
+	if s.stop == nil {
+		return nil
+	}
+	s.stop()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.cancelJobs()
+		logger.Log.Info("Scheduler stopped")
+		return nil
+	case <-ctx.Done():
+		s.cancelJobs()
+		return ctx.Err()
+	}
+}
+
+func (s *Scheduler) schedule(stopContext context.Context, job *Job) {
+	defer s.wg.Done()
+
+	for {
+		next := job.Schedule.Next(time.Now())
+		if next.IsZero() {
+			logger.Log.Warn("Scheduled job has no next run", logger.String("job", job.Name), logger.String("schedule", job.Schedule.Spec))
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-stopContext.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			s.runLocked(job, next)
+		}
+	}
+}
+
+// Run the job if this replica gets the job's advisory lock and no replica has run it for the scheduled time yet. The
+// lock is held on a dedicated connection until the job finishes
+func (s *Scheduler) runLocked(job *Job, scheduled time.Time) {
+	conn, err := s.db.Connx(s.jobContext)
+	if err != nil {
+		logger.Log.Error("Scheduled job connection failed", logger.String("job", job.Name), logger.Error(err))
+		return
+	}
+	defer conn.Close()
+
+	lockKey := jobLockKey(job.Name)
+	var locked bool
+	err = conn.GetContext(s.jobContext, &locked, "SELECT pg_try_advisory_lock($1, $2)", jobLockClass, lockKey)
+	if err != nil {
+		logger.Log.Error("Scheduled job lock failed", logger.String("job", job.Name), logger.Error(err))
+		return
+	}
+
+	if !locked {
+		logger.Log.Debug("Scheduled job running on another replica", logger.String("job", job.Name))
+		return
+	}
+
+	defer func() {
+		// The job context may be cancelled by now, so unlock with a fresh one
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1, $2)", jobLockClass, lockKey); err != nil {
+			logger.Log.Error("Scheduled job unlock failed", logger.String("job", job.Name), logger.Error(err))
+		}
+	}()
+
+	// A replica with a faster clock may have already run this scheduled time and released the lock
+	recordSql := `
+		INSERT INTO scheduled_job (job_name, last_run) VALUES ($1, $2)
+		ON CONFLICT (job_name)
+		DO UPDATE SET last_run = $2
+		WHERE scheduled_job.last_run < $2`
+	result, err := conn.ExecContext(s.jobContext, recordSql, job.Name, scheduled)
+	if err != nil {
+		logger.Log.Error("Scheduled job run could not be recorded", logger.String("job", job.Name), logger.Error(err))
+		return
+	}
+
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		logger.Log.Debug("Scheduled job already ran on another replica", logger.String("job", job.Name), logger.Time("scheduled", scheduled))
+		return
+	}
+
+	s.run(job)
+}
+
+func (s *Scheduler) run(job *Job) {
+	start := time.Now()
+	logger.Log.Info("Scheduled job started", logger.String("job", job.Name))
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Log.Error("Scheduled job panic", logger.String("job", job.Name), logger.Any("panic", r))
+		}
+	}()
+
+	if err := job.Run(s.jobContext); err != nil {
+		logger.Log.Error("Scheduled job failed", logger.String("job", job.Name), logger.Duration("duration", time.Since(start)), logger.Error(err))
+		return
+	}
+
+	logger.Log.Info("Scheduled job finished", logger.String("job", job.Name), logger.Duration("duration", time.Since(start)))
+}
+
+func jobLockKey(name string) int32 {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(name))
+	return int32(hash.Sum32())
+}
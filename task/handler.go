@@ -108,8 +108,8 @@ func (a *TaskRouter) saveTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if valid.IsNull(taskRequest.Name) {
-		api.ErrorJson(w, api.NewFieldError(nil, "Missing required name", api.MissingField, "taskName"), http.StatusBadRequest)
+	if appErr := ValidateTaskName(taskRequest.Name); appErr != nil {
+		api.ErrorJson(w, appErr, http.StatusBadRequest)
 		return
 	}
 
@@ -144,8 +144,8 @@ func (a *TaskRouter) updateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if valid.IsNull(taskRequest.Name) {
-		api.ErrorJson(w, api.NewFieldError(nil, "Missing required name", api.MissingField, "taskName"), http.StatusBadRequest)
+	if appErr := ValidateTaskName(taskRequest.Name); appErr != nil {
+		api.ErrorJson(w, appErr, http.StatusBadRequest)
 		return
 	}
 
@@ -273,6 +273,15 @@ func getTaskRequest(r *http.Request) (*TaskRequest, *api.Error) {
 	return &taskRequest, nil
 }
 
+// Check a task name. Shared by the task handlers and client imports, which create tasks by name
+func ValidateTaskName(name string) *api.Error {
+	if valid.IsNull(name) {
+		return api.NewFieldError(nil, "Missing required name", api.MissingField, "taskName")
+	}
+
+	return nil
+}
+
 func NewTaskResponse(task *Task) *TaskResponse {
 	if task == nil {
 		return nil
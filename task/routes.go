@@ -27,14 +27,24 @@ func (a *TaskRouter) Router() *chi.Mux {
 		r.Use(a.profileRouter.ValidateProfileHandler)
 		r.Use(a.profileRouter.ValidateSessionHandler)
 
-		r.Get("/{taskId}", a.getTask)
-		r.Get("/all", a.getAllTasks)
-		r.Get("/archived", a.getArchivedTasks)
-		r.Post("/", a.saveTask)
-		r.Put("/", a.updateTask)
-		r.Put("/archive", a.archiveTaskHandler)
-		r.Put("/restore", a.restoreTaskHandler)
-		r.Delete("/", a.deleteTaskHandler)
+		r.Group(func(r chi.Router) {
+			r.Use(a.profileRouter.RolePermissionHandler(profile.AllRoles...))
+
+			r.Get("/{taskId}", a.getTask)
+			r.Get("/all", a.getAllTasks)
+			r.Get("/archived", a.getArchivedTasks)
+		})
+
+		// Only admins manage tasks
+		r.Group(func(r chi.Router) {
+			r.Use(a.profileRouter.AdminPermissionHandler)
+
+			r.Post("/", a.saveTask)
+			r.Put("/", a.updateTask)
+			r.Put("/archive", a.archiveTaskHandler)
+			r.Put("/restore", a.restoreTaskHandler)
+			r.Delete("/", a.deleteTaskHandler)
+		})
 	})
 
 	return r
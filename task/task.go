@@ -20,4 +20,7 @@ type ProjectTask struct {
 	Rate          sql.NullFloat64 `json:"-"`
 	Billable      bool            `json:"-"`
 	ProjectActive bool            `json:"-" db:"project_active"`
+	BudgetHours   sql.NullFloat64 `json:"-" db:"budget_hours"`
+	BudgetAmount  sql.NullFloat64 `json:"-" db:"budget_amount"`
+	AlertPercent  int             `json:"-" db:"budget_alert_percent"`
 }
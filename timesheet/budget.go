@@ -0,0 +1,126 @@
+package timesheet
+
+import (
+	"database/sql"
+	"math"
+	"sort"
+
+	"github.com/spf13/viper"
+)
+
+type BudgetStatus string
+
+const (
+	BudgetNone    BudgetStatus = "none"
+	BudgetOnTrack BudgetStatus = "onTrack"
+	BudgetWarning BudgetStatus = "warning"
+	BudgetOver    BudgetStatus = "over"
+)
+
+// Used when no budget.alertThresholds are configured
+var defaultBudgetAlertThresholds = []int{80, 100}
+
+type ProjectBudget struct {
+	AccountId    int             `json:"-" db:"account_id"`
+	ProjectId    int             `json:"-" db:"project_id"`
+	ProjectName  string          `json:"-" db:"project_name"`
+	BudgetHours  sql.NullFloat64 `json:"-" db:"budget_hours"`
+	BudgetAmount sql.NullFloat64 `json:"-" db:"budget_amount"`
+	SpentHours   float64         `json:"-" db:"spent_hours"`
+	SpentAmount  float64         `json:"-" db:"spent_amount"`
+	AlertPercent int             `json:"-" db:"budget_alert_percent"`
+	Tasks        []*TaskBudget   `json:"-" db:"-"`
+}
+
+type TaskBudget struct {
+	TaskId       int             `json:"-" db:"task_id"`
+	TaskName     string          `json:"-" db:"task_name"`
+	BudgetHours  sql.NullFloat64 `json:"-" db:"budget_hours"`
+	BudgetAmount sql.NullFloat64 `json:"-" db:"budget_amount"`
+	SpentHours   float64         `json:"-" db:"spent_hours"`
+	SpentAmount  float64         `json:"-" db:"spent_amount"`
+	AlertPercent int             `json:"-" db:"budget_alert_percent"`
+}
+
+type BudgetAlertRecipient struct {
+	FirstName      string         `json:"-" db:"first_name"`
+	Email          string         `json:"-"`
+	Language       string         `json:"-"`
+	EmailSignature sql.NullString `json:"-" db:"email_signature"`
+	EmailLogoUrl   sql.NullString `json:"-" db:"email_logo_url"`
+}
+
+// Returns the configured percentages of a budget that trigger an alert, in ascending order
+func BudgetAlertThresholds() []int {
+	thresholds := viper.GetIntSlice("budget.alertThresholds")
+	if len(thresholds) == 0 {
+		return defaultBudgetAlertThresholds
+	}
+
+	sorted := append([]int{}, thresholds...)
+	sort.Ints(sorted)
+	return sorted
+}
+
+// Returns the percent of the budget used. When both an hour and money budget are set the larger percentage is used
+func BudgetPercentUsed(budgetHours sql.NullFloat64, budgetAmount sql.NullFloat64, spentHours float64, spentAmount float64) float64 {
+	percent := 0.0
+	if budgetHours.Valid && budgetHours.Float64 > 0 {
+		percent = math.Max(percent, spentHours/budgetHours.Float64*100)
+	}
+
+	if budgetAmount.Valid && budgetAmount.Float64 > 0 {
+		percent = math.Max(percent, spentAmount/budgetAmount.Float64*100)
+	}
+
+	return math.Round(percent*100) / 100
+}
+
+// Returns the budget status for the percent used. A budget is in warning once the lowest alert threshold is reached
+func GetBudgetStatus(budgetHours sql.NullFloat64, budgetAmount sql.NullFloat64, percent float64, thresholds []int) BudgetStatus {
+	if !hasBudget(budgetHours, budgetAmount) {
+		return BudgetNone
+	}
+
+	if percent >= 100 {
+		return BudgetOver
+	}
+
+	if len(thresholds) > 0 && percent >= float64(thresholds[0]) {
+		return BudgetWarning
+	}
+
+	return BudgetOnTrack
+}
+
+func (b *ProjectBudget) PercentUsed() float64 {
+	return BudgetPercentUsed(b.BudgetHours, b.BudgetAmount, b.SpentHours, b.SpentAmount)
+}
+
+func (b *ProjectBudget) Status(thresholds []int) BudgetStatus {
+	return GetBudgetStatus(b.BudgetHours, b.BudgetAmount, b.PercentUsed(), thresholds)
+}
+
+func (b *TaskBudget) PercentUsed() float64 {
+	return BudgetPercentUsed(b.BudgetHours, b.BudgetAmount, b.SpentHours, b.SpentAmount)
+}
+
+func (b *TaskBudget) Status(thresholds []int) BudgetStatus {
+	return GetBudgetStatus(b.BudgetHours, b.BudgetAmount, b.PercentUsed(), thresholds)
+}
+
+// Returns the highest threshold reached by the percent used that has not already been alerted, or 0 if there is none
+func crossedBudgetThreshold(percent float64, alertPercent int, thresholds []int) int {
+	crossed := 0
+	for _, threshold := range thresholds {
+		if threshold > alertPercent && percent >= float64(threshold) {
+			crossed = threshold
+		}
+	}
+
+	return crossed
+}
+
+func hasBudget(budgetHours sql.NullFloat64, budgetAmount sql.NullFloat64) bool {
+	return (budgetHours.Valid && budgetHours.Float64 > 0) || (budgetAmount.Valid && budgetAmount.Float64 > 0)
+}
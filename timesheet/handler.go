@@ -1,12 +1,17 @@
 package timesheet
 
 import (
+	"database/sql"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bryanmorgan/time-tracking-api/api"
 	"github.com/bryanmorgan/time-tracking-api/config"
+	"github.com/bryanmorgan/time-tracking-api/logger"
 	"github.com/bryanmorgan/time-tracking-api/profile"
 	"github.com/bryanmorgan/time-tracking-api/valid"
 
@@ -18,6 +23,7 @@ type TimeEntryRequest struct {
 	Hours     float64
 	ProjectId int
 	TaskId    int
+	Notes     *string // nil keeps the existing notes and an empty string clears them
 }
 
 type TimeEntryRangeRequest struct {
@@ -38,6 +44,17 @@ type ProjectDeleteRequest struct {
 	TaskId    int
 }
 
+type TimesheetReviewRequest struct {
+	ProfileId int
+	Reason    string
+}
+
+type TimerRequest struct {
+	ProjectId int
+	TaskId    int
+	Notes     string
+}
+
 type TimeEntryResponse struct {
 	Day         string  `json:"day"`
 	Hours       float64 `json:"hours"`
@@ -46,16 +63,60 @@ type TimeEntryResponse struct {
 	ClientName  string  `json:"clientName"`
 	ProjectName string  `json:"projectName"`
 	TaskName    string  `json:"taskName"`
+	Notes       string  `json:"notes,omitempty"`
+	UpdatedBy   int64   `json:"updatedBy,omitempty"`
 }
 
 type TimeRangeResponse struct {
-	Start       string               `json:"start"`
-	End         string               `json:"end"`
+	Start        string               `json:"start"`
+	End          string               `json:"end"`
+	Status       string               `json:"status,omitempty"`
+	RejectReason string               `json:"rejectReason,omitempty"`
+	TimeEntries  []*TimeEntryResponse `json:"entries"`
+}
+
+type TimesheetResponse struct {
+	StartDate    string     `json:"startDate"`
+	EndDate      string     `json:"endDate"`
+	ProfileId    int        `json:"profileId"`
+	FirstName    string     `json:"firstName"`
+	LastName     string     `json:"lastName"`
+	Email        string     `json:"email"`
+	Status       string     `json:"status"`
+	Hours        float64    `json:"hours"`
+	RejectReason string     `json:"rejectReason,omitempty"`
+	Submitted    *time.Time `json:"submitted,omitempty"`
+	SubmittedBy  int64      `json:"submittedBy,omitempty"`
+	Reviewed     *time.Time `json:"reviewed,omitempty"`
+}
+
+type TimerResponse struct {
+	ProjectId   int       `json:"projectId"`
+	TaskId      int       `json:"taskId"`
+	ClientName  string    `json:"clientName"`
+	ProjectName string    `json:"projectName"`
+	TaskName    string    `json:"taskName"`
+	Notes       string    `json:"notes,omitempty"`
+	Started     time.Time `json:"started"`
+}
+
+type TimerStopResponse struct {
+	Timer       *TimerResponse       `json:"timer"`
 	TimeEntries []*TimeEntryResponse `json:"entries"`
 }
 
+type TimeImportResponse struct {
+	DryRun   bool         `json:"dryRun"`
+	Rows     int          `json:"rows"`
+	Imported int          `json:"imported"`
+	Hours    float64      `json:"hours"`
+	Errors   []*api.Error `json:"errors,omitempty"`
+}
+
 const (
-	startDatePathParameter = "startDate"
+	startDatePathParameter  = "startDate"
+	profileIdQueryParameter = "profileId"
+	searchMinLength         = 2
 )
 
 func (a *TimeRouter) getTimeEntriesForWeek(w http.ResponseWriter, r *http.Request) {
@@ -65,6 +126,12 @@ func (a *TimeRouter) getTimeEntriesForWeek(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	profileId, appErr := a.getTargetProfileId(r, userProfile)
+	if appErr != nil {
+		api.ErrorJson(w, appErr, getTargetProfileErrorStatus(appErr))
+		return
+	}
+
 	// Get the start weekday from the account
 	weekday := getWeekdayStart(userProfile.WeekStart)
 
@@ -93,13 +160,75 @@ func (a *TimeRouter) getTimeEntriesForWeek(w http.ResponseWriter, r *http.Reques
 		}
 	}
 
-	timeEntries, serviceErr := a.timeService.GetTimeEntriesForRange(userProfile.ProfileId, userProfile.AccountId, start, end)
+	timeEntries, serviceErr := a.timeService.GetTimeEntriesForRange(profileId, userProfile.AccountId, start, end)
+	if serviceErr != nil {
+		api.ErrorJson(w, serviceErr, http.StatusInternalServerError)
+		return
+	}
+
+	timesheet, serviceErr := a.timeService.GetTimesheet(profileId, userProfile.AccountId, start)
+	if serviceErr != nil {
+		api.ErrorJson(w, serviceErr, http.StatusInternalServerError)
+		return
+	}
+
+	response := NewTimeRange(timeEntries, start, end)
+	response.Status = string(timesheet.Status)
+	response.RejectReason = timesheet.RejectReason.String
+	api.Json(w, r, response)
+}
+
+func (a *TimeRouter) searchTimeEntries(w http.ResponseWriter, r *http.Request) {
+	search := strings.TrimSpace(r.URL.Query().Get("q"))
+	fromDateString := r.URL.Query().Get("from")
+	toDateString := r.URL.Query().Get("to")
+
+	if !valid.IsLength(search, searchMinLength, NotesMaxLength) {
+		api.ErrorJson(w, api.NewFieldError(nil, "Search text must be at least 2 characters", api.FieldSize, "q"), http.StatusBadRequest)
+		return
+	}
+
+	if valid.IsNull(fromDateString) {
+		api.ErrorJson(w, api.NewFieldError(nil, "No from parameter", api.InvalidField, "from"), http.StatusBadRequest)
+		return
+	}
+
+	var toDate time.Time
+	fromDate, err := time.Parse(config.ISOShortDateFormat, fromDateString)
+	if err != nil {
+		api.ErrorJson(w, api.NewFieldError(err, "Invalid format. Use ISO8061: YYYY-MM-DD", api.InvalidField, "from"), http.StatusBadRequest)
+		return
+	}
+
+	if !valid.IsNull(toDateString) {
+		toDate, err = time.Parse(config.ISOShortDateFormat, toDateString)
+		if err != nil {
+			api.ErrorJson(w, api.NewFieldError(err, "Invalid format. Use ISO8061: YYYY-MM-DD", api.InvalidField, "to"), http.StatusBadRequest)
+			return
+		}
+	} else {
+		toDate = time.Now()
+	}
+
+	userProfile, ok := r.Context().Value(config.ProfileContextKey).(*profile.Profile)
+	if !ok || userProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid profile context", api.SystemError), http.StatusInternalServerError)
+		return
+	}
+
+	profileId, appErr := a.getTargetProfileId(r, userProfile)
+	if appErr != nil {
+		api.ErrorJson(w, appErr, getTargetProfileErrorStatus(appErr))
+		return
+	}
+
+	timeEntries, serviceErr := a.timeService.SearchTimeEntries(profileId, userProfile.AccountId, fromDate, toDate, search)
 	if serviceErr != nil {
 		api.ErrorJson(w, serviceErr, http.StatusInternalServerError)
 		return
 	}
 
-	api.Json(w, r, NewTimeRange(timeEntries, start, end))
+	api.Json(w, r, NewTimeRange(timeEntries, fromDate, toDate))
 }
 
 func (a *TimeRouter) saveTimeEntries(w http.ResponseWriter, r *http.Request) {
@@ -128,6 +257,12 @@ func (a *TimeRouter) saveTimeEntries(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	profileId, appErr := a.getTargetProfileId(r, userProfile)
+	if appErr != nil {
+		api.ErrorJson(w, appErr, getTargetProfileErrorStatus(appErr))
+		return
+	}
+
 	// Validate profile and account data and build TimeEntry objects
 	var entryData []*TimeEntry
 	for _, entry := range entryRequest.Entries {
@@ -141,19 +276,31 @@ func (a *TimeRouter) saveTimeEntries(w http.ResponseWriter, r *http.Request) {
 			api.ErrorJson(w, api.NewError(err, "Missing or invalid project id or task id", api.InvalidField), http.StatusBadRequest)
 			return
 		}
+
+		if entry.Notes != nil && !valid.IsLength(*entry.Notes, 0, NotesMaxLength) {
+			api.ErrorJson(w, api.NewFieldError(nil, "Notes must be less than 1024 characters", api.FieldSize, "notes"), http.StatusBadRequest)
+			return
+		}
+
 		entryData = append(entryData, &TimeEntry{
 			Day:       entryDate,
 			Hours:     entry.Hours,
-			ProfileId: userProfile.ProfileId,
+			ProfileId: profileId,
 			AccountId: userProfile.AccountId,
 			ProjectId: entry.ProjectId,
 			TaskId:    entry.TaskId,
+			Notes:     valid.PtrToNullString(entry.Notes),
+			UpdatedBy: sql.NullInt64{Int64: int64(userProfile.ProfileId), Valid: true},
 		})
 	}
 
 	err := a.timeService.SaveOrUpdateTimeEntries(entryData)
 	if err != nil {
-		api.ErrorJson(w, err, http.StatusInternalServerError)
+		if err.Code == api.SystemError {
+			api.ErrorJson(w, err, http.StatusInternalServerError)
+		} else {
+			api.ErrorJson(w, err, http.StatusBadRequest)
+		}
 		return
 	}
 
@@ -186,6 +333,12 @@ func (a *TimeRouter) updateTimeEntries(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	profileId, appErr := a.getTargetProfileId(r, userProfile)
+	if appErr != nil {
+		api.ErrorJson(w, appErr, getTargetProfileErrorStatus(appErr))
+		return
+	}
+
 	// Build TimeEntry objects
 	var entryData []*TimeEntry
 	for _, entry := range entryRequest.Entries {
@@ -200,19 +353,30 @@ func (a *TimeRouter) updateTimeEntries(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if entry.Notes != nil && !valid.IsLength(*entry.Notes, 0, NotesMaxLength) {
+			api.ErrorJson(w, api.NewFieldError(nil, "Notes must be less than 1024 characters", api.FieldSize, "notes"), http.StatusBadRequest)
+			return
+		}
+
 		entryData = append(entryData, &TimeEntry{
 			AccountId: userProfile.AccountId,
-			ProfileId: userProfile.ProfileId,
+			ProfileId: profileId,
 			ProjectId: entry.ProjectId,
 			TaskId:    entry.TaskId,
 			Day:       entryDate,
 			Hours:     entry.Hours,
+			Notes:     valid.PtrToNullString(entry.Notes),
+			UpdatedBy: sql.NullInt64{Int64: int64(userProfile.ProfileId), Valid: true},
 		})
 	}
 
 	err := a.timeService.UpdateTimeEntries(entryData)
 	if err != nil {
-		api.ErrorJson(w, err, http.StatusInternalServerError)
+		if err.Code == api.SystemError {
+			api.ErrorJson(w, err, http.StatusInternalServerError)
+		} else {
+			api.ErrorJson(w, err, http.StatusBadRequest)
+		}
 		return
 	}
 
@@ -268,9 +432,19 @@ func (a *TimeRouter) addProjectToWeek(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	appErr := a.timeService.AddInitialProjectTimeEntries(userProfile.ProfileId, userProfile.AccountId, start, end, projectWeekRequest.ProjectId, projectWeekRequest.TaskId)
+	profileId, appErr := a.getTargetProfileId(r, userProfile)
 	if appErr != nil {
-		api.ErrorJson(w, appErr, http.StatusInternalServerError)
+		api.ErrorJson(w, appErr, getTargetProfileErrorStatus(appErr))
+		return
+	}
+
+	appErr = a.timeService.AddInitialProjectTimeEntries(profileId, userProfile.AccountId, start, end, projectWeekRequest.ProjectId, projectWeekRequest.TaskId, userProfile.ProfileId)
+	if appErr != nil {
+		if appErr.Code == api.SystemError {
+			api.ErrorJson(w, appErr, http.StatusInternalServerError)
+		} else {
+			api.ErrorJson(w, appErr, http.StatusBadRequest)
+		}
 		return
 	}
 
@@ -335,7 +509,13 @@ func (a *TimeRouter) deleteProjectForWeek(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	serr := a.timeService.DeleteProjectForDates(userProfile.ProfileId, userProfile.AccountId, request.ProjectId, request.TaskId, start, end)
+	profileId, appErr := a.getTargetProfileId(r, userProfile)
+	if appErr != nil {
+		api.ErrorJson(w, appErr, getTargetProfileErrorStatus(appErr))
+		return
+	}
+
+	serr := a.timeService.DeleteProjectForDates(profileId, userProfile.AccountId, request.ProjectId, request.TaskId, start, end)
 	if serr != nil {
 		if serr.Code == api.SystemError {
 			api.ErrorJson(w, serr, http.StatusInternalServerError)
@@ -348,6 +528,358 @@ func (a *TimeRouter) deleteProjectForWeek(w http.ResponseWriter, r *http.Request
 	api.Json(w, r, nil)
 }
 
+func (a *TimeRouter) submitTimesheet(w http.ResponseWriter, r *http.Request) {
+	userProfile, ok := r.Context().Value(config.ProfileContextKey).(*profile.Profile)
+	if !ok || userProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid profile context", api.SystemError), http.StatusInternalServerError)
+		return
+	}
+
+	profileId, appErr := a.getTargetProfileId(r, userProfile)
+	if appErr != nil {
+		api.ErrorJson(w, appErr, getTargetProfileErrorStatus(appErr))
+		return
+	}
+
+	start, appErr := getWeekStartParameter(r, getWeekdayStart(userProfile.WeekStart))
+	if appErr != nil {
+		api.ErrorJson(w, appErr, http.StatusBadRequest)
+		return
+	}
+
+	appErr = a.timeService.SubmitTimesheet(profileId, userProfile.AccountId, start, userProfile.ProfileId)
+	if appErr != nil {
+		if appErr.Code == api.SystemError {
+			api.ErrorJson(w, appErr, http.StatusInternalServerError)
+		} else {
+			api.ErrorJson(w, appErr, http.StatusBadRequest)
+		}
+		return
+	}
+
+	api.Json(w, r, nil)
+}
+
+func (a *TimeRouter) approveTimesheet(w http.ResponseWriter, r *http.Request) {
+	a.reviewTimesheet(w, r, TimesheetApproved)
+}
+
+func (a *TimeRouter) rejectTimesheet(w http.ResponseWriter, r *http.Request) {
+	a.reviewTimesheet(w, r, TimesheetRejected)
+}
+
+// Reopen an approved week as a draft so it can be changed and submitted again
+func (a *TimeRouter) reopenTimesheet(w http.ResponseWriter, r *http.Request) {
+	a.reviewTimesheet(w, r, TimesheetDraft)
+}
+
+func (a *TimeRouter) reviewTimesheet(w http.ResponseWriter, r *http.Request, status TimesheetStatus) {
+	if r.Body == nil {
+		api.ErrorJson(w, api.NewError(nil, "Empty Body", api.InvalidJson), http.StatusBadRequest)
+		return
+	}
+
+	var request TimesheetReviewRequest
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&request); err != nil {
+		api.ErrorJson(w, api.NewError(err, "Invalid JSON", api.InvalidJson), http.StatusBadRequest)
+		return
+	}
+	defer api.CloseBody(r.Body)
+
+	if request.ProfileId <= 0 {
+		api.ErrorJson(w, api.NewFieldError(nil, "Invalid or missing profileId", api.InvalidField, "profileId"), http.StatusBadRequest)
+		return
+	}
+
+	reason := strings.TrimSpace(request.Reason)
+	if status == TimesheetRejected && !valid.IsLength(reason, 1, RejectReasonMaxLength) {
+		api.ErrorJson(w, api.NewFieldError(nil, "A reason of less than 1024 characters is required", api.FieldSize, "reason"), http.StatusBadRequest)
+		return
+	}
+
+	userProfile, ok := r.Context().Value(config.ProfileContextKey).(*profile.Profile)
+	if !ok || userProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid profile context", api.SystemError), http.StatusInternalServerError)
+		return
+	}
+
+	if request.ProfileId == userProfile.ProfileId {
+		api.ErrorJson(w, api.NewFieldError(nil, "Cannot review your own timesheet", api.InvalidField, "profileId"), http.StatusBadRequest)
+		return
+	}
+
+	start, appErr := getWeekStartParameter(r, getWeekdayStart(userProfile.WeekStart))
+	if appErr != nil {
+		api.ErrorJson(w, appErr, http.StatusBadRequest)
+		return
+	}
+
+	switch status {
+	case TimesheetApproved:
+		appErr = a.timeService.ApproveTimesheet(request.ProfileId, userProfile.AccountId, start, userProfile.ProfileId)
+	case TimesheetRejected:
+		appErr = a.timeService.RejectTimesheet(request.ProfileId, userProfile.AccountId, start, userProfile.ProfileId, reason)
+	default:
+		appErr = a.timeService.ReopenTimesheet(request.ProfileId, userProfile.AccountId, start, userProfile.ProfileId)
+	}
+
+	if appErr != nil {
+		if appErr.Code == api.SystemError {
+			api.ErrorJson(w, appErr, http.StatusInternalServerError)
+		} else {
+			api.ErrorJson(w, appErr, http.StatusBadRequest)
+		}
+		return
+	}
+
+	api.Json(w, r, nil)
+}
+
+func (a *TimeRouter) getSubmittedTimesheets(w http.ResponseWriter, r *http.Request) {
+	userProfile, ok := r.Context().Value(config.ProfileContextKey).(*profile.Profile)
+	if !ok || userProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid profile context", api.SystemError), http.StatusInternalServerError)
+		return
+	}
+
+	timesheets, appErr := a.timeService.GetSubmittedTimesheets(userProfile.AccountId)
+	if appErr != nil {
+		api.ErrorJson(w, appErr, http.StatusInternalServerError)
+		return
+	}
+
+	api.Json(w, r, NewTimesheetsResponse(timesheets))
+}
+
+func (a *TimeRouter) getTimer(w http.ResponseWriter, r *http.Request) {
+	userProfile, ok := r.Context().Value(config.ProfileContextKey).(*profile.Profile)
+	if !ok || userProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid profile context", api.SystemError), http.StatusInternalServerError)
+		return
+	}
+
+	timer, appErr := a.timeService.GetTimer(userProfile.ProfileId)
+	if appErr != nil {
+		api.ErrorJson(w, appErr, http.StatusInternalServerError)
+		return
+	}
+
+	if timer == nil {
+		api.Json(w, r, nil)
+		return
+	}
+
+	api.Json(w, r, NewTimerResponse(timer))
+}
+
+func (a *TimeRouter) startTimer(w http.ResponseWriter, r *http.Request) {
+	if r.Body == nil {
+		api.ErrorJson(w, api.NewError(nil, "Empty Body", api.InvalidJson), http.StatusBadRequest)
+		return
+	}
+
+	var request TimerRequest
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&request); err != nil {
+		api.ErrorJson(w, api.NewError(err, "Invalid JSON", api.InvalidJson), http.StatusBadRequest)
+		return
+	}
+	defer api.CloseBody(r.Body)
+
+	if request.ProjectId <= 0 {
+		api.ErrorJson(w, api.NewFieldError(nil, "Invalid or missing projectId", api.InvalidField, "projectId"), http.StatusBadRequest)
+		return
+	}
+
+	if request.TaskId <= 0 {
+		api.ErrorJson(w, api.NewFieldError(nil, "Invalid or missing taskId", api.InvalidField, "taskId"), http.StatusBadRequest)
+		return
+	}
+
+	if !valid.IsLength(request.Notes, 0, NotesMaxLength) {
+		api.ErrorJson(w, api.NewFieldError(nil, "Notes must be less than 1024 characters", api.FieldSize, "notes"), http.StatusBadRequest)
+		return
+	}
+
+	userProfile, ok := r.Context().Value(config.ProfileContextKey).(*profile.Profile)
+	if !ok || userProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid profile context", api.SystemError), http.StatusInternalServerError)
+		return
+	}
+
+	timer, appErr := a.timeService.StartTimer(&Timer{
+		AccountId: userProfile.AccountId,
+		ProfileId: userProfile.ProfileId,
+		ProjectId: request.ProjectId,
+		TaskId:    request.TaskId,
+		Notes:     valid.ToNullString(request.Notes),
+	})
+	if appErr != nil {
+		if appErr.Code == api.SystemError {
+			api.ErrorJson(w, appErr, http.StatusInternalServerError)
+		} else {
+			api.ErrorJson(w, appErr, http.StatusBadRequest)
+		}
+		return
+	}
+
+	api.Json(w, r, NewTimerResponse(timer))
+}
+
+func (a *TimeRouter) stopTimer(w http.ResponseWriter, r *http.Request) {
+	userProfile, ok := r.Context().Value(config.ProfileContextKey).(*profile.Profile)
+	if !ok || userProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid profile context", api.SystemError), http.StatusInternalServerError)
+		return
+	}
+
+	timer, entries, appErr := a.timeService.StopTimer(userProfile.ProfileId, userProfile.Timezone, userProfile.TimerRounding, userProfile.TimerRoundingMode)
+	if appErr != nil {
+		if appErr.Code == api.SystemError {
+			api.ErrorJson(w, appErr, http.StatusInternalServerError)
+		} else {
+			api.ErrorJson(w, appErr, http.StatusBadRequest)
+		}
+		return
+	}
+
+	response := &TimerStopResponse{
+		Timer:       NewTimerResponse(timer),
+		TimeEntries: []*TimeEntryResponse{},
+	}
+	for _, entry := range entries {
+		response.TimeEntries = append(response.TimeEntries, NewTimeEntryResponse(entry))
+	}
+
+	api.Json(w, r, response)
+}
+
+func (a *TimeRouter) discardTimer(w http.ResponseWriter, r *http.Request) {
+	userProfile, ok := r.Context().Value(config.ProfileContextKey).(*profile.Profile)
+	if !ok || userProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid profile context", api.SystemError), http.StatusInternalServerError)
+		return
+	}
+
+	appErr := a.timeService.DiscardTimer(userProfile.ProfileId)
+	if appErr != nil {
+		if appErr.Code == api.SystemError {
+			api.ErrorJson(w, appErr, http.StatusInternalServerError)
+		} else {
+			api.ErrorJson(w, appErr, http.StatusBadRequest)
+		}
+		return
+	}
+
+	api.Json(w, r, nil)
+}
+
+// Import time entries for any member of the account from a CSV file in the request body. A dry run validates the
+// file and returns the row errors without saving anything. Otherwise every row is saved, or none are if a row has an
+// error
+func (a *TimeRouter) importTimeEntries(w http.ResponseWriter, r *http.Request) {
+	if r.Body == nil {
+		api.ErrorJson(w, api.NewError(nil, "Empty Body", api.InvalidImport), http.StatusBadRequest)
+		return
+	}
+	defer api.CloseBody(r.Body)
+
+	dryRun := false
+	if dryRunString := r.URL.Query().Get("dryRun"); !valid.IsNull(dryRunString) {
+		var err error
+		dryRun, err = strconv.ParseBool(dryRunString)
+		if err != nil {
+			api.BadInputs(w, "dryRun must be true or false", api.InvalidField, "dryRun")
+			return
+		}
+	}
+
+	userProfile, ok := r.Context().Value(config.ProfileContextKey).(*profile.Profile)
+	if !ok || userProfile == nil {
+		api.ErrorJson(w, api.NewError(nil, "Invalid profile context", api.SystemError), http.StatusInternalServerError)
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, ImportMaxBytes)
+	result, appErr := a.timeService.ImportTimeEntries(userProfile.AccountId, userProfile.ProfileId, body, dryRun)
+	if appErr != nil {
+		if appErr.Code == api.SystemError {
+			api.ErrorJson(w, appErr, http.StatusInternalServerError)
+		} else {
+			api.ErrorJson(w, appErr, http.StatusBadRequest)
+		}
+		return
+	}
+
+	if !dryRun && len(result.Errors) > 0 {
+		api.ErrorJson(w, api.NewError(nil, fmt.Sprintf("Nothing imported. %d errors found", len(result.Errors)), api.InvalidImport,
+			api.NewErrorDetail("errors", result.Errors)), http.StatusBadRequest)
+		return
+	}
+
+	api.Json(w, r, NewTimeImportResponse(result))
+}
+
+// Get the profile the request reads or changes time for. Admins can act on behalf of another member of their account
+// with the optional profileId query parameter; changes made on behalf of someone else are logged with both profiles
+func (a *TimeRouter) getTargetProfileId(r *http.Request, userProfile *profile.Profile) (int, *api.Error) {
+	profileIdString := r.URL.Query().Get(profileIdQueryParameter)
+	if valid.IsNull(profileIdString) {
+		return userProfile.ProfileId, nil
+	}
+
+	profileId, err := strconv.Atoi(profileIdString)
+	if err != nil || profileId <= 0 {
+		return 0, api.NewFieldError(err, "Invalid profileId", api.InvalidField, profileIdQueryParameter)
+	}
+
+	if profileId == userProfile.ProfileId {
+		return profileId, nil
+	}
+
+	if !profile.IsAdmin(userProfile.Role) {
+		return 0, api.NewError(nil, "Only admins can act on behalf of another profile", api.NotAuthorized)
+	}
+
+	if appErr := a.timeService.CheckAccountMember(profileId, userProfile.AccountId); appErr != nil {
+		return 0, appErr
+	}
+
+	if r.Method != http.MethodGet {
+		logger.Log.Info(fmt.Sprintf("Profile %d changing time on behalf of profile %d in account %d: %s %s",
+			userProfile.ProfileId, profileId, userProfile.AccountId, r.Method, r.URL.Path))
+	}
+
+	return profileId, nil
+}
+
+func getTargetProfileErrorStatus(appErr *api.Error) int {
+	switch appErr.Code {
+	case api.NotAuthorized:
+		return http.StatusUnauthorized
+	case api.SystemError:
+		return http.StatusInternalServerError
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// Parse the required week start date path parameter and move it to the first day of the account week
+func getWeekStartParameter(r *http.Request, weekday time.Weekday) (time.Time, *api.Error) {
+	startDateString := chi.URLParam(r, startDatePathParameter)
+	if valid.IsNull(startDateString) {
+		return time.Time{}, api.NewFieldError(nil, "Missing start date", api.InvalidField, startDatePathParameter)
+	}
+
+	start, _, err := getWeekRangeFromDate(startDateString, weekday)
+	if err != nil {
+		return time.Time{}, api.NewFieldError(err, "Invalid format. Use ISO8061: YYYY-MM-DD", api.InvalidField, startDatePathParameter)
+	}
+
+	return start, nil
+}
+
 func NewTimeRange(timeEntries []*TimeEntry, start time.Time, end time.Time) *TimeRangeResponse {
 	var response TimeRangeResponse
 	response.Start = start.Format(config.ISOShortDateFormat)
@@ -367,6 +899,25 @@ func NewTimeRange(timeEntries []*TimeEntry, start time.Time, end time.Time) *Tim
 	return &response
 }
 
+func NewTimeImportResponse(result *TimeImport) *TimeImportResponse {
+	if result == nil {
+		return nil
+	}
+
+	response := &TimeImportResponse{
+		DryRun: result.DryRun,
+		Rows:   result.Rows,
+		Hours:  result.Hours(),
+		Errors: result.Errors,
+	}
+
+	if !result.DryRun && len(result.Errors) == 0 {
+		response.Imported = len(result.Entries)
+	}
+
+	return response
+}
+
 func NewTimeEntryResponse(entry *TimeEntry) *TimeEntryResponse {
 	return &TimeEntryResponse{
 		Day:         entry.Day.Format(config.ISOShortDateFormat),
@@ -376,5 +927,56 @@ func NewTimeEntryResponse(entry *TimeEntry) *TimeEntryResponse {
 		TaskName:    entry.TaskName,
 		ProjectId:   entry.ProjectId,
 		TaskId:      entry.TaskId,
+		Notes:       entry.Notes.String,
+		UpdatedBy:   entry.UpdatedBy.Int64,
+	}
+}
+
+func NewTimesheetsResponse(timesheets []*Timesheet) []*TimesheetResponse {
+	response := []*TimesheetResponse{}
+	for _, timesheet := range timesheets {
+		response = append(response, NewTimesheetResponse(timesheet))
+	}
+
+	return response
+}
+
+func NewTimesheetResponse(timesheet *Timesheet) *TimesheetResponse {
+	response := &TimesheetResponse{
+		StartDate:    timesheet.StartDate.Format(config.ISOShortDateFormat),
+		EndDate:      timesheet.StartDate.AddDate(0, 0, 6).Format(config.ISOShortDateFormat),
+		ProfileId:    timesheet.ProfileId,
+		FirstName:    timesheet.FirstName,
+		LastName:     timesheet.LastName,
+		Email:        timesheet.Email,
+		Status:       string(timesheet.Status),
+		Hours:        timesheet.Hours,
+		RejectReason: timesheet.RejectReason.String,
+	}
+
+	if timesheet.Submitted.Valid {
+		response.Submitted = &timesheet.Submitted.Time
+	}
+
+	if timesheet.SubmittedBy.Valid {
+		response.SubmittedBy = timesheet.SubmittedBy.Int64
+	}
+
+	if timesheet.Reviewed.Valid {
+		response.Reviewed = &timesheet.Reviewed.Time
+	}
+
+	return response
+}
+
+func NewTimerResponse(timer *Timer) *TimerResponse {
+	return &TimerResponse{
+		ProjectId:   timer.ProjectId,
+		TaskId:      timer.TaskId,
+		ClientName:  timer.ClientName,
+		ProjectName: timer.ProjectName,
+		TaskName:    timer.TaskName,
+		Notes:       timer.Notes.String,
+		Started:     timer.Started,
 	}
 }
@@ -0,0 +1,315 @@
+package timesheet
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bryanmorgan/time-tracking-api/api"
+	"github.com/bryanmorgan/time-tracking-api/config"
+	"github.com/bryanmorgan/time-tracking-api/valid"
+)
+
+const (
+	ImportMaxRows     = 10000
+	ImportMaxBytes    = 10 << 20
+	ImportMaxDayHours = 24
+)
+
+// Import columns and the header names accepted for each
+const (
+	importDate    = "date"
+	importEmail   = "email"
+	importClient  = "client"
+	importProject = "project"
+	importTask    = "task"
+	importHours   = "hours"
+	importNotes   = "notes"
+)
+
+var importColumnNames = map[string]string{
+	"date":        importDate,
+	"day":         importDate,
+	"email":       importEmail,
+	"personemail": importEmail,
+	"client":      importClient,
+	"clientname":  importClient,
+	"project":     importProject,
+	"projectname": importProject,
+	"projectcode": importProject,
+	"task":        importTask,
+	"taskname":    importTask,
+	"hours":       importHours,
+	"notes":       importNotes,
+	"description": importNotes,
+}
+
+var requiredImportColumns = []string{importDate, importEmail, importClient, importProject, importTask, importHours}
+
+var TooManyImportRowsError = errors.New("too many import rows")
+
+// One row of an imported CSV file before it is validated. Row is the line number in the file, counting the header
+type ImportRow struct {
+	Row     int
+	Date    string
+	Email   string
+	Client  string
+	Project string
+	Task    string
+	Hours   string
+	Notes   string
+}
+
+// An account member that imported rows can be matched to by email
+type ImportMember struct {
+	ProfileId int    `json:"-" db:"profile_id"`
+	Email     string `json:"-"`
+}
+
+// An active project task that imported rows can be matched to by client, project and task name. Projects can also be
+// matched by code
+type ImportProjectTask struct {
+	ClientName  string         `json:"-" db:"client_name"`
+	ProjectId   int            `json:"-" db:"project_id"`
+	ProjectName string         `json:"-" db:"project_name"`
+	ProjectCode sql.NullString `json:"-" db:"code"`
+	TaskId      int            `json:"-" db:"task_id"`
+	TaskName    string         `json:"-" db:"task_name"`
+}
+
+// The result of validating, and unless it is a dry run saving, an import. Errors has an error for each invalid field
+type TimeImport struct {
+	DryRun  bool
+	Rows    int
+	Entries []*TimeEntry
+	Errors  []*api.Error
+}
+
+func (t *TimeImport) Hours() float64 {
+	hours := 0.0
+	for _, entry := range t.Entries {
+		hours += entry.Hours
+	}
+
+	return hours
+}
+
+// The account's members by email and its clients, projects and project tasks by lower case name
+type importLookup struct {
+	members      map[string]int
+	clients      map[string]bool
+	projects     map[string]bool
+	projectTasks map[string]*ImportProjectTask
+}
+
+// Read the rows of an import CSV file. The first line is a header naming the columns, in any order. Returns an error
+// if the file can't be read or is missing a required column
+func ParseTimeImport(reader io.Reader) ([]*ImportRow, *api.Error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if err == io.EOF {
+		return nil, api.NewError(nil, "Import file is empty", api.InvalidImport)
+	}
+	if err != nil {
+		return nil, api.NewError(err, "Invalid CSV file", api.InvalidImport)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		// Spreadsheets often save CSV files with a byte order mark
+		name = strings.TrimPrefix(name, "\ufeff")
+		name = strings.NewReplacer(" ", "", "_", "", "-", "").Replace(strings.ToLower(strings.TrimSpace(name)))
+		if column, ok := importColumnNames[name]; ok {
+			if _, duplicate := columns[column]; !duplicate {
+				columns[column] = i
+			}
+		}
+	}
+
+	for _, column := range requiredImportColumns {
+		if _, ok := columns[column]; !ok {
+			return nil, api.NewFieldError(nil, "Missing "+column+" column", api.InvalidImport, column)
+		}
+	}
+
+	value := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []*ImportRow
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, api.NewError(err, "Invalid CSV file", api.InvalidImport, api.NewErrorDetail("row", parseErr.StartLine))
+		}
+		if err != nil {
+			return nil, api.NewError(err, "Invalid CSV file", api.InvalidImport)
+		}
+
+		// Blank lines are skipped by the reader, so take the line number from the reader
+		line, _ := csvReader.FieldPos(0)
+
+		if isBlankRecord(record) {
+			continue
+		}
+
+		if len(rows) == ImportMaxRows {
+			return nil, api.NewError(TooManyImportRowsError, fmt.Sprintf("Imports are limited to %d rows", ImportMaxRows), api.InvalidImport)
+		}
+
+		rows = append(rows, &ImportRow{
+			Row:     line,
+			Date:    value(record, importDate),
+			Email:   value(record, importEmail),
+			Client:  value(record, importClient),
+			Project: value(record, importProject),
+			Task:    value(record, importTask),
+			Hours:   value(record, importHours),
+			Notes:   value(record, importNotes),
+		})
+	}
+
+	if len(rows) == 0 {
+		return nil, api.NewError(nil, "Import file has no rows", api.InvalidImport)
+	}
+
+	return rows, nil
+}
+
+func isBlankRecord(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newImportLookup(members []*ImportMember, projectTasks []*ImportProjectTask) *importLookup {
+	lookup := &importLookup{
+		members:      make(map[string]int),
+		clients:      make(map[string]bool),
+		projects:     make(map[string]bool),
+		projectTasks: make(map[string]*ImportProjectTask),
+	}
+
+	for _, member := range members {
+		lookup.members[strings.ToLower(member.Email)] = member.ProfileId
+	}
+
+	// The first project task with a name wins when names are repeated
+	add := func(projectTask *ImportProjectTask, project string) {
+		lookup.projects[importKey(projectTask.ClientName, project)] = true
+		key := importKey(projectTask.ClientName, project, projectTask.TaskName)
+		if _, ok := lookup.projectTasks[key]; !ok {
+			lookup.projectTasks[key] = projectTask
+		}
+	}
+
+	for _, projectTask := range projectTasks {
+		lookup.clients[importKey(projectTask.ClientName)] = true
+		add(projectTask, projectTask.ProjectName)
+		if projectTask.ProjectCode.Valid && projectTask.ProjectCode.String != "" {
+			add(projectTask, projectTask.ProjectCode.String)
+		}
+	}
+
+	return lookup
+}
+
+func importKey(names ...string) string {
+	for i, name := range names {
+		names[i] = strings.ToLower(strings.TrimSpace(name))
+	}
+
+	return strings.Join(names, "\x00")
+}
+
+// Validate the rows and match them to the account's members and project tasks. Returns a time entry for every valid
+// row and an error for each invalid field
+func (l *importLookup) resolve(rows []*ImportRow, accountId int, updatedBy int) ([]*TimeEntry, []*api.Error) {
+	var entries []*TimeEntry
+	var rowErrors []*api.Error
+	seen := make(map[string]int)
+
+	for _, row := range rows {
+		rowError := func(message string, code string, field string) {
+			rowErrors = append(rowErrors, api.NewError(nil, message, code,
+				api.NewErrorDetail("row", row.Row),
+				api.NewErrorDetail("field", field)))
+		}
+		errorCount := len(rowErrors)
+
+		day, err := time.Parse(config.ISOShortDateFormat, row.Date)
+		if err != nil {
+			rowError("Invalid date. Use ISO8061: YYYY-MM-DD", api.InvalidField, importDate)
+		}
+
+		profileId, ok := l.members[strings.ToLower(row.Email)]
+		if !ok {
+			rowError("No account member with email "+row.Email, api.ProfileNotFound, importEmail)
+		}
+
+		projectTask := l.projectTasks[importKey(row.Client, row.Project, row.Task)]
+		if projectTask == nil {
+			if !l.clients[importKey(row.Client)] {
+				rowError("No active client named "+row.Client, api.InvalidClient, importClient)
+			} else if !l.projects[importKey(row.Client, row.Project)] {
+				rowError("No active project named "+row.Project+" for client "+row.Client, api.InvalidProject, importProject)
+			} else {
+				rowError("Task "+row.Task+" is not assigned to project "+row.Project, api.InvalidTask, importTask)
+			}
+		}
+
+		hours, err := strconv.ParseFloat(row.Hours, 64)
+		if err != nil || hours <= 0 || hours > ImportMaxDayHours {
+			rowError(fmt.Sprintf("Hours must be a number greater than 0 and at most %d", ImportMaxDayHours), api.InvalidField, importHours)
+		}
+
+		if !valid.IsLength(row.Notes, 0, NotesMaxLength) {
+			rowError(fmt.Sprintf("Notes must be less than %d characters", NotesMaxLength), api.FieldSize, importNotes)
+		}
+
+		if len(rowErrors) > errorCount {
+			continue
+		}
+
+		// Entries are saved with an upsert, so a second row for the same entry would replace the first
+		key := fmt.Sprintf("%d:%d:%d:%s", profileId, projectTask.ProjectId, projectTask.TaskId, row.Date)
+		if firstRow, ok := seen[key]; ok {
+			rowError(fmt.Sprintf("Duplicate of row %d for the same person, project, task and date", firstRow), api.InvalidField, importDate)
+			continue
+		}
+		seen[key] = row.Row
+
+		entries = append(entries, &TimeEntry{
+			Day:       day,
+			Hours:     hours,
+			AccountId: accountId,
+			ProfileId: profileId,
+			ProjectId: projectTask.ProjectId,
+			TaskId:    projectTask.TaskId,
+			Notes:     valid.ToNullString(row.Notes),
+			UpdatedBy: sql.NullInt64{Int64: int64(updatedBy), Valid: true},
+		})
+	}
+
+	return entries, rowErrors
+}
@@ -0,0 +1,68 @@
+package timesheet
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/spf13/viper"
+)
+
+const (
+	// Used when timesheetReminder.expectedHours is not set
+	defaultExpectedWeeklyHours = 40.0
+
+	// Used when timesheetReminder.hour is not set
+	defaultReminderHour = 9
+)
+
+// An account member who logged fewer hours than expected in a week
+type MissingTimesheet struct {
+	ProfileId int     `json:"-" db:"profile_id"`
+	FirstName string  `json:"-" db:"first_name"`
+	LastName  string  `json:"-" db:"last_name"`
+	Email     string  `json:"-"`
+	Language  string  `json:"-"`
+	Reminders bool    `json:"-" db:"timesheet_reminders"`
+	Hours     float64 `json:"-"`
+}
+
+// An owner or admin who receives the digest of missing timesheets
+type ReminderRecipient struct {
+	FirstName string `json:"-" db:"first_name"`
+	Email     string `json:"-"`
+	Language  string `json:"-"`
+}
+
+// Hours each member is expected to log in a week
+func ExpectedWeeklyHours() float64 {
+	hours := viper.GetFloat64("timesheetReminder.expectedHours")
+	if hours <= 0 {
+		return defaultExpectedWeeklyHours
+	}
+
+	return hours
+}
+
+func reminderHour() int {
+	if !viper.IsSet("timesheetReminder.hour") {
+		return defaultReminderHour
+	}
+
+	return viper.GetInt("timesheetReminder.hour")
+}
+
+// Returns the start of the latest week, in the location, whose reminder time has passed and whether reminders for it
+// are due. A week's reminder time is the reminder hour on the first day of the following week. Reminders are due
+// until the last reminded week catches up, so a run that is late or missed one still sends them
+func reminderWeek(now time.Time, location *time.Location, weekdayStart time.Weekday, hour int, lastReminded pq.NullTime) (time.Time, bool) {
+	local := now.In(location)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+	weekStart := getWeekdayStartDate(today, weekdayStart)
+	if today.Equal(weekStart) && local.Hour() < hour {
+		weekStart = weekStart.AddDate(0, 0, -7)
+	}
+
+	week := weekStart.AddDate(0, 0, -7)
+	due := !lastReminded.Valid || lastReminded.Time.Before(week)
+	return week, due
+}
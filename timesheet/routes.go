@@ -27,9 +27,32 @@ func (a *TimeRouter) Router() *chi.Mux {
 		r.Use(a.profileRouter.ValidateProfileHandler)
 		r.Use(a.profileRouter.ValidateSessionHandler)
 
+		// Every role can track their own time
+		r.Use(a.profileRouter.RolePermissionHandler(profile.AllRoles...))
+
 		// Time Entries
 		r.Get("/week", a.getTimeEntriesForWeek)
 		r.Get("/week/{startDate}", a.getTimeEntriesForWeek)
+		r.Get("/search", a.searchTimeEntries)
+
+		// Timers
+		r.Get("/timer/current", a.getTimer)
+		r.Post("/timer/start", a.startTimer)
+		r.Post("/timer/stop", a.stopTimer)
+		r.Post("/timer/discard", a.discardTimer)
+
+		// Timesheet approval
+		r.Post("/week/{startDate}/submit", a.submitTimesheet)
+		r.Group(func(r chi.Router) {
+			r.Use(a.profileRouter.AdminPermissionHandler)
+
+			r.Get("/submitted", a.getSubmittedTimesheets)
+			r.Post("/week/{startDate}/approve", a.approveTimesheet)
+			r.Post("/week/{startDate}/reject", a.rejectTimesheet)
+			r.Post("/week/{startDate}/reopen", a.reopenTimesheet)
+
+			r.Post("/import", a.importTimeEntries)
+		})
 
 		r.Put("/", a.updateTimeEntries)
 		r.Post("/project/week", a.addProjectToWeek)
@@ -1,10 +1,19 @@
 package timesheet
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strconv"
 	"time"
 
 	"github.com/bryanmorgan/time-tracking-api/api"
+	"github.com/bryanmorgan/time-tracking-api/config"
 	"github.com/bryanmorgan/time-tracking-api/database"
+	"github.com/bryanmorgan/time-tracking-api/emails"
+	"github.com/bryanmorgan/time-tracking-api/logger"
+	"github.com/bryanmorgan/time-tracking-api/profile"
 )
 
 // Compile Only: ensure interface is implemented
@@ -12,12 +21,30 @@ var _ TimeService = &TimeResource{}
 
 type TimeService interface {
 	GetTimeEntriesForRange(profileId int, accountId int, start time.Time, end time.Time) ([]*TimeEntry, *api.Error)
+	SearchTimeEntries(profileId int, accountId int, start time.Time, end time.Time, search string) ([]*TimeEntry, *api.Error)
 
 	SaveOrUpdateTimeEntries(entries []*TimeEntry) *api.Error
 	UpdateTimeEntries(entries []*TimeEntry) *api.Error
-	AddInitialProjectTimeEntries(profileId int, accountId int, start time.Time, end time.Time, projectId int, taskId int) *api.Error
+	AddInitialProjectTimeEntries(profileId int, accountId int, start time.Time, end time.Time, projectId int, taskId int, updatedBy int) *api.Error
 
 	DeleteProjectForDates(profileId int, accountId int, projectId int, taskId int, start time.Time, end time.Time) *api.Error
+
+	GetTimesheet(profileId int, accountId int, start time.Time) (*Timesheet, *api.Error)
+	GetSubmittedTimesheets(accountId int) ([]*Timesheet, *api.Error)
+	SubmitTimesheet(profileId int, accountId int, start time.Time, submittedBy int) *api.Error
+	ApproveTimesheet(profileId int, accountId int, start time.Time, reviewerId int) *api.Error
+	RejectTimesheet(profileId int, accountId int, start time.Time, reviewerId int, reason string) *api.Error
+	ReopenTimesheet(profileId int, accountId int, start time.Time, reviewerId int) *api.Error
+
+	CheckAccountMember(profileId int, accountId int) *api.Error
+	ImportTimeEntries(accountId int, updatedBy int, reader io.Reader, dryRun bool) (*TimeImport, *api.Error)
+
+	GetTimer(profileId int) (*Timer, *api.Error)
+	StartTimer(timer *Timer) (*Timer, *api.Error)
+	StopTimer(profileId int, timezone string, roundingMinutes int, roundingMode profile.TimerRoundingMode) (*Timer, []*TimeEntry, *api.Error)
+	DiscardTimer(profileId int) *api.Error
+
+	SendTimesheetReminders(ctx context.Context, now time.Time) (int, *api.Error)
 }
 
 type TimeResource struct {
@@ -37,26 +64,61 @@ func (c *TimeResource) GetTimeEntriesForRange(profileId int, accountId int, star
 	return timeEntries, nil
 }
 
+func (c *TimeResource) SearchTimeEntries(profileId int, accountId int, start time.Time, end time.Time, search string) ([]*TimeEntry, *api.Error) {
+	timeEntries, err := c.store.SearchTimeEntries(profileId, accountId, start, end, search)
+	if err != nil {
+		return nil, api.NewError(err, "Could not search time entries", api.SystemError)
+	}
+
+	return timeEntries, nil
+}
+
 func (c *TimeResource) SaveOrUpdateTimeEntries(entries []*TimeEntry) *api.Error {
 	err := c.store.SaveOrUpdateTimeEntries(entries)
+	if err == TimesheetApprovedError {
+		return api.NewError(err, "Time entries in an approved week cannot be changed", api.TimesheetApproved)
+	}
+
+	if err == TimeInvoicedError {
+		return api.NewError(err, "Invoiced time entries cannot be changed", api.TimeInvoiced)
+	}
+
 	if err != nil {
 		return api.NewError(err, "Failed to save or update time entries", api.SystemError)
 	}
 
+	c.checkBudgetAlerts(entries)
 	return nil
 }
 
 func (c *TimeResource) UpdateTimeEntries(entries []*TimeEntry) *api.Error {
 	err := c.store.UpdateTimeEntries(entries)
+	if err == TimesheetApprovedError {
+		return api.NewError(err, "Time entries in an approved week cannot be changed", api.TimesheetApproved)
+	}
+
+	if err == TimeInvoicedError {
+		return api.NewError(err, "Invoiced time entries cannot be changed", api.TimeInvoiced)
+	}
+
 	if err != nil {
 		return api.NewError(err, "Failed to update time entries", api.SystemError)
 	}
 
+	c.checkBudgetAlerts(entries)
 	return nil
 }
 
-func (c *TimeResource) AddInitialProjectTimeEntries(profileId int, accountId int, start time.Time, end time.Time, projectId int, taskId int) *api.Error {
-	err := c.store.AddInitialProjectTimeEntries(profileId, accountId, start, end, projectId, taskId)
+func (c *TimeResource) AddInitialProjectTimeEntries(profileId int, accountId int, start time.Time, end time.Time, projectId int, taskId int, updatedBy int) *api.Error {
+	err := c.store.AddInitialProjectTimeEntries(profileId, accountId, start, end, projectId, taskId, updatedBy)
+	if err == TimesheetApprovedError {
+		return api.NewError(err, "Time entries in an approved week cannot be changed", api.TimesheetApproved)
+	}
+
+	if err == TimeInvoicedError {
+		return api.NewError(err, "Invoiced time entries cannot be changed", api.TimeInvoiced)
+	}
+
 	if err != nil {
 		return api.NewError(err, "Failed to add initial project time entries", api.SystemError)
 	}
@@ -66,6 +128,14 @@ func (c *TimeResource) AddInitialProjectTimeEntries(profileId int, accountId int
 
 func (c *TimeResource) DeleteProjectForDates(profileId int, accountId int, projectId int, taskId int, start time.Time, end time.Time) *api.Error {
 	err := c.store.DeleteProjectForDates(profileId, accountId, projectId, taskId, start, end)
+	if err == TimesheetApprovedError {
+		return api.NewError(err, "Time entries in an approved week cannot be changed", api.TimesheetApproved)
+	}
+
+	if err == TimeInvoicedError {
+		return api.NewError(err, "Invoiced time entries cannot be changed", api.TimeInvoiced)
+	}
+
 	if err == database.NoRowAffectedError {
 		return api.NewError(err, "No matching project/task time entries found", api.InvalidField)
 	}
@@ -76,3 +146,415 @@ func (c *TimeResource) DeleteProjectForDates(profileId int, accountId int, proje
 
 	return nil
 }
+
+// Get the timesheet status for the week. Weeks that have never been submitted are returned as drafts
+func (c *TimeResource) GetTimesheet(profileId int, accountId int, start time.Time) (*Timesheet, *api.Error) {
+	timesheet, err := c.store.GetTimesheet(profileId, accountId, start)
+	if err != nil {
+		return nil, api.NewError(err, "Could not get timesheet", api.SystemError)
+	}
+
+	if timesheet == nil {
+		timesheet = &Timesheet{
+			AccountId: accountId,
+			ProfileId: profileId,
+			StartDate: start,
+			Status:    TimesheetDraft,
+		}
+	}
+
+	return timesheet, nil
+}
+
+func (c *TimeResource) GetSubmittedTimesheets(accountId int) ([]*Timesheet, *api.Error) {
+	timesheets, err := c.store.GetTimesheetsByStatus(accountId, TimesheetSubmitted)
+	if err != nil {
+		return nil, api.NewError(err, "Could not get submitted timesheets", api.SystemError)
+	}
+
+	return timesheets, nil
+}
+
+func (c *TimeResource) SubmitTimesheet(profileId int, accountId int, start time.Time, submittedBy int) *api.Error {
+	err := c.store.SubmitTimesheet(profileId, accountId, start, submittedBy)
+	if err == database.NoRowAffectedError {
+		return api.NewError(err, "Timesheet is already submitted or approved", api.InvalidTimesheetStatus)
+	}
+
+	if err != nil {
+		return api.NewError(err, "Failed to submit timesheet", api.SystemError)
+	}
+
+	return nil
+}
+
+func (c *TimeResource) ApproveTimesheet(profileId int, accountId int, start time.Time, reviewerId int) *api.Error {
+	err := c.store.ReviewTimesheet(profileId, accountId, start, TimesheetApproved, reviewerId, sql.NullString{})
+	if err == database.NoRowAffectedError {
+		return api.NewError(err, "No submitted timesheet found", api.InvalidTimesheetStatus)
+	}
+
+	if err != nil {
+		return api.NewError(err, "Failed to approve timesheet", api.SystemError)
+	}
+
+	return nil
+}
+
+func (c *TimeResource) RejectTimesheet(profileId int, accountId int, start time.Time, reviewerId int, reason string) *api.Error {
+	err := c.store.ReviewTimesheet(profileId, accountId, start, TimesheetRejected, reviewerId, sql.NullString{String: reason, Valid: true})
+	if err == database.NoRowAffectedError {
+		return api.NewError(err, "No submitted timesheet found", api.InvalidTimesheetStatus)
+	}
+
+	if err != nil {
+		return api.NewError(err, "Failed to reject timesheet", api.SystemError)
+	}
+
+	return nil
+}
+
+func (c *TimeResource) ReopenTimesheet(profileId int, accountId int, start time.Time, reviewerId int) *api.Error {
+	err := c.store.ReopenTimesheet(profileId, accountId, start, reviewerId)
+	if err == database.NoRowAffectedError {
+		return api.NewError(err, "No approved timesheet found", api.InvalidTimesheetStatus)
+	}
+
+	if err != nil {
+		return api.NewError(err, "Failed to reopen timesheet", api.SystemError)
+	}
+
+	return nil
+}
+
+// Returns a ProfileNotFound error unless the profile is a valid member of the account
+func (c *TimeResource) CheckAccountMember(profileId int, accountId int) *api.Error {
+	member, err := c.store.IsAccountMember(profileId, accountId)
+	if err != nil {
+		return api.NewError(err, "Could not get account member", api.SystemError)
+	}
+
+	if !member {
+		return api.NewFieldError(nil, "Profile is not a member of the account", api.ProfileNotFound, "profileId")
+	}
+
+	return nil
+}
+
+// Validate the CSV rows and match them to the account's members and project tasks. Unless it is a dry run, or a row
+// is invalid, the entries are then saved in a single transaction
+func (c *TimeResource) ImportTimeEntries(accountId int, updatedBy int, reader io.Reader, dryRun bool) (*TimeImport, *api.Error) {
+	rows, apperr := ParseTimeImport(reader)
+	if apperr != nil {
+		return nil, apperr
+	}
+
+	members, err := c.store.GetImportMembers(accountId)
+	if err != nil {
+		return nil, api.NewError(err, "Failed to get account members", api.SystemError)
+	}
+
+	projectTasks, err := c.store.GetImportProjectTasks(accountId)
+	if err != nil {
+		return nil, api.NewError(err, "Failed to get project tasks", api.SystemError)
+	}
+
+	result := &TimeImport{DryRun: dryRun, Rows: len(rows)}
+	result.Entries, result.Errors = newImportLookup(members, projectTasks).resolve(rows, accountId, updatedBy)
+	if dryRun || len(result.Errors) > 0 {
+		return result, nil
+	}
+
+	if apperr := c.SaveOrUpdateTimeEntries(result.Entries); apperr != nil {
+		return nil, apperr
+	}
+
+	logger.Log.Info(fmt.Sprintf("Profile %d imported %d time entries into account %d", updatedBy, len(result.Entries), accountId))
+	return result, nil
+}
+
+func (c *TimeResource) GetTimer(profileId int) (*Timer, *api.Error) {
+	timer, err := c.store.GetTimer(profileId)
+	if err != nil {
+		return nil, api.NewError(err, "Could not get timer", api.SystemError)
+	}
+
+	return timer, nil
+}
+
+func (c *TimeResource) StartTimer(timer *Timer) (*Timer, *api.Error) {
+	err := c.store.StartTimer(timer)
+	if err == TimerRunningError {
+		return nil, api.NewError(err, "A timer is already running", api.TimerRunning)
+	}
+
+	if err == InvalidProjectTaskError {
+		return nil, api.NewError(err, "Project and task not found", api.InvalidProject)
+	}
+
+	if err != nil {
+		return nil, api.NewError(err, "Failed to start timer", api.SystemError)
+	}
+
+	return c.GetTimer(timer.ProfileId)
+}
+
+// Stop the running timer, rounding the elapsed time and splitting it at midnight in the profile timezone
+func (c *TimeResource) StopTimer(profileId int, timezone string, roundingMinutes int, roundingMode profile.TimerRoundingMode) (*Timer, []*TimeEntry, *api.Error) {
+	timer, err := c.store.GetTimer(profileId)
+	if err != nil {
+		return nil, nil, api.NewError(err, "Could not get timer", api.SystemError)
+	}
+
+	if timer == nil {
+		return nil, nil, api.NewError(nil, "No timer is running", api.TimerNotRunning)
+	}
+
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		logger.Log.Error("Could not load timezone: " + timezone)
+		location = time.UTC
+	}
+
+	stopped := time.Now()
+	duration := roundTimerDuration(stopped.Sub(timer.Started), roundingMinutes, roundingMode)
+	entries := splitTimerByDay(timer.Started, stopped, duration, location)
+	for _, entry := range entries {
+		entry.AccountId = timer.AccountId
+		entry.ProfileId = timer.ProfileId
+		entry.ProjectId = timer.ProjectId
+		entry.TaskId = timer.TaskId
+		entry.ClientName = timer.ClientName
+		entry.ProjectName = timer.ProjectName
+		entry.TaskName = timer.TaskName
+		entry.Notes = timer.Notes
+		entry.UpdatedBy = sql.NullInt64{Int64: int64(timer.ProfileId), Valid: true}
+	}
+
+	err = c.store.StopTimer(timer, entries)
+	if err == TimesheetApprovedError {
+		return nil, nil, api.NewError(err, "Time entries in an approved week cannot be changed", api.TimesheetApproved)
+	}
+
+	if err == TimeInvoicedError {
+		return nil, nil, api.NewError(err, "Invoiced time entries cannot be changed", api.TimeInvoiced)
+	}
+
+	if err == database.NoRowAffectedError {
+		return nil, nil, api.NewError(err, "No timer is running", api.TimerNotRunning)
+	}
+
+	if err != nil {
+		return nil, nil, api.NewError(err, "Failed to stop timer", api.SystemError)
+	}
+
+	c.checkBudgetAlerts(entries)
+	return timer, entries, nil
+}
+
+func (c *TimeResource) DiscardTimer(profileId int) *api.Error {
+	err := c.store.DeleteTimer(profileId)
+	if err == database.NoRowAffectedError {
+		return api.NewError(err, "No timer is running", api.TimerNotRunning)
+	}
+
+	if err != nil {
+		return api.NewError(err, "Failed to discard timer", api.SystemError)
+	}
+
+	return nil
+}
+
+// --- Budget
+
+// Email the account owners and admins when saved time moves a project, or a task in it, past a budget alert
+// threshold. Alert failures are logged and never fail the time entry change
+func (c *TimeResource) checkBudgetAlerts(entries []*TimeEntry) {
+	checked := make(map[int]bool)
+	thresholds := BudgetAlertThresholds()
+
+	for _, entry := range entries {
+		if checked[entry.ProjectId] {
+			continue
+		}
+		checked[entry.ProjectId] = true
+
+		budget, err := c.store.GetProjectBudget(entry.ProjectId, entry.AccountId)
+		if err != nil {
+			logger.Log.Error("Could not get project budget: " + err.Error())
+			continue
+		}
+
+		if budget == nil {
+			continue
+		}
+
+		percent := budget.PercentUsed()
+		if threshold := crossedBudgetThreshold(percent, budget.AlertPercent, thresholds); threshold > 0 {
+			err = c.store.UpdateBudgetAlertPercent(budget.ProjectId, budget.AccountId, threshold)
+			if budgetAlertRecorded(err) {
+				c.sendBudgetAlert(budget, budget.ProjectName, threshold, percent)
+			}
+		}
+
+		for _, taskBudget := range budget.Tasks {
+			percent := taskBudget.PercentUsed()
+			if threshold := crossedBudgetThreshold(percent, taskBudget.AlertPercent, thresholds); threshold > 0 {
+				err = c.store.UpdateTaskBudgetAlertPercent(budget.ProjectId, taskBudget.TaskId, budget.AccountId, threshold)
+				if budgetAlertRecorded(err) {
+					c.sendBudgetAlert(budget, budget.ProjectName+" / "+taskBudget.TaskName, threshold, percent)
+				}
+			}
+		}
+	}
+}
+
+// Returns true if the alert threshold was recorded by this request. A NoRowAffectedError means another request
+// already recorded it and sent the alert
+func budgetAlertRecorded(err error) bool {
+	if err == database.NoRowAffectedError {
+		return false
+	}
+
+	if err != nil {
+		logger.Log.Error("Could not update budget alert: " + err.Error())
+		return false
+	}
+
+	return true
+}
+
+func (c *TimeResource) sendBudgetAlert(budget *ProjectBudget, name string, threshold int, percent float64) {
+	recipients, err := c.store.GetBudgetAlertRecipients(budget.AccountId)
+	if err != nil {
+		logger.Log.Error("Could not get budget alert recipients: " + err.Error())
+		return
+	}
+
+	projectUrl := config.CreateUrl("/projects/"+strconv.Itoa(budget.ProjectId), "")
+	for _, recipient := range recipients {
+		to := emails.Recipient{Name: recipient.FirstName, Email: recipient.Email, Language: recipient.Language}
+		branding := emails.Branding{Signature: recipient.EmailSignature.String, LogoUrl: recipient.EmailLogoUrl.String}
+		err = emails.SendBudgetAlertEmail(to, branding, name, threshold, percent, projectUrl)
+		if err != nil {
+			logger.Log.Error("Failed to send budget alert email: " + err.Error())
+		}
+	}
+}
+
+// --- Reminders
+
+// Email reminders to members who logged fewer than the expected hours last week, and a digest of them to the owners
+// and admins. Each account is reminded on the first day of its week, in its timezone, once the reminder hour has
+// passed, or by the first run after that. Returns the number of accounts reminded. Stops between accounts when the
+// context is cancelled, leaving the rest for the next run
+func (c *TimeResource) SendTimesheetReminders(ctx context.Context, now time.Time) (int, *api.Error) {
+	accounts, err := c.store.GetReminderAccounts()
+	if err != nil {
+		return 0, api.NewError(err, "Could not get accounts for timesheet reminders", api.SystemError)
+	}
+
+	hour := reminderHour()
+	reminded := 0
+	for _, account := range accounts {
+		if ctx.Err() != nil {
+			return reminded, api.NewError(ctx.Err(), "Timesheet reminders cancelled", api.SystemError)
+		}
+
+		location, err := time.LoadLocation(account.AccountTimezone)
+		if err != nil {
+			logger.Log.Warn("Invalid account timezone for timesheet reminders", logger.Int("accountId", account.AccountId), logger.String("timezone", account.AccountTimezone))
+			location = time.UTC
+		}
+
+		weekStart, due := reminderWeek(now, location, getWeekdayStart(account.WeekStart), hour, account.ReminderWeek)
+		if !due {
+			// Already reminded for this week
+			continue
+		}
+
+		// The week is only recorded once every email is sent, so a failed run is retried by the next one
+		if err := c.sendAccountReminders(account, weekStart); err != nil {
+			logger.Log.Error("Timesheet reminders will be retried", logger.Int("accountId", account.AccountId), logger.Error(err))
+			continue
+		}
+
+		err = c.store.UpdateReminderWeek(account.AccountId, weekStart)
+		if err == database.NoRowAffectedError {
+			// Another run reminded the account for this week
+			continue
+		}
+
+		if err != nil {
+			logger.Log.Error("Could not update timesheet reminder week: " + err.Error())
+			continue
+		}
+
+		reminded++
+	}
+
+	return reminded, nil
+}
+
+// Email failures are logged so one person cannot stop the others being reminded. Returns the last error so the week is
+// retried when any email was not sent
+func (c *TimeResource) sendAccountReminders(account *profile.Account, weekStart time.Time) error {
+	weekEnd := weekStart.AddDate(0, 0, 6)
+	expectedHours := ExpectedWeeklyHours()
+
+	missing, err := c.store.GetMissingTimesheets(account.AccountId, weekStart, weekEnd, expectedHours)
+	if err != nil {
+		return err
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	var sendErr error
+
+	branding := account.EmailBranding()
+	week := weekStart.Format(config.ISOShortDateFormat)
+	timesheetUrl := config.CreateUrl("/time", "week="+week)
+
+	digest := make([]*emails.MissingTimesheet, 0, len(missing))
+	for _, member := range missing {
+		digest = append(digest, &emails.MissingTimesheet{
+			Name:  member.FirstName + " " + member.LastName,
+			Hours: emails.FormatHours(member.Hours),
+		})
+
+		if !member.Reminders {
+			continue
+		}
+
+		to := emails.Recipient{Name: member.FirstName, Email: member.Email, Language: member.Language}
+		err = emails.SendTimesheetReminderEmail(to, branding, account.Company, weekStart, member.Hours, expectedHours, timesheetUrl)
+		if err != nil {
+			logger.Log.Error("Failed to send timesheet reminder email: " + err.Error())
+			sendErr = err
+		}
+	}
+
+	recipients, err := c.store.GetReminderDigestRecipients(account.AccountId)
+	if err != nil {
+		return err
+	}
+
+	reportUrl := config.CreateUrl("/reports", "from="+week+"&to="+weekEnd.Format(config.ISOShortDateFormat))
+	for _, recipient := range recipients {
+		to := emails.Recipient{Name: recipient.FirstName, Email: recipient.Email, Language: recipient.Language}
+		err = emails.SendTimesheetDigestEmail(to, branding, account.Company, weekStart, expectedHours, digest, reportUrl)
+		if err != nil {
+			logger.Log.Error("Failed to send timesheet digest email: " + err.Error())
+			sendErr = err
+		}
+	}
+
+	if sendErr != nil {
+		return sendErr
+	}
+
+	logger.Log.Info("Sent timesheet reminders", logger.Int("accountId", account.AccountId), logger.Int("missing", len(missing)), logger.Int("digests", len(recipients)))
+	return nil
+}
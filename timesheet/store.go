@@ -1,12 +1,14 @@
 package timesheet
 
 import (
+	"database/sql"
 	"errors"
 	"time"
 
 	"github.com/bryanmorgan/time-tracking-api/config"
 	"github.com/bryanmorgan/time-tracking-api/database"
 	"github.com/bryanmorgan/time-tracking-api/logger"
+	"github.com/bryanmorgan/time-tracking-api/profile"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -14,15 +16,46 @@ import (
 // Compile Only: ensure interface is implemented
 var _ TimeStore = &TimeData{}
 
+var TimesheetApprovedError = errors.New("time entries belong to an approved timesheet")
+var TimeInvoicedError = errors.New("time entries have been invoiced")
+var TimerRunningError = errors.New("a timer is already running")
+var InvalidProjectTaskError = errors.New("project and task not found in account")
+
 type TimeStore interface {
 	GetTimeEntriesForRange(profileId int, accountId int, start time.Time, end time.Time) ([]*TimeEntry, error)
+	SearchTimeEntries(profileId int, accountId int, start time.Time, end time.Time, search string) ([]*TimeEntry, error)
 
 	SaveOrUpdateTimeEntries(entries []*TimeEntry) error
 	UpdateTimeEntries(entries []*TimeEntry) error
 
-	AddInitialProjectTimeEntries(profileId int, accountId int, start time.Time, end time.Time, projectId int, taskId int) error
+	AddInitialProjectTimeEntries(profileId int, accountId int, start time.Time, end time.Time, projectId int, taskId int, updatedBy int) error
 
 	DeleteProjectForDates(profileId int, accountId int, projectId int, taskId int, start time.Time, end time.Time) error
+
+	GetTimesheet(profileId int, accountId int, start time.Time) (*Timesheet, error)
+	GetTimesheetsByStatus(accountId int, status TimesheetStatus) ([]*Timesheet, error)
+	SubmitTimesheet(profileId int, accountId int, start time.Time, submittedBy int) error
+	ReviewTimesheet(profileId int, accountId int, start time.Time, status TimesheetStatus, reviewerId int, reason sql.NullString) error
+	ReopenTimesheet(profileId int, accountId int, start time.Time, reviewerId int) error
+
+	IsAccountMember(profileId int, accountId int) (bool, error)
+	GetImportMembers(accountId int) ([]*ImportMember, error)
+	GetImportProjectTasks(accountId int) ([]*ImportProjectTask, error)
+
+	GetTimer(profileId int) (*Timer, error)
+	StartTimer(timer *Timer) error
+	StopTimer(timer *Timer, entries []*TimeEntry) error
+	DeleteTimer(profileId int) error
+
+	GetProjectBudget(projectId int, accountId int) (*ProjectBudget, error)
+	UpdateBudgetAlertPercent(projectId int, accountId int, percent int) error
+	UpdateTaskBudgetAlertPercent(projectId int, taskId int, accountId int, percent int) error
+	GetBudgetAlertRecipients(accountId int) ([]*BudgetAlertRecipient, error)
+
+	GetReminderAccounts() ([]*profile.Account, error)
+	UpdateReminderWeek(accountId int, weekStart time.Time) error
+	GetMissingTimesheets(accountId int, start time.Time, end time.Time, expectedHours float64) ([]*MissingTimesheet, error)
+	GetReminderDigestRecipients(accountId int) ([]*ReminderRecipient, error)
 }
 
 // ProfileData implements database operations for user profiles
@@ -42,14 +75,19 @@ func (c *TimeData) SaveOrUpdateTimeEntries(entries []*TimeEntry) error {
 		return nil
 	}
 
-	tx, err := c.db.Begin()
+	tx, err := c.db.Beginx()
 	if err != nil {
 		return err
 	}
 
+	if err := c.checkEntriesEditable(tx, entries); err != nil {
+		database.RollbackTransaction(tx.Tx)
+		return err
+	}
+
 	for _, entry := range entries {
 		if entry.Day.IsZero() {
-			database.RollbackTransaction(tx)
+			database.RollbackTransaction(tx.Tx)
 			return errors.New("invalid time entry day: " + entry.Day.String())
 		}
 
@@ -58,10 +96,10 @@ func (c *TimeData) SaveOrUpdateTimeEntries(entries []*TimeEntry) error {
 		}
 
 		upsertSql := `
-		INSERT INTO time (account_id, profile_id, project_id, task_id, day, hours)
- 				  VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO time (account_id, profile_id, project_id, task_id, day, hours, notes, updated_by)
+ 				  VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8)
 		ON CONFLICT (account_id, profile_id, project_id, task_id, day)
-		DO UPDATE SET hours = $6
+		DO UPDATE SET hours = $6, notes = NULLIF(COALESCE($7, time.notes), ''), updated = CURRENT_TIMESTAMP, updated_by = $8
 		WHERE time.account_id = $1
 		  AND time.profile_id = $2
 		  AND time.project_id = $3
@@ -69,20 +107,20 @@ func (c *TimeData) SaveOrUpdateTimeEntries(entries []*TimeEntry) error {
 		  AND time.day = $5
 		`
 
-		results, err := c.db.Exec(upsertSql, entry.AccountId, entry.ProfileId, entry.ProjectId, entry.TaskId, entry.Day.Format(config.ISOShortDateFormat), entry.Hours)
+		results, err := tx.Exec(upsertSql, entry.AccountId, entry.ProfileId, entry.ProjectId, entry.TaskId, entry.Day.Format(config.ISOShortDateFormat), entry.Hours, entry.Notes, entry.UpdatedBy)
 		if err != nil {
-			database.RollbackTransaction(tx)
+			database.RollbackTransaction(tx.Tx)
 			return err
 		}
 
 		rows, err := results.RowsAffected()
 		if err != nil {
-			database.RollbackTransaction(tx)
+			database.RollbackTransaction(tx.Tx)
 			return err
 		}
 
 		if rows == 0 {
-			database.RollbackTransaction(tx)
+			database.RollbackTransaction(tx.Tx)
 			return database.NoRowAffectedError
 		}
 	}
@@ -101,14 +139,19 @@ func (c *TimeData) UpdateTimeEntries(entries []*TimeEntry) error {
 		return nil
 	}
 
-	tx, err := c.db.Begin()
+	tx, err := c.db.Beginx()
 	if err != nil {
 		return err
 	}
 
+	if err := c.checkEntriesEditable(tx, entries); err != nil {
+		database.RollbackTransaction(tx.Tx)
+		return err
+	}
+
 	for _, entry := range entries {
 		if entry.Day.IsZero() {
-			database.RollbackTransaction(tx)
+			database.RollbackTransaction(tx.Tx)
 			return errors.New("invalid time entry day: " + entry.Day.String())
 		}
 
@@ -122,7 +165,7 @@ func (c *TimeData) UpdateTimeEntries(entries []*TimeEntry) error {
 		}
 
 		updateSql := `
-			UPDATE time SET hours = $6
+			UPDATE time SET hours = $6, notes = NULLIF(COALESCE($7, time.notes), ''), updated = CURRENT_TIMESTAMP, updated_by = $8
 			WHERE time.account_id = $1
 			  AND time.profile_id = $2
 			  AND time.project_id = $3
@@ -130,15 +173,15 @@ func (c *TimeData) UpdateTimeEntries(entries []*TimeEntry) error {
 			  AND time.day = $5
 		`
 
-		results, err := c.db.Exec(updateSql, entry.AccountId, entry.ProfileId, entry.ProjectId, entry.TaskId, entry.Day.Format(config.ISOShortDateFormat), entry.Hours)
+		results, err := tx.Exec(updateSql, entry.AccountId, entry.ProfileId, entry.ProjectId, entry.TaskId, entry.Day.Format(config.ISOShortDateFormat), entry.Hours, entry.Notes, entry.UpdatedBy)
 		if err != nil {
-			database.RollbackTransaction(tx)
+			database.RollbackTransaction(tx.Tx)
 			return err
 		}
 
 		rows, err := results.RowsAffected()
 		if err != nil {
-			database.RollbackTransaction(tx)
+			database.RollbackTransaction(tx.Tx)
 			return err
 		}
 
@@ -146,23 +189,23 @@ func (c *TimeData) UpdateTimeEntries(entries []*TimeEntry) error {
 			logger.Log.Warn("[Update-Insert] Update time entry failed: trying INSERT")
 
 			insertSql := `
-				INSERT INTO time(account_id, profile_id, project_id, task_id, day, hours)
-					  VALUES ($1, $2, $3, $4, $5, $6)`
-			results, err = c.db.Exec(insertSql, entry.AccountId, entry.ProfileId, entry.ProjectId, entry.TaskId, entry.Day.Format(config.ISOShortDateFormat), entry.Hours)
+				INSERT INTO time(account_id, profile_id, project_id, task_id, day, hours, notes, updated_by)
+					  VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8)`
+			results, err = tx.Exec(insertSql, entry.AccountId, entry.ProfileId, entry.ProjectId, entry.TaskId, entry.Day.Format(config.ISOShortDateFormat), entry.Hours, entry.Notes, entry.UpdatedBy)
 			if err != nil {
 				logger.Log.Error("Update failed and insert failed: " + err.Error())
-				database.RollbackTransaction(tx)
+				database.RollbackTransaction(tx.Tx)
 				return err
 			}
 
 			rows, err := results.RowsAffected()
 			if err != nil {
-				database.RollbackTransaction(tx)
+				database.RollbackTransaction(tx.Tx)
 				return err
 			}
 
 			if rows == 0 {
-				database.RollbackTransaction(tx)
+				database.RollbackTransaction(tx.Tx)
 				return database.NoRowAffectedError
 			}
 		}
@@ -177,31 +220,42 @@ func (c *TimeData) UpdateTimeEntries(entries []*TimeEntry) error {
 }
 
 // Add 0.0 values for all the days between start and end for the project/task
-func (c *TimeData) AddInitialProjectTimeEntries(profileId int, accountId int, start time.Time, end time.Time, projectId int, taskId int) error {
-	tx, err := c.db.Begin()
+func (c *TimeData) AddInitialProjectTimeEntries(profileId int, accountId int, start time.Time, end time.Time, projectId int, taskId int, updatedBy int) error {
+	tx, err := c.db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	approved, err := c.hasApprovedTimesheet(tx, profileId, accountId, start, end)
 	if err != nil {
+		database.RollbackTransaction(tx.Tx)
 		return err
 	}
 
+	if approved {
+		database.RollbackTransaction(tx.Tx)
+		return TimesheetApprovedError
+	}
+
 	for day := start; day.Before(end) || day.Equal(end); day = day.AddDate(0, 0, 1) {
 		insertSql := `
-		INSERT INTO time (account_id, profile_id, project_id, task_id, day, hours)
- 				  VALUES ($1, $2, $3, $4, $5, 0.0)`
+		INSERT INTO time (account_id, profile_id, project_id, task_id, day, hours, updated_by)
+ 				  VALUES ($1, $2, $3, $4, $5, 0.0, $6)`
 
-		results, err := c.db.Exec(insertSql, accountId, profileId, projectId, taskId, day.Format(config.ISOShortDateFormat))
+		results, err := tx.Exec(insertSql, accountId, profileId, projectId, taskId, day.Format(config.ISOShortDateFormat), updatedBy)
 		if err != nil {
-			database.RollbackTransaction(tx)
+			database.RollbackTransaction(tx.Tx)
 			return err
 		}
 
 		rows, err := results.RowsAffected()
 		if err != nil {
-			database.RollbackTransaction(tx)
+			database.RollbackTransaction(tx.Tx)
 			return err
 		}
 
 		if rows == 0 {
-			database.RollbackTransaction(tx)
+			database.RollbackTransaction(tx.Tx)
 			return database.NoRowAffectedError
 		}
 	}
@@ -222,6 +276,8 @@ func (c *TimeData) GetTimeEntriesForRange(profileId int, accountId int, start ti
 		  k.task_name,
 		  t.hours,
 		  t.day,
+		  t.notes,
+		  t.updated_by,
 		  p.project_id,
 		  t.task_id
 		FROM time t,
@@ -256,7 +312,80 @@ func (c *TimeData) GetTimeEntriesForRange(profileId int, accountId int, start ti
 	return timeEntries, nil
 }
 
+// Find the time entries in the date range with notes matching the search text (case-insensitive)
+func (c *TimeData) SearchTimeEntries(profileId int, accountId int, start time.Time, end time.Time, search string) ([]*TimeEntry, error) {
+	sqlStatement := `
+		SELECT
+		  c.client_name,
+		  p.project_name,
+		  k.task_name,
+		  t.hours,
+		  t.day,
+		  t.notes,
+		  t.updated_by,
+		  p.project_id,
+		  t.task_id
+		FROM time t,
+		     project p,
+		     client c,
+		     task k
+		WHERE c.client_id = p.client_id
+		  AND t.project_id = p.project_id
+		  AND t.task_id = k.task_id
+		  AND t.account_id = $1
+		  AND t.profile_id = $2
+		  AND t.day >= $3
+		  AND t.day <= $4
+		  AND t.notes ILIKE '%' || $5 || '%'
+		ORDER BY t.day`
+
+	rows, err := c.db.Queryx(sqlStatement, accountId, profileId, start.Format(config.ISOShortDateFormat), end.Format(config.ISOShortDateFormat), escapeLikePattern(search))
+	if err != nil {
+		return nil, err
+	}
+	defer database.CloseRows(rows)
+
+	var timeEntries []*TimeEntry
+	for rows.Next() {
+		var timeEntry TimeEntry
+		err := rows.StructScan(&timeEntry)
+		if err != nil {
+			return nil, err
+		}
+		timeEntries = append(timeEntries, &timeEntry)
+	}
+
+	return timeEntries, nil
+}
+
 func (c *TimeData) DeleteProjectForDates(profileId int, accountId int, projectId int, taskId int, start time.Time, end time.Time) error {
+	tx, err := c.db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	approved, err := c.hasApprovedTimesheet(tx, profileId, accountId, start, end)
+	if err != nil {
+		database.RollbackTransaction(tx.Tx)
+		return err
+	}
+
+	if approved {
+		database.RollbackTransaction(tx.Tx)
+		return TimesheetApprovedError
+	}
+
+	invoiced, err := c.hasInvoicedTime(tx, profileId, accountId, projectId, taskId, start, end)
+	if err != nil {
+		database.RollbackTransaction(tx.Tx)
+		return err
+	}
+
+	if invoiced {
+		database.RollbackTransaction(tx.Tx)
+		return TimeInvoicedError
+	}
+
 	sql := `
 		DELETE FROM time
 		WHERE profile_id=$1
@@ -266,7 +395,269 @@ func (c *TimeData) DeleteProjectForDates(profileId int, accountId int, projectId
           AND day >= $5
           AND day <= $6
 	`
-	results, err := c.db.Exec(sql, profileId, accountId, projectId, taskId, start.Format(config.ISOShortDateFormat), end.Format(config.ISOShortDateFormat))
+	results, err := tx.Exec(sql, profileId, accountId, projectId, taskId, start.Format(config.ISOShortDateFormat), end.Format(config.ISOShortDateFormat))
+	if err != nil {
+		database.RollbackTransaction(tx.Tx)
+		return err
+	}
+
+	rows, err := results.RowsAffected()
+	if err != nil {
+		database.RollbackTransaction(tx.Tx)
+		return err
+	}
+
+	if rows == 0 {
+		database.RollbackTransaction(tx.Tx)
+		return database.NoRowAffectedError
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Log.Error("Error in commit transaction: " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Returns TimesheetApprovedError if any of the entries fall in an approved timesheet week or TimeInvoicedError
+// if any of the entries have been invoiced. Must run in the transaction that changes the entries
+func (c *TimeData) checkEntriesEditable(tx *sqlx.Tx, entries []*TimeEntry) error {
+	for _, entry := range entries {
+		approved, err := c.hasApprovedTimesheet(tx, entry.ProfileId, entry.AccountId, entry.Day, entry.Day)
+		if err != nil {
+			return err
+		}
+
+		if approved {
+			return TimesheetApprovedError
+		}
+
+		invoiced, err := c.hasInvoicedTime(tx, entry.ProfileId, entry.AccountId, entry.ProjectId, entry.TaskId, entry.Day, entry.Day)
+		if err != nil {
+			return err
+		}
+
+		if invoiced {
+			return TimeInvoicedError
+		}
+	}
+
+	return nil
+}
+
+// Returns true if any time for the project and task between the start and end dates has been invoiced. The time
+// rows stay locked until the transaction ends so they cannot be invoiced while they are changed
+func (c *TimeData) hasInvoicedTime(tx *sqlx.Tx, profileId int, accountId int, projectId int, taskId int, start time.Time, end time.Time) (bool, error) {
+	sqlStatement := `
+		SELECT invoice_id
+		FROM time
+		WHERE account_id = $1
+		  AND profile_id = $2
+		  AND project_id = $3
+		  AND task_id = $4
+		  AND day >= $5
+		  AND day <= $6
+		FOR UPDATE`
+
+	var invoiceIds []sql.NullInt64
+	err := tx.Select(&invoiceIds, sqlStatement, accountId, profileId, projectId, taskId, start.Format(config.ISOShortDateFormat), end.Format(config.ISOShortDateFormat))
+	if err != nil {
+		return false, err
+	}
+
+	for _, invoiceId := range invoiceIds {
+		if invoiceId.Valid {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Returns true if an approved timesheet week overlaps the start and end dates. The overlapping weeks stay locked
+// until the transaction ends so they cannot be approved while their time is changed
+func (c *TimeData) hasApprovedTimesheet(tx *sqlx.Tx, profileId int, accountId int, start time.Time, end time.Time) (bool, error) {
+	sqlStatement := `
+		SELECT timesheet_status
+		FROM timesheet
+		WHERE account_id = $1
+		  AND profile_id = $2
+		  AND start_date <= $4
+		  AND start_date + 6 >= $3
+		FOR UPDATE`
+
+	var statuses []TimesheetStatus
+	err := tx.Select(&statuses, sqlStatement, accountId, profileId, start.Format(config.ISOShortDateFormat), end.Format(config.ISOShortDateFormat))
+	if err != nil {
+		return false, err
+	}
+
+	for _, status := range statuses {
+		if status == TimesheetApproved {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (c *TimeData) GetTimesheet(profileId int, accountId int, start time.Time) (*Timesheet, error) {
+	sqlStatement := `
+		SELECT account_id,
+		       profile_id,
+		       start_date,
+		       timesheet_status,
+		       reject_reason,
+		       submitted,
+		       submitted_by,
+		       reviewed,
+		       reviewed_by
+		FROM timesheet
+		WHERE account_id = $1
+		  AND profile_id = $2
+		  AND start_date = $3`
+
+	var timesheet Timesheet
+	err := c.db.QueryRowx(sqlStatement, accountId, profileId, start.Format(config.ISOShortDateFormat)).StructScan(&timesheet)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &timesheet, nil
+}
+
+// Get the timesheets in the account with the given status, including the total hours for each week
+func (c *TimeData) GetTimesheetsByStatus(accountId int, status TimesheetStatus) ([]*Timesheet, error) {
+	sqlStatement := `
+		SELECT ts.account_id,
+		       ts.profile_id,
+		       ts.start_date,
+		       ts.timesheet_status,
+		       ts.reject_reason,
+		       ts.submitted,
+		       ts.submitted_by,
+		       ts.reviewed,
+		       ts.reviewed_by,
+		       p.first_name,
+		       p.last_name,
+		       p.email,
+		       COALESCE((SELECT sum(t.hours)
+		                 FROM time t
+		                 WHERE t.account_id = ts.account_id
+		                   AND t.profile_id = ts.profile_id
+		                   AND t.day >= ts.start_date
+		                   AND t.day <= ts.start_date + 6), 0) AS hours
+		FROM timesheet ts,
+		     profile p
+		WHERE ts.profile_id = p.profile_id
+		  AND ts.account_id = $1
+		  AND ts.timesheet_status = $2
+		ORDER BY ts.start_date, p.last_name`
+
+	rows, err := c.db.Queryx(sqlStatement, accountId, status)
+	if err != nil {
+		return nil, err
+	}
+	defer database.CloseRows(rows)
+
+	var timesheets []*Timesheet
+	for rows.Next() {
+		var timesheet Timesheet
+		err := rows.StructScan(&timesheet)
+		if err != nil {
+			return nil, err
+		}
+		timesheets = append(timesheets, &timesheet)
+	}
+
+	return timesheets, nil
+}
+
+// Submit a draft or rejected timesheet week for approval. Returns NoRowAffectedError if the week is already submitted or approved
+func (c *TimeData) SubmitTimesheet(profileId int, accountId int, start time.Time, submittedBy int) error {
+	upsertSql := `
+		INSERT INTO timesheet (account_id, profile_id, start_date, timesheet_status, submitted, submitted_by)
+		          VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP, $7)
+		ON CONFLICT (account_id, profile_id, start_date)
+		DO UPDATE SET timesheet_status = $4,
+		              submitted = CURRENT_TIMESTAMP,
+		              submitted_by = $7,
+		              reject_reason = NULL,
+		              reviewed = NULL,
+		              reviewed_by = NULL,
+		              updated = CURRENT_TIMESTAMP
+		WHERE timesheet.timesheet_status IN ($5, $6)`
+
+	results, err := c.db.Exec(upsertSql, accountId, profileId, start.Format(config.ISOShortDateFormat), TimesheetSubmitted, TimesheetDraft, TimesheetRejected, submittedBy)
+	if err != nil {
+		return err
+	}
+
+	rows, err := results.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return database.NoRowAffectedError
+	}
+
+	return nil
+}
+
+// Approve or reject a submitted timesheet week. Returns NoRowAffectedError if there is no submitted week
+func (c *TimeData) ReviewTimesheet(profileId int, accountId int, start time.Time, status TimesheetStatus, reviewerId int, reason sql.NullString) error {
+	updateSql := `
+		UPDATE timesheet
+		SET timesheet_status = $4,
+		    reject_reason = $5,
+		    reviewed = CURRENT_TIMESTAMP,
+		    reviewed_by = $6,
+		    updated = CURRENT_TIMESTAMP
+		WHERE account_id = $1
+		  AND profile_id = $2
+		  AND start_date = $3
+		  AND timesheet_status = $7`
+
+	results, err := c.db.Exec(updateSql, accountId, profileId, start.Format(config.ISOShortDateFormat), status, reason, reviewerId, TimesheetSubmitted)
+	if err != nil {
+		return err
+	}
+
+	rows, err := results.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return database.NoRowAffectedError
+	}
+
+	return nil
+}
+
+// Move an approved timesheet week back to draft, recording who reopened it. Returns NoRowAffectedError if the week
+// is not approved
+func (c *TimeData) ReopenTimesheet(profileId int, accountId int, start time.Time, reviewerId int) error {
+	updateSql := `
+		UPDATE timesheet
+		SET timesheet_status = $4,
+		    submitted = NULL,
+		    submitted_by = NULL,
+		    reviewed = CURRENT_TIMESTAMP,
+		    reviewed_by = $5,
+		    updated = CURRENT_TIMESTAMP
+		WHERE account_id = $1
+		  AND profile_id = $2
+		  AND start_date = $3
+		  AND timesheet_status = $6`
+
+	results, err := c.db.Exec(updateSql, accountId, profileId, start.Format(config.ISOShortDateFormat), TimesheetDraft, reviewerId, TimesheetApproved)
 	if err != nil {
 		return err
 	}
@@ -282,3 +673,446 @@ func (c *TimeData) DeleteProjectForDates(profileId int, accountId int, projectId
 
 	return nil
 }
+
+// Returns true if the profile is a valid member of the account
+func (c *TimeData) IsAccountMember(profileId int, accountId int) (bool, error) {
+	sqlStatement := `
+		SELECT EXISTS(SELECT 1
+		              FROM profile_account
+		              WHERE profile_id = $1
+		                AND account_id = $2
+		                AND profile_account_status = $3)`
+
+	var member bool
+	err := c.db.Get(&member, sqlStatement, profileId, accountId, profile.ProfileAccountValid)
+	if err != nil {
+		return false, err
+	}
+
+	return member, nil
+}
+
+// Get the email of every valid member of the account
+func (c *TimeData) GetImportMembers(accountId int) ([]*ImportMember, error) {
+	sqlStatement := `
+		SELECT p.profile_id,
+		       p.email
+		FROM profile p,
+		     profile_account pa
+		WHERE pa.profile_id = p.profile_id
+		  AND pa.account_id = $1
+		  AND pa.profile_account_status = $2`
+
+	var members []*ImportMember
+	err := c.db.Select(&members, sqlStatement, accountId, profile.ProfileAccountValid)
+	if err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// Get every active task assigned to an active project in the account, oldest first
+func (c *TimeData) GetImportProjectTasks(accountId int) ([]*ImportProjectTask, error) {
+	sqlStatement := `
+		SELECT c.client_name,
+		       p.project_id,
+		       p.project_name,
+		       p.code,
+		       k.task_id,
+		       k.task_name
+		FROM project_task pt,
+		     project p,
+		     client c,
+		     task k
+		WHERE pt.account_id = $1
+		  AND pt.project_id = p.project_id
+		  AND pt.task_id = k.task_id
+		  AND p.client_id = c.client_id
+		  AND pt.project_active = TRUE
+		  AND p.project_active = TRUE
+		  AND c.client_active = TRUE
+		  AND k.task_active = TRUE
+		ORDER BY c.client_id, p.project_id, k.task_id`
+
+	var projectTasks []*ImportProjectTask
+	err := c.db.Select(&projectTasks, sqlStatement, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	return projectTasks, nil
+}
+
+// Get the running timer for the profile, or nil if there is no timer
+func (c *TimeData) GetTimer(profileId int) (*Timer, error) {
+	sqlStatement := `
+		SELECT r.timer_id,
+		       r.account_id,
+		       r.profile_id,
+		       r.project_id,
+		       r.task_id,
+		       r.notes,
+		       r.started,
+		       c.client_name,
+		       p.project_name,
+		       k.task_name
+		FROM timer r,
+		     project p,
+		     client c,
+		     task k
+		WHERE r.profile_id = $1
+		  AND r.project_id = p.project_id
+		  AND p.client_id = c.client_id
+		  AND r.task_id = k.task_id`
+
+	var timer Timer
+	err := c.db.QueryRowx(sqlStatement, profileId).StructScan(&timer)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &timer, nil
+}
+
+// Start a timer for the profile. Returns TimerRunningError if the profile already has a running timer
+func (c *TimeData) StartTimer(timer *Timer) error {
+	var exists bool
+	existsSql := `SELECT EXISTS(SELECT 1 FROM project_task WHERE account_id = $1 AND project_id = $2 AND task_id = $3)`
+	err := c.db.Get(&exists, existsSql, timer.AccountId, timer.ProjectId, timer.TaskId)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return InvalidProjectTaskError
+	}
+
+	insertSql := `
+		INSERT INTO timer (account_id, profile_id, project_id, task_id, notes)
+		           VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (profile_id) DO NOTHING`
+
+	results, err := c.db.Exec(insertSql, timer.AccountId, timer.ProfileId, timer.ProjectId, timer.TaskId, timer.Notes)
+	if err != nil {
+		return err
+	}
+
+	rows, err := results.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return TimerRunningError
+	}
+
+	return nil
+}
+
+// Remove the running timer and add the elapsed hours to the time entries in a single transaction
+func (c *TimeData) StopTimer(timer *Timer, entries []*TimeEntry) error {
+	tx, err := c.db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	if err := c.checkEntriesEditable(tx, entries); err != nil {
+		database.RollbackTransaction(tx.Tx)
+		return err
+	}
+
+	results, err := tx.Exec(`DELETE FROM timer WHERE timer_id = $1`, timer.TimerId)
+	if err != nil {
+		database.RollbackTransaction(tx.Tx)
+		return err
+	}
+
+	rows, err := results.RowsAffected()
+	if err != nil {
+		database.RollbackTransaction(tx.Tx)
+		return err
+	}
+
+	// The timer was stopped or discarded by another request
+	if rows == 0 {
+		database.RollbackTransaction(tx.Tx)
+		return database.NoRowAffectedError
+	}
+
+	for _, entry := range entries {
+		upsertSql := `
+		INSERT INTO time (account_id, profile_id, project_id, task_id, day, hours, notes, updated_by)
+ 				  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (account_id, profile_id, project_id, task_id, day)
+		DO UPDATE SET hours = time.hours + EXCLUDED.hours,
+		              notes = CASE
+		                        WHEN EXCLUDED.notes IS NULL THEN time.notes
+		                        WHEN time.notes IS NULL OR time.notes = '' THEN EXCLUDED.notes
+		                        ELSE time.notes || '; ' || EXCLUDED.notes
+		                      END,
+		              updated = CURRENT_TIMESTAMP,
+		              updated_by = EXCLUDED.updated_by
+		`
+
+		_, err := tx.Exec(upsertSql, entry.AccountId, entry.ProfileId, entry.ProjectId, entry.TaskId, entry.Day.Format(config.ISOShortDateFormat), entry.Hours, entry.Notes, entry.UpdatedBy)
+		if err != nil {
+			database.RollbackTransaction(tx.Tx)
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Log.Error("Error in commit transaction: " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (c *TimeData) DeleteTimer(profileId int) error {
+	results, err := c.db.Exec(`DELETE FROM timer WHERE profile_id = $1`, profileId)
+	if err != nil {
+		return err
+	}
+
+	rows, err := results.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return database.NoRowAffectedError
+	}
+
+	return nil
+}
+
+// Get the project budget with the hours and billable amount spent, and the budget for each of the project's tasks
+func (c *TimeData) GetProjectBudget(projectId int, accountId int) (*ProjectBudget, error) {
+	projectSql := `
+		SELECT p.project_id,
+		       p.account_id,
+		       p.project_name,
+		       p.budget_hours,
+		       p.budget_amount,
+		       p.budget_alert_percent,
+		       COALESCE(sum(t.hours), 0) AS spent_hours,
+		       COALESCE(sum(t.hours * COALESCE(pt.rate, 0)) filter (where pt.billable), 0) AS spent_amount
+		FROM project p
+		         LEFT JOIN time t ON t.project_id = p.project_id AND t.account_id = p.account_id
+		         LEFT JOIN project_task pt ON pt.project_id = t.project_id AND pt.task_id = t.task_id
+		WHERE p.project_id = $1
+		  AND p.account_id = $2
+		GROUP BY p.project_id`
+
+	var budget ProjectBudget
+	err := c.db.Get(&budget, projectSql, projectId, accountId)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	taskSql := `
+		SELECT pt.task_id,
+		       k.task_name,
+		       pt.budget_hours,
+		       pt.budget_amount,
+		       pt.budget_alert_percent,
+		       COALESCE(sum(t.hours), 0) AS spent_hours,
+		       COALESCE(sum(t.hours * COALESCE(pt.rate, 0)) filter (where pt.billable), 0) AS spent_amount
+		FROM project_task pt
+		         JOIN task k ON k.task_id = pt.task_id
+		         LEFT JOIN time t ON t.project_id = pt.project_id AND t.task_id = pt.task_id AND t.account_id = pt.account_id
+		WHERE pt.project_id = $1
+		  AND pt.account_id = $2
+		GROUP BY pt.task_id, k.task_name, pt.budget_hours, pt.budget_amount, pt.budget_alert_percent
+		ORDER BY LOWER(k.task_name)`
+
+	err = c.db.Select(&budget.Tasks, taskSql, projectId, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &budget, nil
+}
+
+// Record the highest budget threshold alerted for a project. Only moves the percent up, so concurrent requests
+// crossing the same threshold send a single alert
+func (c *TimeData) UpdateBudgetAlertPercent(projectId int, accountId int, percent int) error {
+	sqlStatement := `
+		UPDATE project
+		SET budget_alert_percent = $3
+		WHERE project_id = $1
+		  AND account_id = $2
+		  AND budget_alert_percent < $3`
+
+	results, err := c.db.Exec(sqlStatement, projectId, accountId, percent)
+	if err != nil {
+		return err
+	}
+
+	rows, err := results.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return database.NoRowAffectedError
+	}
+
+	return nil
+}
+
+// Record the highest budget threshold alerted for a task in a project. Only moves the percent up
+func (c *TimeData) UpdateTaskBudgetAlertPercent(projectId int, taskId int, accountId int, percent int) error {
+	sqlStatement := `
+		UPDATE project_task
+		SET budget_alert_percent = $4
+		WHERE project_id = $1
+		  AND task_id = $2
+		  AND account_id = $3
+		  AND budget_alert_percent < $4`
+
+	results, err := c.db.Exec(sqlStatement, projectId, taskId, accountId, percent)
+	if err != nil {
+		return err
+	}
+
+	rows, err := results.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return database.NoRowAffectedError
+	}
+
+	return nil
+}
+
+// Get the owners and admins of the account who receive budget alerts
+func (c *TimeData) GetBudgetAlertRecipients(accountId int) ([]*BudgetAlertRecipient, error) {
+	sqlStatement := `
+		SELECT p.first_name, p.email, p.language, a.email_signature, a.email_logo_url
+		FROM profile p,
+		     profile_account pa,
+		     account a
+		WHERE pa.account_id = $1
+		  AND pa.profile_id = p.profile_id
+		  AND a.account_id = pa.account_id
+		  AND pa.profile_account_status = $2
+		  AND pa.role IN ($3, $4)`
+
+	var recipients []*BudgetAlertRecipient
+	err := c.db.Select(&recipients, sqlStatement, accountId, profile.ProfileAccountValid, profile.Owner, profile.Admin)
+	if err != nil {
+		return nil, err
+	}
+
+	return recipients, nil
+}
+
+// Get the valid accounts that are checked for missing timesheets
+func (c *TimeData) GetReminderAccounts() ([]*profile.Account, error) {
+	sqlStatement := `
+		SELECT account_id,
+		       company,
+		       account_status,
+		       week_start,
+		       account_timezone,
+		       email_signature,
+		       email_logo_url,
+		       timesheet_reminder_week
+		FROM account
+		WHERE account_status = $1
+		ORDER BY account_id`
+
+	var accounts []*profile.Account
+	err := c.db.Select(&accounts, sqlStatement, profile.AccountValid)
+	if err != nil {
+		return nil, err
+	}
+
+	return accounts, nil
+}
+
+// Record the week the account was sent reminders for once they are all sent. Only moves the week forward. Returns
+// NoRowAffectedError if the week was already recorded
+func (c *TimeData) UpdateReminderWeek(accountId int, weekStart time.Time) error {
+	updateSql := `
+		UPDATE account
+		SET timesheet_reminder_week = $2
+		WHERE account_id = $1
+		  AND (timesheet_reminder_week IS NULL OR timesheet_reminder_week < $2)`
+
+	result, err := c.db.Exec(updateSql, accountId, weekStart)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return database.NoRowAffectedError
+	}
+
+	return nil
+}
+
+// Get the valid account members who logged fewer than the expected hours between the start and end dates
+func (c *TimeData) GetMissingTimesheets(accountId int, start time.Time, end time.Time, expectedHours float64) ([]*MissingTimesheet, error) {
+	query := `
+		SELECT p.profile_id, p.first_name, p.last_name, p.email, p.language, p.timesheet_reminders,
+		       COALESCE(SUM(t.hours), 0) AS hours
+		FROM profile_account pa
+		JOIN profile p ON p.profile_id = pa.profile_id
+		LEFT JOIN time t ON t.profile_id = pa.profile_id
+		                AND t.account_id = pa.account_id
+		                AND t.day BETWEEN $2 AND $3
+		WHERE pa.account_id = $1
+		  AND pa.profile_account_status = $4
+		  AND p.profile_status = $5
+		GROUP BY p.profile_id
+		HAVING COALESCE(SUM(t.hours), 0) < $6
+		ORDER BY p.last_name, p.first_name`
+
+	var missing []*MissingTimesheet
+	err := c.db.Select(&missing, query, accountId, start, end, profile.ProfileAccountValid, profile.ProfileValid, expectedHours)
+	if err != nil {
+		return nil, err
+	}
+
+	return missing, nil
+}
+
+// Get the owners and admins of the account who have not opted out of timesheet reminders
+func (c *TimeData) GetReminderDigestRecipients(accountId int) ([]*ReminderRecipient, error) {
+	query := `
+		SELECT p.first_name, p.email, p.language
+		FROM profile p,
+		     profile_account pa
+		WHERE pa.account_id = $1
+		  AND pa.profile_id = p.profile_id
+		  AND pa.profile_account_status = $2
+		  AND pa.role IN ($3, $4)
+		  AND p.timesheet_digest`
+
+	var recipients []*ReminderRecipient
+	err := c.db.Select(&recipients, query, accountId, profile.ProfileAccountValid, profile.Owner, profile.Admin)
+	if err != nil {
+		return nil, err
+	}
+
+	return recipients, nil
+}
@@ -1,28 +1,76 @@
 package timesheet
 
 import (
+	"database/sql"
+	"math"
+
 	"github.com/bryanmorgan/time-tracking-api/config"
 	"github.com/bryanmorgan/time-tracking-api/logger"
+	"github.com/bryanmorgan/time-tracking-api/profile"
+	"github.com/lib/pq"
 	"strconv"
+	"strings"
 	"time"
 )
 
+type TimesheetStatus string
+
+// Timesheet week lifecycle: draft -> submitted -> approved or rejected (rejected weeks can be submitted again and
+// admins can reopen approved weeks as drafts)
+const (
+	TimesheetDraft     TimesheetStatus = "draft"
+	TimesheetSubmitted TimesheetStatus = "submitted"
+	TimesheetApproved  TimesheetStatus = "approved"
+	TimesheetRejected  TimesheetStatus = "rejected"
+)
+
 type TimeEntry struct {
-	Day         time.Time `json:"-"`
-	Hours       float64   `json:"-"`
-	AccountId   int       `json:"-" db:"account_id"`
-	ProfileId   int       `json:"-" db:"profile_id"`
-	ProjectId   int       `json:"-" db:"project_id"`
-	TaskId      int       `json:"-" db:"task_id"`
-	ClientName  string    `json:"-" db:"client_name"`
-	ProjectName string    `json:"-" db:"project_name"`
-	TaskName    string    `json:"-" db:"task_name"`
+	Day         time.Time      `json:"-"`
+	Hours       float64        `json:"-"`
+	AccountId   int            `json:"-" db:"account_id"`
+	ProfileId   int            `json:"-" db:"profile_id"`
+	ProjectId   int            `json:"-" db:"project_id"`
+	TaskId      int            `json:"-" db:"task_id"`
+	ClientName  string         `json:"-" db:"client_name"`
+	ProjectName string         `json:"-" db:"project_name"`
+	TaskName    string         `json:"-" db:"task_name"`
+	Notes       sql.NullString `json:"-" db:"notes"`
+	UpdatedBy   sql.NullInt64  `json:"-" db:"updated_by"`
 }
 
+const (
+	NotesMaxLength        = 1024
+	RejectReasonMaxLength = 1024
+)
+
 type Timesheet struct {
-	Days      [7]TimeEntry `json:"-"`
-	StartDate time.Time    `json:"-"`
-	Status    string       `json:"-"`
+	Days         [7]TimeEntry    `json:"-"`
+	StartDate    time.Time       `json:"-" db:"start_date"`
+	Status       TimesheetStatus `json:"-" db:"timesheet_status"`
+	AccountId    int             `json:"-" db:"account_id"`
+	ProfileId    int             `json:"-" db:"profile_id"`
+	FirstName    string          `json:"-" db:"first_name"`
+	LastName     string          `json:"-" db:"last_name"`
+	Email        string          `json:"-"`
+	Hours        float64         `json:"-"`
+	RejectReason sql.NullString  `json:"-" db:"reject_reason"`
+	Submitted    pq.NullTime     `json:"-"`
+	SubmittedBy  sql.NullInt64   `json:"-" db:"submitted_by"`
+	Reviewed     pq.NullTime     `json:"-"`
+	ReviewedBy   sql.NullInt64   `json:"-" db:"reviewed_by"`
+}
+
+type Timer struct {
+	TimerId     int            `json:"-" db:"timer_id"`
+	AccountId   int            `json:"-" db:"account_id"`
+	ProfileId   int            `json:"-" db:"profile_id"`
+	ProjectId   int            `json:"-" db:"project_id"`
+	TaskId      int            `json:"-" db:"task_id"`
+	ClientName  string         `json:"-" db:"client_name"`
+	ProjectName string         `json:"-" db:"project_name"`
+	TaskName    string         `json:"-" db:"task_name"`
+	Notes       sql.NullString `json:"-" db:"notes"`
+	Started     time.Time      `json:"-"`
 }
 
 // Returns the 6 day week start and end range based on the current date/time and timezone
@@ -73,3 +121,60 @@ func getWeekdayStart(weekStart int) time.Weekday {
 	logger.Log.Warn("Invalid week start conversion using value: " + strconv.Itoa(weekStart))
 	return time.Monday
 }
+
+// Escape the LIKE/ILIKE wildcard characters so search text is matched literally
+func escapeLikePattern(search string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	return replacer.Replace(search)
+}
+
+// Round the elapsed timer duration to the account increment in minutes. An increment of 0 disables rounding
+func roundTimerDuration(elapsed time.Duration, minutes int, mode profile.TimerRoundingMode) time.Duration {
+	if minutes <= 0 {
+		return elapsed
+	}
+
+	increment := time.Duration(minutes) * time.Minute
+	switch mode {
+	case profile.RoundUp:
+		rounded := elapsed.Truncate(increment)
+		if rounded < elapsed {
+			rounded += increment
+		}
+		return rounded
+	case profile.RoundDown:
+		return elapsed.Truncate(increment)
+	default:
+		return elapsed.Round(increment)
+	}
+}
+
+// Split a timer into one entry per day, using midnight in the location as the day boundary. The rounded duration
+// is allocated to the days in order, so any rounding difference is added to or taken from the latest days
+func splitTimerByDay(started time.Time, stopped time.Time, duration time.Duration, location *time.Location) []*TimeEntry {
+	var entries []*TimeEntry
+	current := started.In(location)
+	end := stopped.In(location)
+	remaining := duration
+
+	for current.Before(end) && remaining > 0 {
+		midnight := time.Date(current.Year(), current.Month(), current.Day()+1, 0, 0, 0, 0, location)
+		segment := remaining
+		if midnight.Before(end) {
+			segment = midnight.Sub(current)
+			if segment > remaining {
+				segment = remaining
+			}
+		}
+
+		entries = append(entries, &TimeEntry{
+			Day:   time.Date(current.Year(), current.Month(), current.Day(), 0, 0, 0, 0, time.UTC),
+			Hours: math.Round(segment.Hours()*100) / 100,
+		})
+
+		remaining -= segment
+		current = midnight
+	}
+
+	return entries
+}
@@ -1,11 +1,16 @@
 package timesheet
 
 import (
+	"database/sql"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/bryanmorgan/time-tracking-api/api"
 	"github.com/bryanmorgan/time-tracking-api/config"
+	"github.com/bryanmorgan/time-tracking-api/profile"
+	"github.com/lib/pq"
 )
 
 // Test different timezones and weekday starts with the current date to make sure we get the correct week start/end dates
@@ -138,3 +143,379 @@ func TestWeekStartEnd(t *testing.T) {
 		})
 	}
 }
+
+func TestEscapeLikePattern(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		search   string
+		expected string
+	}{
+		{"Plain text", "meeting", "meeting"},
+		{"Percent", "100%", `100\%`},
+		{"Underscore", "client_a", `client\_a`},
+		{"Backslash", `c:\temp`, `c:\\temp`},
+		{"Mixed", `%_\`, `\%\_\\`},
+		{"Empty", "", ""},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			escaped := escapeLikePattern(testCase.search)
+			if escaped != testCase.expected {
+				t.Errorf("Invalid escaped search: [%s] wanted: [%s]", escaped, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestRoundTimerDuration(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		elapsed  time.Duration
+		minutes  int
+		mode     profile.TimerRoundingMode
+		expected time.Duration
+	}{
+		{"No rounding", 37 * time.Minute, 0, profile.RoundNearest, 37 * time.Minute},
+		{"Nearest 15 down", 37 * time.Minute, 15, profile.RoundNearest, 30 * time.Minute},
+		{"Nearest 15 up", 38 * time.Minute, 15, profile.RoundNearest, 45 * time.Minute},
+		{"Up 15", 31 * time.Minute, 15, profile.RoundUp, 45 * time.Minute},
+		{"Up 15 exact", 30 * time.Minute, 15, profile.RoundUp, 30 * time.Minute},
+		{"Down 15", 44 * time.Minute, 15, profile.RoundDown, 30 * time.Minute},
+		{"Up 6 seconds", 5 * time.Second, 6, profile.RoundUp, 6 * time.Minute},
+		{"Unknown mode uses nearest", 8 * time.Minute, 5, "sideways", 10 * time.Minute},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			rounded := roundTimerDuration(testCase.elapsed, testCase.minutes, testCase.mode)
+			if rounded != testCase.expected {
+				t.Errorf("Invalid rounded duration: [%s] wanted: [%s]", rounded, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestSplitTimerByDay(t *testing.T) {
+	t.Parallel()
+
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("Could not load timezone: [%s]", err)
+	}
+
+	testCases := []struct {
+		name     string
+		started  string
+		stopped  string
+		duration time.Duration
+		days     []string
+		hours    []float64
+	}{
+		{"Same day", "2017-11-20T14:00:00Z", "2017-11-20T16:30:00Z", 150 * time.Minute, []string{"2017-11-20"}, []float64{2.5}},
+		{"Crosses midnight in New York", "2017-11-21T04:00:00Z", "2017-11-21T07:00:00Z", 3 * time.Hour, []string{"2017-11-20", "2017-11-21"}, []float64{1, 2}},
+		{"UTC midnight is not a boundary", "2017-11-20T23:00:00Z", "2017-11-21T01:00:00Z", 2 * time.Hour, []string{"2017-11-20"}, []float64{2}},
+		{"Rounded up added to last day", "2017-11-21T04:00:00Z", "2017-11-21T06:50:00Z", 3 * time.Hour, []string{"2017-11-20", "2017-11-21"}, []float64{1, 2}},
+		{"Rounded down fills earlier day first", "2017-11-21T04:30:00Z", "2017-11-21T05:10:00Z", 30 * time.Minute, []string{"2017-11-20"}, []float64{0.5}},
+		{"Zero duration", "2017-11-20T14:00:00Z", "2017-11-20T14:05:00Z", 0, []string{}, []float64{}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			started, _ := time.Parse(time.RFC3339, testCase.started)
+			stopped, _ := time.Parse(time.RFC3339, testCase.stopped)
+
+			entries := splitTimerByDay(started, stopped, testCase.duration, newYork)
+			if len(entries) != len(testCase.days) {
+				t.Fatalf("Wrong entry count: [%d] wanted: [%d]", len(entries), len(testCase.days))
+			}
+
+			for i, entry := range entries {
+				if entry.Day.Format(config.ISOShortDateFormat) != testCase.days[i] {
+					t.Errorf("Invalid day: [%s] wanted: [%s]", entry.Day.Format(config.ISOShortDateFormat), testCase.days[i])
+				}
+
+				if entry.Hours != testCase.hours[i] {
+					t.Errorf("Invalid hours for [%s]: [%g] wanted: [%g]", testCase.days[i], entry.Hours, testCase.hours[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBudgetPercentUsedAndStatus(t *testing.T) {
+	t.Parallel()
+
+	thresholds := []int{80, 100}
+	none := sql.NullFloat64{}
+	testCases := []struct {
+		name         string
+		budgetHours  sql.NullFloat64
+		budgetAmount sql.NullFloat64
+		spentHours   float64
+		spentAmount  float64
+		percent      float64
+		status       BudgetStatus
+	}{
+		{"No budget", none, none, 10, 500, 0, BudgetNone},
+		{"Hours on track", sql.NullFloat64{Float64: 100, Valid: true}, none, 50, 0, 50, BudgetOnTrack},
+		{"Hours warning", sql.NullFloat64{Float64: 100, Valid: true}, none, 80, 0, 80, BudgetWarning},
+		{"Amount over", none, sql.NullFloat64{Float64: 1000, Valid: true}, 0, 1250, 125, BudgetOver},
+		{"Larger of hours and amount", sql.NullFloat64{Float64: 10, Valid: true}, sql.NullFloat64{Float64: 1000, Valid: true}, 9, 100, 90, BudgetWarning},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			percent := BudgetPercentUsed(testCase.budgetHours, testCase.budgetAmount, testCase.spentHours, testCase.spentAmount)
+			if percent != testCase.percent {
+				t.Errorf("Invalid percent used: [%g] wanted: [%g]", percent, testCase.percent)
+			}
+
+			status := GetBudgetStatus(testCase.budgetHours, testCase.budgetAmount, percent, thresholds)
+			if status != testCase.status {
+				t.Errorf("Invalid status: [%s] wanted: [%s]", status, testCase.status)
+			}
+		})
+	}
+}
+
+func TestCrossedBudgetThreshold(t *testing.T) {
+	t.Parallel()
+
+	thresholds := []int{80, 100}
+	testCases := []struct {
+		name         string
+		percent      float64
+		alertPercent int
+		expected     int
+	}{
+		{"Below thresholds", 79.99, 0, 0},
+		{"Cross first threshold", 80, 0, 80},
+		{"Already alerted", 95, 80, 0},
+		{"Cross second threshold", 101, 80, 100},
+		{"Cross both at once", 150, 0, 100},
+		{"Already alerted at 100", 150, 100, 0},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			crossed := crossedBudgetThreshold(testCase.percent, testCase.alertPercent, thresholds)
+			if crossed != testCase.expected {
+				t.Errorf("Invalid threshold: [%d] wanted: [%d]", crossed, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestReminderWeek(t *testing.T) {
+	t.Parallel()
+
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("Timezone data not available")
+	}
+
+	reminded := func(date string) pq.NullTime {
+		week, _ := time.Parse(config.ISOShortDateFormat, date)
+		return pq.NullTime{Time: week, Valid: true}
+	}
+
+	testCases := []struct {
+		name         string
+		now          time.Time
+		location     *time.Location
+		weekStart    time.Weekday
+		lastReminded pq.NullTime
+		week         string
+		due          bool
+	}{
+		{"Monday after the hour", time.Date(2021, 3, 8, 9, 30, 0, 0, time.UTC), time.UTC, time.Monday, reminded("2021-02-22"), "2021-03-01", true},
+		{"Monday before the hour", time.Date(2021, 3, 8, 8, 59, 0, 0, time.UTC), time.UTC, time.Monday, reminded("2021-02-22"), "2021-02-22", false},
+		{"Already reminded", time.Date(2021, 3, 8, 10, 0, 0, 0, time.UTC), time.UTC, time.Monday, reminded("2021-03-01"), "2021-03-01", false},
+		{"Never reminded", time.Date(2021, 3, 8, 9, 30, 0, 0, time.UTC), time.UTC, time.Monday, pq.NullTime{}, "2021-03-01", true},
+		{"Late run", time.Date(2021, 3, 10, 15, 0, 0, 0, time.UTC), time.UTC, time.Monday, reminded("2021-02-22"), "2021-03-01", true},
+		{"Late run already reminded", time.Date(2021, 3, 9, 9, 30, 0, 0, time.UTC), time.UTC, time.Monday, reminded("2021-03-01"), "2021-03-01", false},
+		{"Missed week", time.Date(2021, 3, 9, 9, 30, 0, 0, time.UTC), time.UTC, time.Monday, reminded("2021-02-15"), "2021-03-01", true},
+		{"Sunday week start", time.Date(2021, 3, 7, 10, 0, 0, 0, time.UTC), time.UTC, time.Sunday, reminded("2021-02-21"), "2021-02-28", true},
+		{"Still Sunday in New York", time.Date(2021, 3, 8, 3, 0, 0, 0, time.UTC), newYork, time.Monday, reminded("2021-02-22"), "2021-02-22", false},
+		{"Monday in New York", time.Date(2021, 3, 8, 14, 0, 0, 0, time.UTC), newYork, time.Monday, reminded("2021-02-22"), "2021-03-01", true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			week, due := reminderWeek(testCase.now, testCase.location, testCase.weekStart, 9, testCase.lastReminded)
+			if week.Format(config.ISOShortDateFormat) != testCase.week || due != testCase.due {
+				t.Errorf("Reminder week: [%s] due: [%t] wanted: [%s] [%t]", week.Format(config.ISOShortDateFormat), due, testCase.week, testCase.due)
+			}
+		})
+	}
+}
+
+// Only CheckAccountMember is called when resolving the target profile
+type memberTimeService struct {
+	TimeService
+	members map[int]bool
+}
+
+func (s *memberTimeService) CheckAccountMember(profileId int, accountId int) *api.Error {
+	if !s.members[profileId] {
+		return api.NewError(nil, "Profile is not a member of the account", api.ProfileNotFound)
+	}
+
+	return nil
+}
+
+func TestGetTargetProfileId(t *testing.T) {
+	t.Parallel()
+
+	router := &TimeRouter{timeService: &memberTimeService{members: map[int]bool{20: true}}}
+	admin := &profile.Profile{ProfileId: 10, Account: profile.Account{AccountId: 1}, Role: profile.Admin}
+	user := &profile.Profile{ProfileId: 10, Account: profile.Account{AccountId: 1}, Role: profile.User}
+
+	testCases := []struct {
+		name      string
+		profile   *profile.Profile
+		method    string
+		query     string
+		profileId int
+		errorCode string
+	}{
+		{"No profileId", user, "GET", "", 10, ""},
+		{"Own profileId", user, "GET", "?profileId=10", 10, ""},
+		{"Admin views member", admin, "GET", "?profileId=20", 20, ""},
+		{"Admin changes member", admin, "PUT", "?profileId=20", 20, ""},
+		{"User views member", user, "GET", "?profileId=20", 0, api.NotAuthorized},
+		{"Admin views non-member", admin, "GET", "?profileId=30", 0, api.ProfileNotFound},
+		{"Invalid profileId", admin, "GET", "?profileId=abc", 0, api.InvalidField},
+		{"Negative profileId", admin, "GET", "?profileId=-20", 0, api.InvalidField},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			r := httptest.NewRequest(testCase.method, "/api/time/week"+testCase.query, nil)
+			profileId, appErr := router.getTargetProfileId(r, testCase.profile)
+			if testCase.errorCode != "" {
+				if appErr == nil || appErr.Code != testCase.errorCode {
+					t.Errorf("Got error %v, wanted code %s", appErr, testCase.errorCode)
+				}
+				return
+			}
+
+			if appErr != nil {
+				t.Fatalf("Unexpected error: %v", appErr)
+			}
+
+			if profileId != testCase.profileId {
+				t.Errorf("Got profile %d, wanted %d", profileId, testCase.profileId)
+			}
+		})
+	}
+}
+
+func TestParseTimeImport(t *testing.T) {
+	t.Parallel()
+
+	file := "\ufeffDate,Person Email,Client,Project,Task,Hours,Notes\n" +
+		"2019-01-07,ann@example.com,ACME,Website,Design,2.5,\"Home page, nav\"\n" +
+		",,,,,,\n" +
+		"\n" +
+		"2019-01-08,ann@example.com,ACME,Website,Design,1\n"
+
+	rows, appErr := ParseTimeImport(strings.NewReader(file))
+	if appErr != nil {
+		t.Fatalf("Unexpected error: %v", appErr)
+	}
+
+	if len(rows) != 2 || rows[0].Row != 2 || rows[0].Notes != "Home page, nav" || rows[1].Row != 5 || rows[1].Hours != "1" || rows[1].Notes != "" {
+		t.Fatalf("Invalid rows: [%+v] [%+v]", rows[0], rows[1])
+	}
+
+	testCases := []struct {
+		name  string
+		file  string
+		field interface{}
+	}{
+		{"Empty file", "", nil},
+		{"Header only", "date,email,client,project,task,hours\n", nil},
+		{"Missing column", "date,email,client,project,hours\n2019-01-07,a@b.com,ACME,Website,1\n", "task"},
+		{"Invalid quotes", "date,email,client,project,task,hours\n2019-01-07,\"a@b.com,ACME,Website,Design,1\n", nil},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, appErr := ParseTimeImport(strings.NewReader(testCase.file))
+			if appErr == nil || appErr.Code != api.InvalidImport {
+				t.Fatalf("Got error %v, wanted code %s", appErr, api.InvalidImport)
+			}
+
+			if testCase.field != nil && appErr.Detail["field"] != testCase.field {
+				t.Errorf("Got field %v, wanted %v", appErr.Detail["field"], testCase.field)
+			}
+		})
+	}
+}
+
+func TestResolveTimeImport(t *testing.T) {
+	t.Parallel()
+
+	lookup := newImportLookup(
+		[]*ImportMember{{ProfileId: 10, Email: "Ann@Example.com"}},
+		[]*ImportProjectTask{
+			{ClientName: "ACME", ProjectId: 20, ProjectName: "Website", ProjectCode: sql.NullString{String: "WEB", Valid: true}, TaskId: 30, TaskName: "Design"},
+			{ClientName: "ACME", ProjectId: 21, ProjectName: "Website", TaskId: 31, TaskName: "Design"},
+		})
+
+	row := func(number int, date string, email string, project string, task string, hours string) *ImportRow {
+		return &ImportRow{Row: number, Date: date, Email: email, Client: "acme", Project: project, Task: task, Hours: hours}
+	}
+
+	rows := []*ImportRow{
+		row(2, "2019-01-07", "ann@example.com", "website", "design", "2.5"),
+		row(3, "2019-01-08", "ANN@example.com", "WEB", "Design", "8"),
+		row(4, "2019-01-07", "ann@example.com", "Website", "Design", "1"),
+		row(5, "01/09/2019", "bob@example.com", "Website", "Design", "0"),
+		row(6, "2019-01-09", "ann@example.com", "Mobile", "Design", "1"),
+		row(7, "2019-01-09", "ann@example.com", "Website", "Testing", "25"),
+		{Row: 8, Date: "2019-01-09", Email: "ann@example.com", Client: "Globex", Project: "Website", Task: "Design", Hours: "1"},
+	}
+
+	entries, rowErrors := lookup.resolve(rows, 1, 10)
+	if len(entries) != 2 {
+		t.Fatalf("Wrong number of entries: [%d] wanted: [2]", len(entries))
+	}
+
+	for _, entry := range entries {
+		if entry.AccountId != 1 || entry.ProfileId != 10 || entry.ProjectId != 20 || entry.TaskId != 30 || entry.UpdatedBy.Int64 != 10 {
+			t.Errorf("Invalid entry: [%+v]", entry)
+		}
+	}
+
+	expected := []struct {
+		row   int
+		field string
+		code  string
+	}{
+		{4, "date", api.InvalidField},
+		{5, "date", api.InvalidField},
+		{5, "email", api.ProfileNotFound},
+		{5, "hours", api.InvalidField},
+		{6, "project", api.InvalidProject},
+		{7, "task", api.InvalidTask},
+		{7, "hours", api.InvalidField},
+		{8, "client", api.InvalidClient},
+	}
+
+	if len(rowErrors) != len(expected) {
+		t.Fatalf("Wrong number of errors: [%d] wanted: [%d] %v", len(rowErrors), len(expected), rowErrors)
+	}
+
+	for i, rowError := range rowErrors {
+		if rowError.Detail["row"] != expected[i].row || rowError.Detail["field"] != expected[i].field || rowError.Code != expected[i].code {
+			t.Errorf("Got error [%v] %v, wanted row %d field %s code %s", rowError, rowError.Detail, expected[i].row, expected[i].field, expected[i].code)
+		}
+	}
+}
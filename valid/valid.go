@@ -65,6 +65,15 @@ func ToNullString(s string) sql.NullString {
 	return sql.NullString{String: s, Valid: s != ""}
 }
 
+// A nil string is NULL and an empty string is valid, so an omitted JSON field can be told apart from a cleared one
+func PtrToNullString(s *string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+
+	return sql.NullString{String: *s, Valid: true}
+}
+
 func ToNullTime(t time.Time) pq.NullTime {
 	return pq.NullTime{Time: t, Valid: !t.IsZero()}
 }
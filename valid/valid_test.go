@@ -177,6 +177,31 @@ func TestNullString(t *testing.T) {
 	}
 }
 
+func TestPtrToNullString(t *testing.T) {
+	t.Parallel()
+
+	empty := ""
+	notes := "notes"
+
+	testCases := []struct {
+		name   string
+		value  *string
+		result sql.NullString
+	}{
+		{"Nil", nil, sql.NullString{}},
+		{"Empty String", &empty, sql.NullString{String: "", Valid: true}},
+		{"Not Empty", &notes, sql.NullString{String: "notes", Valid: true}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if result := PtrToNullString(testCase.value); result != testCase.result {
+				t.Errorf("Expected %v got %v", testCase.result, result)
+			}
+		})
+	}
+}
+
 func TestIsTimeZone(t *testing.T) {
 	t.Parallel()
 